@@ -1,13 +1,17 @@
 package main
 
 import (
+	"context"
 	"errors"
+	"fmt"
 	"io"
 	"io/fs"
+	"net"
 	"net/http"
 	"os"
 	"os/signal"
 	"path"
+	"strconv"
 	"strings"
 	"syscall"
 	"time"
@@ -90,13 +94,11 @@ func main() {
 
 	// Setup zerolog
 	zerolog.TimeFieldFormat = time.RFC3339
-	level, err := zerolog.ParseLevel(string(cfg.LogLevel))
-	if err != nil {
-		level = zerolog.InfoLevel
-	}
-	zerolog.SetGlobalLevel(level)
+	setLogLevel(cfg.LogLevel)
 	// Pretty console output for development
 	log.Logger = log.Output(zerolog.ConsoleWriter{Out: os.Stderr, TimeFormat: "15:04:05"})
+	// log.Ctx falls back to the global logger for contexts without a request's
+	zerolog.DefaultContextLogger = &log.Logger
 
 	// Validate config
 	if err := cfg.Validate(); err != nil {
@@ -107,21 +109,41 @@ func main() {
 		Int("port", cfg.Port).
 		Str("data_dir", cfg.DataDir).
 		Str("runtime", cfg.Runtime).
+		Str("storage", cfg.StorageBackend).
 		Str("socket", cfg.Socket).
 		Msg("Starting DBnest")
 
+	// Register custom engines alongside the built-ins
+	if cfg.EnginesFile != "" {
+		types, err := database.LoadCustomEngines(cfg.EnginesFile)
+		if err != nil {
+			log.Fatal().Err(err).Msg("Failed to load custom engines")
+		}
+		log.Info().Strs("engines", types).Msg("Registered custom engines")
+	}
+
 	// Initialize storage
-	store, err := storage.New(cfg.StoragePath(), cfg.DataDir)
+	store, err := storage.Open(cfg.StorageBackend, cfg.StoragePath(), cfg.StorageDSN, cfg.DataDir)
 	if err != nil {
 		log.Fatal().Err(err).Msg("Failed to initialize storage")
 	}
 	defer store.Close()
 
 	// Initialize container runtime client
-	runtimeClient, err := cruntime.New(cfg.Runtime, cfg.Socket, cfg.DockerNetwork())
-	if err != nil {
-		log.Fatal().Err(err).Msg("Failed to initialize container runtime")
+	var registries []cruntime.RegistryAuth
+	if cfg.RegistryServer != "" {
+		registries = append(registries, cruntime.RegistryAuth{
+			Server:   cfg.RegistryServer,
+			Username: cfg.RegistryUsername,
+			Password: cfg.RegistryPassword,
+		})
 	}
+	// An unreachable runtime doesn't stop DBnest starting: stored data stays
+	// available and runtime operations fail until the daemon comes back.
+	// A daemon restart later on is reconnected to the same way.
+	runtimeClient := cruntime.NewRecovering(func() (cruntime.Client, error) {
+		return cruntime.New(cfg.Runtime, cfg.Socket, cfg.RuntimeTLS(), cfg.DockerNetwork(), registries)
+	})
 	defer func(runtimeClient cruntime.Client) {
 		err := runtimeClient.Close()
 		if err != nil {
@@ -131,15 +153,22 @@ func main() {
 
 	// Initialize database manager
 	dbManager := database.NewManager(store, runtimeClient)
+	dbManager.SetOptions(managerOptions(cfg))
+	dbManager.FailInterruptedProvisioning()
 
 	// Initialize and start scheduler (handles backups + status sync)
 	backupScheduler := scheduler.New(store, dbManager)
+	backupScheduler.SetOptions(schedulerOptions(cfg))
 	if err := backupScheduler.Start(); err != nil {
 		log.Fatal().Err(err).Msg("Failed to start scheduler")
 	}
 
 	// Create API server (auth always enabled)
-	apiServer := api.NewServer(dbManager, store, runtimeClient)
+	apiServer := api.NewServer(dbManager, store, runtimeClient, backupScheduler)
+	apiServer.SetOptions(apiOptions(cfg))
+
+	// Apply config changes live on SIGHUP
+	go reloadOnSIGHUP(cfg, dbManager, apiServer, backupScheduler)
 
 	// Setup routes
 	mux := http.NewServeMux()
@@ -147,13 +176,21 @@ func main() {
 	// API routes
 	mux.Handle("/api/", apiServer.Handler())
 
-	subFS, err := fs.Sub(frontendContent, "dist")
-	if err != nil {
-		log.Fatal().Err(err).Msg("Failed to get frontend filesystem")
+	// Frontend routes
+	switch {
+	case cfg.NoUI:
+		log.Info().Msg("Web UI disabled, serving API only")
+	case cfg.UIDir != "":
+		log.Info().Str("dir", cfg.UIDir).Msg("Serving frontend from directory")
+		mux.Handle("/", spaFileServer(http.Dir(cfg.UIDir)))
+	default:
+		subFS, err := fs.Sub(frontendContent, "dist")
+		if err != nil {
+			log.Fatal().Err(err).Msg("Failed to get frontend filesystem")
+		}
+		log.Info().Msg("Serving embedded frontend")
+		mux.Handle("/", spaFileServer(http.FS(subFS)))
 	}
-	log.Info().Msg("Serving embedded frontend")
-	frontendHandler := spaFileServer(http.FS(subFS))
-	mux.Handle("/", frontendHandler)
 
 	// Start server
 	addr := cfg.Addr()
@@ -162,21 +199,151 @@ func main() {
 		Handler: mux,
 	}
 
-	// Graceful shutdown
+	// Optional plain HTTP listener that redirects to HTTPS
+	var redirectServer *http.Server
+	if cfg.TLSEnabled() && cfg.HTTPRedirectPort != 0 {
+		redirectServer = &http.Server{
+			Addr:    fmt.Sprintf(":%d", cfg.HTTPRedirectPort),
+			Handler: httpsRedirect(cfg.Port),
+		}
+		go func() {
+			log.Info().Str("addr", redirectServer.Addr).Msg("Redirecting HTTP to HTTPS")
+			if err := redirectServer.ListenAndServe(); !errors.Is(err, http.ErrServerClosed) {
+				log.Error().Err(err).Msg("HTTP redirect server error")
+			}
+		}()
+	}
+
+	// Graceful shutdown: drain HTTP requests first, then stop the scheduler.
+	// The runtime client and store are closed by the deferred calls once main returns.
+	shutdownDone := make(chan struct{})
 	go func() {
+		defer close(shutdownDone)
 		sigChan := make(chan os.Signal, 1)
 		signal.Notify(sigChan, syscall.SIGINT, syscall.SIGTERM)
 		<-sigChan
 
-		log.Info().Msg("Shutting down server...")
-		backupScheduler.Stop() // Stop scheduler (backups + status sync)
-		if err := server.Close(); err != nil {
-			log.Error().Err(err).Msg("Error closing server")
+		log.Info().Dur("timeout", cfg.ShutdownTimeout).Msg("Shutting down server...")
+		ctx, cancel := context.WithTimeout(context.Background(), cfg.ShutdownTimeout)
+		defer cancel()
+
+		if redirectServer != nil {
+			redirectServer.Shutdown(ctx)
+		}
+		if err := server.Shutdown(ctx); err != nil {
+			// Long-lived streams (logs, metrics, shells) may still be open
+			log.Warn().Err(err).Msg("Timed out waiting for requests, closing remaining connections")
+			server.Close()
 		}
+
+		backupScheduler.Stop() // Stop scheduler (backups + status sync)
 	}()
 
-	log.Info().Str("addr", addr).Msg("Server started")
-	if err := server.ListenAndServe(); !errors.Is(err, http.ErrServerClosed) {
+	if cfg.TLSEnabled() {
+		log.Info().Str("addr", addr).Msg("Server started with TLS")
+		err = server.ListenAndServeTLS(cfg.TLSCert, cfg.TLSKey)
+	} else {
+		log.Info().Str("addr", addr).Msg("Server started")
+		err = server.ListenAndServe()
+	}
+	if !errors.Is(err, http.ErrServerClosed) {
 		log.Fatal().Err(err).Msg("Server error")
 	}
+
+	// Serve returns as soon as shutdown starts; wait for it to finish
+	<-shutdownDone
+	log.Info().Msg("Server stopped")
+}
+
+// httpsRedirect redirects every request to the same host and path over HTTPS on httpsPort
+func httpsRedirect(httpsPort int) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		host, _, err := net.SplitHostPort(r.Host)
+		if err != nil {
+			// No port in the Host header
+			host = strings.Trim(r.Host, "[]")
+		}
+		target := "https://" + net.JoinHostPort(host, strconv.Itoa(httpsPort)) + r.URL.RequestURI()
+		http.Redirect(w, r, target, http.StatusMovedPermanently)
+	})
+}
+
+// setLogLevel sets the global log level, falling back to info if it is invalid
+func setLogLevel(l config.LogLevel) {
+	level, err := zerolog.ParseLevel(string(l))
+	if err != nil {
+		level = zerolog.InfoLevel
+	}
+	zerolog.SetGlobalLevel(level)
+}
+
+// managerOptions maps the config onto database manager settings
+func managerOptions(cfg *config.Config) database.Options {
+	return database.Options{
+		MetricsInterval:  cfg.MetricsInterval,
+		MetricsRetention: cfg.MetricsRetention,
+
+		DefaultMemoryLimit:  cfg.DefaultMemoryMB,
+		DefaultCPULimit:     cfg.DefaultCPU,
+		DefaultStorageLimit: cfg.DefaultStorageMB,
+
+		DefaultRestartPolicy:  cfg.RestartPolicy,
+		DefaultBindAddress:    cfg.BindAddress,
+		DefaultRunAsUser:      cfg.RunAsUser,
+		DefaultRunAsGroup:     cfg.RunAsGroup,
+		DefaultUsernsMode:     cfg.UsernsMode,
+		DefaultReadOnlyRootfs: cfg.ReadOnlyRootfs,
+		ContainerSecurity: cruntime.SecurityOptions{
+			NoNewPrivileges: cfg.NoNewPrivileges,
+			SeccompProfile:  cfg.SeccompProfile,
+			CapDrop:         cfg.CapDrop,
+		},
+
+		MemoryOvercommitRatio: cfg.MemoryOvercommit,
+		HostMemoryLimit:       cfg.HostMemoryMB,
+
+		HostDataRoot: cfg.HostDataRoot,
+
+		PullAttempts:         cfg.PullAttempts,
+		PullBackoff:          cfg.PullBackoff,
+		ProvisionConcurrency: cfg.ProvisionConcurrency,
+
+		QueryRowLimit: cfg.QueryRowLimit,
+
+		BackupDir:            cfg.BackupDir,
+		BackupBinlogPosition: cfg.BackupBinlogPosition,
+		BackupEncryptionKey:  cfg.BackupKey,
+	}
+}
+
+// schedulerOptions maps the config onto scheduler settings
+func schedulerOptions(cfg *config.Config) scheduler.Options {
+	return scheduler.Options{
+		BackupAttempts: cfg.BackupAttempts,
+		BackupBackoff:  cfg.BackupBackoff,
+	}
+}
+
+// apiOptions maps the config onto API server settings
+func apiOptions(cfg *config.Config) api.Options {
+	return api.Options{
+		AuthRateLimit:      cfg.AuthRateLimit,
+		AuthRateWindow:     cfg.AuthRateWindow,
+		HealthCheckTimeout: cfg.HealthCheckTimeout,
+		CORSOrigins:        cfg.CORSOrigins,
+		BulkConcurrency:    cfg.BulkConcurrency,
+		QueryHistorySize:   cfg.QueryHistorySize,
+		Runtime:            cfg.Runtime,
+		RuntimeMode:        runtimeMode(cfg.Socket),
+
+		RequireDeleteConfirmation: cfg.RequireDeleteConfirmation,
+	}
+}
+
+// runtimeMode reports how the runtime is driven, matching runtime.New
+func runtimeMode(socket string) string {
+	if socket != "" {
+		return "SDK"
+	}
+	return "CLI"
 }
@@ -0,0 +1,158 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"os/signal"
+	"reflect"
+	"syscall"
+
+	"github.com/rs/zerolog/log"
+	"github.com/sirrobot01/dbnest/pkg/api"
+	"github.com/sirrobot01/dbnest/pkg/config"
+	"github.com/sirrobot01/dbnest/pkg/database"
+	"github.com/sirrobot01/dbnest/pkg/scheduler"
+)
+
+// restartOnlySettings are config fields read once at startup. Changes to them
+// are reported on reload but only take effect after a restart.
+var restartOnlySettings = map[string]bool{
+	"ConfigFile":       true,
+	"Port":             true,
+	"DataDir":          true,
+	"Socket":           true,
+	"Runtime":          true,
+	"RuntimeTLSCA":     true,
+	"RuntimeTLSCert":   true,
+	"RuntimeTLSKey":    true,
+	"TLSCert":          true,
+	"TLSKey":           true,
+	"HTTPRedirectPort": true,
+	"ShutdownTimeout":  true,
+	"StorageBackend":   true,
+	"StorageDSN":       true,
+	"EnginesFile":      true,
+	"NoUI":             true,
+	"UIDir":            true,
+	"RegistryServer":   true,
+	"RegistryUsername": true,
+	"RegistryPassword": true,
+}
+
+// liveSettings are config fields applied on reload. A field listed in neither
+// this nor restartOnlySettings is treated as restart-only.
+var liveSettings = map[string]bool{
+	"LogLevel":                  true,
+	"BackupDir":                 true,
+	"BackupBinlogPosition":      true,
+	"BackupKeyFile":             true,
+	"BackupKey":                 true,
+	"BackupAttempts":            true,
+	"BackupBackoff":             true,
+	"AuthRateLimit":             true,
+	"AuthRateWindow":            true,
+	"CORSOrigins":               true,
+	"HealthCheckTimeout":        true,
+	"BulkConcurrency":           true,
+	"QueryRowLimit":             true,
+	"QueryHistorySize":          true,
+	"RequireDeleteConfirmation": true,
+	"DefaultMemoryMB":           true,
+	"DefaultCPU":                true,
+	"DefaultStorageMB":          true,
+	"RestartPolicy":             true,
+	"BindAddress":               true,
+	"RunAs":                     true,
+	"RunAsUser":                 true,
+	"RunAsGroup":                true,
+	"UsernsMode":                true,
+	"ReadOnlyRootfs":            true,
+	"NoNewPrivileges":           true,
+	"SeccompProfile":            true,
+	"CapDrop":                   true,
+	"MemoryOvercommit":          true,
+	"HostMemoryMB":              true,
+	"HostDataRoot":              true,
+	"PullAttempts":              true,
+	"PullBackoff":               true,
+	"ProvisionConcurrency":      true,
+	"MetricsInterval":           true,
+	"MetricsRetention":          true,
+}
+
+// secretSettings are never written to the log
+var secretSettings = map[string]bool{
+	"StorageDSN":       true,
+	"RegistryPassword": true,
+	"BackupKey":        true,
+}
+
+// reloadOnSIGHUP re-reads the config on every SIGHUP and applies the settings
+// that can change live: log level, manager, API and scheduler options, and backup schedules
+func reloadOnSIGHUP(cfg *config.Config, dbManager *database.Manager, apiServer *api.Server, backupScheduler *scheduler.Scheduler) {
+	sigChan := make(chan os.Signal, 1)
+	signal.Notify(sigChan, syscall.SIGHUP)
+
+	current := *cfg
+	for range sigChan {
+		log.Info().Str("config_file", current.ConfigFile).Msg("Received SIGHUP, reloading config")
+
+		next, err := config.Load(os.Args[1:])
+		if err == nil {
+			err = next.Validate()
+		}
+		if err != nil {
+			log.Error().Err(err).Msg("Config reload failed, keeping current config")
+			continue
+		}
+
+		if changed := applyConfigChanges(&current, next); changed == 0 {
+			log.Info().Msg("Config reloaded, no live settings changed")
+		}
+
+		setLogLevel(current.LogLevel)
+		dbManager.SetOptions(managerOptions(&current))
+		apiServer.SetOptions(apiOptions(&current))
+		backupScheduler.SetOptions(schedulerOptions(&current))
+		if err := backupScheduler.Resync(); err != nil {
+			log.Error().Err(err).Msg("Failed to resync backup schedules")
+		}
+	}
+}
+
+// applyConfigChanges copies live settings from next into current, logging each
+// change, and warns about restart-only settings that differ. It returns how
+// many live settings changed.
+func applyConfigChanges(current, next *config.Config) int {
+	changed := 0
+	cur := reflect.ValueOf(current).Elem()
+	nxt := reflect.ValueOf(next).Elem()
+	for i := 0; i < cur.NumField(); i++ {
+		name := cur.Type().Field(i).Name
+		oldValue, newValue := cur.Field(i).Interface(), nxt.Field(i).Interface()
+		if reflect.DeepEqual(oldValue, newValue) {
+			continue
+		}
+
+		restartOnly := restartOnlySettings[name] || !liveSettings[name]
+		event := log.Info()
+		if restartOnly {
+			event = log.Warn()
+		}
+		if secretSettings[name] {
+			event = event.Str("old", "(redacted)").Str("new", "(redacted)")
+		} else {
+			event = event.Str("old", fmt.Sprint(oldValue)).Str("new", fmt.Sprint(newValue))
+		}
+		event = event.Str("setting", name)
+
+		if restartOnly {
+			event.Msg("Config change requires a restart to take effect")
+			continue
+		}
+		event.Msg("Config changed")
+		cur.Field(i).Set(nxt.Field(i))
+		changed++
+	}
+	return changed
+}
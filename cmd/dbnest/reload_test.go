@@ -0,0 +1,90 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/rs/zerolog"
+	"github.com/rs/zerolog/log"
+	"github.com/sirrobot01/dbnest/pkg/config"
+)
+
+func TestApplyConfigChanges(t *testing.T) {
+	var buf bytes.Buffer
+	logger := log.Logger
+	log.Logger = zerolog.New(&buf)
+	defer func() { log.Logger = logger }()
+
+	current := config.Config{
+		Port:             8080,
+		StorageDSN:       "postgres://dbnest:old-secret@db/dbnest",
+		RegistryPassword: "old-secret",
+		QueryRowLimit:    100,
+		BackupKey:        []byte("old-secret"),
+	}
+	next := current
+	next.Port = 9090
+	next.StorageDSN = "postgres://dbnest:new-secret@db/dbnest"
+	next.RegistryPassword = "new-secret"
+	next.QueryRowLimit = 500
+	next.BackupKey = []byte("new-secret")
+
+	if changed := applyConfigChanges(&current, &next); changed != 2 {
+		t.Errorf("expected 2 live settings changed, got %d", changed)
+	}
+
+	// Live settings are copied, restart-only ones are left alone
+	if current.QueryRowLimit != 500 || string(current.BackupKey) != "new-secret" {
+		t.Errorf("expected live settings to be applied, got row limit %d and key %q", current.QueryRowLimit, current.BackupKey)
+	}
+	if current.Port != 8080 || current.RegistryPassword != "old-secret" || !strings.Contains(current.StorageDSN, "old-secret") {
+		t.Errorf("expected restart-only settings to be kept, got %+v", current)
+	}
+
+	logged := map[string]map[string]string{}
+	for _, line := range strings.Split(strings.TrimSpace(buf.String()), "\n") {
+		var entry map[string]string
+		if err := json.Unmarshal([]byte(line), &entry); err != nil {
+			t.Fatalf("failed to parse log line %q: %v", line, err)
+		}
+		logged[entry["setting"]] = entry
+	}
+	if len(logged) != 5 {
+		t.Errorf("expected a log line per changed setting, got %v", logged)
+	}
+	for name := range secretSettings {
+		if entry, ok := logged[name]; !ok || entry["old"] != "(redacted)" || entry["new"] != "(redacted)" {
+			t.Errorf("expected %s to be logged redacted, got %v", name, entry)
+		}
+	}
+	if entry := logged["Port"]; entry["level"] != "warn" || entry["new"] != "9090" {
+		t.Errorf("expected a restart warning for Port, got %v", entry)
+	}
+	if strings.Contains(buf.String(), "secret") {
+		t.Errorf("expected no secrets in the log, got %s", buf.String())
+	}
+}
+
+// Every config field must be classified, so a new one isn't reloaded live by accident
+func TestConfigSettingsClassified(t *testing.T) {
+	fields := map[string]bool{}
+	typ := reflect.TypeOf(config.Config{})
+	for i := 0; i < typ.NumField(); i++ {
+		name := typ.Field(i).Name
+		fields[name] = true
+		if liveSettings[name] == restartOnlySettings[name] {
+			t.Errorf("config field %s must be listed in exactly one of liveSettings and restartOnlySettings", name)
+		}
+	}
+
+	for _, settings := range []map[string]bool{liveSettings, restartOnlySettings, secretSettings} {
+		for name := range settings {
+			if !fields[name] {
+				t.Errorf("%s is not a config field", name)
+			}
+		}
+	}
+}
@@ -30,7 +30,7 @@ interface CreateDatabaseModalProps {
     onCreate?: () => void;
 }
 
-type DatabaseEngine = "postgresql" | "mysql" | "mariadb" | "redis";
+type DatabaseEngine = "postgresql" | "mysql" | "mariadb" | "redis" | "clickhouse";
 
 const engineConfig: Record<DatabaseEngine, {
     name: string;
@@ -52,6 +52,10 @@ const engineConfig: Record<DatabaseEngine, {
         name: "Redis",
         versions: ["7", "7.2", "6"],
     },
+    clickhouse: {
+        name: "ClickHouse",
+        versions: ["24.8", "24.3", "23.8"],
+    },
 };
 
 interface FormData {
@@ -267,7 +267,7 @@ export function DatabaseCard({
                             size="sm"
                             className="flex-1 border-amber-500/50 text-amber-600 hover:bg-amber-500/10 hover:text-amber-700 hover:border-amber-500"
                             onClick={handleStop}
-                            disabled={database.status === "creating" || actionLoading === 'stop'}
+                            disabled={database.status === "creating" || database.status === "queued" || actionLoading === 'stop'}
                         >
                             {actionLoading === 'stop' ? (
                                 <Loader2 className="w-3.5 h-3.5 mr-1.5 animate-spin" />
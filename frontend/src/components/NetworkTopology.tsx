@@ -2,7 +2,7 @@
 
 import { useState, useMemo } from "react";
 import { Link } from "react-router-dom";
-import { TopologyNetwork } from "@/lib/api";
+import { TopologyEdge, TopologyNetwork } from "@/lib/api";
 import { Card } from "@/components/ui/card";
 import {
     Database,
@@ -16,6 +16,7 @@ import { cn } from "@/lib/utils";
 interface NetworkTopologyProps {
     className?: string;
     topology: TopologyNetwork[];
+    edges: TopologyEdge[];
     loading: boolean;
     error: string | null;
 }
@@ -38,11 +39,13 @@ interface Node {
 const statusColors: Record<string, string> = {
     running: "bg-green-500 shadow-[0_0_8px_rgba(34,197,94,0.6)]",
     stopped: "bg-amber-500",
+    paused: "bg-sky-500",
+    queued: "bg-slate-400 animate-pulse",
     creating: "bg-yellow-500 animate-pulse",
     error: "bg-red-500 animate-pulse",
 };
 
-export function NetworkTopology({ className, topology, loading, error }: NetworkTopologyProps) {
+export function NetworkTopology({ className, topology, edges, loading, error }: NetworkTopologyProps) {
     const [hoveredNode, setHoveredNode] = useState<string | null>(null);
 
     // Canvas dimensions
@@ -67,7 +70,7 @@ export function NetworkTopology({ className, topology, loading, error }: Network
         };
         nodes.push(hostNode);
 
-        if (topology.length === 0) return { nodes, links };
+        if (topology.length === 0) return { nodes, links, relations: [] };
 
         // 2. Calculate columns for Networks
         // Divide the width into equal columns for each network
@@ -128,8 +131,16 @@ export function NetworkTopology({ className, topology, loading, error }: Network
             });
         });
 
-        return { nodes, links };
-    }, [topology, width, centerX]);
+        // 4. Edges between databases, drawn as arcs below the database tier
+        const positions = new Map(nodes.filter(n => n.type === 'database').map(n => [n.id, n] as [string, Node]));
+        const relations = edges.flatMap(edge => {
+            const source = positions.get(edge.source);
+            const target = positions.get(edge.target);
+            return source && target ? [{ ...edge, from: { x: source.x, y: source.y + 30 }, to: { x: target.x, y: target.y + 30 } }] : [];
+        });
+
+        return { nodes, links, relations };
+    }, [topology, edges, width, centerX]);
 
     if (loading) {
         return (
@@ -177,6 +188,21 @@ export function NetworkTopology({ className, topology, loading, error }: Network
                             />
                         );
                     })}
+                    {graph.relations.map((edge, i) => {
+                        const isHovered = hoveredNode === edge.source || hoveredNode === edge.target;
+                        const depth = 40 + Math.abs(edge.to.x - edge.from.x) / 4;
+                        return (
+                            <path
+                                key={`edge-${i}`}
+                                d={`M ${edge.from.x} ${edge.from.y} C ${edge.from.x} ${edge.from.y + depth}, ${edge.to.x} ${edge.to.y + depth}, ${edge.to.x} ${edge.to.y}`}
+                                stroke={edge.type === 'clone' ? "rgba(245,158,11,0.6)" : "rgba(129,140,248,0.4)"}
+                                strokeWidth={isHovered ? 2 : 1}
+                                strokeDasharray={edge.type === 'clone' ? "4 4" : undefined}
+                                fill="none"
+                                className="transition-all duration-300"
+                            />
+                        );
+                    })}
                 </svg>
 
                 {/* HTML Layer for Nodes */}
@@ -278,6 +304,14 @@ export function NetworkTopology({ className, topology, loading, error }: Network
                 </div>
 
                 {/* Overlay Controls / Zoom (Optional Future Add) */}
+                <div className="absolute bottom-4 left-4 flex gap-4 text-xs text-zinc-500">
+                    <span className="flex items-center gap-1.5">
+                        <span className="w-4 border-t border-dashed border-amber-500" /> Cloned from
+                    </span>
+                    <span className="flex items-center gap-1.5">
+                        <span className="w-4 border-t border-indigo-400" /> Shared network
+                    </span>
+                </div>
                 <div className="absolute bottom-4 right-4 text-xs text-zinc-600">
                     Live Topology
                 </div>
@@ -1,10 +1,11 @@
 "use client";
 
-import { useState } from "react";
+import { useEffect, useState } from "react";
 import { Link, useLocation, useNavigate } from "react-router-dom";
 import { Button } from "@/components/ui/button";
 import { ThemeToggle } from "@/components/ThemeToggle";
 import { useAuth } from "@/components/AuthProvider";
+import { api } from "@/lib/api";
 import { cn } from "@/lib/utils";
 import {
     Database,
@@ -36,6 +37,13 @@ export function Sidebar({ onCreateDatabase }: SidebarProps) {
     const pathname = location.pathname;
     const [collapsed, setCollapsed] = useState(false);
     const { user, isAuthenticated, authEnabled, logout } = useAuth();
+    const [version, setVersion] = useState("");
+
+    useEffect(() => {
+        api.getVersion()
+            .then((info) => setVersion(info.version))
+            .catch(() => setVersion(""));
+    }, []);
 
     const handleLogout = async () => {
         await logout();
@@ -150,8 +158,8 @@ export function Sidebar({ onCreateDatabase }: SidebarProps) {
 
                 {/* Version and theme toggle */}
                 <div className={cn("flex items-center", collapsed ? "justify-center" : "justify-between")}>
-                    {!collapsed && (
-                        <span className="text-xs text-muted-foreground">v1.0.0</span>
+                    {!collapsed && version && (
+                        <span className="text-xs text-muted-foreground">{version}</span>
                     )}
                     <ThemeToggle />
                 </div>
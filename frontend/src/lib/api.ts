@@ -4,9 +4,16 @@ const API_BASE = '/api/v1';
 export interface DatabaseInstance {
     id: string;
     name: string;
-    engine: 'postgresql' | 'mysql' | 'mariadb' | 'redis';
+    engine: 'postgresql' | 'mysql' | 'mariadb' | 'redis' | 'clickhouse';
     version: string;
-    status: 'running' | 'stopped' | 'error' | 'creating';
+    image?: string; // Custom image overriding the engine default
+    imageDigest?: string; // Pinned image digest (sha256:...), resolved after the first pull
+    hostDataPath?: string; // Host directory bind-mounted as the data directory
+    extraEnv?: Record<string, string>; // Additional container env vars
+    configParams?: Record<string, string>; // Engine server settings
+    redisBackupMode?: 'rdb' | 'aof'; // Redis backup strategy
+    redisDb?: number; // Redis logical database index
+    status: 'running' | 'stopped' | 'paused' | 'error' | 'oom-killed' | 'starting' | 'unhealthy' | 'queued' | 'creating';
     host: string;
     port: number;
     username: string;
@@ -25,6 +32,16 @@ export interface DatabaseInstance {
     backupSchedule?: string; // cron expression
     backupRetentionCount?: number;
     lastBackupAt?: string;
+    lastBackup?: BackupResult; // outcome of the last scheduled backup
+    backupSkipUnchanged?: boolean;
+}
+
+export interface BackupResult {
+    success: boolean;
+    backupId?: string;
+    error?: string;
+    attempts: number;
+    at: string;
 }
 
 export interface Backup {
@@ -34,6 +51,30 @@ export interface Backup {
     createdAt: string;
     size: number;
     status: 'completed' | 'in-progress' | 'failed';
+    tables?: string[]; // set for partial backups
+    encrypted: boolean;
+    checksum?: string; // hex SHA-256 of the backup file
+    error?: string; // set for failed backups
+}
+
+export interface SchemaColumn {
+    name: string;
+    type: string;
+    nullable: boolean;
+}
+
+export interface SchemaTable {
+    schema?: string;
+    name: string;
+    type: 'table' | 'view';
+    columns?: SchemaColumn[];
+}
+
+export interface DatabaseSchema {
+    engine: string;
+    tables?: SchemaTable[];
+    keyPatterns?: { pattern: string; count: number }[]; // Redis
+    truncated?: boolean;
 }
 
 export interface DatabaseMetrics {
@@ -81,6 +122,11 @@ export interface TopologyNode {
     engine: string;
     status: string;
     network: string;
+    host: string;
+    port: number;
+    exposePort: boolean;
+    bindAddress?: string;
+    clonedFrom?: string;
 }
 
 export interface TopologyNetwork {
@@ -88,6 +134,51 @@ export interface TopologyNetwork {
     databases: TopologyNode[];
 }
 
+export interface TopologyEdge {
+    source: string;
+    target: string;
+    type: 'clone' | 'network';
+}
+
+export interface QueryResult {
+    statement?: string; // The statement of a multi-statement script this result is for
+    columns?: string[];
+    rows?: unknown[][];
+    message?: string;
+    error?: string;
+    rowCount: number;
+    truncated?: boolean; // Rows past the row limit were dropped
+}
+
+export interface QueryHistoryEntry {
+    id: string;
+    databaseId: string;
+    userId: string;
+    timestamp: string;
+    query: string;
+    rowCount: number;
+    success: boolean;
+    error?: string;
+}
+
+export interface SummaryError {
+    databaseId: string;
+    databaseName: string;
+    source: 'database' | 'backup' | 'scheduled-backup';
+    message: string;
+    at?: string;
+}
+
+export interface Summary {
+    databases: number;
+    byStatus: Record<string, number>;
+    byEngine: Record<string, number>;
+    storageUsed: number;
+    backups: number;
+    backupSize: number;
+    recentErrors: SummaryError[];
+}
+
 export interface BackupInfo {
     id: string;
     databaseId: string;
@@ -113,8 +204,20 @@ export interface MetricsHistoryPoint {
 
 export interface CreateDatabaseRequest {
     name: string;
-    engine: 'postgresql' | 'mysql' | 'mariadb' | 'redis';
+    engine: 'postgresql' | 'mysql' | 'mariadb' | 'redis' | 'clickhouse';
     version: string;
+    image?: string; // Optional custom image, e.g. timescale/timescaledb
+    imageDigest?: string; // Optional sha256:... digest pinning the exact image
+    hostDataPath?: string; // Optional absolute host directory to bind-mount instead of a volume
+    extraEnv?: Record<string, string>; // Extra engine env vars, e.g. POSTGRES_INITDB_ARGS
+    restartPolicy?: string; // "no", "always", "unless-stopped" or "on-failure[:N]"
+    runAsUser?: number; // Numeric UID the database runs as (default: server's --run-as-user, else the image's user)
+    runAsGroup?: number; // Numeric GID, used together with runAsUser
+    usernsMode?: string; // User namespace mode, e.g. "host", or "auto"/"keep-id" on podman
+    readOnlyRootfs?: boolean; // Read-only root filesystem (default: server's --read-only-rootfs)
+    redisBackupMode?: 'rdb' | 'aof'; // Redis only: back up RDB snapshots (default) or the AOF
+    redisDb?: number; // Redis only: logical database index (0-15)
+    labels?: Record<string, string>; // Extra container labels; "dbnest." keys are reserved
     username: string;
     password?: string; // Optional - auto-generated if not provided
     database: string;
@@ -122,6 +225,9 @@ export interface CreateDatabaseRequest {
     memoryLimit: number;
     network?: string; // Docker network name
     exposePort?: boolean; // Whether to bind port to host
+    bindAddress?: string; // Host IP to publish the port on (default: server's --bind-address)
+    maxConnections?: number; // Server connection limit, 1-10000 (default: 100)
+    configParams?: Record<string, string>; // Engine settings, e.g. { shared_buffers: '256MB' }
     // Restore from backup
     restoreFromBackupId?: string;
     // Backup settings
@@ -167,8 +273,34 @@ export interface LoginResponse extends User {
     token: string;
 }
 
+export interface RuntimeHealth {
+    name: string;
+    mode: "SDK" | "CLI";
+    version?: string;
+    reachable: boolean;
+    error?: string;
+}
+
+export interface VersionInfo {
+    version: string;
+    commit: string;
+    date: string;
+    goVersion: string;
+}
+
 export interface ApiError {
-    error: string;
+    code: string; // stable, e.g. DATABASE_NOT_FOUND
+    message: string;
+    details?: Record<string, unknown>;
+    error: string; // same as message
+}
+
+// ApiRequestError is thrown for error responses, carrying the error code
+export class ApiRequestError extends Error {
+    constructor(public status: number, public code: string, message: string, public details?: Record<string, unknown>) {
+        super(message);
+        this.name = 'ApiRequestError';
+    }
 }
 
 class ApiClient {
@@ -185,8 +317,13 @@ class ApiClient {
         });
 
         if (!response.ok) {
-            const error: ApiError = await response.json().catch(() => ({ error: 'Unknown error' }));
-            throw new Error(error.error || `HTTP ${response.status}`);
+            const error: Partial<ApiError> = await response.json().catch(() => ({}));
+            throw new ApiRequestError(
+                response.status,
+                error.code || 'UNKNOWN',
+                error.message || error.error || `HTTP ${response.status}`,
+                error.details,
+            );
         }
 
         // Handle 204 No Content
@@ -198,10 +335,15 @@ class ApiClient {
     }
 
     // Health
-    async health(): Promise<{ status: string; version: string }> {
+    // runtime is only detailed for signed-in callers
+    async health(): Promise<{ status: string; version: string; runtime: RuntimeHealth | "ok" | "unavailable" }> {
         return this.request('/health');
     }
 
+    async getVersion(): Promise<VersionInfo> {
+        return this.request('/version');
+    }
+
     // Databases
     async listDatabases(): Promise<DatabaseInstance[]> {
         const result = await this.request<DatabaseInstance[] | null>('/databases');
@@ -219,8 +361,14 @@ class ApiClient {
         });
     }
 
-    async deleteDatabase(id: string): Promise<void> {
-        await this.request(`/databases/${id}`, { method: 'DELETE' });
+    // confirm is the database's name, required when the server has
+    // --require-delete-confirmation on
+    async deleteDatabase(id: string, options: { keepBackups?: boolean; confirm?: string } = {}): Promise<void> {
+        const query = options.keepBackups ? '?keepBackups=true' : '';
+        await this.request(`/databases/${id}${query}`, {
+            method: 'DELETE',
+            body: JSON.stringify({ confirm: options.confirm }),
+        });
     }
 
     async startDatabase(id: string): Promise<DatabaseInstance> {
@@ -231,25 +379,55 @@ class ApiClient {
         return this.request(`/databases/${id}/stop`, { method: 'POST' });
     }
 
+    async pauseDatabase(id: string): Promise<DatabaseInstance> {
+        return this.request(`/databases/${id}/pause`, { method: 'POST' });
+    }
+
+    async resumeDatabase(id: string): Promise<DatabaseInstance> {
+        return this.request(`/databases/${id}/resume`, { method: 'POST' });
+    }
+
+    async getSchema(databaseId: string, columns = false): Promise<DatabaseSchema> {
+        return this.request(`/databases/${databaseId}/schema${columns ? '?columns=true' : ''}`);
+    }
+
+    // Results are capped at the server's --query-row-limit, or limit if lower.
+    // readOnly refuses writes; viewer API keys always run read-only.
+    async executeQuery(databaseId: string, query: string, options: { limit?: number; readOnly?: boolean } = {}): Promise<QueryResult[]> {
+        return this.request(`/databases/${databaseId}/query`, {
+            method: 'POST',
+            body: JSON.stringify({ query, ...options }),
+        });
+    }
+
+    // The current user's console queries for a database, newest first
+    async getQueryHistory(databaseId: string): Promise<QueryHistoryEntry[]> {
+        return this.request(`/databases/${databaseId}/query/history`);
+    }
+
+    async clearQueryHistory(databaseId: string): Promise<void> {
+        await this.request(`/databases/${databaseId}/query/history`, { method: 'DELETE' });
+    }
+
     async getMetrics(databaseId: string): Promise<DatabaseMetrics> {
         return this.request(`/databases/${databaseId}/metrics`);
     }
 
-    async restoreBackup(databaseId: string, backupId: string): Promise<void> {
+    async restoreBackup(databaseId: string, backupId: string, tables?: string[]): Promise<void> {
         await this.request(`/databases/${databaseId}/restore`, {
             method: 'POST',
-            body: JSON.stringify({ backupId }),
+            body: JSON.stringify({ backupId, tables }),
         });
     }
 
-    async updateBackupSettings(id: string, settings: { backupEnabled: boolean; backupSchedule: string; backupRetentionCount: number }): Promise<DatabaseInstance> {
+    async updateBackupSettings(id: string, settings: { backupEnabled: boolean; backupSchedule: string; backupRetentionCount: number; backupSkipUnchanged?: boolean }): Promise<DatabaseInstance> {
         return this.request(`/databases/${id}/backup-settings`, {
             method: 'PUT',
             body: JSON.stringify(settings),
         });
     }
 
-    async updateResources(id: string, memoryLimit: number, cpuLimit: number): Promise<DatabaseInstance> {
+    async updateResources(id: string, memoryLimit: number, cpuLimit: number): Promise<DatabaseInstance & { restartRequired?: boolean; message?: string }> {
         return this.request(`/databases/${id}/resources`, {
             method: 'PATCH',
             body: JSON.stringify({ memoryLimit: memoryLimit * 1024 * 1024, cpuLimit }), // Convert MB to bytes
@@ -260,8 +438,9 @@ class ApiClient {
         return this.request(`/databases/${id}/credentials`);
     }
 
-    async getConnectionExamples(id: string): Promise<ConnectionExample[]> {
-        return this.request(`/databases/${id}/connection-strings`);
+    // reveal fills in the real password (admin only, audited)
+    async getConnectionExamples(id: string, reveal = false): Promise<ConnectionExample[]> {
+        return this.request(`/databases/${id}/connection-strings${reveal ? '?reveal=true' : ''}`);
     }
 
     async getBackupInfo(backupId: string): Promise<BackupInfo> {
@@ -276,6 +455,14 @@ class ApiClient {
         return this.request('/topology');
     }
 
+    async getTopologyEdges(): Promise<TopologyEdge[]> {
+        return this.request('/topology/edges');
+    }
+
+    async getSummary(): Promise<Summary> {
+        return this.request('/summary');
+    }
+
     async getHealthCheck(databaseId: string): Promise<{
         status: string;
         healthy: boolean;
@@ -283,14 +470,37 @@ class ApiClient {
         engine: string;
         host: string;
         port: number;
+        oomKilled: boolean;
+        errorMessage?: string;
         connectionVerified?: boolean;
         connectionError?: string;
     }> {
         return this.request(`/databases/${databaseId}/health`);
     }
 
-    async getLogs(id: string): Promise<{ logs: string }> {
-        return this.request(`/databases/${id}/logs`);
+    // Dials the database's port from the DBnest server, as a client would
+    async checkConnectivity(databaseId: string): Promise<{
+        address: string;
+        reachable: boolean;
+        latencyMs?: number;
+        handshake: boolean;
+        error?: string;
+    }> {
+        return this.request(`/databases/${databaseId}/connectivity`);
+    }
+
+    async getLogs(id: string, options: { tail?: number; since?: string; grep?: string; timestamps?: boolean } = {}): Promise<{ logs: string }> {
+        const params = new URLSearchParams();
+        if (options.tail) params.set('tail', String(options.tail));
+        if (options.since) params.set('since', options.since); // Go duration, e.g. "15m"
+        if (options.grep) params.set('grep', options.grep);
+        if (options.timestamps) params.set('timestamps', 'true');
+        const query = params.toString();
+        return this.request(`/databases/${id}/logs${query ? `?${query}` : ''}`);
+    }
+
+    async downloadCredentialsFile(databaseId: string): Promise<void> {
+        window.open(`${API_BASE}/databases/${databaseId}/credentials/file`, '_blank');
     }
 
     async downloadBackup(backupId: string): Promise<void> {
@@ -304,8 +514,11 @@ class ApiClient {
         return result || [];
     }
 
-    async createBackup(databaseId: string): Promise<Backup> {
-        return this.request(`/databases/${databaseId}/backup`, { method: 'POST' });
+    async createBackup(databaseId: string, tables?: string[]): Promise<Backup> {
+        return this.request(`/databases/${databaseId}/backup`, {
+            method: 'POST',
+            body: tables?.length ? JSON.stringify({ tables }) : undefined,
+        });
     }
 
     // Networks
@@ -354,27 +567,43 @@ class ApiClient {
 
 
 
-    async bulkStart(ids: string[]): Promise<{ message: string; errors?: string[] }> {
+    async bulkStart(ids: string[]): Promise<{ message: string; results: Record<string, string>; errors?: string[] }> {
         return this.request('/databases/bulk/start', {
             method: 'POST',
             body: JSON.stringify({ ids }),
         });
     }
 
-    async bulkStop(ids: string[]): Promise<{ message: string; errors?: string[] }> {
+    async bulkStop(ids: string[]): Promise<{ message: string; results: Record<string, string>; errors?: string[] }> {
         return this.request('/databases/bulk/stop', {
             method: 'POST',
             body: JSON.stringify({ ids }),
         });
     }
 
-    async bulkDelete(ids: string[]): Promise<{ message: string; errors?: string[] }> {
-        return this.request('/databases/bulk/delete', {
+    // confirm maps each database ID to its name, like deleteDatabase's confirm
+    async bulkDelete(ids: string[], options: { keepBackups?: boolean; confirm?: Record<string, string> } = {}): Promise<{ message: string; results: Record<string, string>; errors?: string[] }> {
+        const query = options.keepBackups ? '?keepBackups=true' : '';
+        return this.request(`/databases/bulk/delete${query}`, {
+            method: 'POST',
+            body: JSON.stringify({ ids, confirm: options.confirm }),
+        });
+    }
+
+    async bulkBackup(ids: string[]): Promise<{ message: string; backups: Record<string, string>; errors?: string[] }> {
+        return this.request('/databases/bulk/backup', {
             method: 'POST',
             body: JSON.stringify({ ids }),
         });
     }
 
+    async bulkClone(items: { id: string; newName: string }[]): Promise<{ id: string; newName: string; cloneId?: string; error?: string }[]> {
+        return this.request('/databases/bulk/clone', {
+            method: 'POST',
+            body: JSON.stringify(items),
+        });
+    }
+
     async deleteBackup(id: string): Promise<void> {
         await this.request(`/backups/${id}`, { method: 'DELETE' });
     }
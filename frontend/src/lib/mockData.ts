@@ -1,7 +1,7 @@
 // Mock data types and configurations
 
 export type DatabaseEngine = "postgresql" | "mysql" | "mariadb" | "redis" | "sqlite";
-export type DatabaseStatus = "running" | "stopped" | "error" | "creating";
+export type DatabaseStatus = "running" | "stopped" | "error" | "oom-killed" | "starting" | "unhealthy" | "queued" | "creating";
 
 export interface DatabaseInstance {
   id: string;
@@ -101,6 +101,30 @@ export const statusConfig = {
     bgColor: "bg-destructive",
     dotColor: "bg-destructive-foreground",
   },
+  "oom-killed": {
+    label: "Out of Memory",
+    color: "text-destructive-foreground",
+    bgColor: "bg-destructive",
+    dotColor: "bg-destructive-foreground",
+  },
+  starting: {
+    label: "Starting",
+    color: "text-warning-foreground",
+    bgColor: "bg-warning",
+    dotColor: "bg-warning-foreground",
+  },
+  unhealthy: {
+    label: "Unhealthy",
+    color: "text-warning-foreground",
+    bgColor: "bg-warning",
+    dotColor: "bg-warning-foreground",
+  },
+  queued: {
+    label: "Queued",
+    color: "text-muted-foreground",
+    bgColor: "bg-muted",
+    dotColor: "bg-muted-foreground",
+  },
   creating: {
     label: "Creating",
     color: "text-warning-foreground",
@@ -113,7 +113,10 @@ export default function Dashboard() {
         if (!confirm(`Are you sure you want to delete ${selectedIds.size} databases? This cannot be undone.`)) return;
         setBulkLoading('delete');
         try {
-            await api.bulkDelete([...selectedIds]);
+            const names = Object.fromEntries(
+                databases.filter((db) => selectedIds.has(db.id)).map((db) => [db.id, db.name])
+            );
+            await api.bulkDelete([...selectedIds], { confirm: names });
             toast.success(`Deleted ${selectedIds.size} databases`);
             setSelectedIds(new Set());
             fetchDatabases();
@@ -144,7 +147,7 @@ export default function Dashboard() {
 
     const handleDeleteDatabase = async (id: string) => {
         try {
-            await api.deleteDatabase(id);
+            await api.deleteDatabase(id, { confirm: databases.find((db) => db.id === id)?.name });
             await fetchDatabases();
         } catch (err) {
             setError(err instanceof Error ? err.message : 'Failed to delete database');
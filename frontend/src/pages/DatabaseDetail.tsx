@@ -52,6 +52,7 @@ const engineConfig: Record<string, { name: string }> = {
     mysql: { name: "MySQL" },
     mariadb: { name: "MariaDB" },
     redis: { name: "Redis" },
+    clickhouse: { name: "ClickHouse" },
 };
 
 export default function DatabaseDetail() {
@@ -72,6 +73,7 @@ export default function DatabaseDetail() {
     const [backupEnabled, setBackupEnabled] = useState(false);
     const [backupSchedule, setBackupSchedule] = useState("0 0 * * *");
     const [backupRetention, setBackupRetention] = useState(7);
+    const [backupSkipUnchanged, setBackupSkipUnchanged] = useState(false);
     const [savingBackupSettings, setSavingBackupSettings] = useState(false);
 
     // Resource settings state
@@ -114,6 +116,7 @@ export default function DatabaseDetail() {
             setBackupEnabled(db.backupEnabled || false);
             setBackupSchedule(db.backupSchedule || "0 0 * * *");
             setBackupRetention(db.backupRetentionCount || 7);
+            setBackupSkipUnchanged(db.backupSkipUnchanged || false);
 
             // Set resource settings from database
             setMemoryLimit(db.memoryLimit || 256);
@@ -230,7 +233,7 @@ export default function DatabaseDetail() {
         if (!confirm('Are you sure you want to delete this database? This cannot be undone.')) return;
         setActionLoading('delete');
         try {
-            await api.deleteDatabase(id);
+            await api.deleteDatabase(id, { confirm: database?.name });
             toast.success('Database deleted');
             navigate('/');
         } catch (err) {
@@ -264,6 +267,7 @@ export default function DatabaseDetail() {
                 backupEnabled,
                 backupSchedule,
                 backupRetentionCount: backupRetention,
+                backupSkipUnchanged,
             });
             toast.success('Backup settings saved');
             fetchData();
@@ -278,8 +282,12 @@ export default function DatabaseDetail() {
         if (!id) return;
         setSavingResources(true);
         try {
-            await api.updateResources(id, memoryLimit, cpuLimit);
-            toast.success('Resource limits updated');
+            const result = await api.updateResources(id, memoryLimit, cpuLimit);
+            if (result.restartRequired) {
+                toast.warning(result.message || 'Restart the database to apply the new limits');
+            } else {
+                toast.success('Resource limits updated');
+            }
             fetchData();
         } catch (err) {
             toast.error(err instanceof Error ? err.message : 'Failed to update resources');
@@ -435,7 +443,7 @@ export default function DatabaseDetail() {
                                     )}
                                     Start
                                 </Button>
-                            ) : database.status === "running" ? (
+                            ) : ["running", "starting", "unhealthy"].includes(database.status) ? (
                                 <Button
                                     size="sm"
                                     variant="outline"
@@ -903,6 +911,16 @@ export default function DatabaseDetail() {
                                                     />
                                                 </div>
                                             </div>
+                                            <div className="flex items-center justify-between">
+                                                <div>
+                                                    <p className="font-medium">Skip If Unchanged</p>
+                                                    <p className="text-sm text-muted-foreground">Skip scheduled backups when nothing was written since the last one</p>
+                                                </div>
+                                                <Switch
+                                                    checked={backupSkipUnchanged}
+                                                    onCheckedChange={setBackupSkipUnchanged}
+                                                />
+                                            </div>
                                         </>
                                     )}
 
@@ -21,6 +21,7 @@ const engineConfig = {
     mysql: { name: "MySQL" },
     redis: { name: "Redis" },
     mariadb: { name: "MariaDB" },
+    clickhouse: { name: "ClickHouse" },
 };
 
 export default function Databases() {
@@ -87,7 +88,7 @@ export default function Databases() {
 
     const handleDeleteDatabase = async (id: string) => {
         try {
-            await api.deleteDatabase(id);
+            await api.deleteDatabase(id, { confirm: databases.find((db) => db.id === id)?.name });
             await fetchDatabases();
         } catch (err) {
             setError(err instanceof Error ? err.message : 'Failed to delete database');
@@ -4,11 +4,12 @@ import { CreateDatabaseModal } from "@/components/CreateDatabaseModal";
 import { NetworkTopology } from "@/components/NetworkTopology";
 import { Button } from "@/components/ui/button";
 import { RefreshCw } from "lucide-react";
-import { api, TopologyNetwork } from "@/lib/api";
+import { api, TopologyEdge, TopologyNetwork } from "@/lib/api";
 
 export default function Topology() {
     const [createModalOpen, setCreateModalOpen] = useState(false);
     const [topology, setTopology] = useState<TopologyNetwork[]>([]);
+    const [edges, setEdges] = useState<TopologyEdge[]>([]);
     const [loading, setLoading] = useState(true);
     const [error, setError] = useState<string | null>(null);
     const [isRefreshing, setIsRefreshing] = useState(false);
@@ -16,8 +17,9 @@ export default function Topology() {
     const fetchTopology = useCallback(async () => {
         try {
             setError(null);
-            const data = await api.getTopology();
-            setTopology(data);
+            const [networks, edges] = await Promise.all([api.getTopology(), api.getTopologyEdges()]);
+            setTopology(networks);
+            setEdges(edges);
         } catch (err) {
             console.error('Failed to fetch topology:', err);
             setError('Failed to load network topology');
@@ -65,7 +67,7 @@ export default function Topology() {
                 </header>
 
                 <div className="p-6">
-                    <NetworkTopology topology={topology} loading={loading} error={error} />
+                    <NetworkTopology topology={topology} edges={edges} loading={loading} error={error} />
                 </div>
             </main>
 
@@ -0,0 +1,183 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"path"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/rs/zerolog/log"
+	"github.com/sirrobot01/dbnest/pkg/auth"
+	"github.com/sirrobot01/dbnest/pkg/storage"
+)
+
+// apiKeyLastUsedInterval is how stale a key's last used time may get before
+// a request records it again, so busy keys don't write to the store on every call
+const apiKeyLastUsedInterval = time.Minute
+
+// authenticateAPIKey validates an X-API-Key header and serves the request as the key's owner
+func (s *Server) authenticateAPIKey(w http.ResponseWriter, r *http.Request, next http.Handler, apiKey string) {
+	key, err := s.store.GetAPIKeyByHash(auth.HashAPIKey(apiKey))
+	if err != nil {
+		errorResponse(w, http.StatusUnauthorized, CodeInvalidAPIKey, "Invalid API key")
+		return
+	}
+
+	if key.ExpiresAt != nil && time.Now().After(*key.ExpiresAt) {
+		errorResponse(w, http.StatusUnauthorized, CodeInvalidAPIKey, "API key expired")
+		return
+	}
+
+	user, err := s.store.GetUser(key.UserID)
+	if err != nil {
+		errorResponse(w, http.StatusUnauthorized, CodeInvalidSession, "User not found")
+		return
+	}
+
+	// Viewer keys are restricted to read-only requests. Console queries are
+	// POSTs, but a viewer's queries are forced read-only by the handler, and
+	// clearing query history only touches the viewer's own.
+	if key.Role == auth.RoleViewer && r.Method != http.MethodGet && r.Method != http.MethodHead && !isQueryRequest(r) {
+		errorResponse(w, http.StatusForbidden, CodeForbidden, "API key does not permit this operation")
+		return
+	}
+
+	if now := time.Now(); key.LastUsedAt == nil || now.Sub(*key.LastUsedAt) >= apiKeyLastUsedInterval {
+		key.LastUsedAt = &now
+		if err := s.store.UpdateAPIKey(key); err != nil {
+			log.Debug().Err(err).Str("key", key.ID).Msg("Failed to update API key last used time")
+		}
+	}
+
+	ctx := context.WithValue(r.Context(), userContextKey, user)
+	ctx = context.WithValue(ctx, roleContextKey, key.Role)
+	next.ServeHTTP(w, r.WithContext(ctx))
+}
+
+// isQueryRequest reports whether r runs a console query or clears the
+// caller's query history
+func isQueryRequest(r *http.Request) bool {
+	if matched, _ := path.Match("/api/v1/databases/*/query", r.URL.Path); matched {
+		return r.Method == http.MethodPost
+	}
+	matched, _ := path.Match("/api/v1/databases/*/query/history", r.URL.Path)
+	return matched && r.Method == http.MethodDelete
+}
+
+// requireAdmin rejects requests whose authenticated role is not admin
+func requireAdmin(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if role, _ := r.Context().Value(roleContextKey).(string); role != auth.RoleAdmin {
+			errorResponse(w, http.StatusForbidden, CodeForbidden, "Admin access required")
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// handleListAPIKeys returns all API keys (without secrets)
+func (s *Server) handleListAPIKeys(w http.ResponseWriter, r *http.Request) {
+	keys := s.store.ListAPIKeys()
+	if keys == nil {
+		keys = []*storage.APIKey{}
+	}
+	jsonResponse(w, http.StatusOK, keys)
+}
+
+// apiKeyRequest is the body of an API key create
+type apiKeyRequest struct {
+	Name          string `json:"name"`
+	Role          string `json:"role"`
+	ExpiresInDays int    `json:"expiresInDays"` // Optional, 0 = never expires
+}
+
+// handleCreateAPIKey mints a new API key. The plaintext key is only returned once.
+func (s *Server) handleCreateAPIKey(w http.ResponseWriter, r *http.Request) {
+	var req apiKeyRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		errorResponse(w, http.StatusBadRequest, CodeInvalidBody, "Invalid request body")
+		return
+	}
+
+	if req.Name == "" {
+		errorResponse(w, http.StatusBadRequest, CodeInvalidRequest, "Name is required")
+		return
+	}
+	if req.Role == "" {
+		req.Role = auth.RoleAdmin
+	}
+	if !auth.ValidRole(req.Role) {
+		errorResponse(w, http.StatusBadRequest, CodeInvalidRequest, "Role must be one of: admin, viewer")
+		return
+	}
+	if req.ExpiresInDays < 0 {
+		errorResponse(w, http.StatusBadRequest, CodeInvalidRequest, "expiresInDays cannot be negative")
+		return
+	}
+
+	user, ok := r.Context().Value(userContextKey).(*storage.User)
+	if !ok {
+		errorResponse(w, http.StatusUnauthorized, CodeAuthRequired, "Authentication required")
+		return
+	}
+
+	secret, err := auth.GenerateAPIKey()
+	if err != nil {
+		errorResponse(w, http.StatusInternalServerError, CodeInternal, "Failed to generate API key")
+		return
+	}
+
+	key := &storage.APIKey{
+		ID:        auth.GenerateID(),
+		Name:      req.Name,
+		UserID:    user.ID,
+		Role:      req.Role,
+		Prefix:    secret[:len(auth.APIKeyPrefix)+6],
+		KeyHash:   auth.HashAPIKey(secret),
+		CreatedAt: time.Now(),
+	}
+	if req.ExpiresInDays > 0 {
+		expires := key.CreatedAt.Add(time.Duration(req.ExpiresInDays) * 24 * time.Hour)
+		key.ExpiresAt = &expires
+	}
+
+	err = s.store.CreateAPIKey(key)
+	s.audit(r, "apikey.create", key.ID, err)
+	if err != nil {
+		errorResponse(w, http.StatusInternalServerError, CodeInternal, "Failed to create API key")
+		return
+	}
+
+	log.Info().Str("id", key.ID).Str("name", key.Name).Str("role", key.Role).Msg("API key created")
+
+	jsonResponse(w, http.StatusCreated, map[string]interface{}{
+		"id":        key.ID,
+		"name":      key.Name,
+		"role":      key.Role,
+		"prefix":    key.Prefix,
+		"createdAt": key.CreatedAt,
+		"expiresAt": key.ExpiresAt,
+		"key":       secret, // Only returned once
+	})
+}
+
+// handleDeleteAPIKey revokes an API key
+func (s *Server) handleDeleteAPIKey(w http.ResponseWriter, r *http.Request) {
+	id := chi.URLParam(r, "id")
+	if id == "" {
+		errorResponse(w, http.StatusBadRequest, CodeInvalidRequest, "API key ID is required")
+		return
+	}
+
+	err := s.store.DeleteAPIKey(id)
+	s.audit(r, "apikey.delete", id, err)
+	if err != nil {
+		errorResponse(w, http.StatusNotFound, CodeAPIKeyNotFound, "API key not found")
+		return
+	}
+
+	log.Info().Str("id", id).Msg("API key revoked")
+	w.WriteHeader(http.StatusNoContent)
+}
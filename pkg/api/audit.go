@@ -0,0 +1,82 @@
+package api
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/rs/zerolog/log"
+	"github.com/sirrobot01/dbnest/pkg/storage"
+)
+
+// Audit results
+const (
+	auditSuccess = "success"
+	auditFailure = "failure"
+)
+
+// defaultAuditLimit caps how many events a query returns when no limit is given
+const defaultAuditLimit = 500
+
+// audit records a mutating operation, attributed to the authenticated user.
+// Failures to write the audit log are logged but never fail the request.
+func (s *Server) audit(r *http.Request, action, targetID string, opErr error) {
+	event := &storage.AuditEvent{
+		ID:        uuid.New().String(),
+		Timestamp: time.Now(),
+		Action:    action,
+		TargetID:  targetID,
+		Result:    auditSuccess,
+	}
+	if user, ok := r.Context().Value(userContextKey).(*storage.User); ok {
+		event.UserID = user.ID
+		event.Username = user.Username
+	}
+	if opErr != nil {
+		event.Result = auditFailure
+		event.Error = opErr.Error()
+	}
+
+	if err := s.store.AddAuditEvent(event); err != nil {
+		log.Error().Err(err).Str("action", action).Str("target", targetID).Msg("Failed to write audit event")
+	}
+}
+
+// handleListAudit returns audit events, newest first. Supports filtering by
+// ?user= (username or ID), ?target=, ?since= and ?until= (RFC 3339), and ?limit=.
+func (s *Server) handleListAudit(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	filter := storage.AuditFilter{
+		User:     query.Get("user"),
+		TargetID: query.Get("target"),
+		Limit:    defaultAuditLimit,
+	}
+
+	if v := query.Get("since"); v != "" {
+		t, err := time.Parse(time.RFC3339, v)
+		if err != nil {
+			errorResponse(w, http.StatusBadRequest, CodeInvalidRequest, "since must be an RFC 3339 timestamp")
+			return
+		}
+		filter.Since = t
+	}
+	if v := query.Get("until"); v != "" {
+		t, err := time.Parse(time.RFC3339, v)
+		if err != nil {
+			errorResponse(w, http.StatusBadRequest, CodeInvalidRequest, "until must be an RFC 3339 timestamp")
+			return
+		}
+		filter.Until = t
+	}
+	if v := query.Get("limit"); v != "" {
+		limit, err := strconv.Atoi(v)
+		if err != nil || limit <= 0 {
+			errorResponse(w, http.StatusBadRequest, CodeInvalidRequest, "limit must be a positive integer")
+			return
+		}
+		filter.Limit = limit
+	}
+
+	jsonResponse(w, http.StatusOK, s.store.ListAuditEvents(filter))
+}
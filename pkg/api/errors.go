@@ -0,0 +1,53 @@
+package api
+
+import "net/http"
+
+// Error codes identify the kind of an error response. They are part of the
+// API: clients branch on them instead of matching messages, so existing
+// codes must not change.
+const (
+	CodeInvalidRequest        = "INVALID_REQUEST"
+	CodeInvalidBody           = "INVALID_BODY"
+	CodeInvalidVersion        = "INVALID_VERSION"
+	CodeConfirmationMismatch  = "CONFIRMATION_MISMATCH"
+	CodeAuthRequired          = "AUTH_REQUIRED"
+	CodeInvalidSession        = "INVALID_SESSION"
+	CodeInvalidCredentials    = "INVALID_CREDENTIALS"
+	CodeInvalidAPIKey         = "INVALID_API_KEY"
+	CodeForbidden             = "FORBIDDEN"
+	CodeRegistrationClosed    = "REGISTRATION_CLOSED"
+	CodeReadOnlyQuery         = "READ_ONLY_QUERY"
+	CodeDatabaseNotFound      = "DATABASE_NOT_FOUND"
+	CodeBackupNotFound        = "BACKUP_NOT_FOUND"
+	CodeWebhookNotFound       = "WEBHOOK_NOT_FOUND"
+	CodeAPIKeyNotFound        = "API_KEY_NOT_FOUND"
+	CodeDatabaseNotRunning    = "DATABASE_NOT_RUNNING"
+	CodeBackupNotReady        = "BACKUP_NOT_READY"
+	CodeBackupCorrupted       = "BACKUP_CORRUPTED"
+	CodePortUnavailable       = "PORT_UNAVAILABLE"
+	CodeNetworkInUse          = "NETWORK_IN_USE"
+	CodeInsufficientResources = "INSUFFICIENT_RESOURCES"
+	CodeInsufficientStorage   = "INSUFFICIENT_STORAGE"
+	CodeRateLimited           = "RATE_LIMITED"
+	CodeRuntimeUnavailable    = "RUNTIME_UNAVAILABLE"
+	CodeInternal              = "INTERNAL_ERROR"
+)
+
+// APIError is the body of every error response
+type APIError struct {
+	Code    string `json:"code"`
+	Message string `json:"message"`
+	// Details carries data specific to the code, e.g. the valid versions
+	// for INVALID_VERSION
+	Details interface{} `json:"details,omitempty"`
+	// Error repeats Message for clients written before codes existed
+	Error string `json:"error"`
+}
+
+func errorResponse(w http.ResponseWriter, status int, code, message string) {
+	errorDetailsResponse(w, status, code, message, nil)
+}
+
+func errorDetailsResponse(w http.ResponseWriter, status int, code, message string, details interface{}) {
+	jsonResponse(w, status, APIError{Code: code, Message: message, Details: details, Error: message})
+}
@@ -1,19 +1,214 @@
 package api
 
-import "net/http"
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
 
-// corsMiddleware adds CORS headers to responses
-func corsMiddleware(next http.Handler) http.Handler {
+	"github.com/go-chi/chi/v5/middleware"
+	"github.com/rs/zerolog/log"
+	"golang.org/x/time/rate"
+)
+
+// requestLogger logs each request once it has been served, with the ID set
+// by middleware.RequestID. The ID is returned in the X-Request-Id header,
+// and a logger carrying it is put in the request context so log.Ctx picks
+// it up in handlers and the background work they start.
+func requestLogger(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		w.Header().Set("Access-Control-Allow-Origin", "*")
-		w.Header().Set("Access-Control-Allow-Methods", "GET, POST, PUT, DELETE, OPTIONS")
-		w.Header().Set("Access-Control-Allow-Headers", "Content-Type, Authorization")
+		start := time.Now()
+		id := middleware.GetReqID(r.Context())
+		if id != "" {
+			w.Header().Set(middleware.RequestIDHeader, id)
+		}
+		logger := log.Ctx(r.Context()).With().Str("request_id", id).Logger()
+		ww := middleware.NewWrapResponseWriter(w, r.ProtoMajor)
+
+		next.ServeHTTP(ww, r.WithContext(logger.WithContext(r.Context())))
+
+		status := ww.Status()
+		if status == 0 {
+			status = http.StatusOK
+		}
+		event := logger.Debug()
+		switch {
+		case status >= 500:
+			event = logger.Error()
+		case status >= 400:
+			event = logger.Warn()
+		case r.Method != http.MethodGet && r.Method != http.MethodHead:
+			// Reads are polled by the UI, so only changes are logged by default
+			event = logger.Info()
+		}
+		event.
+			Str("method", r.Method).
+			Str("path", r.URL.Path).
+			Int("status", status).
+			Dur("duration", time.Since(start)).
+			Int("bytes", ww.BytesWritten()).
+			Msg("Request served")
+	})
+}
+
+// corsMiddleware adds CORS headers for origins in the configured allow-list.
+// With no allowed origins only same-origin requests work, since browsers
+// enforce that on their own. A "*" entry allows any origin without credentials.
+func (s *Server) corsMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		origin := r.Header.Get("Origin")
+		if origin != "" {
+			w.Header().Add("Vary", "Origin")
+
+			allowed, credentials := corsAllowed(s.options().CORSOrigins, origin)
+			if allowed {
+				if credentials {
+					w.Header().Set("Access-Control-Allow-Origin", origin)
+					w.Header().Set("Access-Control-Allow-Credentials", "true")
+				} else {
+					w.Header().Set("Access-Control-Allow-Origin", "*")
+				}
+				w.Header().Set("Access-Control-Allow-Methods", "GET, POST, PUT, PATCH, DELETE, OPTIONS")
+				w.Header().Set("Access-Control-Allow-Headers", "Content-Type, Authorization, X-API-Key")
+			}
+		}
 
 		if r.Method == "OPTIONS" {
-			w.WriteHeader(http.StatusOK)
+			w.WriteHeader(http.StatusNoContent)
 			return
 		}
 
 		next.ServeHTTP(w, r)
 	})
 }
+
+// corsAllowed reports whether origin may make cross-origin requests, and
+// whether it may send credentials (only for explicitly listed origins)
+func corsAllowed(allowList []string, origin string) (allowed, credentials bool) {
+	for _, o := range allowList {
+		if strings.EqualFold(strings.TrimSuffix(o, "/"), origin) {
+			return true, true
+		}
+		if o == "*" {
+			allowed = true
+		}
+	}
+	return allowed, false
+}
+
+// rateLimiter keeps an in-memory token bucket per key (client IP, username, ...)
+type rateLimiter struct {
+	mu       sync.Mutex
+	limiters map[string]*limiterEntry
+}
+
+type limiterEntry struct {
+	limiter  *rate.Limiter
+	lastSeen time.Time
+}
+
+// newRateLimiter creates an empty rate limiter
+func newRateLimiter() *rateLimiter {
+	return &rateLimiter{
+		limiters: make(map[string]*limiterEntry),
+	}
+}
+
+// allow reports whether another request for key is permitted, allowing
+// limit requests per window. A non-positive limit disables limiting.
+func (rl *rateLimiter) allow(key string, limit int, window time.Duration) bool {
+	if limit <= 0 || window <= 0 {
+		return true
+	}
+
+	rl.mu.Lock()
+	defer rl.mu.Unlock()
+
+	now := time.Now()
+	every := rate.Every(window / time.Duration(limit))
+
+	// Drop buckets that have been idle long enough to be full again
+	for k, e := range rl.limiters {
+		if now.Sub(e.lastSeen) > window {
+			delete(rl.limiters, k)
+		}
+	}
+
+	entry, ok := rl.limiters[key]
+	if !ok || entry.limiter.Limit() != every || entry.limiter.Burst() != limit {
+		entry = &limiterEntry{limiter: rate.NewLimiter(every, limit)}
+		rl.limiters[key] = entry
+	}
+	entry.lastSeen = now
+	return entry.limiter.Allow()
+}
+
+// authRateLimit throttles auth attempts per client IP and per username
+func (s *Server) authRateLimit(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		opts := s.options()
+
+		keys := []string{"ip:" + clientIP(r)}
+
+		// Peek at the body for the username, then restore it for the handler
+		body, err := io.ReadAll(io.LimitReader(r.Body, 1<<20))
+		if err == nil {
+			r.Body = io.NopCloser(bytes.NewReader(body))
+			var req struct {
+				Username string `json:"username"`
+			}
+			if json.Unmarshal(body, &req) == nil && req.Username != "" {
+				keys = append(keys, "user:"+strings.ToLower(req.Username))
+			}
+		}
+
+		for _, key := range keys {
+			if !s.authLimiter.allow(key, opts.AuthRateLimit, opts.AuthRateWindow) {
+				log.Warn().Str("key", key).Str("path", r.URL.Path).Msg("Auth rate limit exceeded")
+				w.Header().Set("Retry-After", fmt.Sprintf("%d", int(opts.AuthRateWindow.Seconds())))
+				errorResponse(w, http.StatusTooManyRequests, CodeRateLimited, "Too many attempts, please try again later")
+				return
+			}
+		}
+
+		next.ServeHTTP(w, r)
+	})
+}
+
+// clientIP returns the remote IP address of the request
+func clientIP(r *http.Request) string {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+	return host
+}
+
+// runtimeAvailable reports whether the container runtime can be used. A
+// client that can run without its runtime says so through Available.
+func (s *Server) runtimeAvailable() bool {
+	if s.docker == nil {
+		return false
+	}
+	if rc, ok := s.docker.(interface{ Available() bool }); ok {
+		return rc.Available()
+	}
+	return true
+}
+
+// requireRuntime answers 503 for routes that need the container runtime
+// while it is unavailable, instead of letting them fail partway
+func (s *Server) requireRuntime(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !s.runtimeAvailable() {
+			errorResponse(w, http.StatusServiceUnavailable, CodeRuntimeUnavailable, "Container runtime is unavailable, try again once it is back")
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
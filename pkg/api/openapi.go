@@ -0,0 +1,544 @@
+package api
+
+import (
+	"net/http"
+	"reflect"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+
+	"github.com/sirrobot01/dbnest/pkg/database"
+	"github.com/sirrobot01/dbnest/pkg/notify"
+	"github.com/sirrobot01/dbnest/pkg/runtime"
+	"github.com/sirrobot01/dbnest/pkg/storage"
+	"github.com/sirrobot01/dbnest/pkg/version"
+)
+
+// apiOperation documents a route for the OpenAPI spec. Request and response
+// schemas are generated from the Go types the handlers encode and decode.
+type apiOperation struct {
+	Method  string
+	Path    string // relative to /api/v1, with {param} placeholders
+	Tag     string
+	Summary string
+	// Public routes are served without authentication
+	Public bool
+	Query  []apiParam
+	// Request is a value of the JSON body's type, or a content type string
+	// for other bodies. Nil means no body.
+	Request interface{}
+	// Responses maps success statuses to a value of the JSON body's type, a
+	// content type string for other bodies, or nil for no body
+	Responses map[int]interface{}
+}
+
+// apiParam is a query parameter of an operation
+type apiParam struct {
+	Name        string
+	Type        string // "string", "integer" or "boolean"
+	Description string
+}
+
+// Response bodies the handlers build as maps, described for the spec
+
+type healthResponse struct {
+	Status  string `json:"status"` // "healthy" or "degraded"
+	Version string `json:"version"`
+	// Runtime is only detailed for authenticated callers; others get "ok" or "unavailable"
+	Runtime RuntimeHealth `json:"runtime"`
+}
+
+type authStatusResponse struct {
+	Enabled    bool `json:"enabled"`
+	Configured bool `json:"configured"`
+}
+
+type userResponse struct {
+	ID        string    `json:"id"`
+	Username  string    `json:"username"`
+	CreatedAt time.Time `json:"createdAt"`
+}
+
+type loginResponse struct {
+	userResponse
+	Token string `json:"token"`
+}
+
+type databaseHealthResponse struct {
+	Status             string `json:"status"`
+	Healthy            bool   `json:"healthy"`
+	ContainerID        string `json:"containerId"`
+	Engine             string `json:"engine"`
+	Host               string `json:"host"`
+	Port               int    `json:"port"`
+	OOMKilled          bool   `json:"oomKilled"`
+	ErrorMessage       string `json:"errorMessage,omitempty"`
+	ConnectionVerified bool   `json:"connectionVerified,omitempty"`
+	ConnectionError    string `json:"connectionError,omitempty"`
+}
+
+type metricsResponse struct {
+	CPUPercent    float64 `json:"cpuPercent"`
+	MemoryUsage   int64   `json:"memoryUsage"`
+	MemoryLimit   int64   `json:"memoryLimit"`
+	MemoryPercent float64 `json:"memoryPercent"`
+	NetworkRx     int64   `json:"networkRx"`
+	NetworkTx     int64   `json:"networkTx"`
+	StorageUsed   int64   `json:"storageUsed"`
+	Connections   int     `json:"connections"`
+}
+
+type credentialsResponse struct {
+	Username string `json:"username"`
+	Password string `json:"password"`
+	Database string `json:"database"`
+	Host     string `json:"host"`
+	Port     int    `json:"port"`
+	Engine   string `json:"engine"`
+}
+
+type backupInfoResponse struct {
+	ID           string    `json:"id"`
+	DatabaseID   string    `json:"databaseId"`
+	DatabaseName string    `json:"databaseName"`
+	CreatedAt    time.Time `json:"createdAt"`
+	Size         int64     `json:"size"`
+	Status       string    `json:"status"`
+	Checksum     string    `json:"checksum"`
+	Engine       string    `json:"engine"`
+	Version      string    `json:"version"`
+}
+
+type logsResponse struct {
+	Logs string `json:"logs"`
+}
+
+type restoreResponse struct {
+	Status string `json:"status"`
+}
+
+type bulkResponse struct {
+	Message string            `json:"message"`
+	Results map[string]string `json:"results"` // database ID -> outcome or "failed"
+	Errors  []string          `json:"errors,omitempty"`
+}
+
+type bulkBackupResponse struct {
+	Message string            `json:"message"`
+	Backups map[string]string `json:"backups"` // database ID -> backup ID
+	Errors  []string          `json:"errors,omitempty"`
+}
+
+type apiKeyCreatedResponse struct {
+	ID        string     `json:"id"`
+	Name      string     `json:"name"`
+	Role      string     `json:"role"`
+	Prefix    string     `json:"prefix"`
+	CreatedAt time.Time  `json:"createdAt"`
+	ExpiresAt *time.Time `json:"expiresAt"`
+	Key       string     `json:"key"` // only ever returned here
+}
+
+// Bodies that aren't JSON, by content type
+const (
+	contentSQL    = "application/sql"
+	contentBinary = "application/octet-stream"
+	contentText   = "text/plain"
+	contentStream = "text/event-stream"
+	contentUpload = "multipart/form-data"
+)
+
+var (
+	keepBackupsParam = apiParam{Name: "keepBackups", Type: "boolean", Description: "Keep the database's backups"}
+	databaseResult   = &storage.DatabaseInstance{}
+)
+
+// apiOperations lists every route under /api/v1. TestOpenAPISpec checks it
+// against the router.
+var apiOperations = []apiOperation{
+	{Method: "GET", Path: "/health", Tag: "system", Public: true, Summary: "Server and container runtime health, with runtime details for authenticated callers",
+		Responses: map[int]interface{}{200: healthResponse{}}},
+	{Method: "GET", Path: "/version", Tag: "system", Public: true, Summary: "Build information",
+		Responses: map[int]interface{}{200: version.Info{}}},
+	{Method: "GET", Path: "/openapi.json", Tag: "system", Public: true, Summary: "This OpenAPI document",
+		Responses: map[int]interface{}{200: "application/json"}},
+
+	{Method: "GET", Path: "/auth/status", Tag: "auth", Public: true, Summary: "Whether a user has been registered",
+		Responses: map[int]interface{}{200: authStatusResponse{}}},
+	{Method: "POST", Path: "/auth/register", Tag: "auth", Public: true, Summary: "Register the first user",
+		Request: credentialsRequest{}, Responses: map[int]interface{}{201: userResponse{}}},
+	{Method: "POST", Path: "/auth/login", Tag: "auth", Public: true, Summary: "Log in, setting the session cookie",
+		Request: credentialsRequest{}, Responses: map[int]interface{}{200: loginResponse{}}},
+	{Method: "POST", Path: "/auth/logout", Tag: "auth", Public: true, Summary: "End the current session",
+		Responses: map[int]interface{}{204: nil}},
+	{Method: "GET", Path: "/auth/me", Tag: "auth", Public: true, Summary: "The logged in user",
+		Responses: map[int]interface{}{200: userResponse{}}},
+
+	{Method: "GET", Path: "/databases", Tag: "databases", Summary: "List databases",
+		Responses: map[int]interface{}{200: []*storage.DatabaseInstance{}}},
+	{Method: "POST", Path: "/databases", Tag: "databases", Summary: "Create a database. Provisioning continues in the background.",
+		Query:   []apiParam{{Name: "dryRun", Type: "boolean", Description: "Validate and return the planned container without creating anything"}},
+		Request: database.CreateRequest{}, Responses: map[int]interface{}{201: databaseResult, 200: runtime.ContainerConfig{}}},
+	{Method: "GET", Path: "/databases/{id}", Tag: "databases", Summary: "Get a database",
+		Responses: map[int]interface{}{200: databaseResult}},
+	{Method: "DELETE", Path: "/databases/{id}", Tag: "databases", Summary: "Delete a database and, unless kept, its backups",
+		Query:   []apiParam{keepBackupsParam},
+		Request: deleteRequest{}, Responses: map[int]interface{}{204: nil}},
+	{Method: "POST", Path: "/databases/{id}/start", Tag: "databases", Summary: "Start a database",
+		Responses: map[int]interface{}{200: databaseResult}},
+	{Method: "POST", Path: "/databases/{id}/stop", Tag: "databases", Summary: "Stop a database",
+		Query: []apiParam{
+			{Name: "timeout", Type: "integer", Description: "Seconds to wait for a clean shutdown"},
+			{Name: "force", Type: "boolean", Description: "Kill the database immediately"},
+		},
+		Responses: map[int]interface{}{200: databaseResult}},
+	{Method: "POST", Path: "/databases/{id}/pause", Tag: "databases", Summary: "Pause a database",
+		Responses: map[int]interface{}{200: databaseResult}},
+	{Method: "POST", Path: "/databases/{id}/resume", Tag: "databases", Summary: "Resume a paused database",
+		Responses: map[int]interface{}{200: databaseResult}},
+	{Method: "PATCH", Path: "/databases/{id}/resources", Tag: "databases", Summary: "Change memory and CPU limits",
+		Request: resourcesRequest{}, Responses: map[int]interface{}{200: resourcesResponse{}}},
+	{Method: "POST", Path: "/databases/{id}/network", Tag: "databases", Summary: "Move a database to another network",
+		Request: setNetworkRequest{}, Responses: map[int]interface{}{200: databaseResult}},
+	{Method: "PUT", Path: "/databases/{id}/backup-settings", Tag: "databases", Summary: "Update scheduled backup settings",
+		Request: backupSettingsRequest{}, Responses: map[int]interface{}{200: databaseResult}},
+	{Method: "GET", Path: "/databases/{id}/health", Tag: "databases", Summary: "Check a database accepts queries",
+		Responses: map[int]interface{}{200: databaseHealthResponse{}}},
+	{Method: "GET", Path: "/databases/{id}/connectivity", Tag: "databases", Summary: "Check the database's port is reachable",
+		Responses: map[int]interface{}{200: database.Connectivity{}}},
+	{Method: "GET", Path: "/databases/{id}/credentials", Tag: "databases", Summary: "Connection credentials, including the password (admins only)",
+		Responses: map[int]interface{}{200: credentialsResponse{}}},
+	{Method: "GET", Path: "/databases/{id}/credentials/file", Tag: "databases", Summary: "Credentials as the engine's CLI config file (admins only)",
+		Responses: map[int]interface{}{200: contentText}},
+	{Method: "GET", Path: "/databases/{id}/connection-strings", Tag: "databases", Summary: "Connection examples for common languages",
+		Query:     []apiParam{{Name: "reveal", Type: "boolean", Description: "Fill in the real password (admins only)"}},
+		Responses: map[int]interface{}{200: []ConnectionExample{}}},
+	{Method: "GET", Path: "/databases/{id}/events", Tag: "databases", Summary: "Stream provisioning progress as server-sent events",
+		Responses: map[int]interface{}{200: contentStream}},
+	{Method: "GET", Path: "/databases/{id}/logs", Tag: "databases", Summary: "Container logs",
+		Query: []apiParam{
+			{Name: "tail", Type: "integer", Description: "Number of lines from the end"},
+			{Name: "since", Type: "string", Description: "Only lines newer than this duration, e.g. 15m"},
+			{Name: "grep", Type: "string", Description: "Only lines containing this text"},
+			{Name: "timestamps", Type: "boolean", Description: "Prefix lines with their timestamps"},
+		},
+		Responses: map[int]interface{}{200: logsResponse{}}},
+	{Method: "GET", Path: "/databases/{id}/metrics", Tag: "metrics", Summary: "Current resource usage",
+		Responses: map[int]interface{}{200: metricsResponse{}}},
+	{Method: "GET", Path: "/databases/{id}/metrics/stream", Tag: "metrics", Summary: "Stream resource usage as server-sent events",
+		Query:     []apiParam{{Name: "interval", Type: "string", Description: "Time between samples, e.g. 5s"}},
+		Responses: map[int]interface{}{200: contentStream}},
+	{Method: "GET", Path: "/databases/{id}/metrics/history", Tag: "metrics", Summary: "Recorded resource usage",
+		Query:     []apiParam{{Name: "range", Type: "string", Description: "Lookback window, e.g. 24h; defaults to 1h"}},
+		Responses: map[int]interface{}{200: []*database.MetricsPoint{}}},
+	{Method: "POST", Path: "/databases/{id}/query", Tag: "console", Summary: "Run a console query, with a result per statement",
+		Request: queryRequest{}, Responses: map[int]interface{}{200: []*database.QueryResult{}}},
+	{Method: "GET", Path: "/databases/{id}/query/history", Tag: "console", Summary: "The caller's console history",
+		Responses: map[int]interface{}{200: []*storage.QueryHistoryEntry{}}},
+	{Method: "DELETE", Path: "/databases/{id}/query/history", Tag: "console", Summary: "Clear the caller's console history",
+		Responses: map[int]interface{}{204: nil}},
+	{Method: "GET", Path: "/databases/{id}/schema", Tag: "console", Summary: "Tables (or Redis key patterns) of a database",
+		Query:     []apiParam{{Name: "columns", Type: "boolean", Description: "Include each table's columns"}},
+		Responses: map[int]interface{}{200: database.Schema{}}},
+	{Method: "GET", Path: "/databases/{id}/shell", Tag: "console", Summary: "Interactive shell over a WebSocket (admins only)",
+		Responses: map[int]interface{}{101: nil}},
+
+	{Method: "POST", Path: "/databases/{id}/backup", Tag: "backups", Summary: "Start a backup",
+		Request: backupRequest{}, Responses: map[int]interface{}{202: &storage.Backup{}}},
+	{Method: "POST", Path: "/databases/{id}/restore", Tag: "backups", Summary: "Restore a backup into the database",
+		Request: restoreRequest{}, Responses: map[int]interface{}{200: restoreResponse{}}},
+	{Method: "POST", Path: "/databases/{id}/import", Tag: "backups", Summary: "Import a dump file, plain or gzipped, sent as the file field",
+		Request: contentUpload, Responses: map[int]interface{}{200: database.ImportResult{}}},
+	{Method: "GET", Path: "/databases/{id}/export", Tag: "backups", Summary: "Download an SQL dump (admins only)",
+		Query:     []apiParam{{Name: "format", Type: "string", Description: "Only sql is supported"}},
+		Responses: map[int]interface{}{200: contentSQL}},
+	{Method: "GET", Path: "/backups", Tag: "backups", Summary: "List backups",
+		Query:     []apiParam{{Name: "databaseId", Type: "string", Description: "Only this database's backups"}},
+		Responses: map[int]interface{}{200: []*storage.Backup{}}},
+	{Method: "GET", Path: "/backups/{id}/info", Tag: "backups", Summary: "Backup details",
+		Responses: map[int]interface{}{200: backupInfoResponse{}}},
+	{Method: "GET", Path: "/backups/{id}/download", Tag: "backups", Summary: "Download a backup file (admins only)",
+		Responses: map[int]interface{}{200: contentBinary}},
+	{Method: "DELETE", Path: "/backups/{id}", Tag: "backups", Summary: "Delete a backup and its file",
+		Responses: map[int]interface{}{204: nil}},
+
+	{Method: "POST", Path: "/databases/bulk/start", Tag: "bulk", Summary: "Start several databases",
+		Request: bulkRequest{}, Responses: map[int]interface{}{200: bulkResponse{}, 206: bulkResponse{}}},
+	{Method: "POST", Path: "/databases/bulk/stop", Tag: "bulk", Summary: "Stop several databases",
+		Request: bulkRequest{}, Responses: map[int]interface{}{200: bulkResponse{}, 206: bulkResponse{}}},
+	{Method: "POST", Path: "/databases/bulk/delete", Tag: "bulk", Summary: "Delete several databases",
+		Query:   []apiParam{keepBackupsParam},
+		Request: bulkRequest{}, Responses: map[int]interface{}{200: bulkResponse{}, 206: bulkResponse{}}},
+	{Method: "POST", Path: "/databases/bulk/backup", Tag: "bulk", Summary: "Back up several databases",
+		Request: bulkRequest{}, Responses: map[int]interface{}{202: bulkBackupResponse{}, 206: bulkBackupResponse{}}},
+	{Method: "POST", Path: "/databases/bulk/clone", Tag: "bulk", Summary: "Clone several databases, waiting until all are ready",
+		Request: []cloneRequest{}, Responses: map[int]interface{}{200: []BulkCloneResult{}, 206: []BulkCloneResult{}}},
+
+	{Method: "GET", Path: "/networks", Tag: "networks", Summary: "List networks",
+		Responses: map[int]interface{}{200: []runtime.NetworkInfo{}}},
+	{Method: "POST", Path: "/networks", Tag: "networks", Summary: "Create a network",
+		Request: networkRequest{}, Responses: map[int]interface{}{201: &runtime.NetworkInfo{}}},
+	{Method: "DELETE", Path: "/networks/{name}", Tag: "networks", Summary: "Delete a network",
+		Query:     []apiParam{{Name: "force", Type: "boolean", Description: "Move attached databases to the default network first"}},
+		Responses: map[int]interface{}{204: nil}},
+	{Method: "GET", Path: "/topology", Tag: "networks", Summary: "Databases grouped by network",
+		Responses: map[int]interface{}{200: []TopologyNetwork{}}},
+	{Method: "GET", Path: "/topology/edges", Tag: "networks", Summary: "Clone and shared network edges between databases",
+		Responses: map[int]interface{}{200: []TopologyEdge{}}},
+
+	{Method: "GET", Path: "/summary", Tag: "system", Summary: "Counts for the dashboard",
+		Responses: map[int]interface{}{200: Summary{}}},
+	{Method: "GET", Path: "/reconcile", Tag: "system", Summary: "Containers and databases that have drifted apart",
+		Responses: map[int]interface{}{200: database.ReconcileReport{}}},
+	{Method: "POST", Path: "/reconcile", Tag: "system", Summary: "Remove orphaned containers and volumes (admins only)",
+		Responses: map[int]interface{}{200: database.CleanupResult{}}},
+	{Method: "GET", Path: "/audit", Tag: "system", Summary: "Audit events, newest first (admins only)",
+		Query: []apiParam{
+			{Name: "user", Type: "string", Description: "Username or user ID"},
+			{Name: "target", Type: "string", Description: "Target ID"},
+			{Name: "since", Type: "string", Description: "RFC 3339 timestamp"},
+			{Name: "until", Type: "string", Description: "RFC 3339 timestamp"},
+			{Name: "limit", Type: "integer", Description: "Maximum number of events"},
+		},
+		Responses: map[int]interface{}{200: []*storage.AuditEvent{}}},
+
+	{Method: "GET", Path: "/apikeys", Tag: "apikeys", Summary: "List API keys (admins only)",
+		Responses: map[int]interface{}{200: []*storage.APIKey{}}},
+	{Method: "POST", Path: "/apikeys", Tag: "apikeys", Summary: "Create an API key (admins only)",
+		Request: apiKeyRequest{}, Responses: map[int]interface{}{201: apiKeyCreatedResponse{}}},
+	{Method: "DELETE", Path: "/apikeys/{id}", Tag: "apikeys", Summary: "Revoke an API key (admins only)",
+		Responses: map[int]interface{}{204: nil}},
+
+	{Method: "GET", Path: "/webhooks", Tag: "webhooks", Summary: "List webhooks (admins only)",
+		Responses: map[int]interface{}{200: []*notify.Webhook{}}},
+	{Method: "POST", Path: "/webhooks", Tag: "webhooks", Summary: "Add a webhook (admins only)",
+		Request: notify.Webhook{}, Responses: map[int]interface{}{201: &notify.Webhook{}}},
+	{Method: "DELETE", Path: "/webhooks/{id}", Tag: "webhooks", Summary: "Remove a webhook (admins only)",
+		Responses: map[int]interface{}{204: nil}},
+}
+
+var pathParamRegex = regexp.MustCompile(`\{(\w+)\}`)
+
+// openAPISpec builds the OpenAPI 3 document for apiOperations
+func openAPISpec() map[string]interface{} {
+	schemas := newSchemaBuilder()
+	errorRef := schemas.schema(reflect.TypeOf(APIError{}))
+
+	paths := map[string]map[string]interface{}{}
+	for _, op := range apiOperations {
+		operation := map[string]interface{}{
+			"tags":        []string{op.Tag},
+			"summary":     op.Summary,
+			"operationId": operationID(op),
+		}
+		if op.Public {
+			operation["security"] = []interface{}{}
+		}
+
+		var params []interface{}
+		for _, m := range pathParamRegex.FindAllStringSubmatch(op.Path, -1) {
+			params = append(params, map[string]interface{}{
+				"name": m[1], "in": "path", "required": true,
+				"schema": map[string]interface{}{"type": "string"},
+			})
+		}
+		for _, p := range op.Query {
+			params = append(params, map[string]interface{}{
+				"name": p.Name, "in": "query", "description": p.Description,
+				"schema": map[string]interface{}{"type": p.Type},
+			})
+		}
+		if len(params) > 0 {
+			operation["parameters"] = params
+		}
+
+		if op.Request != nil {
+			operation["requestBody"] = map[string]interface{}{
+				"content": schemas.content(op.Request),
+			}
+		}
+
+		responses := map[string]interface{}{
+			"default": map[string]interface{}{
+				"description": "Error",
+				"content":     map[string]interface{}{"application/json": map[string]interface{}{"schema": errorRef}},
+			},
+		}
+		for status, body := range op.Responses {
+			response := map[string]interface{}{"description": http.StatusText(status)}
+			if body != nil {
+				response["content"] = schemas.content(body)
+			}
+			responses[strconv.Itoa(status)] = response
+		}
+		operation["responses"] = responses
+
+		path := "/api/v1" + op.Path
+		if paths[path] == nil {
+			paths[path] = map[string]interface{}{}
+		}
+		paths[path][strings.ToLower(op.Method)] = operation
+	}
+
+	return map[string]interface{}{
+		"openapi": "3.0.3",
+		"info": map[string]interface{}{
+			"title":       "DBnest API",
+			"description": "Manage containerized databases. Routes that need the container runtime return 503 while it's unreachable.",
+			"version":     version.Version,
+		},
+		"paths": paths,
+		"components": map[string]interface{}{
+			"schemas": schemas.schemas,
+			"securitySchemes": map[string]interface{}{
+				"bearerAuth":    map[string]interface{}{"type": "http", "scheme": "bearer", "description": "Session token from /auth/login"},
+				"apiKey":        map[string]interface{}{"type": "apiKey", "in": "header", "name": "X-API-Key"},
+				"sessionCookie": map[string]interface{}{"type": "apiKey", "in": "cookie", "name": "session"},
+			},
+		},
+		"security": []interface{}{
+			map[string]interface{}{"bearerAuth": []string{}},
+			map[string]interface{}{"apiKey": []string{}},
+			map[string]interface{}{"sessionCookie": []string{}},
+		},
+	}
+}
+
+// operationID derives an operation ID such as "getDatabasesIdMetrics"
+func operationID(op apiOperation) string {
+	id := strings.ToLower(op.Method)
+	for _, part := range strings.FieldsFunc(op.Path, func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	}) {
+		id += strings.ToUpper(part[:1]) + part[1:]
+	}
+	return id
+}
+
+// schemaBuilder generates JSON schemas from Go types, collecting named
+// structs as components
+type schemaBuilder struct {
+	schemas map[string]interface{}
+	types   map[string]reflect.Type
+}
+
+func newSchemaBuilder() *schemaBuilder {
+	return &schemaBuilder{schemas: map[string]interface{}{}, types: map[string]reflect.Type{}}
+}
+
+// content returns the content map of a request or response body
+func (b *schemaBuilder) content(body interface{}) map[string]interface{} {
+	if contentType, ok := body.(string); ok {
+		schema := map[string]interface{}{"type": "string"}
+		if contentType == contentBinary {
+			schema["format"] = "binary"
+		}
+		if contentType == contentUpload {
+			schema = map[string]interface{}{
+				"type":       "object",
+				"properties": map[string]interface{}{"file": map[string]interface{}{"type": "string", "format": "binary"}},
+			}
+		}
+		return map[string]interface{}{contentType: map[string]interface{}{"schema": schema}}
+	}
+	return map[string]interface{}{"application/json": map[string]interface{}{"schema": b.schema(reflect.TypeOf(body))}}
+}
+
+var timeType = reflect.TypeOf(time.Time{})
+
+// schema returns the schema of t, or a reference to it for named structs
+func (b *schemaBuilder) schema(t reflect.Type) map[string]interface{} {
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	if t == timeType {
+		return map[string]interface{}{"type": "string", "format": "date-time"}
+	}
+
+	switch t.Kind() {
+	case reflect.Bool:
+		return map[string]interface{}{"type": "boolean"}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return map[string]interface{}{"type": "integer"}
+	case reflect.Float32, reflect.Float64:
+		return map[string]interface{}{"type": "number"}
+	case reflect.String:
+		return map[string]interface{}{"type": "string"}
+	case reflect.Slice, reflect.Array:
+		if t.Elem().Kind() == reflect.Uint8 {
+			return map[string]interface{}{"type": "string", "format": "byte"}
+		}
+		return map[string]interface{}{"type": "array", "items": b.schema(t.Elem())}
+	case reflect.Map:
+		return map[string]interface{}{"type": "object", "additionalProperties": b.schema(t.Elem())}
+	case reflect.Struct:
+		if t.Name() == "" {
+			return b.object(t)
+		}
+		name := b.name(t)
+		if _, ok := b.schemas[name]; !ok {
+			b.schemas[name] = nil // placeholder so recursive types terminate
+			b.schemas[name] = b.object(t)
+		}
+		return map[string]interface{}{"$ref": "#/components/schemas/" + name}
+	default:
+		// interface{} values can be anything
+		return map[string]interface{}{}
+	}
+}
+
+// name returns the component name of a named struct: its exported type name,
+// qualified by package if another package has a type of the same name
+func (b *schemaBuilder) name(t reflect.Type) string {
+	name := strings.ToUpper(t.Name()[:1]) + t.Name()[1:]
+	if other, ok := b.types[name]; ok && other != t {
+		pkg := t.PkgPath()[strings.LastIndex(t.PkgPath(), "/")+1:]
+		name = strings.ToUpper(pkg[:1]) + pkg[1:] + name
+	}
+	b.types[name] = t
+	return name
+}
+
+// object returns the schema of a struct's JSON encoding, with embedded
+// structs' fields promoted
+func (b *schemaBuilder) object(t reflect.Type) map[string]interface{} {
+	properties := map[string]interface{}{}
+	b.addFields(t, properties)
+	return map[string]interface{}{"type": "object", "properties": properties}
+}
+
+func (b *schemaBuilder) addFields(t reflect.Type, properties map[string]interface{}) {
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		tag := field.Tag.Get("json")
+		if tag == "-" {
+			continue
+		}
+		name, _, _ := strings.Cut(tag, ",")
+		if field.Anonymous && name == "" {
+			embedded := field.Type
+			if embedded.Kind() == reflect.Ptr {
+				embedded = embedded.Elem()
+			}
+			if embedded.Kind() == reflect.Struct {
+				b.addFields(embedded, properties)
+				continue
+			}
+		}
+		if !field.IsExported() {
+			continue
+		}
+		if name == "" {
+			name = field.Name
+		}
+		properties[name] = b.schema(field.Type)
+	}
+}
+
+// handleOpenAPI serves the OpenAPI document describing this API
+func (s *Server) handleOpenAPI(w http.ResponseWriter, r *http.Request) {
+	jsonResponse(w, http.StatusOK, openAPISpec())
+}
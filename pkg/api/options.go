@@ -0,0 +1,64 @@
+package api
+
+import "time"
+
+// Options holds tunable API server settings
+type Options struct {
+	// AuthRateLimit is the number of login/register attempts allowed per
+	// AuthRateWindow for each client IP and each username (0 = unlimited)
+	AuthRateLimit  int
+	AuthRateWindow time.Duration
+
+	// HealthCheckTimeout bounds the connectivity query run by the database
+	// health endpoint, the port check run by the connectivity endpoint and
+	// the runtime ping run by the server health endpoint
+	HealthCheckTimeout time.Duration
+
+	// CORSOrigins lists origins allowed to make credentialed cross-origin
+	// requests, e.g. "https://admin.example.com". Empty allows same-origin only.
+	CORSOrigins []string
+
+	// BulkConcurrency caps how many databases a bulk operation works on at once
+	BulkConcurrency int
+
+	// QueryHistorySize caps the console queries remembered per database and
+	// user, oldest dropped first. 0 disables query history.
+	QueryHistorySize int
+
+	// RequireDeleteConfirmation makes deleting a database take its name as
+	// confirmation, guarding against deleting the wrong one by ID
+	RequireDeleteConfirmation bool
+
+	// Runtime and RuntimeMode name the container runtime in use and whether
+	// it is driven over its socket ("SDK") or its CLI ("CLI"), for the health
+	// endpoint
+	Runtime     string
+	RuntimeMode string
+}
+
+// DefaultOptions returns the default API server settings
+func DefaultOptions() Options {
+	return Options{
+		AuthRateLimit:      10,
+		AuthRateWindow:     time.Minute,
+		HealthCheckTimeout: 5 * time.Second,
+		BulkConcurrency:    5,
+		QueryHistorySize:   100,
+		Runtime:            "docker",
+		RuntimeMode:        "CLI",
+	}
+}
+
+// SetOptions replaces the server settings. Safe to call while serving requests.
+func (s *Server) SetOptions(opts Options) {
+	s.optsMu.Lock()
+	defer s.optsMu.Unlock()
+	s.opts = opts
+}
+
+// options returns a snapshot of the current server settings
+func (s *Server) options() Options {
+	s.optsMu.RLock()
+	defer s.optsMu.RUnlock()
+	return s.opts
+}
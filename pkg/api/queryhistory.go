@@ -0,0 +1,77 @@
+package api
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/google/uuid"
+	"github.com/rs/zerolog/log"
+	"github.com/sirrobot01/dbnest/pkg/database"
+	"github.com/sirrobot01/dbnest/pkg/storage"
+)
+
+// recordQuery adds a console query to the user's history for the database.
+// Failures to write the history are logged but never fail the request.
+func (s *Server) recordQuery(r *http.Request, databaseID, query string, results []*database.QueryResult, queryErr error) {
+	size := s.options().QueryHistorySize
+	user, ok := r.Context().Value(userContextKey).(*storage.User)
+	if size <= 0 || !ok {
+		return
+	}
+
+	entry := &storage.QueryHistoryEntry{
+		ID:         uuid.New().String(),
+		DatabaseID: databaseID,
+		UserID:     user.ID,
+		Timestamp:  time.Now(),
+		Query:      query,
+		Success:    true,
+	}
+	if queryErr != nil {
+		entry.Success = false
+		entry.Error = queryErr.Error()
+	}
+	for _, result := range results {
+		entry.RowCount += result.RowCount
+		if result.Error != "" {
+			entry.Success = false
+			entry.Error = result.Error
+		}
+	}
+
+	if err := s.store.AddQueryHistory(entry, size); err != nil {
+		log.Error().Err(err).Str("database", databaseID).Msg("Failed to record query history")
+	}
+}
+
+// handleGetQueryHistory returns the caller's console queries for a database, newest first
+func (s *Server) handleGetQueryHistory(w http.ResponseWriter, r *http.Request) {
+	id := chi.URLParam(r, "id")
+	if _, err := s.db.Get(id); err != nil {
+		errorResponse(w, http.StatusNotFound, CodeDatabaseNotFound, "Database not found")
+		return
+	}
+	user, ok := r.Context().Value(userContextKey).(*storage.User)
+	if !ok {
+		jsonResponse(w, http.StatusOK, []*storage.QueryHistoryEntry{})
+		return
+	}
+	jsonResponse(w, http.StatusOK, s.store.ListQueryHistory(id, user.ID))
+}
+
+// handleClearQueryHistory clears the caller's console queries for a database
+func (s *Server) handleClearQueryHistory(w http.ResponseWriter, r *http.Request) {
+	id := chi.URLParam(r, "id")
+	if _, err := s.db.Get(id); err != nil {
+		errorResponse(w, http.StatusNotFound, CodeDatabaseNotFound, "Database not found")
+		return
+	}
+	if user, ok := r.Context().Value(userContextKey).(*storage.User); ok {
+		if err := s.store.DeleteQueryHistory(id, user.ID); err != nil {
+			errorResponse(w, http.StatusInternalServerError, CodeInternal, err.Error())
+			return
+		}
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
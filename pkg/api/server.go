@@ -3,9 +3,16 @@ package api
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"io"
 	"net/http"
+	"net/url"
+	"os"
+	"sort"
+	"strconv"
 	"strings"
+	"sync"
 	"time"
 
 	"github.com/go-chi/chi/v5"
@@ -14,27 +21,41 @@ import (
 	"github.com/sirrobot01/dbnest/pkg/auth"
 	"github.com/sirrobot01/dbnest/pkg/database"
 	"github.com/sirrobot01/dbnest/pkg/runtime"
+	"github.com/sirrobot01/dbnest/pkg/scheduler"
 	"github.com/sirrobot01/dbnest/pkg/storage"
+	"github.com/sirrobot01/dbnest/pkg/version"
 )
 
 // Server handles API requests
 type Server struct {
-	db     *database.Manager
-	store  storage.Storage
-	docker runtime.Client
+	db          *database.Manager
+	store       storage.Storage
+	docker      runtime.Client
+	scheduler   *scheduler.Scheduler
+	authLimiter *rateLimiter
+	health      healthCache
+
+	optsMu sync.RWMutex
+	opts   Options
 }
 
 // contextKey is a custom type for context keys
 type contextKey string
 
-const userContextKey contextKey = "user"
+const (
+	userContextKey contextKey = "user"
+	roleContextKey contextKey = "role"
+)
 
 // NewServer creates a new API server
-func NewServer(db *database.Manager, store storage.Storage, dockerClient runtime.Client) *Server {
+func NewServer(db *database.Manager, store storage.Storage, dockerClient runtime.Client, sched *scheduler.Scheduler) *Server {
 	return &Server{
-		db:     db,
-		store:  store,
-		docker: dockerClient,
+		db:          db,
+		store:       store,
+		docker:      dockerClient,
+		scheduler:   sched,
+		authLimiter: newRateLimiter(),
+		opts:        DefaultOptions(),
 	}
 }
 
@@ -43,19 +64,23 @@ func (s *Server) Handler() http.Handler {
 	r := chi.NewRouter()
 
 	// Middleware
+	r.Use(middleware.RequestID)
+	r.Use(requestLogger)
 	r.Use(middleware.Recoverer)
-	r.Use(corsMiddleware)
+	r.Use(s.corsMiddleware)
 
 	// API routes
 	r.Route("/api/v1", func(r chi.Router) {
 		// Public routes (no auth required)
 		r.Get("/health", s.handleHealthCheck)
+		r.Get("/version", s.handleVersion)
+		r.Get("/openapi.json", s.handleOpenAPI)
 
 		// Auth routes (always accessible)
 		r.Route("/auth", func(r chi.Router) {
 			r.Get("/status", s.handleAuthStatus)
-			r.Post("/register", s.handleRegister)
-			r.Post("/login", s.handleLogin)
+			r.With(s.authRateLimit).Post("/register", s.handleRegister)
+			r.With(s.authRateLimit).Post("/login", s.handleLogin)
 			r.Post("/logout", s.handleLogout)
 			r.Get("/me", s.handleGetCurrentUser)
 		})
@@ -67,53 +92,109 @@ func (s *Server) Handler() http.Handler {
 
 			// Database routes
 			r.Route("/databases", func(r chi.Router) {
+				// Stored data is served even while the runtime is down
 				r.Get("/", s.handleListDatabases)
-				r.Post("/", s.handleCreateDatabase)
 				r.Get("/{id}", s.handleGetDatabase)
-				r.Delete("/{id}", s.handleDeleteDatabase)
-				r.Post("/{id}/start", s.handleStartDatabase)
-				r.Post("/{id}/stop", s.handleStopDatabase)
-				r.Post("/{id}/backup", s.handleCreateBackup)
-				r.Post("/{id}/restore", s.handleRestoreBackup)
-				r.Get("/{id}/metrics", s.handleGetMetrics)
+				r.Get("/{id}/query/history", s.handleGetQueryHistory)
+				r.Delete("/{id}/query/history", s.handleClearQueryHistory)
 				r.Get("/{id}/metrics/history", s.handleGetMetricsHistory)
-				r.Get("/{id}/health", s.handleHealthCheckDatabase)
-				// Credentials and connection strings
-				r.Get("/{id}/credentials", s.handleGetCredentials)
+				r.Get("/{id}/events", s.handleProvisionEvents)
+				r.Get("/{id}/connectivity", s.handleCheckConnectivity)
+				// Credentials and connection strings. The password is admin-only;
+				// connection strings reveal it only to admins.
+				r.With(requireAdmin).Get("/{id}/credentials", s.handleGetCredentials)
+				r.With(requireAdmin).Get("/{id}/credentials/file", s.handleGetCredentialsFile)
 				r.Get("/{id}/connection-strings", s.handleGetConnectionStrings)
-				r.Get("/{id}/logs", s.handleGetLogs)
 				// Backup settings for scheduler
 				r.Put("/{id}/backup-settings", s.handleUpdateBackupSettings)
-				// Upscale/downscale resources
-				r.Patch("/{id}/resources", s.handleUpdateResources)
+
+				r.Group(func(r chi.Router) {
+					r.Use(s.requireRuntime)
+					r.Post("/", s.handleCreateDatabase)
+					r.Delete("/{id}", s.handleDeleteDatabase)
+					r.Post("/{id}/start", s.handleStartDatabase)
+					r.Post("/{id}/stop", s.handleStopDatabase)
+					r.Post("/{id}/pause", s.handlePauseDatabase)
+					r.Post("/{id}/resume", s.handleResumeDatabase)
+					r.Post("/{id}/backup", s.handleCreateBackup)
+					r.Post("/{id}/restore", s.handleRestoreBackup)
+					r.Post("/{id}/import", s.handleImportDump)
+					// Dumps hold all of a database's data, so viewers can't take them
+					r.With(requireAdmin).Get("/{id}/export", s.handleExportDatabase)
+					r.Get("/{id}/schema", s.handleGetSchema)
+					r.Post("/{id}/query", s.handleQuery)
+					r.Get("/{id}/metrics", s.handleGetMetrics)
+					r.Get("/{id}/metrics/stream", s.handleMetricsStream)
+					r.Get("/{id}/health", s.handleHealthCheckDatabase)
+					r.Get("/{id}/logs", s.handleGetLogs)
+					// Interactive CLI session over WebSocket
+					r.With(requireAdmin).Get("/{id}/shell", s.handleShell)
+					// Upscale/downscale resources
+					r.Patch("/{id}/resources", s.handleUpdateResources)
+					// Move to another network
+					r.Post("/{id}/network", s.handleSetNetwork)
+				})
 			})
 
 			// Bulk operations
 			r.Route("/databases/bulk", func(r chi.Router) {
+				r.Use(s.requireRuntime)
 				r.Post("/start", s.handleBulkStart)
 				r.Post("/stop", s.handleBulkStop)
 				r.Post("/delete", s.handleBulkDelete)
+				r.Post("/backup", s.handleBulkBackup)
+				r.Post("/clone", s.handleBulkClone)
 			})
 
 			// Backup routes
 			r.Get("/backups", s.handleListBackups)
-			r.Get("/backups/{id}/download", s.handleDownloadBackup)
+			r.With(requireAdmin).Get("/backups/{id}/download", s.handleDownloadBackup)
 			r.Get("/backups/{id}/info", s.handleGetBackupInfo)
 			r.Delete("/backups/{id}", s.handleDeleteBackup)
 
 			// Network routes
-			r.Get("/networks", s.handleListNetworks)
-			r.Post("/networks", s.handleCreateNetwork)
-			r.Delete("/networks/{name}", s.handleDeleteNetwork)
+			r.With(s.requireRuntime).Get("/networks", s.handleListNetworks)
+			r.With(s.requireRuntime).Post("/networks", s.handleCreateNetwork)
+			r.With(s.requireRuntime).Delete("/networks/{name}", s.handleDeleteNetwork)
 
-			// Topology route
+			// Topology routes
 			r.Get("/topology", s.handleGetTopology)
+			r.Get("/topology/edges", s.handleGetTopologyEdges)
+
+			// Dashboard summary
+			r.Get("/summary", s.handleGetSummary)
+
+			// Store/runtime reconciliation
+			r.With(s.requireRuntime).Get("/reconcile", s.handleGetReconcile)
+			r.With(requireAdmin, s.requireRuntime).Post("/reconcile", s.handleReconcile)
+
+			// API key routes (admin only)
+			r.Route("/apikeys", func(r chi.Router) {
+				r.Use(requireAdmin)
+				r.Get("/", s.handleListAPIKeys)
+				r.Post("/", s.handleCreateAPIKey)
+				r.Delete("/{id}", s.handleDeleteAPIKey)
+			})
+
+			// Audit log (admin only)
+			r.With(requireAdmin).Get("/audit", s.handleListAudit)
+
+			// Webhook routes (admin only)
+			r.Route("/webhooks", func(r chi.Router) {
+				r.Use(requireAdmin)
+				r.Get("/", s.handleListWebhooks)
+				r.Post("/", s.handleCreateWebhook)
+				r.Delete("/{id}", s.handleDeleteWebhook)
+			})
 		})
 	})
 
 	return r
 }
 
+// maxStopTimeoutSeconds caps the grace period a stop request may ask for
+const maxStopTimeoutSeconds = 600
+
 // Response helpers
 func jsonResponse(w http.ResponseWriter, status int, data interface{}) {
 	w.Header().Set("Content-Type", "application/json")
@@ -121,18 +202,86 @@ func jsonResponse(w http.ResponseWriter, status int, data interface{}) {
 	json.NewEncoder(w).Encode(data)
 }
 
-func errorResponse(w http.ResponseWriter, status int, message string) {
-	jsonResponse(w, status, map[string]string{"error": message})
+// RuntimeHealth describes the container runtime in the health check
+type RuntimeHealth struct {
+	Name      string `json:"name"`
+	Mode      string `json:"mode"`
+	Version   string `json:"version,omitempty"`
+	Reachable bool   `json:"reachable"`
+	Error     string `json:"error,omitempty"`
+}
+
+// runtimeHealthTTL is how long a runtime health check is reused, so a
+// frequently polled /health doesn't ping the daemon on every request
+const runtimeHealthTTL = 5 * time.Second
+
+// healthCache holds the last runtime health check
+type healthCache struct {
+	mu      sync.Mutex
+	checked time.Time
+	runtime RuntimeHealth
+}
+
+// runtimeHealth pings the container runtime, or returns the last result if
+// it is recent. Concurrent callers wait for a single ping.
+func (s *Server) runtimeHealth() RuntimeHealth {
+	s.health.mu.Lock()
+	defer s.health.mu.Unlock()
+	if time.Since(s.health.checked) < runtimeHealthTTL {
+		return s.health.runtime
+	}
+
+	opts := s.options()
+	ctx, cancel := context.WithTimeout(context.Background(), opts.HealthCheckTimeout)
+	defer cancel()
+
+	rt := RuntimeHealth{Name: opts.Runtime, Mode: opts.RuntimeMode}
+	if err := s.docker.Ping(ctx); err != nil {
+		rt.Error = err.Error()
+	} else {
+		rt.Reachable = true
+		if v, err := s.docker.Version(ctx); err != nil {
+			rt.Error = err.Error()
+		} else {
+			rt.Version = v
+		}
+	}
+	s.health.runtime, s.health.checked = rt, time.Now()
+	return rt
 }
 
-// Health check handler
+// Health check handler. It reports the container runtime alongside the
+// server's own status, so an unreachable daemon shows up here rather than as
+// failing database operations. The endpoint is public, so unauthenticated
+// callers only learn whether the runtime is "ok" or "unavailable"; daemon
+// details and errors need a session or API key.
 func (s *Server) handleHealthCheck(w http.ResponseWriter, r *http.Request) {
-	jsonResponse(w, http.StatusOK, map[string]string{
-		"status":  "healthy",
-		"version": "1.0.0",
+	rt := s.runtimeHealth()
+
+	// The server still answers without its runtime, in degraded mode
+	status := "healthy"
+	if !rt.Reachable {
+		status = "degraded"
+	}
+	var runtimeStatus interface{} = rt
+	if !s.authenticated(r) {
+		runtimeStatus = "ok"
+		if !rt.Reachable {
+			runtimeStatus = "unavailable"
+		}
+	}
+	jsonResponse(w, http.StatusOK, map[string]interface{}{
+		"status":  status,
+		"version": version.Version,
+		"runtime": runtimeStatus,
 	})
 }
 
+// handleVersion returns the build information of the running binary
+func (s *Server) handleVersion(w http.ResponseWriter, r *http.Request) {
+	jsonResponse(w, http.StatusOK, version.Get())
+}
+
 // Database handlers
 
 func (s *Server) handleListDatabases(w http.ResponseWriter, r *http.Request) {
@@ -143,37 +292,102 @@ func (s *Server) handleListDatabases(w http.ResponseWriter, r *http.Request) {
 func (s *Server) handleCreateDatabase(w http.ResponseWriter, r *http.Request) {
 	var req database.CreateRequest
 	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-		errorResponse(w, http.StatusBadRequest, "Invalid request body")
+		errorResponse(w, http.StatusBadRequest, CodeInvalidBody, "Invalid request body")
 		return
 	}
 
 	// Validation
 	if req.Name == "" {
-		errorResponse(w, http.StatusBadRequest, "Name is required")
+		errorResponse(w, http.StatusBadRequest, CodeInvalidRequest, "Name is required")
 		return
 	}
 	if req.Engine == "" {
-		errorResponse(w, http.StatusBadRequest, "Engine is required")
+		errorResponse(w, http.StatusBadRequest, CodeInvalidRequest, "Engine is required")
 		return
 	}
 
 	// Username and database are always required (password is optional - auto-generated if empty)
 	if req.Username == "" {
-		errorResponse(w, http.StatusBadRequest, "Username is required")
+		errorResponse(w, http.StatusBadRequest, CodeInvalidRequest, "Username is required")
 		return
 	}
 	if req.Database == "" {
-		errorResponse(w, http.StatusBadRequest, "Database name is required")
+		errorResponse(w, http.StatusBadRequest, CodeInvalidRequest, "Database name is required")
+		return
+	}
+	if req.Image != "" {
+		if err := database.ValidateImage(req.Image); err != nil {
+			errorResponse(w, http.StatusBadRequest, CodeInvalidRequest, err.Error())
+			return
+		}
+	} else if engine, err := database.GetEngine(req.Engine); err == nil {
+		// Custom images have their own tags, so only the stock image's versions are checked
+		if err := database.ValidateVersion(engine, req.Version); err != nil {
+			errorDetailsResponse(w, http.StatusBadRequest, CodeInvalidVersion, err.Error(), map[string]interface{}{
+				"versions": engine.Versions(),
+			})
+			return
+		}
+	}
+	if req.ImageDigest != "" {
+		if err := database.ValidateImageDigest(req.Image, req.ImageDigest); err != nil {
+			errorResponse(w, http.StatusBadRequest, CodeInvalidRequest, err.Error())
+			return
+		}
+	}
+	if err := database.ValidateExtraEnv(req.ExtraEnv); err != nil {
+		errorResponse(w, http.StatusBadRequest, CodeInvalidRequest, err.Error())
+		return
+	}
+	if err := database.ValidateConfigParams(req.Engine, req.ConfigParams); err != nil {
+		errorResponse(w, http.StatusBadRequest, CodeInvalidRequest, err.Error())
+		return
+	}
+	if err := database.ValidateLabels(req.Labels); err != nil {
+		errorResponse(w, http.StatusBadRequest, CodeInvalidRequest, err.Error())
+		return
+	}
+	if _, _, err := runtime.ParseRestartPolicy(req.RestartPolicy); err != nil {
+		errorResponse(w, http.StatusBadRequest, CodeInvalidRequest, err.Error())
+		return
+	}
+	if req.HostDataPath != "" {
+		if _, err := s.db.ValidateHostDataPath(req.HostDataPath); err != nil {
+			errorResponse(w, http.StatusBadRequest, CodeInvalidRequest, err.Error())
+			return
+		}
+	}
+
+	// Dry run: validate and return the planned container without creating anything
+	if r.URL.Query().Get("dryRun") == "true" {
+		cfg, err := s.db.Plan(&req)
+		if err != nil {
+			errorResponse(w, http.StatusBadRequest, CodeInvalidRequest, err.Error())
+			return
+		}
+		jsonResponse(w, http.StatusOK, cfg)
 		return
 	}
 
 	db, err := s.db.Create(r.Context(), &req)
+	if err != nil {
+		s.audit(r, "database.create", req.Name, err)
+	}
+	if errors.Is(err, database.ErrInsufficientResources) {
+		errorResponse(w, http.StatusBadRequest, CodeInsufficientResources, err.Error())
+		return
+	}
+	if errors.Is(err, database.ErrPortUnavailable) {
+		errorResponse(w, http.StatusConflict, CodePortUnavailable, err.Error())
+		return
+	}
 	if err != nil {
 		log.Error().Err(err).Str("name", req.Name).Str("engine", req.Engine).Msg("Failed to create database")
-		errorResponse(w, http.StatusInternalServerError, err.Error())
+		errorResponse(w, http.StatusInternalServerError, CodeInternal, err.Error())
 		return
 	}
 
+	s.audit(r, "database.create", db.ID, nil)
 	log.Info().Str("id", db.ID).Str("name", db.Name).Str("engine", db.Engine).Msg("Database creation initiated")
 	jsonResponse(w, http.StatusCreated, db)
 }
@@ -181,28 +395,56 @@ func (s *Server) handleCreateDatabase(w http.ResponseWriter, r *http.Request) {
 func (s *Server) handleGetDatabase(w http.ResponseWriter, r *http.Request) {
 	id := chi.URLParam(r, "id")
 	if id == "" {
-		errorResponse(w, http.StatusBadRequest, "Database ID is required")
+		errorResponse(w, http.StatusBadRequest, CodeInvalidRequest, "Database ID is required")
 		return
 	}
 
 	db, err := s.db.Get(id)
 	if err != nil {
-		errorResponse(w, http.StatusNotFound, "Database not found")
+		errorResponse(w, http.StatusNotFound, CodeDatabaseNotFound, "Database not found")
 		return
 	}
 
 	jsonResponse(w, http.StatusOK, db)
 }
 
+// deleteRequest is the body of a database delete when deletes require confirmation
+type deleteRequest struct {
+	// Confirm is the database's name
+	Confirm string `json:"confirm"`
+}
+
+// handleDeleteDatabase deletes a database. When deletes require confirmation
+// the body must be {"confirm": "<database name>"}.
 func (s *Server) handleDeleteDatabase(w http.ResponseWriter, r *http.Request) {
 	id := chi.URLParam(r, "id")
 	if id == "" {
-		errorResponse(w, http.StatusBadRequest, "Database ID is required")
+		errorResponse(w, http.StatusBadRequest, CodeInvalidRequest, "Database ID is required")
 		return
 	}
 
-	if err := s.db.Delete(r.Context(), id); err != nil {
-		errorResponse(w, http.StatusInternalServerError, err.Error())
+	if s.options().RequireDeleteConfirmation {
+		db, err := s.db.Get(id)
+		if err != nil {
+			errorResponse(w, http.StatusNotFound, CodeDatabaseNotFound, "Database not found")
+			return
+		}
+		var req deleteRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil && err != io.EOF {
+			errorResponse(w, http.StatusBadRequest, CodeInvalidBody, "Invalid request body")
+			return
+		}
+		if req.Confirm != db.Name {
+			errorResponse(w, http.StatusBadRequest, CodeConfirmationMismatch, "Confirmation does not match the database name")
+			return
+		}
+	}
+
+	opts := database.DeleteOptions{KeepBackups: r.URL.Query().Get("keepBackups") == "true"}
+	err := s.db.Delete(r.Context(), id, opts)
+	s.audit(r, "database.delete", id, err)
+	if err != nil {
+		errorResponse(w, http.StatusInternalServerError, CodeInternal, err.Error())
 		return
 	}
 
@@ -212,12 +454,50 @@ func (s *Server) handleDeleteDatabase(w http.ResponseWriter, r *http.Request) {
 func (s *Server) handleStartDatabase(w http.ResponseWriter, r *http.Request) {
 	id := chi.URLParam(r, "id")
 	if id == "" {
-		errorResponse(w, http.StatusBadRequest, "Database ID is required")
+		errorResponse(w, http.StatusBadRequest, CodeInvalidRequest, "Database ID is required")
+		return
+	}
+
+	err := s.db.Start(r.Context(), id)
+	s.audit(r, "database.start", id, err)
+	if err != nil {
+		errorResponse(w, http.StatusInternalServerError, CodeInternal, err.Error())
+		return
+	}
+
+	db, _ := s.db.Get(id)
+	jsonResponse(w, http.StatusOK, db)
+}
+
+func (s *Server) handlePauseDatabase(w http.ResponseWriter, r *http.Request) {
+	id := chi.URLParam(r, "id")
+	if id == "" {
+		errorResponse(w, http.StatusBadRequest, CodeInvalidRequest, "Database ID is required")
 		return
 	}
 
-	if err := s.db.Start(r.Context(), id); err != nil {
-		errorResponse(w, http.StatusInternalServerError, err.Error())
+	err := s.db.Pause(r.Context(), id)
+	s.audit(r, "database.pause", id, err)
+	if err != nil {
+		errorResponse(w, http.StatusInternalServerError, CodeInternal, err.Error())
+		return
+	}
+
+	db, _ := s.db.Get(id)
+	jsonResponse(w, http.StatusOK, db)
+}
+
+func (s *Server) handleResumeDatabase(w http.ResponseWriter, r *http.Request) {
+	id := chi.URLParam(r, "id")
+	if id == "" {
+		errorResponse(w, http.StatusBadRequest, CodeInvalidRequest, "Database ID is required")
+		return
+	}
+
+	err := s.db.Resume(r.Context(), id)
+	s.audit(r, "database.resume", id, err)
+	if err != nil {
+		errorResponse(w, http.StatusInternalServerError, CodeInternal, err.Error())
 		return
 	}
 
@@ -228,12 +508,28 @@ func (s *Server) handleStartDatabase(w http.ResponseWriter, r *http.Request) {
 func (s *Server) handleStopDatabase(w http.ResponseWriter, r *http.Request) {
 	id := chi.URLParam(r, "id")
 	if id == "" {
-		errorResponse(w, http.StatusBadRequest, "Database ID is required")
+		errorResponse(w, http.StatusBadRequest, CodeInvalidRequest, "Database ID is required")
 		return
 	}
 
-	if err := s.db.Stop(r.Context(), id); err != nil {
-		errorResponse(w, http.StatusInternalServerError, err.Error())
+	// ?timeout=N gives the database N seconds to flush; ?force=true kills immediately
+	timeout := runtime.DefaultStopTimeout
+	if v := r.URL.Query().Get("timeout"); v != "" {
+		secs, err := strconv.Atoi(v)
+		if err != nil || secs < 0 || secs > maxStopTimeoutSeconds {
+			errorResponse(w, http.StatusBadRequest, CodeInvalidRequest, fmt.Sprintf("timeout must be between 0 and %d seconds", maxStopTimeoutSeconds))
+			return
+		}
+		timeout = time.Duration(secs) * time.Second
+	}
+	if r.URL.Query().Get("force") == "true" {
+		timeout = 0
+	}
+
+	err := s.db.Stop(r.Context(), id, timeout)
+	s.audit(r, "database.stop", id, err)
+	if err != nil {
+		errorResponse(w, http.StatusInternalServerError, CodeInternal, err.Error())
 		return
 	}
 
@@ -241,77 +537,320 @@ func (s *Server) handleStopDatabase(w http.ResponseWriter, r *http.Request) {
 	jsonResponse(w, http.StatusOK, db)
 }
 
+// backupRequest is the optional body of a backup
+type backupRequest struct {
+	// Tables limits the backup to these tables
+	Tables []string `json:"tables"`
+}
+
 func (s *Server) handleCreateBackup(w http.ResponseWriter, r *http.Request) {
 	id := chi.URLParam(r, "id")
 	if id == "" {
-		errorResponse(w, http.StatusBadRequest, "Database ID is required")
+		errorResponse(w, http.StatusBadRequest, CodeInvalidRequest, "Database ID is required")
+		return
+	}
+
+	// The body is optional; without one the whole database is backed up
+	var req backupRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil && err != io.EOF {
+		errorResponse(w, http.StatusBadRequest, CodeInvalidBody, "Invalid request body")
 		return
 	}
+	if len(req.Tables) > 0 {
+		db, err := s.store.GetDatabase(id)
+		if err != nil {
+			errorResponse(w, http.StatusNotFound, CodeDatabaseNotFound, "Database not found")
+			return
+		}
+		if err := database.ValidateBackupTables(db.Engine, req.Tables); err != nil {
+			errorResponse(w, http.StatusBadRequest, CodeInvalidRequest, err.Error())
+			return
+		}
+	}
 
-	backup, err := s.db.CreateBackup(r.Context(), id)
+	backup, err := s.db.CreateBackup(r.Context(), id, req.Tables)
+	s.audit(r, "database.backup", id, err)
+	if errors.Is(err, database.ErrInsufficientDiskSpace) {
+		errorResponse(w, http.StatusInsufficientStorage, CodeInsufficientStorage, err.Error())
+		return
+	}
 	if err != nil {
-		errorResponse(w, http.StatusInternalServerError, err.Error())
+		errorResponse(w, http.StatusInternalServerError, CodeInternal, err.Error())
 		return
 	}
 
 	jsonResponse(w, http.StatusAccepted, backup)
 }
 
+// restoreRequest is the body of a restore
+type restoreRequest struct {
+	BackupID string `json:"backupId"`
+	// Tables limits the restore to these tables from the backup
+	Tables []string `json:"tables"`
+}
+
 func (s *Server) handleRestoreBackup(w http.ResponseWriter, r *http.Request) {
 	id := chi.URLParam(r, "id")
 	if id == "" {
-		errorResponse(w, http.StatusBadRequest, "Database ID is required")
+		errorResponse(w, http.StatusBadRequest, CodeInvalidRequest, "Database ID is required")
 		return
 	}
 
-	var req struct {
-		BackupID string `json:"backupId"`
-	}
+	var req restoreRequest
 	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-		errorResponse(w, http.StatusBadRequest, "Invalid request body")
+		errorResponse(w, http.StatusBadRequest, CodeInvalidBody, "Invalid request body")
 		return
 	}
 
 	if req.BackupID == "" {
-		errorResponse(w, http.StatusBadRequest, "Backup ID is required")
+		errorResponse(w, http.StatusBadRequest, CodeInvalidRequest, "Backup ID is required")
 		return
 	}
 
-	if err := s.db.RestoreBackup(r.Context(), req.BackupID, id); err != nil {
-		errorResponse(w, http.StatusInternalServerError, err.Error())
+	if len(req.Tables) > 0 {
+		backup, err := s.store.GetBackup(req.BackupID)
+		if err != nil {
+			errorResponse(w, http.StatusNotFound, CodeBackupNotFound, "Backup not found")
+			return
+		}
+		db, err := s.store.GetDatabase(id)
+		if err != nil {
+			errorResponse(w, http.StatusNotFound, CodeDatabaseNotFound, "Database not found")
+			return
+		}
+		if err := database.ValidateRestoreTables(backup, db.Engine, req.Tables); err != nil {
+			errorResponse(w, http.StatusBadRequest, CodeInvalidRequest, err.Error())
+			return
+		}
+	}
+
+	err := s.db.RestoreBackup(r.Context(), req.BackupID, id, req.Tables)
+	s.audit(r, "database.restore", id, err)
+	if errors.Is(err, database.ErrBackupCorrupted) {
+		errorResponse(w, http.StatusUnprocessableEntity, CodeBackupCorrupted, err.Error())
+		return
+	}
+	if err != nil {
+		errorResponse(w, http.StatusInternalServerError, CodeInternal, err.Error())
 		return
 	}
 
 	jsonResponse(w, http.StatusOK, map[string]string{"status": "restored"})
 }
 
+// handleImportDump streams an uploaded dump file (plain or gzip) into the database.
+// The multipart body is read part by part so the file is never buffered whole.
+func (s *Server) handleImportDump(w http.ResponseWriter, r *http.Request) {
+	id := chi.URLParam(r, "id")
+	if id == "" {
+		errorResponse(w, http.StatusBadRequest, CodeInvalidRequest, "Database ID is required")
+		return
+	}
+
+	db, err := s.db.Get(id)
+	if err != nil {
+		errorResponse(w, http.StatusNotFound, CodeDatabaseNotFound, "Database not found")
+		return
+	}
+	if !database.ContainerUp(db.Status) {
+		errorResponse(w, http.StatusConflict, CodeDatabaseNotRunning, "Database is not running")
+		return
+	}
+
+	reader, err := r.MultipartReader()
+	if err != nil {
+		errorResponse(w, http.StatusBadRequest, CodeInvalidBody, "Expected multipart/form-data body")
+		return
+	}
+
+	for {
+		part, err := reader.NextPart()
+		if err == io.EOF {
+			errorResponse(w, http.StatusBadRequest, CodeInvalidRequest, "Missing 'file' field")
+			return
+		}
+		if err != nil {
+			errorResponse(w, http.StatusBadRequest, CodeInvalidBody, "Invalid multipart body")
+			return
+		}
+		if part.FormName() != "file" {
+			part.Close()
+			continue
+		}
+
+		result, err := s.db.Import(r.Context(), id, part)
+		part.Close()
+		s.audit(r, "database.import", id, err)
+		if err != nil {
+			errorResponse(w, http.StatusInternalServerError, CodeInternal, err.Error())
+			return
+		}
+		jsonResponse(w, http.StatusOK, result)
+		return
+	}
+}
+
+// handleExportDatabase streams a plain-text SQL dump as a file download
+func (s *Server) handleExportDatabase(w http.ResponseWriter, r *http.Request) {
+	id := chi.URLParam(r, "id")
+	if id == "" {
+		errorResponse(w, http.StatusBadRequest, CodeInvalidRequest, "Database ID is required")
+		return
+	}
+
+	if format := r.URL.Query().Get("format"); format != "" && format != "sql" {
+		errorResponse(w, http.StatusBadRequest, CodeInvalidRequest, "Unsupported export format: "+format)
+		return
+	}
+
+	db, err := s.db.Get(id)
+	if err != nil {
+		errorResponse(w, http.StatusNotFound, CodeDatabaseNotFound, "Database not found")
+		return
+	}
+	if !database.ContainerUp(db.Status) {
+		errorResponse(w, http.StatusConflict, CodeDatabaseNotRunning, "Database is not running")
+		return
+	}
+
+	engine, err := database.GetEngine(db.Engine)
+	if err != nil {
+		errorResponse(w, http.StatusBadRequest, CodeInvalidRequest, err.Error())
+		return
+	}
+	if cmd, _ := engine.ExportCommand(db); cmd == nil {
+		errorResponse(w, http.StatusBadRequest, CodeInvalidRequest, "SQL export not supported for "+engine.Name())
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/sql")
+	w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=%s.sql", db.Name))
+
+	// Headers are already sent once streaming starts, so failures can only be logged
+	if err := s.db.Export(r.Context(), id, w); err != nil {
+		log.Error().Err(err).Str("db", id).Msg("SQL export failed")
+	}
+}
+
+// handleGetSchema lists a database's tables, with their columns if
+// ?columns=true, or its Redis key patterns
+func (s *Server) handleGetSchema(w http.ResponseWriter, r *http.Request) {
+	id := chi.URLParam(r, "id")
+	if id == "" {
+		errorResponse(w, http.StatusBadRequest, CodeInvalidRequest, "Database ID is required")
+		return
+	}
+
+	db, err := s.db.Get(id)
+	if err != nil {
+		errorResponse(w, http.StatusNotFound, CodeDatabaseNotFound, "Database not found")
+		return
+	}
+	if !database.ContainerUp(db.Status) {
+		errorResponse(w, http.StatusConflict, CodeDatabaseNotRunning, "Database is not running")
+		return
+	}
+
+	schema, err := s.db.GetSchema(r.Context(), id, r.URL.Query().Get("columns") == "true")
+	if err != nil {
+		errorResponse(w, http.StatusInternalServerError, CodeInternal, err.Error())
+		return
+	}
+
+	jsonResponse(w, http.StatusOK, schema)
+}
+
+// queryRequest is the body of a console query
+type queryRequest struct {
+	Query    string `json:"query"`
+	Limit    int    `json:"limit,omitempty"`
+	ReadOnly bool   `json:"readOnly,omitempty"`
+}
+
+// handleQuery runs a query from the console and responds with a result per
+// statement. Results are capped at the configured row limit, or the
+// request's limit if lower. Viewers can only run read-only queries.
+func (s *Server) handleQuery(w http.ResponseWriter, r *http.Request) {
+	id := chi.URLParam(r, "id")
+
+	var req queryRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		errorResponse(w, http.StatusBadRequest, CodeInvalidBody, "Invalid request body")
+		return
+	}
+	if strings.TrimSpace(req.Query) == "" {
+		errorResponse(w, http.StatusBadRequest, CodeInvalidRequest, "Query is required")
+		return
+	}
+	if req.Limit < 0 {
+		errorResponse(w, http.StatusBadRequest, CodeInvalidRequest, "limit cannot be negative")
+		return
+	}
+
+	db, err := s.db.Get(id)
+	if err != nil {
+		errorResponse(w, http.StatusNotFound, CodeDatabaseNotFound, "Database not found")
+		return
+	}
+	if !database.ContainerUp(db.Status) {
+		errorResponse(w, http.StatusConflict, CodeDatabaseNotRunning, "Database is not running")
+		return
+	}
+
+	if role, _ := r.Context().Value(roleContextKey).(string); role != auth.RoleAdmin {
+		req.ReadOnly = true
+	}
+
+	results, err := s.db.Query(r.Context(), id, req.Query, database.QueryOptions{Limit: req.Limit, ReadOnly: req.ReadOnly})
+	s.recordQuery(r, id, req.Query, results, err)
+	if errors.Is(err, database.ErrReadOnlyQuery) {
+		errorResponse(w, http.StatusForbidden, CodeReadOnlyQuery, err.Error())
+		return
+	}
+	if err != nil {
+		errorResponse(w, http.StatusInternalServerError, CodeInternal, err.Error())
+		return
+	}
+	jsonResponse(w, http.StatusOK, results)
+}
+
 func (s *Server) handleGetMetrics(w http.ResponseWriter, r *http.Request) {
 	id := chi.URLParam(r, "id")
 	if id == "" {
-		errorResponse(w, http.StatusBadRequest, "Database ID is required")
+		errorResponse(w, http.StatusBadRequest, CodeInvalidRequest, "Database ID is required")
 		return
 	}
 
 	db, err := s.db.Get(id)
 	if err != nil {
-		errorResponse(w, http.StatusNotFound, "Database not found")
+		errorResponse(w, http.StatusNotFound, CodeDatabaseNotFound, "Database not found")
 		return
 	}
 
 	// All databases are dedicated now - get container stats
 	if db.ContainerID == "" {
-		errorResponse(w, http.StatusBadRequest, "Database has no container")
+		errorResponse(w, http.StatusBadRequest, CodeInvalidRequest, "Database has no container")
 		return
 	}
 
-	stats, err := s.db.GetContainerStats(r.Context(), db.ContainerID)
+	metrics, err := s.collectMetrics(r.Context(), db)
 	if err != nil {
-		errorResponse(w, http.StatusInternalServerError, err.Error())
+		errorResponse(w, http.StatusInternalServerError, CodeInternal, err.Error())
 		return
 	}
 
+	jsonResponse(w, http.StatusOK, metrics)
+}
+
+// collectMetrics fetches current container stats for a database and records them in the history
+func (s *Server) collectMetrics(ctx context.Context, db *storage.DatabaseInstance) (map[string]interface{}, error) {
+	stats, err := s.db.GetContainerStats(ctx, db.ContainerID)
+	if err != nil {
+		return nil, err
+	}
+
 	// Record metrics for history
-	s.db.RecordMetrics(id, database.MetricsPoint{
+	s.db.RecordMetrics(db.ID, database.MetricsPoint{
 		Timestamp:     time.Now(),
 		CPUPercent:    stats.CPUPercent,
 		MemoryUsage:   stats.MemoryUsage,
@@ -323,7 +862,7 @@ func (s *Server) handleGetMetrics(w http.ResponseWriter, r *http.Request) {
 		NetworkTx:     stats.NetworkTx,
 	})
 
-	jsonResponse(w, http.StatusOK, map[string]interface{}{
+	return map[string]interface{}{
 		"cpuPercent":    stats.CPUPercent,
 		"memoryUsage":   stats.MemoryUsage,
 		"memoryLimit":   stats.MemoryLimit,
@@ -332,19 +871,40 @@ func (s *Server) handleGetMetrics(w http.ResponseWriter, r *http.Request) {
 		"networkTx":     stats.NetworkTx,
 		"storageUsed":   db.StorageUsed,
 		"connections":   db.Connections,
-	})
+	}, nil
 }
 
 func (s *Server) handleGetLogs(w http.ResponseWriter, r *http.Request) {
 	id := chi.URLParam(r, "id")
 	if id == "" {
-		errorResponse(w, http.StatusBadRequest, "Database ID is required")
+		errorResponse(w, http.StatusBadRequest, CodeInvalidRequest, "Database ID is required")
 		return
 	}
 
-	logs, err := s.db.GetLogs(r.Context(), id)
+	var opts database.LogOptions
+	q := r.URL.Query()
+	if v := q.Get("tail"); v != "" {
+		tail, err := strconv.Atoi(v)
+		if err != nil || tail < 1 || tail > database.MaxLogTail {
+			errorResponse(w, http.StatusBadRequest, CodeInvalidRequest, fmt.Sprintf("tail must be between 1 and %d", database.MaxLogTail))
+			return
+		}
+		opts.Tail = tail
+	}
+	if v := q.Get("since"); v != "" {
+		since, err := time.ParseDuration(v)
+		if err != nil || since <= 0 {
+			errorResponse(w, http.StatusBadRequest, CodeInvalidRequest, "since must be a positive duration, e.g. 15m")
+			return
+		}
+		opts.Since = since
+	}
+	opts.Grep = q.Get("grep")
+	opts.Timestamps = q.Get("timestamps") == "true"
+
+	logs, err := s.db.GetLogs(r.Context(), id, opts)
 	if err != nil {
-		errorResponse(w, http.StatusInternalServerError, err.Error())
+		errorResponse(w, http.StatusInternalServerError, CodeInternal, err.Error())
 		return
 	}
 
@@ -362,28 +922,43 @@ func (s *Server) handleListBackups(w http.ResponseWriter, r *http.Request) {
 func (s *Server) handleDownloadBackup(w http.ResponseWriter, r *http.Request) {
 	id := chi.URLParam(r, "id")
 	if id == "" {
-		errorResponse(w, http.StatusBadRequest, "Backup ID is required")
+		errorResponse(w, http.StatusBadRequest, CodeInvalidRequest, "Backup ID is required")
 		return
 	}
 
 	backup, err := s.store.GetBackup(id)
 	if err != nil || backup == nil {
-		errorResponse(w, http.StatusNotFound, "Backup not found")
+		errorResponse(w, http.StatusNotFound, CodeBackupNotFound, "Backup not found")
 		return
 	}
 
-	// Get backup file path
-	backupPath := s.store.GetBackupPath(id)
-	if backupPath == "" {
-		errorResponse(w, http.StatusNotFound, "Backup file not found")
+	if backup.Status != "completed" {
+		errorResponse(w, http.StatusConflict, CodeBackupNotReady, fmt.Sprintf("Backup is %s", backup.Status))
+		return
+	}
+
+	// Encrypted backups are decrypted on the fly; ranges apply to the plaintext
+	f, size, modTime, err := s.db.OpenBackup(backup)
+	if errors.Is(err, os.ErrNotExist) {
+		errorResponse(w, http.StatusNotFound, CodeBackupNotFound, "Backup file not found")
+		return
+	}
+	if err != nil {
+		errorResponse(w, http.StatusInternalServerError, CodeInternal, err.Error())
 		return
 	}
+	defer f.Close()
 
-	// Set headers for download
+	// Set headers for download. The dump is served byte-for-byte (no
+	// Content-Encoding, even for gzip dumps) so ranges stay valid, and the ETag
+	// lets clients resume with If-Range.
 	w.Header().Set("Content-Type", "application/octet-stream")
 	w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=%s-%s.backup", backup.DatabaseName, backup.ID))
+	w.Header().Set("Accept-Ranges", "bytes")
+	w.Header().Set("ETag", fmt.Sprintf(`"%s-%d-%d"`, backup.ID, size, modTime.Unix()))
 
-	http.ServeFile(w, r, backupPath)
+	// ServeContent handles Range/If-Range, 206 and 416 responses, and Content-Length
+	http.ServeContent(w, r, "", modTime, f)
 }
 
 // handleListNetworks returns all available Docker networks
@@ -395,30 +970,34 @@ func (s *Server) handleListNetworks(w http.ResponseWriter, r *http.Request) {
 
 	networks, err := s.docker.ListNetworks(r.Context())
 	if err != nil {
-		errorResponse(w, http.StatusInternalServerError, err.Error())
+		errorResponse(w, http.StatusInternalServerError, CodeInternal, err.Error())
 		return
 	}
 
 	jsonResponse(w, http.StatusOK, networks)
 }
 
+// networkRequest is the body of a network create
+type networkRequest struct {
+	// Name is prefixed with "dbnest-"
+	Name string `json:"name"`
+}
+
 // handleCreateNetwork creates a new Docker network
 func (s *Server) handleCreateNetwork(w http.ResponseWriter, r *http.Request) {
 	if s.docker == nil {
-		errorResponse(w, http.StatusInternalServerError, "Docker not available")
+		errorResponse(w, http.StatusInternalServerError, CodeRuntimeUnavailable, "Docker not available")
 		return
 	}
 
-	var req struct {
-		Name string `json:"name"`
-	}
+	var req networkRequest
 	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-		errorResponse(w, http.StatusBadRequest, "Invalid request body")
+		errorResponse(w, http.StatusBadRequest, CodeInvalidBody, "Invalid request body")
 		return
 	}
 
 	if req.Name == "" {
-		errorResponse(w, http.StatusBadRequest, "Network name is required")
+		errorResponse(w, http.StatusBadRequest, CodeInvalidRequest, "Network name is required")
 		return
 	}
 
@@ -426,8 +1005,9 @@ func (s *Server) handleCreateNetwork(w http.ResponseWriter, r *http.Request) {
 	networkName := "dbnest-" + req.Name
 
 	network, err := s.docker.CreateNetwork(r.Context(), networkName)
+	s.audit(r, "network.create", networkName, err)
 	if err != nil {
-		errorResponse(w, http.StatusInternalServerError, err.Error())
+		errorResponse(w, http.StatusInternalServerError, CodeInternal, err.Error())
 		return
 	}
 
@@ -437,18 +1017,49 @@ func (s *Server) handleCreateNetwork(w http.ResponseWriter, r *http.Request) {
 // handleDeleteNetwork deletes a Docker network
 func (s *Server) handleDeleteNetwork(w http.ResponseWriter, r *http.Request) {
 	if s.docker == nil {
-		errorResponse(w, http.StatusInternalServerError, "Docker not available")
+		errorResponse(w, http.StatusInternalServerError, CodeRuntimeUnavailable, "Docker not available")
 		return
 	}
 
 	name := chi.URLParam(r, "name")
 	if name == "" {
-		errorResponse(w, http.StatusBadRequest, "Network name is required")
+		errorResponse(w, http.StatusBadRequest, CodeInvalidRequest, "Network name is required")
 		return
 	}
 
-	if err := s.docker.DeleteNetwork(r.Context(), name); err != nil {
-		errorResponse(w, http.StatusInternalServerError, err.Error())
+	type attachedDatabase struct {
+		ID   string `json:"id"`
+		Name string `json:"name"`
+	}
+	var attached []attachedDatabase
+	for _, db := range s.store.ListDatabases() {
+		if db.Network == name {
+			attached = append(attached, attachedDatabase{ID: db.ID, Name: db.Name})
+		}
+	}
+
+	if len(attached) > 0 {
+		// ?force=true moves attached databases back to the default network first
+		if r.URL.Query().Get("force") != "true" {
+			errorDetailsResponse(w, http.StatusConflict, CodeNetworkInUse, "Network is in use by databases; detach them or retry with force=true", map[string]interface{}{
+				"databases": attached,
+			})
+			return
+		}
+		for _, db := range attached {
+			_, err := s.db.SetNetwork(r.Context(), db.ID, "")
+			s.audit(r, "database.network", db.ID, err)
+			if err != nil {
+				errorResponse(w, http.StatusInternalServerError, CodeInternal, fmt.Sprintf("failed to detach %s: %v", db.Name, err))
+				return
+			}
+		}
+	}
+
+	err := s.docker.DeleteNetwork(r.Context(), name)
+	s.audit(r, "network.delete", name, err)
+	if err != nil {
+		errorResponse(w, http.StatusInternalServerError, CodeInternal, err.Error())
 		return
 	}
 
@@ -457,11 +1068,15 @@ func (s *Server) handleDeleteNetwork(w http.ResponseWriter, r *http.Request) {
 
 // TopologyNode represents a database in the topology
 type TopologyNode struct {
-	ID      string `json:"id"`
-	Name    string `json:"name"`
-	Engine  string `json:"engine"`
-	Status  string `json:"status"`
-	Network string `json:"network"`
+	ID         string `json:"id"`
+	Name       string `json:"name"`
+	Engine     string `json:"engine"`
+	Status     string `json:"status"`
+	Network    string `json:"network"`
+	Host       string `json:"host"`
+	Port       int    `json:"port"`
+	ExposePort bool   `json:"exposePort"`
+	ClonedFrom string `json:"clonedFrom,omitempty"`
 }
 
 // TopologyNetwork represents a network with its databases
@@ -470,52 +1085,117 @@ type TopologyNetwork struct {
 	Databases []TopologyNode `json:"databases"`
 }
 
+// Topology edge types
+const (
+	edgeClone   = "clone"   // Target was cloned from source
+	edgeNetwork = "network" // Source and target share a user-defined network and can reach each other
+)
+
+// TopologyEdge is a relationship between two databases
+type TopologyEdge struct {
+	Source string `json:"source"`
+	Target string `json:"target"`
+	Type   string `json:"type"`
+}
+
 // handleGetTopology returns network topology for visualization
 func (s *Server) handleGetTopology(w http.ResponseWriter, r *http.Request) {
 	databases := s.store.ListDatabases()
+	sort.Slice(databases, func(i, j int) bool { return databases[i].Name < databases[j].Name })
 
 	// Group databases by network
 	networkMap := make(map[string][]TopologyNode)
 
 	for _, db := range databases {
-		networkName := db.Network
-		if networkName == "" {
-			networkName = "default"
-		}
+		networkName := topologyNetwork(db)
 
 		node := TopologyNode{
-			ID:      db.ID,
-			Name:    db.Name,
-			Engine:  db.Engine,
-			Status:  db.Status,
-			Network: networkName,
+			ID:         db.ID,
+			Name:       db.Name,
+			Engine:     db.Engine,
+			Status:     db.Status,
+			Network:    networkName,
+			Host:       db.Host,
+			Port:       db.Port,
+			ExposePort: db.ExposePort,
+			ClonedFrom: db.ClonedFrom,
 		}
 
 		networkMap[networkName] = append(networkMap[networkName], node)
 	}
 
 	// Convert to slice
-	var topology []TopologyNetwork
+	topology := []TopologyNetwork{}
 	for name, dbs := range networkMap {
 		topology = append(topology, TopologyNetwork{
 			Name:      name,
 			Databases: dbs,
 		})
 	}
+	sort.Slice(topology, func(i, j int) bool { return topology[i].Name < topology[j].Name })
 
 	jsonResponse(w, http.StatusOK, topology)
 }
 
+// handleGetTopologyEdges returns the relationships between databases, for
+// drawing on top of the network grouping from handleGetTopology
+func (s *Server) handleGetTopologyEdges(w http.ResponseWriter, r *http.Request) {
+	databases := s.store.ListDatabases()
+	sort.Slice(databases, func(i, j int) bool { return databases[i].Name < databases[j].Name })
+
+	edges := []TopologyEdge{}
+	exists := make(map[string]bool)
+	networkMap := make(map[string][]string)
+	for _, db := range databases {
+		exists[db.ID] = true
+		networkMap[topologyNetwork(db)] = append(networkMap[topologyNetwork(db)], db.ID)
+	}
+
+	// Containers on the same user-defined network resolve each other by name
+	names := make([]string, 0, len(networkMap))
+	for name := range networkMap {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		if name == "default" {
+			continue
+		}
+		ids := networkMap[name]
+		for i := range ids {
+			for j := i + 1; j < len(ids); j++ {
+				edges = append(edges, TopologyEdge{Source: ids[i], Target: ids[j], Type: edgeNetwork})
+			}
+		}
+	}
+
+	for _, db := range databases {
+		if db.ClonedFrom != "" && exists[db.ClonedFrom] {
+			edges = append(edges, TopologyEdge{Source: db.ClonedFrom, Target: db.ID, Type: edgeClone})
+		}
+	}
+
+	jsonResponse(w, http.StatusOK, edges)
+}
+
+// topologyNetwork is the network a database is shown under
+func topologyNetwork(db *storage.DatabaseInstance) string {
+	if db.Network == "" {
+		return "default"
+	}
+	return db.Network
+}
+
 func (s *Server) handleHealthCheckDatabase(w http.ResponseWriter, r *http.Request) {
 	id := chi.URLParam(r, "id")
 	if id == "" {
-		errorResponse(w, http.StatusBadRequest, "Database ID is required")
+		errorResponse(w, http.StatusBadRequest, CodeInvalidRequest, "Database ID is required")
 		return
 	}
 
 	db, err := s.db.Get(id)
 	if err != nil {
-		errorResponse(w, http.StatusNotFound, "Database not found")
+		errorResponse(w, http.StatusNotFound, CodeDatabaseNotFound, "Database not found")
 		return
 	}
 
@@ -526,10 +1206,14 @@ func (s *Server) handleHealthCheckDatabase(w http.ResponseWriter, r *http.Reques
 		"engine":      db.Engine,
 		"host":        db.Host,
 		"port":        db.Port,
+		"oomKilled":   db.Status == "oom-killed",
+	}
+	if db.ErrorMessage != "" {
+		health["errorMessage"] = db.ErrorMessage
 	}
 
-	// If running, try to check actual connectivity
-	if db.Status == "running" && db.ContainerID != "" {
+	// If the container is up, try to check actual connectivity
+	if database.ContainerUp(db.Status) && db.ContainerID != "" {
 		// Get engine and run a simple health query
 		engine, err := database.GetEngine(db.Engine)
 		if err == nil {
@@ -537,19 +1221,38 @@ func (s *Server) handleHealthCheckDatabase(w http.ResponseWriter, r *http.Reques
 			switch db.Engine {
 			case "postgresql":
 				testQuery = "SELECT 1"
-			case "mysql", "mariadb":
+			case "mysql", "mariadb", "clickhouse":
 				testQuery = "SELECT 1"
 			case "redis":
 				testQuery = "PING"
 			}
 
 			if testQuery != "" {
-				result, err := engine.ExecuteQuery(r.Context(), s.docker, db, testQuery)
-				if err != nil || (result != nil && result.Error != "") {
+				ctx, cancel := context.WithTimeout(r.Context(), s.options().HealthCheckTimeout)
+				defer cancel()
+
+				// Run the query in the background so a hung exec can't outlive the timeout
+				type queryResult struct {
+					result *database.QueryResult
+					err    error
+				}
+				done := make(chan queryResult, 1)
+				go func() {
+					result, err := engine.ExecuteQuery(ctx, s.docker, db, testQuery)
+					done <- queryResult{result, err}
+				}()
+
+				select {
+				case <-ctx.Done():
 					health["healthy"] = false
-					health["connectionError"] = "Failed to execute health check query"
-				} else {
-					health["connectionVerified"] = true
+					health["connectionError"] = "timeout"
+				case res := <-done:
+					if res.err != nil || (res.result != nil && res.result.Error != "") {
+						health["healthy"] = false
+						health["connectionError"] = "Failed to execute health check query"
+					} else {
+						health["connectionVerified"] = true
+					}
 				}
 			}
 		}
@@ -558,58 +1261,93 @@ func (s *Server) handleHealthCheckDatabase(w http.ResponseWriter, r *http.Reques
 	jsonResponse(w, http.StatusOK, health)
 }
 
+// handleCheckConnectivity dials the database's published port from the
+// DBnest process. Unlike the health check, which queries the engine inside
+// its container, this shows whether clients can reach it.
+func (s *Server) handleCheckConnectivity(w http.ResponseWriter, r *http.Request) {
+	db, err := s.db.Get(chi.URLParam(r, "id"))
+	if err != nil {
+		errorResponse(w, http.StatusNotFound, CodeDatabaseNotFound, "Database not found")
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(r.Context(), s.options().HealthCheckTimeout)
+	defer cancel()
+	jsonResponse(w, http.StatusOK, database.CheckConnectivity(ctx, db))
+}
+
 // Auth middleware
 
-// authMiddleware checks for valid session token and adds user to context
+// authMiddleware checks for a valid API key or session token and adds user to context
 func (s *Server) authMiddleware(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		// Try to get token from Authorization header first
-		token := ""
-		authHeader := r.Header.Get("Authorization")
-		if strings.HasPrefix(authHeader, "Bearer ") {
-			token = strings.TrimPrefix(authHeader, "Bearer ")
-		}
-
-		// Fall back to cookie
-		if token == "" {
-			cookie, err := r.Cookie("session")
-			if err == nil {
-				token = cookie.Value
-			}
+		// API keys take precedence over session tokens
+		if apiKey := r.Header.Get("X-API-Key"); apiKey != "" {
+			s.authenticateAPIKey(w, r, next, apiKey)
+			return
 		}
 
+		token := sessionToken(r)
 		if token == "" {
-			errorResponse(w, http.StatusUnauthorized, "Authentication required")
+			errorResponse(w, http.StatusUnauthorized, CodeAuthRequired, "Authentication required")
 			return
 		}
 
 		// Validate session
 		session, err := s.store.GetSessionByToken(token)
 		if err != nil {
-			errorResponse(w, http.StatusUnauthorized, "Invalid session")
+			errorResponse(w, http.StatusUnauthorized, CodeInvalidSession, "Invalid session")
 			return
 		}
 
 		// Check if session expired
 		if time.Now().After(session.ExpiresAt) {
 			s.store.DeleteSession(session.ID)
-			errorResponse(w, http.StatusUnauthorized, "Session expired")
+			errorResponse(w, http.StatusUnauthorized, CodeInvalidSession, "Session expired")
 			return
 		}
 
 		// Get user
 		user, err := s.store.GetUser(session.UserID)
 		if err != nil {
-			errorResponse(w, http.StatusUnauthorized, "User not found")
+			errorResponse(w, http.StatusUnauthorized, CodeInvalidSession, "User not found")
 			return
 		}
 
-		// Add user to context
+		// Add user to context (session users have full access)
 		ctx := context.WithValue(r.Context(), userContextKey, user)
+		ctx = context.WithValue(ctx, roleContextKey, auth.RoleAdmin)
 		next.ServeHTTP(w, r.WithContext(ctx))
 	})
 }
 
+// sessionToken returns the session token of a request, from the
+// Authorization header or else the session cookie
+func sessionToken(r *http.Request) string {
+	if token, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer "); ok && token != "" {
+		return token
+	}
+	if cookie, err := r.Cookie("session"); err == nil {
+		return cookie.Value
+	}
+	return ""
+}
+
+// authenticated reports whether a request on a public route carries a valid
+// API key or session, without rejecting it if not
+func (s *Server) authenticated(r *http.Request) bool {
+	if apiKey := r.Header.Get("X-API-Key"); apiKey != "" {
+		key, err := s.store.GetAPIKeyByHash(auth.HashAPIKey(apiKey))
+		return err == nil && (key.ExpiresAt == nil || time.Now().Before(*key.ExpiresAt))
+	}
+	token := sessionToken(r)
+	if token == "" {
+		return false
+	}
+	session, err := s.store.GetSessionByToken(token)
+	return err == nil && time.Now().Before(session.ExpiresAt)
+}
+
 // Auth handlers
 
 // handleAuthStatus returns auth configuration status
@@ -620,40 +1358,43 @@ func (s *Server) handleAuthStatus(w http.ResponseWriter, r *http.Request) {
 	})
 }
 
+// credentialsRequest is the body of a register or login
+type credentialsRequest struct {
+	Username string `json:"username"`
+	Password string `json:"password"`
+}
+
 // handleRegister creates the first user (only works when no users exist)
 func (s *Server) handleRegister(w http.ResponseWriter, r *http.Request) {
 	// Registration only works if no users exist yet
 	if s.store.UserCount() > 0 {
-		errorResponse(w, http.StatusForbidden, "Registration closed. Users already exist.")
+		errorResponse(w, http.StatusForbidden, CodeRegistrationClosed, "Registration closed. Users already exist.")
 		return
 	}
 
-	var req struct {
-		Username string `json:"username"`
-		Password string `json:"password"`
-	}
+	var req credentialsRequest
 	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-		errorResponse(w, http.StatusBadRequest, "Invalid request body")
+		errorResponse(w, http.StatusBadRequest, CodeInvalidBody, "Invalid request body")
 		return
 	}
 
 	if req.Username == "" {
-		errorResponse(w, http.StatusBadRequest, "Username is required")
+		errorResponse(w, http.StatusBadRequest, CodeInvalidRequest, "Username is required")
 		return
 	}
 	if req.Password == "" {
-		errorResponse(w, http.StatusBadRequest, "Password is required")
+		errorResponse(w, http.StatusBadRequest, CodeInvalidRequest, "Password is required")
 		return
 	}
 	if len(req.Password) < 8 {
-		errorResponse(w, http.StatusBadRequest, "Password must be at least 8 characters")
+		errorResponse(w, http.StatusBadRequest, CodeInvalidRequest, "Password must be at least 8 characters")
 		return
 	}
 
 	// Hash password
 	hash, err := auth.HashPassword(req.Password)
 	if err != nil {
-		errorResponse(w, http.StatusInternalServerError, "Failed to hash password")
+		errorResponse(w, http.StatusInternalServerError, CodeInternal, "Failed to hash password")
 		return
 	}
 
@@ -666,7 +1407,7 @@ func (s *Server) handleRegister(w http.ResponseWriter, r *http.Request) {
 	}
 
 	if err := s.store.CreateUser(user); err != nil {
-		errorResponse(w, http.StatusInternalServerError, "Failed to create user")
+		errorResponse(w, http.StatusInternalServerError, CodeInternal, "Failed to create user")
 		return
 	}
 
@@ -680,37 +1421,34 @@ func (s *Server) handleRegister(w http.ResponseWriter, r *http.Request) {
 
 // handleLogin authenticates a user and creates a session
 func (s *Server) handleLogin(w http.ResponseWriter, r *http.Request) {
-	var req struct {
-		Username string `json:"username"`
-		Password string `json:"password"`
-	}
+	var req credentialsRequest
 	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-		errorResponse(w, http.StatusBadRequest, "Invalid request body")
+		errorResponse(w, http.StatusBadRequest, CodeInvalidBody, "Invalid request body")
 		return
 	}
 
 	if req.Username == "" || req.Password == "" {
-		errorResponse(w, http.StatusBadRequest, "Username and password are required")
+		errorResponse(w, http.StatusBadRequest, CodeInvalidRequest, "Username and password are required")
 		return
 	}
 
 	// Find user
 	user, err := s.store.GetUserByUsername(req.Username)
 	if err != nil {
-		errorResponse(w, http.StatusUnauthorized, "Invalid credentials")
+		errorResponse(w, http.StatusUnauthorized, CodeInvalidCredentials, "Invalid credentials")
 		return
 	}
 
 	// Check password
 	if !auth.CheckPassword(req.Password, user.PasswordHash) {
-		errorResponse(w, http.StatusUnauthorized, "Invalid credentials")
+		errorResponse(w, http.StatusUnauthorized, CodeInvalidCredentials, "Invalid credentials")
 		return
 	}
 
 	// Generate session token
 	token, err := auth.GenerateToken()
 	if err != nil {
-		errorResponse(w, http.StatusInternalServerError, "Failed to generate session")
+		errorResponse(w, http.StatusInternalServerError, CodeInternal, "Failed to generate session")
 		return
 	}
 
@@ -727,7 +1465,7 @@ func (s *Server) handleLogin(w http.ResponseWriter, r *http.Request) {
 	}
 
 	if err := s.store.CreateSession(session); err != nil {
-		errorResponse(w, http.StatusInternalServerError, "Failed to create session")
+		errorResponse(w, http.StatusInternalServerError, CodeInternal, "Failed to create session")
 		return
 	}
 
@@ -802,28 +1540,28 @@ func (s *Server) handleGetCurrentUser(w http.ResponseWriter, r *http.Request) {
 	}
 
 	if token == "" {
-		errorResponse(w, http.StatusUnauthorized, "Not authenticated")
+		errorResponse(w, http.StatusUnauthorized, CodeAuthRequired, "Not authenticated")
 		return
 	}
 
 	// Validate session
 	session, err := s.store.GetSessionByToken(token)
 	if err != nil {
-		errorResponse(w, http.StatusUnauthorized, "Invalid session")
+		errorResponse(w, http.StatusUnauthorized, CodeInvalidSession, "Invalid session")
 		return
 	}
 
 	// Check if session expired
 	if time.Now().After(session.ExpiresAt) {
 		s.store.DeleteSession(session.ID)
-		errorResponse(w, http.StatusUnauthorized, "Session expired")
+		errorResponse(w, http.StatusUnauthorized, CodeInvalidSession, "Session expired")
 		return
 	}
 
 	// Get user
 	user, err := s.store.GetUser(session.UserID)
 	if err != nil {
-		errorResponse(w, http.StatusUnauthorized, "User not found")
+		errorResponse(w, http.StatusUnauthorized, CodeInvalidSession, "User not found")
 		return
 	}
 
@@ -834,182 +1572,422 @@ func (s *Server) handleGetCurrentUser(w http.ResponseWriter, r *http.Request) {
 	})
 }
 
+// backupSettingsRequest is the body of a backup settings update
+type backupSettingsRequest struct {
+	BackupEnabled        bool   `json:"backupEnabled"`
+	BackupSchedule       string `json:"backupSchedule"`
+	BackupRetentionCount int    `json:"backupRetentionCount"`
+	BackupSkipUnchanged  bool   `json:"backupSkipUnchanged"`
+}
+
 // handleUpdateBackupSettings updates backup settings for a database
 func (s *Server) handleUpdateBackupSettings(w http.ResponseWriter, r *http.Request) {
 	id := chi.URLParam(r, "id")
 	if id == "" {
-		errorResponse(w, http.StatusBadRequest, "Database ID is required")
+		errorResponse(w, http.StatusBadRequest, CodeInvalidRequest, "Database ID is required")
 		return
 	}
 
-	var req struct {
-		BackupEnabled        bool   `json:"backupEnabled"`
-		BackupSchedule       string `json:"backupSchedule"`
-		BackupRetentionCount int    `json:"backupRetentionCount"`
-	}
+	var req backupSettingsRequest
 	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-		errorResponse(w, http.StatusBadRequest, "Invalid request body")
+		errorResponse(w, http.StatusBadRequest, CodeInvalidBody, "Invalid request body")
 		return
 	}
 
 	db, err := s.store.GetDatabase(id)
 	if err != nil {
-		errorResponse(w, http.StatusNotFound, "Database not found")
+		errorResponse(w, http.StatusNotFound, CodeDatabaseNotFound, "Database not found")
 		return
 	}
 
 	db.BackupEnabled = req.BackupEnabled
 	db.BackupSchedule = req.BackupSchedule
 	db.BackupRetentionCount = req.BackupRetentionCount
+	if !req.BackupSkipUnchanged {
+		// A stale marker could skip a backup after the option is re-enabled
+		db.LastChangeMarker = ""
+	}
+	db.BackupSkipUnchanged = req.BackupSkipUnchanged
 
-	if err := s.store.UpdateDatabase(db); err != nil {
-		errorResponse(w, http.StatusInternalServerError, err.Error())
+	err = s.store.UpdateDatabase(db)
+	s.audit(r, "database.backup_settings", id, err)
+	if err != nil {
+		errorResponse(w, http.StatusInternalServerError, CodeInternal, err.Error())
 		return
 	}
 
+	// Apply the new schedule now rather than on the next periodic sync
+	if err := s.scheduler.RefreshSchedule(id); err != nil {
+		log.Error().Err(err).Str("db", id).Str("schedule", db.BackupSchedule).Msg("Failed to refresh backup schedule")
+	}
+
 	jsonResponse(w, http.StatusOK, db)
 }
 
+// handleGetReconcile reports containers and databases that have drifted apart
+func (s *Server) handleGetReconcile(w http.ResponseWriter, r *http.Request) {
+	report, err := s.db.Reconcile(r.Context())
+	if err != nil {
+		errorResponse(w, http.StatusInternalServerError, CodeInternal, err.Error())
+		return
+	}
+	jsonResponse(w, http.StatusOK, report)
+}
+
+// handleReconcile removes orphaned containers and volumes
+func (s *Server) handleReconcile(w http.ResponseWriter, r *http.Request) {
+	result, err := s.db.CleanupOrphans(r.Context())
+	s.audit(r, "reconcile.cleanup", "", err)
+	if err != nil {
+		errorResponse(w, http.StatusInternalServerError, CodeInternal, err.Error())
+		return
+	}
+	jsonResponse(w, http.StatusOK, result)
+}
+
+// resourcesRequest is the body of a resources update
+type resourcesRequest struct {
+	MemoryLimit int64   `json:"memoryLimit"` // bytes
+	CPULimit    float64 `json:"cpuLimit"`    // cores
+}
+
+// resourcesResponse is the updated database, noting whether the new limits
+// wait for a restart
+type resourcesResponse struct {
+	*storage.DatabaseInstance
+	RestartRequired bool   `json:"restartRequired"`
+	Message         string `json:"message,omitempty"`
+}
+
 // handleUpdateResources updates memory and CPU limits for a database (upscale/downscale)
 func (s *Server) handleUpdateResources(w http.ResponseWriter, r *http.Request) {
 	id := chi.URLParam(r, "id")
 	if id == "" {
-		errorResponse(w, http.StatusBadRequest, "Database ID is required")
+		errorResponse(w, http.StatusBadRequest, CodeInvalidRequest, "Database ID is required")
 		return
 	}
 
-	var req struct {
-		MemoryLimit int64   `json:"memoryLimit"` // bytes
-		CPULimit    float64 `json:"cpuLimit"`    // cores
-	}
+	var req resourcesRequest
 	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-		errorResponse(w, http.StatusBadRequest, "Invalid request body")
+		errorResponse(w, http.StatusBadRequest, CodeInvalidBody, "Invalid request body")
 		return
 	}
 
 	if req.MemoryLimit <= 0 && req.CPULimit <= 0 {
-		errorResponse(w, http.StatusBadRequest, "At least one of memoryLimit or cpuLimit must be specified")
+		errorResponse(w, http.StatusBadRequest, CodeInvalidRequest, "At least one of memoryLimit or cpuLimit must be specified")
 		return
 	}
 
-	db, err := s.db.UpdateResources(r.Context(), id, req.MemoryLimit, req.CPULimit)
+	db, restartRequired, err := s.db.UpdateResources(r.Context(), id, req.MemoryLimit, req.CPULimit)
+	s.audit(r, "database.resources", id, err)
 	if err != nil {
-		errorResponse(w, http.StatusInternalServerError, err.Error())
+		errorResponse(w, http.StatusInternalServerError, CodeInternal, err.Error())
 		return
 	}
 
-	jsonResponse(w, http.StatusOK, db)
+	resp := resourcesResponse{DatabaseInstance: db, RestartRequired: restartRequired}
+	if restartRequired {
+		// Limits were saved but the running container keeps its old ones until restarted
+		resp.Message = "New limits saved; restart the database to apply them"
+	}
+
+	jsonResponse(w, http.StatusOK, resp)
 }
 
-// handleBulkStart starts multiple databases at once
-func (s *Server) handleBulkStart(w http.ResponseWriter, r *http.Request) {
-	var req struct {
-		IDs []string `json:"ids"`
+// setNetworkRequest is the body of a network change
+type setNetworkRequest struct {
+	// Network is empty for the default network
+	Network string `json:"network"`
+}
+
+// handleSetNetwork attaches a database to a different network, detaching it from
+// its current one. An empty name moves it back to the default network.
+func (s *Server) handleSetNetwork(w http.ResponseWriter, r *http.Request) {
+	id := chi.URLParam(r, "id")
+	if id == "" {
+		errorResponse(w, http.StatusBadRequest, CodeInvalidRequest, "Database ID is required")
+		return
 	}
+
+	var req setNetworkRequest
 	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-		errorResponse(w, http.StatusBadRequest, "Invalid request body")
+		errorResponse(w, http.StatusBadRequest, CodeInvalidBody, "Invalid request body")
+		return
+	}
+
+	if _, err := s.db.Get(id); err != nil {
+		errorResponse(w, http.StatusNotFound, CodeDatabaseNotFound, "Database not found")
+		return
+	}
+
+	db, err := s.db.SetNetwork(r.Context(), id, req.Network)
+	s.audit(r, "database.network", id, err)
+	if err != nil {
+		errorResponse(w, http.StatusInternalServerError, CodeInternal, err.Error())
 		return
 	}
 
+	jsonResponse(w, http.StatusOK, db)
+}
+
+// forEachBounded calls fn(i) for every i in [0, n) with at most limit calls
+// running at once, and returns when all have finished
+func forEachBounded(n, limit int, fn func(i int)) {
+	if limit < 1 {
+		limit = 1
+	}
+	sem := make(chan struct{}, limit)
+	var wg sync.WaitGroup
+	for i := 0; i < n; i++ {
+		wg.Add(1)
+		sem <- struct{}{}
+		go func(i int) {
+			defer wg.Done()
+			defer func() { <-sem }()
+			fn(i)
+		}(i)
+	}
+	wg.Wait()
+}
+
+// bulkRequest is the body of a bulk operation
+type bulkRequest struct {
+	IDs []string `json:"ids"`
+	// Confirm maps database IDs to their names, confirming a bulk delete
+	// when deletes require confirmation
+	Confirm map[string]string `json:"confirm,omitempty"`
+}
+
+// decodeBulkRequest reads a bulk request body, writing an error response and
+// returning false if it is invalid or has no IDs
+func decodeBulkRequest(w http.ResponseWriter, r *http.Request) (*bulkRequest, bool) {
+	var req bulkRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		errorResponse(w, http.StatusBadRequest, CodeInvalidBody, "Invalid request body")
+		return nil, false
+	}
+
 	if len(req.IDs) == 0 {
-		errorResponse(w, http.StatusBadRequest, "No database IDs provided")
+		errorResponse(w, http.StatusBadRequest, CodeInvalidRequest, "No database IDs provided")
+		return nil, false
+	}
+	return &req, true
+}
+
+// decodeBulkIDs reads a {"ids": [...]} request body, writing an error response
+// and returning false if it is invalid or empty
+func decodeBulkIDs(w http.ResponseWriter, r *http.Request) ([]string, bool) {
+	req, ok := decodeBulkRequest(w, r)
+	if !ok {
+		return nil, false
+	}
+	return req.IDs, true
+}
+
+// runBulk applies op to each requested database concurrently and responds with
+// a per-ID status: done for successes, "failed" otherwise. verb names the
+// operation in messages and the audit log, e.g. "start".
+func (s *Server) runBulk(w http.ResponseWriter, r *http.Request, verb, done string, op func(ctx context.Context, id string) error) {
+	ids, ok := decodeBulkIDs(w, r)
+	if !ok {
 		return
 	}
+	s.runBulkIDs(w, r, ids, verb, done, op)
+}
 
+// runBulkIDs is runBulk for IDs already read from the request
+func (s *Server) runBulkIDs(w http.ResponseWriter, r *http.Request, ids []string, verb, done string, op func(ctx context.Context, id string) error) {
+	errs := make([]error, len(ids))
+	forEachBounded(len(ids), s.options().BulkConcurrency, func(i int) {
+		errs[i] = op(r.Context(), ids[i])
+		s.audit(r, "database."+verb, ids[i], errs[i])
+	})
+
+	results := make(map[string]string, len(ids))
 	var errors []string
-	for _, id := range req.IDs {
-		if err := s.db.Start(r.Context(), id); err != nil {
-			errors = append(errors, fmt.Sprintf("%s: %v", id, err))
+	for i, id := range ids {
+		if errs[i] != nil {
+			results[id] = "failed"
+			errors = append(errors, fmt.Sprintf("%s: %v", id, errs[i]))
+			continue
 		}
+		results[id] = done
 	}
 
 	if len(errors) > 0 {
 		jsonResponse(w, http.StatusPartialContent, map[string]interface{}{
-			"message": "Some databases failed to start",
+			"message": fmt.Sprintf("Some databases failed to %s", verb),
+			"results": results,
 			"errors":  errors,
 		})
 		return
 	}
 
-	jsonResponse(w, http.StatusOK, map[string]string{"message": "All databases started"})
+	jsonResponse(w, http.StatusOK, map[string]interface{}{
+		"message": fmt.Sprintf("All databases %s", done),
+		"results": results,
+	})
+}
+
+// handleBulkStart starts multiple databases at once
+func (s *Server) handleBulkStart(w http.ResponseWriter, r *http.Request) {
+	s.runBulk(w, r, "start", "started", s.db.Start)
 }
 
 // handleBulkStop stops multiple databases at once
 func (s *Server) handleBulkStop(w http.ResponseWriter, r *http.Request) {
-	var req struct {
-		IDs []string `json:"ids"`
-	}
-	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-		errorResponse(w, http.StatusBadRequest, "Invalid request body")
+	s.runBulk(w, r, "stop", "stopped", func(ctx context.Context, id string) error {
+		return s.db.Stop(ctx, id, runtime.DefaultStopTimeout)
+	})
+}
+
+// handleBulkDelete deletes multiple databases at once
+func (s *Server) handleBulkDelete(w http.ResponseWriter, r *http.Request) {
+	req, ok := decodeBulkRequest(w, r)
+	if !ok {
 		return
 	}
+	if s.options().RequireDeleteConfirmation {
+		// Refuse the whole request rather than delete the confirmed part of it
+		var unconfirmed []string
+		for _, id := range req.IDs {
+			if db, err := s.db.Get(id); err == nil && req.Confirm[id] != db.Name {
+				unconfirmed = append(unconfirmed, id)
+			}
+		}
+		if len(unconfirmed) > 0 {
+			errorResponse(w, http.StatusBadRequest, CodeConfirmationMismatch, "Confirmation does not match the database name for: "+strings.Join(unconfirmed, ", "))
+			return
+		}
+	}
 
-	if len(req.IDs) == 0 {
-		errorResponse(w, http.StatusBadRequest, "No database IDs provided")
+	opts := database.DeleteOptions{KeepBackups: r.URL.Query().Get("keepBackups") == "true"}
+	s.runBulkIDs(w, r, req.IDs, "delete", "deleted", func(ctx context.Context, id string) error {
+		return s.db.Delete(ctx, id, opts)
+	})
+}
+
+// handleBulkBackup starts a backup of multiple databases at once and returns
+// the backup ID started for each database
+func (s *Server) handleBulkBackup(w http.ResponseWriter, r *http.Request) {
+	ids, ok := decodeBulkIDs(w, r)
+	if !ok {
 		return
 	}
 
+	backupIDs := make([]string, len(ids))
+	errs := make([]error, len(ids))
+	forEachBounded(len(ids), s.options().BulkConcurrency, func(i int) {
+		backup, err := s.db.CreateBackup(r.Context(), ids[i], nil)
+		s.audit(r, "database.backup", ids[i], err)
+		if err != nil {
+			errs[i] = err
+			return
+		}
+		backupIDs[i] = backup.ID
+	})
+
+	backups := make(map[string]string)
 	var errors []string
-	for _, id := range req.IDs {
-		if err := s.db.Stop(r.Context(), id); err != nil {
-			errors = append(errors, fmt.Sprintf("%s: %v", id, err))
+	for i, id := range ids {
+		if errs[i] != nil {
+			errors = append(errors, fmt.Sprintf("%s: %v", id, errs[i]))
+			continue
 		}
+		backups[id] = backupIDs[i]
 	}
 
 	if len(errors) > 0 {
 		jsonResponse(w, http.StatusPartialContent, map[string]interface{}{
-			"message": "Some databases failed to stop",
+			"message": "Some backups failed to start",
+			"backups": backups,
 			"errors":  errors,
 		})
 		return
 	}
 
-	jsonResponse(w, http.StatusOK, map[string]string{"message": "All databases stopped"})
+	jsonResponse(w, http.StatusAccepted, map[string]interface{}{
+		"message": "All backups started",
+		"backups": backups,
+	})
 }
 
-// handleBulkDelete deletes multiple databases at once
-func (s *Server) handleBulkDelete(w http.ResponseWriter, r *http.Request) {
-	var req struct {
-		IDs []string `json:"ids"`
-	}
+// cloneRequest is one item of a bulk clone
+type cloneRequest struct {
+	ID      string `json:"id"`
+	NewName string `json:"newName"`
+}
+
+// BulkCloneResult is the outcome of one item of a bulk clone
+type BulkCloneResult struct {
+	ID      string `json:"id"`
+	NewName string `json:"newName"`
+	CloneID string `json:"cloneId,omitempty"`
+	Error   string `json:"error,omitempty"`
+}
+
+// handleBulkClone clones several databases concurrently. Each clone waits for
+// its backup and provisioning, so the request returns once all have finished.
+func (s *Server) handleBulkClone(w http.ResponseWriter, r *http.Request) {
+	var req []cloneRequest
 	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-		errorResponse(w, http.StatusBadRequest, "Invalid request body")
+		errorResponse(w, http.StatusBadRequest, CodeInvalidBody, "Invalid request body")
 		return
 	}
 
-	if len(req.IDs) == 0 {
-		errorResponse(w, http.StatusBadRequest, "No database IDs provided")
+	if len(req) == 0 {
+		errorResponse(w, http.StatusBadRequest, CodeInvalidRequest, "No databases provided")
 		return
 	}
-
-	var errors []string
-	for _, id := range req.IDs {
-		if err := s.db.Delete(r.Context(), id); err != nil {
-			errors = append(errors, fmt.Sprintf("%s: %v", id, err))
+	for _, item := range req {
+		if item.ID == "" || item.NewName == "" {
+			errorResponse(w, http.StatusBadRequest, CodeInvalidRequest, "Each item requires id and newName")
+			return
 		}
 	}
 
-	if len(errors) > 0 {
-		jsonResponse(w, http.StatusPartialContent, map[string]interface{}{
-			"message": "Some databases failed to delete",
-			"errors":  errors,
-		})
-		return
-	}
+	// Each worker writes only its own slot, so results need no lock
+	results := make([]BulkCloneResult, len(req))
+	forEachBounded(len(req), s.options().BulkConcurrency, func(i int) {
+		item := req[i]
+		results[i] = BulkCloneResult{ID: item.ID, NewName: item.NewName}
+		clone, err := s.db.Clone(r.Context(), item.ID, item.NewName)
+		if err != nil {
+			results[i].Error = err.Error()
+			s.audit(r, "database.clone", item.ID, err)
+			return
+		}
+		results[i].CloneID = clone.ID
+		s.audit(r, "database.clone", clone.ID, nil)
+	})
 
-	jsonResponse(w, http.StatusOK, map[string]string{"message": "All databases deleted"})
+	status := http.StatusOK
+	for _, result := range results {
+		if result.Error != "" {
+			status = http.StatusPartialContent
+			break
+		}
+	}
+	jsonResponse(w, status, results)
 }
 
 // handleDeleteBackup deletes a backup
 func (s *Server) handleDeleteBackup(w http.ResponseWriter, r *http.Request) {
 	id := chi.URLParam(r, "id")
 	if id == "" {
-		errorResponse(w, http.StatusBadRequest, "Backup ID is required")
+		errorResponse(w, http.StatusBadRequest, CodeInvalidRequest, "Backup ID is required")
+		return
+	}
+
+	if _, err := s.store.GetBackup(id); err != nil {
+		errorResponse(w, http.StatusNotFound, CodeBackupNotFound, "Backup not found")
 		return
 	}
 
-	if err := s.store.DeleteBackup(id); err != nil {
-		errorResponse(w, http.StatusInternalServerError, err.Error())
+	err := s.db.DeleteBackup(id)
+	s.audit(r, "backup.delete", id, err)
+	if err != nil {
+		errorResponse(w, http.StatusInternalServerError, CodeInternal, err.Error())
 		return
 	}
 
@@ -1020,13 +1998,13 @@ func (s *Server) handleDeleteBackup(w http.ResponseWriter, r *http.Request) {
 func (s *Server) handleGetCredentials(w http.ResponseWriter, r *http.Request) {
 	id := chi.URLParam(r, "id")
 	if id == "" {
-		errorResponse(w, http.StatusBadRequest, "Database ID is required")
+		errorResponse(w, http.StatusBadRequest, CodeInvalidRequest, "Database ID is required")
 		return
 	}
 
 	db, err := s.store.GetDatabase(id)
 	if err != nil {
-		errorResponse(w, http.StatusNotFound, "Database not found")
+		errorResponse(w, http.StatusNotFound, CodeDatabaseNotFound, "Database not found")
 		return
 	}
 
@@ -1041,21 +2019,61 @@ func (s *Server) handleGetCredentials(w http.ResponseWriter, r *http.Request) {
 	})
 }
 
+// handleGetCredentialsFile downloads the credentials as the engine's CLI
+// config file, e.g. a .pgpass line or a my.cnf
+func (s *Server) handleGetCredentialsFile(w http.ResponseWriter, r *http.Request) {
+	id := chi.URLParam(r, "id")
+	if id == "" {
+		errorResponse(w, http.StatusBadRequest, CodeInvalidRequest, "Database ID is required")
+		return
+	}
+
+	db, err := s.store.GetDatabase(id)
+	if err != nil {
+		errorResponse(w, http.StatusNotFound, CodeDatabaseNotFound, "Database not found")
+		return
+	}
+
+	name, content, err := database.CredentialsFile(db)
+	if err != nil {
+		errorResponse(w, http.StatusBadRequest, CodeInvalidRequest, err.Error())
+		return
+	}
+	s.audit(r, "database.credentials_file", id, nil)
+
+	w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+	w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=%q", name))
+	w.Header().Set("Cache-Control", "no-store")
+	io.WriteString(w, content)
+}
+
 // handleGetConnectionStrings returns connection strings for various languages/frameworks
 func (s *Server) handleGetConnectionStrings(w http.ResponseWriter, r *http.Request) {
 	id := chi.URLParam(r, "id")
 	if id == "" {
-		errorResponse(w, http.StatusBadRequest, "Database ID is required")
+		errorResponse(w, http.StatusBadRequest, CodeInvalidRequest, "Database ID is required")
 		return
 	}
 
+	// ?reveal=true fills in the real password; admins only, and audited
+	reveal := r.URL.Query().Get("reveal") == "true"
+	if reveal {
+		if role, _ := r.Context().Value(roleContextKey).(string); role != auth.RoleAdmin {
+			errorResponse(w, http.StatusForbidden, CodeForbidden, "Admin access required to reveal the password")
+			return
+		}
+	}
+
 	db, err := s.store.GetDatabase(id)
 	if err != nil {
-		errorResponse(w, http.StatusNotFound, "Database not found")
+		errorResponse(w, http.StatusNotFound, CodeDatabaseNotFound, "Database not found")
 		return
 	}
 
-	strings := generateConnectionExamples(db)
+	if reveal {
+		s.audit(r, "database.reveal_password", id, nil)
+	}
+	strings := generateConnectionExamples(db, reveal)
 	jsonResponse(w, http.StatusOK, strings)
 }
 
@@ -1063,13 +2081,13 @@ func (s *Server) handleGetConnectionStrings(w http.ResponseWriter, r *http.Reque
 func (s *Server) handleGetBackupInfo(w http.ResponseWriter, r *http.Request) {
 	id := chi.URLParam(r, "id")
 	if id == "" {
-		errorResponse(w, http.StatusBadRequest, "Backup ID is required")
+		errorResponse(w, http.StatusBadRequest, CodeInvalidRequest, "Backup ID is required")
 		return
 	}
 
 	backup, err := s.store.GetBackup(id)
 	if err != nil {
-		errorResponse(w, http.StatusNotFound, "Backup not found")
+		errorResponse(w, http.StatusNotFound, CodeBackupNotFound, "Backup not found")
 		return
 	}
 
@@ -1087,6 +2105,7 @@ func (s *Server) handleGetBackupInfo(w http.ResponseWriter, r *http.Request) {
 		"createdAt":    backup.CreatedAt,
 		"size":         backup.Size,
 		"status":       backup.Status,
+		"checksum":     backup.Checksum,
 		"engine":       dbEngine,
 		"version":      dbVersion,
 	})
@@ -1096,12 +2115,23 @@ func (s *Server) handleGetBackupInfo(w http.ResponseWriter, r *http.Request) {
 func (s *Server) handleGetMetricsHistory(w http.ResponseWriter, r *http.Request) {
 	id := chi.URLParam(r, "id")
 	if id == "" {
-		errorResponse(w, http.StatusBadRequest, "Database ID is required")
+		errorResponse(w, http.StatusBadRequest, CodeInvalidRequest, "Database ID is required")
 		return
 	}
 
+	// Optional lookback window, e.g. ?range=24h (default: last hour)
+	window := time.Hour
+	if v := r.URL.Query().Get("range"); v != "" {
+		d, err := time.ParseDuration(v)
+		if err != nil || d <= 0 {
+			errorResponse(w, http.StatusBadRequest, CodeInvalidRequest, "range must be a positive duration like 1h or 72h")
+			return
+		}
+		window = d
+	}
+
 	// Get metrics history from manager
-	history := s.db.GetMetricsHistory(id)
+	history := s.db.GetMetricsHistory(id, time.Now().Add(-window))
 	jsonResponse(w, http.StatusOK, history)
 }
 
@@ -1113,8 +2143,13 @@ type ConnectionExample struct {
 	Description string `json:"description"`
 }
 
-// generateConnectionExamples creates full code examples for different languages/tools
-func generateConnectionExamples(db *storage.DatabaseInstance) []ConnectionExample {
+// passwordPlaceholder stands in for the password in connection examples
+// unless it is explicitly revealed
+const passwordPlaceholder = "<password>"
+
+// generateConnectionExamples creates full code examples for different languages/tools.
+// The password is a placeholder unless reveal is set.
+func generateConnectionExamples(db *storage.DatabaseInstance, reveal bool) []ConnectionExample {
 	var examples []ConnectionExample
 
 	// Return empty if database is still being created
@@ -1127,6 +2162,24 @@ func generateConnectionExamples(db *storage.DatabaseInstance) []ConnectionExampl
 	user := db.Username
 	pass := db.Password
 	dbName := db.Database
+	if pass != "" && !reveal {
+		pass = passwordPlaceholder
+	}
+
+	if engine, err := database.GetEngine(db.Engine); err == nil {
+		uri := engine.ConnectionStrings(db).URI
+		if reveal {
+			// Escaped for the userinfo part of the URI
+			escaped := strings.TrimPrefix(url.UserPassword("u", db.Password).String(), "u:")
+			uri = strings.Replace(uri, passwordPlaceholder, escaped, 1)
+		}
+		examples = append(examples, ConnectionExample{
+			Title:       "Connection URI",
+			Language:    "bash",
+			Description: "Connection string for drivers and tools that accept a URI",
+			Code:        uri,
+		})
+	}
 
 	// Helper to safely truncate container ID
 	containerID := db.ContainerID
@@ -1422,6 +2475,67 @@ redis.set('test_key', 'Hello, Redis!');
 redis.get('test_key').then(console.log);`, host, port),
 			})
 		}
+
+	case "clickhouse":
+		examples = append(examples, ConnectionExample{
+			Title:       "Docker",
+			Language:    "bash",
+			Description: "Connect using the container's clickhouse-client",
+			Code:        fmt.Sprintf("docker exec -it %s clickhouse-client --user %s --password %s --database %s", containerID, user, pass, dbName),
+		})
+		examples = append(examples, ConnectionExample{
+			Title:       "CLI",
+			Language:    "bash",
+			Description: "Connect using local clickhouse-client",
+			Code:        fmt.Sprintf("clickhouse-client --host %s --port %d --user %s --password %s --database %s", host, port, user, pass, dbName),
+		})
+		examples = append(examples, ConnectionExample{
+			Title:       "Python",
+			Language:    "python",
+			Description: "Connect using clickhouse-driver",
+			Code: fmt.Sprintf(`from clickhouse_driver import Client
+
+client = Client(
+    host="%s",
+    port=%d,
+    user="%s",
+    password="%s",
+    database="%s"
+)
+
+print(client.execute("SELECT version()"))`, host, port, user, pass, dbName),
+		})
+		examples = append(examples, ConnectionExample{
+			Title:       "Go",
+			Language:    "go",
+			Description: "Connect using clickhouse-go",
+			Code: fmt.Sprintf(`package main
+
+import (
+    "context"
+    "fmt"
+    "github.com/ClickHouse/clickhouse-go/v2"
+)
+
+func main() {
+    conn, err := clickhouse.Open(&clickhouse.Options{
+        Addr: []string{"%s:%d"},
+        Auth: clickhouse.Auth{
+            Database: "%s",
+            Username: "%s",
+            Password: "%s",
+        },
+    })
+    if err != nil {
+        panic(err)
+    }
+    defer conn.Close()
+
+    var version string
+    conn.QueryRow(context.Background(), "SELECT version()").Scan(&version)
+    fmt.Println(version)
+}`, host, port, dbName, user, pass),
+		})
 	}
 
 	return examples
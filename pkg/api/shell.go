@@ -0,0 +1,138 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/gorilla/websocket"
+	"github.com/rs/zerolog/log"
+	"github.com/sirrobot01/dbnest/pkg/database"
+	"github.com/sirrobot01/dbnest/pkg/runtime"
+)
+
+// shellUpgrader upgrades shell requests to WebSocket connections.
+// The default origin check only accepts same-host requests, which keeps
+// session cookies from being used by other sites.
+var shellUpgrader = websocket.Upgrader{
+	ReadBufferSize:  4096,
+	WriteBufferSize: 4096,
+}
+
+// shellMessage is a control message sent by the client as a text frame.
+// Binary frames are written to the shell's stdin as-is.
+type shellMessage struct {
+	Type string `json:"type"` // "input" or "resize"
+	Data string `json:"data,omitempty"`
+	Cols uint   `json:"cols,omitempty"`
+	Rows uint   `json:"rows,omitempty"`
+}
+
+// wsWriter sends shell output to the client as binary frames
+type wsWriter struct {
+	mu   sync.Mutex
+	conn *websocket.Conn
+}
+
+func (w *wsWriter) Write(p []byte) (int, error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if err := w.conn.WriteMessage(websocket.BinaryMessage, p); err != nil {
+		return 0, err
+	}
+	return len(p), nil
+}
+
+func (w *wsWriter) close(code int, reason string) {
+	// Close frame payloads are limited to 125 bytes (2 for the code)
+	if len(reason) > 123 {
+		reason = reason[:123]
+	}
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	msg := websocket.FormatCloseMessage(code, reason)
+	w.conn.WriteControl(websocket.CloseMessage, msg, time.Now().Add(time.Second))
+}
+
+// handleShell bridges a WebSocket to an interactive CLI session in the database container
+func (s *Server) handleShell(w http.ResponseWriter, r *http.Request) {
+	id := chi.URLParam(r, "id")
+
+	db, err := s.store.GetDatabase(id)
+	if err != nil {
+		errorResponse(w, http.StatusNotFound, CodeDatabaseNotFound, "Database not found")
+		return
+	}
+	// A database whose healthcheck hasn't passed can still be shelled into to
+	// see what's wrong
+	if !database.ContainerUp(db.Status) {
+		errorResponse(w, http.StatusConflict, CodeDatabaseNotRunning, "Database is not running")
+		return
+	}
+
+	conn, err := shellUpgrader.Upgrade(w, r, nil)
+	if err != nil {
+		// Upgrade has already written an HTTP error response
+		return
+	}
+	defer conn.Close()
+	conn.SetReadLimit(64 * 1024)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	stdinR, stdinW := io.Pipe()
+	resize := make(chan runtime.TerminalSize, 1)
+	out := &wsWriter{conn: conn}
+
+	// Read client frames until the socket closes
+	go func() {
+		defer cancel()
+		defer stdinW.Close()
+		for {
+			msgType, data, err := conn.ReadMessage()
+			if err != nil {
+				return
+			}
+
+			if msgType == websocket.BinaryMessage {
+				if _, err := stdinW.Write(data); err != nil {
+					return
+				}
+				continue
+			}
+
+			var msg shellMessage
+			if err := json.Unmarshal(data, &msg); err != nil {
+				continue
+			}
+			switch msg.Type {
+			case "input":
+				if _, err := stdinW.Write([]byte(msg.Data)); err != nil {
+					return
+				}
+			case "resize":
+				if msg.Cols == 0 || msg.Rows == 0 {
+					continue
+				}
+				// Only the latest size matters; drop a pending one
+				select {
+				case <-resize:
+				default:
+				}
+				resize <- runtime.TerminalSize{Cols: msg.Cols, Rows: msg.Rows}
+			}
+		}
+	}()
+
+	if err := s.db.Shell(ctx, id, stdinR, out, resize); err != nil && ctx.Err() == nil {
+		log.Debug().Err(err).Str("db", id).Msg("Shell session ended with error")
+		out.close(websocket.CloseInternalServerErr, err.Error())
+		return
+	}
+	out.close(websocket.CloseNormalClosure, "session ended")
+}
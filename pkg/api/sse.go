@@ -0,0 +1,171 @@
+package api
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/sirrobot01/dbnest/pkg/database"
+)
+
+const (
+	defaultStreamInterval = 5 * time.Second
+	minStreamInterval     = time.Second
+	maxStreamInterval     = time.Minute
+)
+
+// sseWriter writes Server-Sent Events to a streaming response
+type sseWriter struct {
+	w       http.ResponseWriter
+	flusher http.Flusher
+}
+
+// newSSEWriter sets the event-stream headers. It returns false if the
+// response writer does not support streaming.
+func newSSEWriter(w http.ResponseWriter) (*sseWriter, bool) {
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		return nil, false
+	}
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("Connection", "keep-alive")
+	w.Header().Set("X-Accel-Buffering", "no") // Disable proxy buffering (nginx)
+	w.WriteHeader(http.StatusOK)
+	flusher.Flush()
+
+	return &sseWriter{w: w, flusher: flusher}, true
+}
+
+// send writes a named event with a JSON payload
+func (s *sseWriter) send(event string, data interface{}) error {
+	payload, err := json.Marshal(data)
+	if err != nil {
+		return err
+	}
+	if _, err := fmt.Fprintf(s.w, "event: %s\ndata: %s\n\n", event, payload); err != nil {
+		return err
+	}
+	s.flusher.Flush()
+	return nil
+}
+
+// handleMetricsStream pushes live container metrics over SSE until the
+// client disconnects or the database stops running
+func (s *Server) handleMetricsStream(w http.ResponseWriter, r *http.Request) {
+	id := chi.URLParam(r, "id")
+
+	db, err := s.db.Get(id)
+	if err != nil {
+		errorResponse(w, http.StatusNotFound, CodeDatabaseNotFound, "Database not found")
+		return
+	}
+	if db.ContainerID == "" {
+		errorResponse(w, http.StatusBadRequest, CodeInvalidRequest, "Database has no container")
+		return
+	}
+
+	interval := defaultStreamInterval
+	if v := r.URL.Query().Get("interval"); v != "" {
+		secs, err := strconv.Atoi(v)
+		if err != nil {
+			errorResponse(w, http.StatusBadRequest, CodeInvalidRequest, "interval must be a number of seconds")
+			return
+		}
+		interval = min(max(time.Duration(secs)*time.Second, minStreamInterval), maxStreamInterval)
+	}
+
+	sse, ok := newSSEWriter(w)
+	if !ok {
+		errorResponse(w, http.StatusInternalServerError, CodeInternal, "Streaming not supported")
+		return
+	}
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		// Stop once the container is no longer running
+		db, err = s.db.Get(id)
+		if err != nil || !database.ContainerUp(db.Status) {
+			sse.send("end", map[string]string{"reason": "database is not running"})
+			return
+		}
+
+		metrics, err := s.collectMetrics(r.Context(), db)
+		if err != nil {
+			if r.Context().Err() != nil {
+				return
+			}
+			sse.send("end", map[string]string{"reason": err.Error()})
+			return
+		}
+		if err := sse.send("metrics", metrics); err != nil {
+			return
+		}
+
+		select {
+		case <-r.Context().Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// handleProvisionEvents streams a database's provisioning milestones over SSE,
+// replaying those already reached, until it is ready or has failed
+func (s *Server) handleProvisionEvents(w http.ResponseWriter, r *http.Request) {
+	id := chi.URLParam(r, "id")
+
+	db, err := s.db.Get(id)
+	if err != nil {
+		errorResponse(w, http.StatusNotFound, CodeDatabaseNotFound, "Database not found")
+		return
+	}
+
+	history, events, cancel, ok := s.db.SubscribeProvisioning(id)
+	defer cancel()
+
+	sse, streaming := newSSEWriter(w)
+	if !streaming {
+		errorResponse(w, http.StatusInternalServerError, CodeInternal, "Streaming not supported")
+		return
+	}
+
+	// Nothing observed since startup: report the outcome from the stored status
+	if !ok {
+		switch db.Status {
+		case "creating", "queued":
+			sse.send("end", map[string]string{"reason": "no provisioning progress available"})
+		case "error", "oom-killed":
+			sse.send("progress", database.ProvisionEvent{Stage: database.StageError, Message: db.ErrorMessage, Time: time.Now()})
+		default:
+			sse.send("progress", database.ProvisionEvent{Stage: database.StageReady, Time: time.Now()})
+		}
+		return
+	}
+
+	for _, event := range history {
+		if err := sse.send("progress", event); err != nil {
+			return
+		}
+	}
+
+	for events != nil {
+		select {
+		case <-r.Context().Done():
+			return
+		case event, open := <-events:
+			if !open {
+				return
+			}
+			if err := sse.send("progress", event); err != nil {
+				return
+			}
+		}
+	}
+}
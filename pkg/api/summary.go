@@ -0,0 +1,103 @@
+package api
+
+import (
+	"net/http"
+	"sort"
+	"time"
+)
+
+// maxSummaryErrors caps how many recent errors the summary lists
+const maxSummaryErrors = 10
+
+// Summary aggregates the state of every database and backup for the dashboard
+type Summary struct {
+	Databases    int            `json:"databases"`
+	ByStatus     map[string]int `json:"byStatus"`
+	ByEngine     map[string]int `json:"byEngine"`
+	StorageUsed  int64          `json:"storageUsed"` // bytes, summed over databases
+	Backups      int            `json:"backups"`
+	BackupSize   int64          `json:"backupSize"` // bytes, summed over completed backups
+	RecentErrors []SummaryError `json:"recentErrors"`
+}
+
+// SummaryError is a database or backup failure shown on the dashboard
+type SummaryError struct {
+	DatabaseID   string     `json:"databaseId"`
+	DatabaseName string     `json:"databaseName"`
+	Source       string     `json:"source"` // "database", "backup" or "scheduled-backup"
+	Message      string     `json:"message"`
+	At           *time.Time `json:"at,omitempty"` // when known
+}
+
+// handleGetSummary returns aggregate counts for the dashboard in a single
+// pass over databases and backups
+func (s *Server) handleGetSummary(w http.ResponseWriter, r *http.Request) {
+	summary := Summary{
+		ByStatus:     make(map[string]int),
+		ByEngine:     make(map[string]int),
+		RecentErrors: []SummaryError{},
+	}
+
+	names := make(map[string]string)
+	for _, db := range s.store.ListDatabases() {
+		names[db.ID] = db.Name
+		summary.Databases++
+		summary.ByStatus[db.Status]++
+		summary.ByEngine[db.Engine]++
+		summary.StorageUsed += db.StorageUsed
+
+		if db.ErrorMessage != "" {
+			summary.RecentErrors = append(summary.RecentErrors, SummaryError{
+				DatabaseID:   db.ID,
+				DatabaseName: db.Name,
+				Source:       "database",
+				Message:      db.ErrorMessage,
+			})
+		}
+		if last := db.LastBackup; last != nil && !last.Success {
+			at := last.At
+			summary.RecentErrors = append(summary.RecentErrors, SummaryError{
+				DatabaseID:   db.ID,
+				DatabaseName: db.Name,
+				Source:       "scheduled-backup",
+				Message:      last.Error,
+				At:           &at,
+			})
+		}
+	}
+
+	for _, backup := range s.store.ListBackups("") {
+		summary.Backups++
+		switch backup.Status {
+		case "completed":
+			summary.BackupSize += backup.Size
+		case "failed":
+			at := backup.CreatedAt
+			name := backup.DatabaseName
+			if current, ok := names[backup.DatabaseID]; ok {
+				name = current
+			}
+			summary.RecentErrors = append(summary.RecentErrors, SummaryError{
+				DatabaseID:   backup.DatabaseID,
+				DatabaseName: name,
+				Source:       "backup",
+				Message:      backup.Error,
+				At:           &at,
+			})
+		}
+	}
+
+	// Newest first; errors without a time (current database errors) lead
+	sort.SliceStable(summary.RecentErrors, func(i, j int) bool {
+		a, b := summary.RecentErrors[i].At, summary.RecentErrors[j].At
+		if a == nil || b == nil {
+			return a == nil && b != nil
+		}
+		return a.After(*b)
+	})
+	if len(summary.RecentErrors) > maxSummaryErrors {
+		summary.RecentErrors = summary.RecentErrors[:maxSummaryErrors]
+	}
+
+	jsonResponse(w, http.StatusOK, summary)
+}
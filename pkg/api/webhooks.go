@@ -0,0 +1,54 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/rs/zerolog/log"
+	"github.com/sirrobot01/dbnest/pkg/notify"
+)
+
+// handleListWebhooks returns the configured status webhooks
+func (s *Server) handleListWebhooks(w http.ResponseWriter, r *http.Request) {
+	jsonResponse(w, http.StatusOK, s.db.Notifier().Webhooks())
+}
+
+// handleCreateWebhook registers a notification target (generic webhook, Slack or Discord)
+func (s *Server) handleCreateWebhook(w http.ResponseWriter, r *http.Request) {
+	var req notify.Webhook
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		errorResponse(w, http.StatusBadRequest, CodeInvalidBody, "Invalid request body")
+		return
+	}
+	if req.URL == "" {
+		errorResponse(w, http.StatusBadRequest, CodeInvalidRequest, "URL is required")
+		return
+	}
+
+	hook, err := s.db.Notifier().AddWebhook(&req)
+	if err != nil {
+		s.audit(r, "webhook.create", "", err)
+		errorResponse(w, http.StatusBadRequest, CodeInvalidRequest, err.Error())
+		return
+	}
+
+	s.audit(r, "webhook.create", hook.ID, nil)
+	log.Info().Str("id", hook.ID).Str("type", hook.Type).Msg("Webhook created")
+	jsonResponse(w, http.StatusCreated, hook)
+}
+
+// handleDeleteWebhook removes a webhook
+func (s *Server) handleDeleteWebhook(w http.ResponseWriter, r *http.Request) {
+	id := chi.URLParam(r, "id")
+
+	err := s.db.Notifier().DeleteWebhook(id)
+	s.audit(r, "webhook.delete", id, err)
+	if err != nil {
+		errorResponse(w, http.StatusNotFound, CodeWebhookNotFound, "Webhook not found")
+		return
+	}
+
+	log.Info().Str("id", id).Msg("Webhook deleted")
+	w.WriteHeader(http.StatusNoContent)
+}
@@ -2,7 +2,9 @@ package auth
 
 import (
 	"crypto/rand"
+	"crypto/sha256"
 	"encoding/base64"
+	"encoding/hex"
 	"fmt"
 
 	"github.com/google/uuid"
@@ -14,8 +16,23 @@ const (
 	TokenLength = 32
 	// BcryptCost is the cost factor for bcrypt hashing
 	BcryptCost = 12
+	// APIKeyPrefix is prepended to generated API keys so they are easy to recognise
+	APIKeyPrefix = "dbn_"
 )
 
+// Roles that can be granted to an API key
+const (
+	// RoleAdmin has full access to the API
+	RoleAdmin = "admin"
+	// RoleViewer can only perform read (GET) requests
+	RoleViewer = "viewer"
+)
+
+// ValidRole reports whether role is a known role
+func ValidRole(role string) bool {
+	return role == RoleAdmin || role == RoleViewer
+}
+
 // HashPassword hashes a password using bcrypt
 func HashPassword(password string) (string, error) {
 	if password == "" {
@@ -44,6 +61,23 @@ func GenerateToken() (string, error) {
 	return base64.URLEncoding.EncodeToString(b), nil
 }
 
+// GenerateAPIKey generates a new random API key
+func GenerateAPIKey() (string, error) {
+	token, err := GenerateToken()
+	if err != nil {
+		return "", err
+	}
+	return APIKeyPrefix + token, nil
+}
+
+// HashAPIKey returns the SHA-256 hex digest of an API key.
+// API keys are high-entropy random values, so a fast hash is sufficient
+// and allows direct lookup (unlike bcrypt).
+func HashAPIKey(key string) string {
+	sum := sha256.Sum256([]byte(key))
+	return hex.EncodeToString(sum[:])
+}
+
 // GenerateID generates a unique ID for users/sessions
 func GenerateID() string {
 	return uuid.New().String()
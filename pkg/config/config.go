@@ -1,10 +1,19 @@
 package config
 
 import (
+	"encoding/base64"
+	"errors"
 	"flag"
 	"fmt"
+	"net"
 	"os"
 	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/sirrobot01/dbnest/pkg/runtime"
+	"github.com/sirrobot01/dbnest/pkg/storage"
 )
 
 type LogLevel string
@@ -19,11 +28,115 @@ const (
 
 // Config holds all application configuration
 type Config struct {
+	// ConfigFile is the optional file of flag values, re-read on SIGHUP
+	ConfigFile string
+
 	LogLevel LogLevel
 	Port     int
 	DataDir  string
-	Socket   string // Docker socket path (only used for docker runtime with SDK mode)
+	Socket   string // Runtime socket path or URL; docker, podman and containerd use SDK mode when set
 	Runtime  string // Container runtime: "docker", "podman", or "containerd"
+
+	// RuntimeTLSCA, RuntimeTLSCert and RuntimeTLSKey secure a tcp:// runtime socket
+	RuntimeTLSCA   string
+	RuntimeTLSCert string
+	RuntimeTLSKey  string
+
+	// TLS: served over HTTPS when both are set
+	TLSCert string
+	TLSKey  string
+	// HTTPRedirectPort, when set with TLS, serves plain HTTP on this port redirecting to HTTPS
+	HTTPRedirectPort int
+
+	// ShutdownTimeout is how long in-flight requests get to finish on SIGTERM
+	ShutdownTimeout time.Duration
+
+	// Storage backend for DBnest's own state
+	StorageBackend string // "bolt" (file in DataDir) or "postgres"
+	StorageDSN     string // PostgreSQL connection string for the postgres backend
+
+	// EnginesFile is a JSON file of custom engine definitions registered at startup
+	EnginesFile string
+
+	// BackupDir is where backups are written; empty means <DataDir>/backups
+	BackupDir string
+	// BackupBinlogPosition records binlog coordinates in MySQL/MariaDB backups
+	BackupBinlogPosition bool
+	// BackupKeyFile holds a base64 encoded 32-byte key; when set backups are
+	// encrypted at rest. BackupKey is the decoded key, loaded by Validate.
+	BackupKeyFile string
+	BackupKey     []byte
+	// Scheduled backup retries
+	BackupAttempts int           // attempts before a scheduled backup is recorded as failed
+	BackupBackoff  time.Duration // delay before the first retry, doubled after each
+
+	// Frontend serving
+	NoUI  bool   // serve the API only
+	UIDir string // serve the frontend from this directory instead of the embedded build
+
+	// Auth rate limiting (per client IP and per username)
+	AuthRateLimit  int           // attempts allowed per window, 0 disables
+	AuthRateWindow time.Duration // window over which attempts are counted
+
+	// CORSOrigins are origins allowed to call the API cross-origin; empty means same-origin only
+	CORSOrigins []string
+
+	// HealthCheckTimeout bounds the database health endpoint's connectivity query
+	HealthCheckTimeout time.Duration
+
+	// BulkConcurrency caps how many databases a bulk operation works on at once
+	BulkConcurrency int
+	// QueryRowLimit caps the rows a console query returns; 0 means no limit
+	QueryRowLimit int
+	// QueryHistorySize caps the console queries remembered per database and user; 0 disables history
+	QueryHistorySize int
+	// RequireDeleteConfirmation makes database deletes name the database being deleted
+	RequireDeleteConfirmation bool
+
+	// Default limits for databases created without explicit limits
+	DefaultMemoryMB  int64   // 0 = unlimited
+	DefaultCPU       float64 // cores
+	DefaultStorageMB int64   // 0 = unlimited
+
+	// RestartPolicy is the default container restart policy for new databases
+	RestartPolicy string
+	// BindAddress is the default host IP database ports are published on
+	BindAddress string
+	// RunAs is the default "UID[:GID]" database containers run as; empty keeps
+	// the image's user. RunAsUser and RunAsGroup are parsed from it by Validate.
+	RunAs      string
+	RunAsUser  int
+	RunAsGroup int
+	// UsernsMode is the default user namespace mode for database containers
+	UsernsMode string
+	// ReadOnlyRootfs runs new database containers with a read-only root filesystem
+	ReadOnlyRootfs bool
+	// Security options applied to every database container
+	NoNewPrivileges bool
+	SeccompProfile  string   // JSON profile path or "unconfined"; empty = runtime default
+	CapDrop         []string // Linux capabilities dropped from the runtime's default set
+
+	// Host memory capacity check for new databases
+	MemoryOvercommit float64 // max sum of memory limits as a multiple of host memory, 0 disables
+	HostMemoryMB     int64   // overrides detected host memory, 0 = read /proc/meminfo
+
+	// HostDataRoot is the directory host data paths must be inside; empty disables them
+	HostDataRoot string
+
+	// Image pull retries
+	PullAttempts int           // attempts before provisioning fails
+	PullBackoff  time.Duration // delay before the first retry, doubled after each
+	// ProvisionConcurrency caps how many databases are provisioned at once
+	ProvisionConcurrency int
+
+	// Metrics history
+	MetricsInterval  time.Duration // minimum spacing between stored points per database
+	MetricsRetention time.Duration // how long stored points are kept
+
+	// Private registry credentials for image pulls
+	RegistryServer   string // e.g. "registry.example.com:5000"; empty disables
+	RegistryUsername string
+	RegistryPassword string
 }
 
 // DockerNetwork returns the default Docker network name
@@ -36,6 +149,16 @@ func (c *Config) StoragePath() string {
 	return filepath.Join(c.DataDir, "dbnest.db")
 }
 
+// RuntimeTLS returns the TLS files for a remote runtime daemon
+func (c *Config) RuntimeTLS() runtime.TLSConfig {
+	return runtime.TLSConfig{CA: c.RuntimeTLSCA, Cert: c.RuntimeTLSCert, Key: c.RuntimeTLSKey}
+}
+
+// TLSEnabled reports whether the server should serve HTTPS
+func (c *Config) TLSEnabled() bool {
+	return c.TLSCert != "" && c.TLSKey != ""
+}
+
 // Addr returns the HTTP server address
 func (c *Config) Addr() string {
 	if c.Port == 0 {
@@ -44,14 +167,86 @@ func (c *Config) Addr() string {
 	return fmt.Sprintf(":%d", c.Port)
 }
 
-// FromArgs creates a Config from CLI arguments
+// FromArgs creates a Config from CLI arguments, exiting on invalid flags
 func FromArgs() *Config {
-	port := flag.Int("port", 8080, "HTTP server port")
-	dataDir := flag.String("data", "./data", "Data directory for storage")
-	socket := flag.String("socket", "", "Docker socket path (only used for docker runtime with SDK mode)")
-	runtime := flag.String("runtime", "docker", "Container runtime: docker, podman, or containerd")
-	logLevel := flag.String("log-level", "info", "Logging level (info, debug, error, trace)")
-	flag.Parse()
+	cfg, err := Load(os.Args[1:])
+	if errors.Is(err, flag.ErrHelp) {
+		os.Exit(0)
+	}
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(2)
+	}
+	return cfg
+}
+
+// Load creates a Config from command-line args and, if --config is given, a
+// config file of "flag = value" lines. Flags on the command line take
+// precedence over the file. Called again on SIGHUP to pick up file changes.
+func Load(args []string) (*Config, error) {
+	fs := flag.NewFlagSet("dbnest", flag.ContinueOnError)
+	defaultCapDrop := strings.Join(runtime.DefaultCapDrop, ",") // the runtime flag shadows the package below
+	configFile := fs.String("config", "", "Config file of \"flag = value\" lines; command-line flags take precedence (re-read on SIGHUP)")
+	port := fs.Int("port", 8080, "HTTP server port")
+	dataDir := fs.String("data", "./data", "Data directory for storage")
+	socket := fs.String("socket", "", "Runtime socket path or URL for SDK mode, e.g. /var/run/docker.sock, tcp://host:2376 or ssh://user@host; defaults to DOCKER_HOST when neither --socket nor --runtime is set (default: CLI mode)")
+	runtime := fs.String("runtime", "docker", "Container runtime: docker, podman, or containerd")
+	runtimeTLSCA := fs.String("runtime-tls-ca", "", "CA certificate verifying a tcp:// runtime daemon (default: ca.pem in DOCKER_CERT_PATH)")
+	runtimeTLSCert := fs.String("runtime-tls-cert", "", "Client certificate for a tcp:// runtime daemon, with --runtime-tls-key (default: cert.pem in DOCKER_CERT_PATH)")
+	runtimeTLSKey := fs.String("runtime-tls-key", "", "Client key for a tcp:// runtime daemon (default: key.pem in DOCKER_CERT_PATH)")
+	logLevel := fs.String("log-level", "info", "Logging level (info, debug, error, trace)")
+	tlsCert := fs.String("tls-cert", "", "TLS certificate file (enables HTTPS with --tls-key)")
+	tlsKey := fs.String("tls-key", "", "TLS private key file")
+	httpRedirectPort := fs.Int("http-redirect-port", 0, "With TLS, also listen on this port and redirect HTTP to HTTPS (0 disables)")
+	shutdownTimeout := fs.Duration("shutdown-timeout", 30*time.Second, "How long to wait for in-flight requests on shutdown")
+	storageBackend := fs.String("storage-backend", "bolt", "Storage backend for DBnest state: bolt or postgres")
+	storageDSN := fs.String("storage-dsn", os.Getenv("DBNEST_STORAGE_DSN"), "PostgreSQL connection string for the postgres storage backend (env DBNEST_STORAGE_DSN)")
+	enginesFile := fs.String("engines-file", "", "JSON file of custom engine definitions to register alongside the built-in engines")
+	backupDir := fs.String("backup-dir", "", "Directory for backup files (default: <data>/backups)")
+	backupBinlogPosition := fs.Bool("backup-binlog-position", false, "Record binary log coordinates in MySQL/MariaDB backups (needs binary logging enabled)")
+	backupKeyFile := fs.String("backup-key-file", "", "File with a base64 encoded 32-byte key to encrypt backups with (e.g. from openssl rand -base64 32)")
+	backupAttempts := fs.Int("backup-attempts", 3, "Max attempts for a scheduled backup before it is recorded as failed")
+	backupBackoff := fs.Duration("backup-backoff", time.Minute, "Delay before retrying a failed scheduled backup, doubled after each attempt")
+	noUI := fs.Bool("no-ui", false, "Disable the web UI and serve only the API")
+	uiDir := fs.String("ui-dir", "", "Serve the web UI from this directory instead of the embedded build")
+	authRateLimit := fs.Int("auth-rate-limit", 10, "Max login/register attempts per window per IP and username (0 disables)")
+	authRateWindow := fs.Duration("auth-rate-window", time.Minute, "Window for auth rate limiting")
+	corsOrigins := fs.String("cors-origins", "", "Comma-separated origins allowed to call the API cross-origin (default: same-origin only)")
+	healthCheckTimeout := fs.Duration("health-check-timeout", 5*time.Second, "Timeout for database health check queries")
+	queryRowLimit := fs.Int("query-row-limit", 1000, "Max rows a console query returns; larger results are truncated (0 = unlimited)")
+	queryHistorySize := fs.Int("query-history-size", 100, "Console queries remembered per database and user (0 disables history)")
+	bulkConcurrency := fs.Int("bulk-concurrency", 5, "Max databases a bulk operation works on at once")
+	requireDeleteConfirmation := fs.Bool("require-delete-confirmation", false, "Require database deletes to confirm the database's name")
+	defaultMemoryMB := fs.Int64("default-memory-mb", 0, "Memory limit in MB for databases created without one (0 = unlimited)")
+	defaultCPU := fs.Float64("default-cpu", 1.0, "CPU limit in cores for new databases")
+	defaultStorageMB := fs.Int64("default-storage-mb", 0, "Storage limit in MB for databases created without one (0 = unlimited)")
+	bindAddress := fs.String("bind-address", "127.0.0.1", "Default host IP (IPv4 or IPv6) database ports are published on; 0.0.0.0 exposes them on all interfaces")
+	runAs := fs.String("run-as-user", "", "Default UID[:GID] database containers run as (default: the image's user)")
+	usernsMode := fs.String("userns-mode", "", "Default user namespace mode for database containers, e.g. host, or auto/keep-id on podman")
+	readOnlyRootfs := fs.Bool("read-only-rootfs", false, "Run new database containers with a read-only root filesystem; only data and scratch paths stay writable")
+	noNewPrivileges := fs.Bool("no-new-privileges", true, "Stop database container processes from gaining privileges through setuid binaries")
+	seccompProfile := fs.String("seccomp-profile", "", "Seccomp profile JSON file for database containers, or \"unconfined\" (default: the runtime's profile)")
+	capDrop := fs.String("cap-drop", defaultCapDrop, "Comma-separated Linux capabilities dropped from database containers (empty keeps the runtime's defaults)")
+	restartPolicy := fs.String("restart-policy", "unless-stopped", "Default container restart policy: no, always, unless-stopped, on-failure[:N]")
+	memoryOvercommit := fs.Float64("memory-overcommit", 1.0, "Max total database memory limits as a multiple of host memory (0 disables the check)")
+	hostMemoryMB := fs.Int64("host-memory-mb", 0, "Host memory in MB for capacity checks (0 = detect from /proc/meminfo)")
+	hostDataRoot := fs.String("host-data-root", "", "Directory databases' host data paths must be inside (default: host data paths disabled)")
+	pullAttempts := fs.Int("pull-attempts", 4, "Max image pull attempts before provisioning fails")
+	pullBackoff := fs.Duration("pull-backoff", 2*time.Second, "Delay before retrying a failed image pull, doubled after each attempt")
+	provisionConcurrency := fs.Int("provision-concurrency", 3, "Max databases provisioned at once; further creates are queued")
+	metricsInterval := fs.Duration("metrics-interval", time.Minute, "Minimum interval between stored metrics points per database")
+	metricsRetention := fs.Duration("metrics-retention", 7*24*time.Hour, "How long to keep metrics history")
+	registryServer := fs.String("registry", os.Getenv("DBNEST_REGISTRY"), "Private registry host for image pulls (env DBNEST_REGISTRY)")
+	registryUsername := fs.String("registry-username", os.Getenv("DBNEST_REGISTRY_USERNAME"), "Private registry username (env DBNEST_REGISTRY_USERNAME)")
+	registryPassword := fs.String("registry-password", os.Getenv("DBNEST_REGISTRY_PASSWORD"), "Private registry password or token (prefer env DBNEST_REGISTRY_PASSWORD)")
+	if err := fs.Parse(args); err != nil {
+		return nil, err
+	}
+	if *configFile != "" {
+		if err := applyConfigFile(fs, *configFile); err != nil {
+			return nil, fmt.Errorf("config file %s: %w", *configFile, err)
+		}
+	}
 
 	if *dataDir == "" {
 		*dataDir = "./data"
@@ -59,24 +254,307 @@ func FromArgs() *Config {
 	if *runtime == "" {
 		*runtime = "docker"
 	}
+	// Like the docker CLI, fall back to DOCKER_HOST and DOCKER_CERT_PATH, but
+	// only when the runtime isn't configured at all. An explicit --runtime
+	// without --socket asks for CLI mode, where the CLI reads DOCKER_HOST itself.
+	explicit := make(map[string]bool)
+	fs.Visit(func(f *flag.Flag) { explicit[f.Name] = true })
+	if !explicit["socket"] && !explicit["runtime"] {
+		*socket = os.Getenv("DOCKER_HOST")
+	}
+	if certPath := os.Getenv("DOCKER_CERT_PATH"); certPath != "" && strings.HasPrefix(*socket, "tcp://") &&
+		*runtimeTLSCA == "" && *runtimeTLSCert == "" && *runtimeTLSKey == "" {
+		*runtimeTLSCA = filepath.Join(certPath, "ca.pem")
+		*runtimeTLSCert = filepath.Join(certPath, "cert.pem")
+		*runtimeTLSKey = filepath.Join(certPath, "key.pem")
+	}
 	if *logLevel == "" {
 		*logLevel = "info"
 	}
 
 	return &Config{
+		ConfigFile: *configFile,
+
 		Port:     *port,
 		DataDir:  *dataDir,
 		Socket:   *socket,
 		Runtime:  *runtime,
 		LogLevel: LogLevel(*logLevel),
+
+		RuntimeTLSCA:   *runtimeTLSCA,
+		RuntimeTLSCert: *runtimeTLSCert,
+		RuntimeTLSKey:  *runtimeTLSKey,
+
+		TLSCert:          *tlsCert,
+		TLSKey:           *tlsKey,
+		HTTPRedirectPort: *httpRedirectPort,
+
+		ShutdownTimeout: *shutdownTimeout,
+
+		StorageBackend: *storageBackend,
+		StorageDSN:     *storageDSN,
+
+		EnginesFile: *enginesFile,
+
+		BackupDir:            *backupDir,
+		BackupBinlogPosition: *backupBinlogPosition,
+		BackupKeyFile:        *backupKeyFile,
+		BackupAttempts:       *backupAttempts,
+		BackupBackoff:        *backupBackoff,
+
+		NoUI:  *noUI,
+		UIDir: *uiDir,
+
+		AuthRateLimit:  *authRateLimit,
+		AuthRateWindow: *authRateWindow,
+
+		CORSOrigins: splitList(*corsOrigins),
+
+		HealthCheckTimeout: *healthCheckTimeout,
+
+		BulkConcurrency:  *bulkConcurrency,
+		QueryRowLimit:    *queryRowLimit,
+		QueryHistorySize: *queryHistorySize,
+
+		RequireDeleteConfirmation: *requireDeleteConfirmation,
+
+		DefaultMemoryMB:  *defaultMemoryMB,
+		DefaultCPU:       *defaultCPU,
+		DefaultStorageMB: *defaultStorageMB,
+
+		RestartPolicy:  *restartPolicy,
+		BindAddress:    *bindAddress,
+		RunAs:          *runAs,
+		UsernsMode:     *usernsMode,
+		ReadOnlyRootfs: *readOnlyRootfs,
+
+		NoNewPrivileges: *noNewPrivileges,
+		SeccompProfile:  *seccompProfile,
+		CapDrop:         splitList(*capDrop),
+
+		MemoryOvercommit: *memoryOvercommit,
+		HostMemoryMB:     *hostMemoryMB,
+
+		HostDataRoot: *hostDataRoot,
+
+		PullAttempts:         *pullAttempts,
+		PullBackoff:          *pullBackoff,
+		ProvisionConcurrency: *provisionConcurrency,
+
+		MetricsInterval:  *metricsInterval,
+		MetricsRetention: *metricsRetention,
+
+		RegistryServer:   *registryServer,
+		RegistryUsername: *registryUsername,
+		RegistryPassword: *registryPassword,
+	}, nil
+}
+
+// applyConfigFile sets flags from a file of "name = value" lines, skipping
+// blank lines and # comments. Flags already set on the command line win.
+func applyConfigFile(fs *flag.FlagSet, path string) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return err
 	}
+
+	explicit := make(map[string]bool)
+	fs.Visit(func(f *flag.Flag) { explicit[f.Name] = true })
+
+	for i, line := range strings.Split(string(data), "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		name, value, ok := strings.Cut(line, "=")
+		if !ok {
+			return fmt.Errorf("line %d: expected \"name = value\"", i+1)
+		}
+		name = strings.TrimLeft(strings.TrimSpace(name), "-")
+		if name == "config" {
+			return fmt.Errorf("line %d: config files cannot include other config files", i+1)
+		}
+		if explicit[name] {
+			continue
+		}
+		if err := fs.Set(name, strings.TrimSpace(value)); err != nil {
+			return fmt.Errorf("line %d: %w", i+1, err)
+		}
+	}
+	return nil
+}
+
+// splitList splits a comma-separated flag value, dropping empty entries
+func splitList(value string) []string {
+	var items []string
+	for _, item := range strings.Split(value, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
+
+// parseRunAs parses a "UID[:GID]" pair of numeric IDs
+func parseRunAs(value string) (int, int, error) {
+	user, group, hasGroup := strings.Cut(value, ":")
+	uid, err := strconv.Atoi(user)
+	if err != nil || uid <= 0 {
+		return 0, 0, fmt.Errorf("--run-as-user must be a positive numeric UID[:GID], got %q", value)
+	}
+	gid := 0
+	if hasGroup {
+		if gid, err = strconv.Atoi(group); err != nil || gid < 0 {
+			return 0, 0, fmt.Errorf("--run-as-user must be a positive numeric UID[:GID], got %q", value)
+		}
+	}
+	return uid, gid, nil
 }
 
 // Validate validates the configuration and creates necessary directories
 func (c *Config) Validate() error {
+	if (c.TLSCert == "") != (c.TLSKey == "") {
+		return fmt.Errorf("--tls-cert and --tls-key must be set together")
+	}
+	for _, f := range []string{c.TLSCert, c.TLSKey} {
+		if f == "" {
+			continue
+		}
+		if _, err := os.Stat(f); err != nil {
+			return fmt.Errorf("TLS file not accessible: %w", err)
+		}
+	}
+	if c.HTTPRedirectPort != 0 && !c.TLSEnabled() {
+		return fmt.Errorf("--http-redirect-port requires --tls-cert and --tls-key")
+	}
+	if c.HTTPRedirectPort != 0 && c.HTTPRedirectPort == c.Port {
+		return fmt.Errorf("--http-redirect-port must differ from --port")
+	}
+	switch c.Runtime {
+	case "docker", "podman", "containerd":
+	default:
+		return fmt.Errorf("unknown runtime: %s (valid: docker, podman, containerd)", c.Runtime)
+	}
+	if scheme, _, ok := strings.Cut(c.Socket, "://"); ok {
+		switch {
+		case c.Runtime == "containerd" && scheme != "unix":
+			return fmt.Errorf("--socket for containerd must be a local socket, got %s", c.Socket)
+		case scheme != "unix" && scheme != "tcp" && scheme != "ssh":
+			return fmt.Errorf("unsupported --socket scheme %s:// (valid: unix, tcp, ssh)", scheme)
+		}
+	}
+	if c.RuntimeTLS().Enabled() {
+		if !strings.HasPrefix(c.Socket, "tcp://") {
+			return fmt.Errorf("--runtime-tls-* need a tcp:// --socket")
+		}
+		if (c.RuntimeTLSCert == "") != (c.RuntimeTLSKey == "") {
+			return fmt.Errorf("--runtime-tls-cert and --runtime-tls-key must be set together")
+		}
+		for _, f := range []string{c.RuntimeTLSCA, c.RuntimeTLSCert, c.RuntimeTLSKey} {
+			if f == "" {
+				continue
+			}
+			if _, err := os.Stat(f); err != nil {
+				return fmt.Errorf("runtime TLS file not accessible: %w", err)
+			}
+		}
+	}
+	switch c.StorageBackend {
+	case storage.BackendBolt:
+	case storage.BackendPostgres:
+		if c.StorageDSN == "" {
+			return fmt.Errorf("--storage-dsn is required with --storage-backend=postgres")
+		}
+	default:
+		return fmt.Errorf("unknown storage backend: %s (valid: bolt, postgres)", c.StorageBackend)
+	}
+	if c.NoUI && c.UIDir != "" {
+		return fmt.Errorf("--no-ui and --ui-dir cannot be used together")
+	}
+	if c.UIDir != "" {
+		if info, err := os.Stat(c.UIDir); err != nil || !info.IsDir() {
+			return fmt.Errorf("--ui-dir %s is not a directory", c.UIDir)
+		}
+	}
+	if c.RegistryServer != "" && c.RegistryUsername == "" {
+		return fmt.Errorf("--registry-username is required when --registry is set")
+	}
+
+	if c.DefaultMemoryMB < 0 || c.DefaultStorageMB < 0 {
+		return fmt.Errorf("default memory and storage limits cannot be negative")
+	}
+	if _, _, err := runtime.ParseRestartPolicy(c.RestartPolicy); err != nil {
+		return err
+	}
+	if net.ParseIP(c.BindAddress) == nil {
+		return fmt.Errorf("--bind-address must be an IPv4 or IPv6 address, got %q", c.BindAddress)
+	}
+	if c.RunAs != "" {
+		uid, gid, err := parseRunAs(c.RunAs)
+		if err != nil {
+			return err
+		}
+		c.RunAsUser, c.RunAsGroup = uid, gid
+	}
+	if strings.ContainsAny(c.UsernsMode, " \t") {
+		return fmt.Errorf("--userns-mode must not contain whitespace, got %q", c.UsernsMode)
+	}
+	if c.SeccompProfile != "" && c.SeccompProfile != runtime.SeccompUnconfined {
+		if _, err := os.Stat(c.SeccompProfile); err != nil {
+			return fmt.Errorf("--seccomp-profile: %w", err)
+		}
+	}
+	for i, capability := range c.CapDrop {
+		capability = strings.TrimPrefix(strings.ToUpper(capability), "CAP_")
+		if capability == "" || strings.Trim(capability, "ABCDEFGHIJKLMNOPQRSTUVWXYZ_") != "" {
+			return fmt.Errorf("--cap-drop: invalid capability %q", c.CapDrop[i])
+		}
+		c.CapDrop[i] = capability
+	}
+	if c.MemoryOvercommit < 0 || c.HostMemoryMB < 0 {
+		return fmt.Errorf("--memory-overcommit and --host-memory-mb cannot be negative")
+	}
+	if c.QueryRowLimit < 0 {
+		return fmt.Errorf("--query-row-limit cannot be negative")
+	}
+	if c.QueryHistorySize < 0 {
+		return fmt.Errorf("--query-history-size cannot be negative")
+	}
+	if c.BulkConcurrency < 1 {
+		return fmt.Errorf("--bulk-concurrency must be at least 1")
+	}
+	if c.PullAttempts < 1 {
+		return fmt.Errorf("--pull-attempts must be at least 1")
+	}
+	if c.ProvisionConcurrency < 1 {
+		return fmt.Errorf("--provision-concurrency must be at least 1")
+	}
+	if c.BackupAttempts < 1 {
+		return fmt.Errorf("--backup-attempts must be at least 1")
+	}
+	if c.DefaultCPU <= 0 {
+		return fmt.Errorf("--default-cpu must be greater than 0")
+	}
+
 	// Ensure data directory exists
 	if err := os.MkdirAll(c.DataDir, 0755); err != nil {
 		return err
 	}
+	if c.BackupDir != "" {
+		if err := os.MkdirAll(c.BackupDir, 0755); err != nil {
+			return fmt.Errorf("--backup-dir: %w", err)
+		}
+	}
+	if c.BackupKeyFile != "" {
+		data, err := os.ReadFile(c.BackupKeyFile)
+		if err != nil {
+			return fmt.Errorf("--backup-key-file: %w", err)
+		}
+		key, err := base64.StdEncoding.DecodeString(strings.TrimSpace(string(data)))
+		if err != nil || len(key) != 32 {
+			return fmt.Errorf("--backup-key-file must contain a base64 encoded 32-byte key")
+		}
+		c.BackupKey = key
+	}
 	return nil
 }
@@ -0,0 +1,117 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestDockerHostFallback(t *testing.T) {
+	tests := []struct {
+		name       string
+		args       []string
+		dockerHost string
+		wantSocket string
+	}{
+		{"unset", nil, "", ""},
+		{"nothing configured", nil, "tcp://remote:2376", "tcp://remote:2376"},
+		{"explicit socket wins", []string{"--socket", "/run/docker.sock"}, "tcp://remote:2376", "/run/docker.sock"},
+		{"explicit runtime keeps CLI mode", []string{"--runtime", "docker"}, "tcp://remote:2376", ""},
+		{"containerd ignores it", []string{"--runtime", "containerd"}, "tcp://remote:2376", ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Setenv("DOCKER_HOST", tt.dockerHost)
+			t.Setenv("DOCKER_CERT_PATH", "")
+			cfg, err := Load(tt.args)
+			if err != nil {
+				t.Fatalf("Load failed: %v", err)
+			}
+			if cfg.Socket != tt.wantSocket {
+				t.Errorf("expected socket %q, got %q", tt.wantSocket, cfg.Socket)
+			}
+		})
+	}
+}
+
+func TestDockerHostFallbackFromConfigFile(t *testing.T) {
+	t.Setenv("DOCKER_HOST", "tcp://remote:2376")
+	path := filepath.Join(t.TempDir(), "dbnest.conf")
+	os.WriteFile(path, []byte("runtime = podman\n"), 0644)
+
+	cfg, err := Load([]string{"--config", path})
+	if err != nil {
+		t.Fatalf("Load failed: %v", err)
+	}
+	if cfg.Socket != "" {
+		t.Errorf("expected a runtime set in the config file to keep CLI mode, got socket %q", cfg.Socket)
+	}
+}
+
+func TestDockerCertPathFallback(t *testing.T) {
+	certs := t.TempDir()
+	tests := []struct {
+		name   string
+		args   []string
+		wantCA string
+	}{
+		{"tcp socket", []string{"--socket", "tcp://remote:2376"}, filepath.Join(certs, "ca.pem")},
+		{"local socket", []string{"--socket", "/run/docker.sock"}, ""},
+		{"ssh socket", []string{"--socket", "ssh://me@remote"}, ""},
+		{"explicit TLS flags win", []string{"--socket", "tcp://remote:2376", "--runtime-tls-ca", "/etc/ca.pem"}, "/etc/ca.pem"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Setenv("DOCKER_HOST", "")
+			t.Setenv("DOCKER_CERT_PATH", certs)
+			cfg, err := Load(tt.args)
+			if err != nil {
+				t.Fatalf("Load failed: %v", err)
+			}
+			if cfg.RuntimeTLSCA != tt.wantCA {
+				t.Errorf("expected CA %q, got %q", tt.wantCA, cfg.RuntimeTLSCA)
+			}
+		})
+	}
+}
+
+func TestValidateSocketScheme(t *testing.T) {
+	tests := []struct {
+		runtime string
+		socket  string
+		wantErr string
+	}{
+		{"docker", "/var/run/docker.sock", ""},
+		{"docker", "unix:///var/run/docker.sock", ""},
+		{"docker", "tcp://remote:2376", ""},
+		{"podman", "ssh://me@remote", ""},
+		{"docker", "http://remote:2375", "unsupported --socket scheme"},
+		{"containerd", "/run/containerd/containerd.sock", ""},
+		{"containerd", "unix:///run/containerd/containerd.sock", ""},
+		{"containerd", "tcp://remote:2376", "must be a local socket"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.runtime+" "+tt.socket, func(t *testing.T) {
+			t.Setenv("DOCKER_HOST", "")
+			t.Setenv("DOCKER_CERT_PATH", "")
+			cfg, err := Load([]string{"--runtime", tt.runtime, "--socket", tt.socket})
+			if err != nil {
+				t.Fatalf("Load failed: %v", err)
+			}
+			err = cfg.Validate()
+			switch {
+			case tt.wantErr == "" && err != nil:
+				t.Errorf("expected %s to be accepted, got %v", tt.socket, err)
+			case tt.wantErr != "" && (err == nil || !strings.Contains(err.Error(), tt.wantErr)):
+				t.Errorf("expected %q error, got %v", tt.wantErr, err)
+			}
+		})
+	}
+
+	// Runtime TLS only applies to tcp:// daemons
+	cfg, _ := Load([]string{"--socket", "/var/run/docker.sock", "--runtime-tls-ca", "/etc/ca.pem"})
+	if err := cfg.Validate(); err == nil || !strings.Contains(err.Error(), "need a tcp://") {
+		t.Errorf("expected TLS with a local socket to be rejected, got %v", err)
+	}
+}
@@ -0,0 +1,127 @@
+package database
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"strconv"
+	"time"
+
+	"github.com/sirrobot01/dbnest/pkg/storage"
+)
+
+// Connectivity reports whether a database's port accepts connections from
+// the DBnest process, as a client would connect
+type Connectivity struct {
+	Address   string  `json:"address"`
+	Reachable bool    `json:"reachable"`
+	LatencyMs float64 `json:"latencyMs,omitempty"` // time to establish the TCP connection
+	// Handshake is true when the server answered with its engine's protocol,
+	// not just an open port
+	Handshake bool   `json:"handshake"`
+	Error     string `json:"error,omitempty"`
+}
+
+// CheckConnectivity dials the database's host and port and performs the
+// first step of its wire protocol. ctx bounds the whole check.
+func CheckConnectivity(ctx context.Context, db *storage.DatabaseInstance) *Connectivity {
+	result := &Connectivity{Address: net.JoinHostPort(db.Host, strconv.Itoa(db.Port))}
+
+	var dialer net.Dialer
+	start := time.Now()
+	conn, err := dialer.DialContext(ctx, "tcp", result.Address)
+	if err != nil {
+		result.Error = err.Error()
+		return result
+	}
+	defer conn.Close()
+	result.Reachable = true
+	result.LatencyMs = float64(time.Since(start).Microseconds()) / 1000
+
+	if deadline, ok := ctx.Deadline(); ok {
+		conn.SetDeadline(deadline)
+	}
+	if err := handshake(db.Engine, conn); err != nil {
+		result.Error = fmt.Sprintf("port is open but the server did not respond as %s: %v", db.Engine, err)
+		return result
+	}
+	result.Handshake = true
+	return result
+}
+
+// handshake runs the opening exchange of the engine's protocol. It stops
+// before authentication, so it needs no credentials.
+func handshake(engine string, conn net.Conn) error {
+	switch engine {
+	case "postgresql":
+		// An SSLRequest is answered with a single 'S' or 'N'
+		request := make([]byte, 8)
+		binary.BigEndian.PutUint32(request[0:4], 8)
+		binary.BigEndian.PutUint32(request[4:8], 80877103)
+		if _, err := conn.Write(request); err != nil {
+			return err
+		}
+		reply := make([]byte, 1)
+		if _, err := conn.Read(reply); err != nil {
+			return err
+		}
+		if reply[0] != 'S' && reply[0] != 'N' {
+			return fmt.Errorf("unexpected SSL response %q", reply[0])
+		}
+	case "mysql", "mariadb":
+		// The server speaks first: a packet header, then protocol version 10
+		// or 0xff for an error such as "host is not allowed to connect"
+		header := make([]byte, 5)
+		if _, err := io.ReadFull(conn, header); err != nil {
+			return err
+		}
+		if header[4] != 10 && header[4] != 0xff {
+			return fmt.Errorf("unexpected greeting byte %#x", header[4])
+		}
+	case "redis":
+		// PING gets +PONG, or an error such as -NOAUTH when a password is set
+		if _, err := conn.Write([]byte("PING\r\n")); err != nil {
+			return err
+		}
+		reply := make([]byte, 64)
+		n, err := conn.Read(reply)
+		if err != nil {
+			return err
+		}
+		if reply[0] != '+' && reply[0] != '-' {
+			return fmt.Errorf("unexpected reply %q", bytes.TrimSpace(reply[:n]))
+		}
+	case "clickhouse":
+		// A native protocol Hello is answered with the server's Hello (0), or
+		// an exception (2)
+		appendString := func(b []byte, s string) []byte {
+			return append(binary.AppendUvarint(b, uint64(len(s))), s...)
+		}
+		hello := binary.AppendUvarint(nil, 0) // packet type
+		hello = appendString(hello, "dbnest")
+		hello = binary.AppendUvarint(hello, 1)     // client version major
+		hello = binary.AppendUvarint(hello, 0)     // and minor
+		hello = binary.AppendUvarint(hello, 54449) // protocol revision
+		// Database, user and password; a refused login is still answered
+		for _, s := range []string{"", "default", ""} {
+			hello = appendString(hello, s)
+		}
+		if _, err := conn.Write(hello); err != nil {
+			return err
+		}
+		reply := make([]byte, 1)
+		if _, err := conn.Read(reply); err != nil {
+			return err
+		}
+		if reply[0] != 0 && reply[0] != 2 {
+			return fmt.Errorf("unexpected packet type %d", reply[0])
+		}
+	default:
+		return errors.New("no handshake for this engine")
+	}
+	return nil
+}
@@ -0,0 +1,55 @@
+package database
+
+import (
+	"encoding/xml"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/sirrobot01/dbnest/pkg/storage"
+)
+
+// CredentialsFile renders a database's credentials in the config file format
+// its CLI tools read: a .pgpass line for PostgreSQL, a my.cnf option file for
+// MySQL/MariaDB, a REDISCLI_AUTH env file for Redis and a clickhouse-client
+// config file for ClickHouse. It returns a suggested file name and the contents.
+func CredentialsFile(db *storage.DatabaseInstance) (name, content string, err error) {
+	switch db.Engine {
+	case "postgresql":
+		// hostname:port:database:username:password, with ':' and '\' escaped
+		escape := strings.NewReplacer(`\`, `\\`, `:`, `\:`).Replace
+		content = fmt.Sprintf("# chmod 600 ~/.pgpass\n%s:%d:%s:%s:%s\n",
+			escape(db.Host), db.Port, escape(db.Database), escape(db.Username), escape(db.Password))
+		return db.Name + ".pgpass", content, nil
+
+	case "mysql", "mariadb":
+		quote := func(s string) string {
+			return `"` + strings.NewReplacer(`\`, `\\`, `"`, `\"`).Replace(s) + `"`
+		}
+		// The default database goes under [mysql] since other client tools,
+		// e.g. mysqldump, reject it in [client]
+		content = fmt.Sprintf("# Use with: mysql --defaults-extra-file=%s.my.cnf\n[client]\nhost=%s\nport=%d\nuser=%s\npassword=%s\n\n[mysql]\ndatabase=%s\n",
+			db.Name, db.Host, db.Port, quote(db.Username), quote(db.Password), quote(db.Database))
+		return db.Name + ".my.cnf", content, nil
+
+	case "redis":
+		var b strings.Builder
+		fmt.Fprintf(&b, "# Use with: set -a; . ./%s.redis.env; set +a; redis-cli -h $REDIS_HOST -p $REDIS_PORT\n", db.Name)
+		fmt.Fprintf(&b, "REDIS_HOST=%s\nREDIS_PORT=%d\n", db.Host, db.Port)
+		if db.Password != "" {
+			fmt.Fprintf(&b, "REDISCLI_AUTH='%s'\n", strings.ReplaceAll(db.Password, "'", `'\''`))
+		}
+		return db.Name + ".redis.env", b.String(), nil
+
+	case "clickhouse":
+		var escaped [5]strings.Builder
+		for i, v := range []string{db.Host, strconv.Itoa(db.Port), db.Username, db.Password, db.Database} {
+			xml.EscapeText(&escaped[i], []byte(v))
+		}
+		content = fmt.Sprintf("<!-- Use with: clickhouse-client --config-file %s.clickhouse-client.xml -->\n<config>\n"+
+			"    <host>%s</host>\n    <port>%s</port>\n    <user>%s</user>\n    <password>%s</password>\n    <database>%s</database>\n</config>\n",
+			db.Name, escaped[0].String(), escaped[1].String(), escaped[2].String(), escaped[3].String(), escaped[4].String())
+		return db.Name + ".clickhouse-client.xml", content, nil
+	}
+	return "", "", fmt.Errorf("credentials file not supported for %s", db.Engine)
+}
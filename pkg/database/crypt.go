@@ -0,0 +1,253 @@
+package database
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+)
+
+// Encrypted backup files are a header followed by AES-256-GCM sealed chunks.
+// Every chunk but the last holds encChunkSize bytes of plaintext, so any
+// offset maps to a chunk and downloads can seek without decrypting the file.
+//
+//	header: magic (8) | key ID (8) | base nonce (12)
+//	chunk:  ciphertext of up to encChunkSize bytes | GCM tag (16)
+//
+// Each chunk's nonce is the base nonce XOR its index, and the last chunk is
+// sealed with different additional data so truncation is detected.
+const (
+	encMagic      = "DBNENC01"
+	encKeyIDSize  = 8
+	encHeaderSize = len(encMagic) + encKeyIDSize + 12
+	encChunkSize  = 64 * 1024
+	encTagSize    = 16
+)
+
+// BackupKeySize is the length of a backup encryption key (AES-256)
+const BackupKeySize = 32
+
+var (
+	chunkAAD     = []byte{0}
+	lastChunkAAD = []byte{1}
+)
+
+// backupKeyID fingerprints a key so backups encrypted with a different key
+// fail with a clear error rather than an authentication failure
+func backupKeyID(key []byte) []byte {
+	sum := sha256.Sum256(key)
+	return sum[:encKeyIDSize]
+}
+
+func newBackupAEAD(key []byte) (cipher.AEAD, error) {
+	if len(key) != BackupKeySize {
+		return nil, fmt.Errorf("backup encryption key must be %d bytes, got %d", BackupKeySize, len(key))
+	}
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
+
+// chunkNonce derives the nonce for chunk i from the file's base nonce
+func chunkNonce(base []byte, i int64) []byte {
+	nonce := append([]byte(nil), base...)
+	tail := binary.BigEndian.Uint64(nonce[len(nonce)-8:]) ^ uint64(i)
+	binary.BigEndian.PutUint64(nonce[len(nonce)-8:], tail)
+	return nonce
+}
+
+// encryptingWriter encrypts what is written to it into out. Plaintext is
+// only ever held in memory, a chunk at a time, so it never reaches the disk.
+type encryptingWriter struct {
+	pw   *io.PipeWriter
+	done chan error
+}
+
+func newEncryptingWriter(key []byte, out io.Writer) (*encryptingWriter, error) {
+	aead, err := newBackupAEAD(key)
+	if err != nil {
+		return nil, err
+	}
+	pr, pw := io.Pipe()
+	w := &encryptingWriter{pw: pw, done: make(chan error, 1)}
+	go func() {
+		err := encryptStream(aead, backupKeyID(key), bufio.NewReaderSize(pr, encChunkSize), out)
+		// Unblock the writer if encryption stopped early
+		pr.CloseWithError(err)
+		w.done <- err
+	}()
+	return w, nil
+}
+
+func (w *encryptingWriter) Write(p []byte) (int, error) {
+	return w.pw.Write(p)
+}
+
+// Close seals the last chunk and waits for it to be written to out
+func (w *encryptingWriter) Close() error {
+	w.pw.Close()
+	return <-w.done
+}
+
+func encryptStream(aead cipher.AEAD, keyID []byte, in *bufio.Reader, out io.Writer) error {
+	base := make([]byte, aead.NonceSize())
+	if _, err := rand.Read(base); err != nil {
+		return err
+	}
+	header := append(append([]byte(encMagic), keyID...), base...)
+	if _, err := out.Write(header); err != nil {
+		return err
+	}
+
+	buf := make([]byte, encChunkSize)
+	sealed := make([]byte, 0, encChunkSize+encTagSize)
+	for i := int64(0); ; i++ {
+		n, err := io.ReadFull(in, buf)
+		if err != nil && err != io.EOF && err != io.ErrUnexpectedEOF {
+			return err
+		}
+		_, peekErr := in.Peek(1)
+		last := peekErr != nil
+		aad := chunkAAD
+		if last {
+			aad = lastChunkAAD
+		}
+		sealed = aead.Seal(sealed[:0], chunkNonce(base, i), buf[:n], aad)
+		if _, err := out.Write(sealed); err != nil {
+			return err
+		}
+		if last {
+			return nil
+		}
+	}
+}
+
+// backupReader decrypts an encrypted backup file with random access
+type backupReader struct {
+	f      *os.File
+	aead   cipher.AEAD
+	base   []byte
+	size   int64 // plaintext size
+	chunks int64
+	pos    int64
+
+	cached     int64 // index of the decrypted chunk in plain, -1 if none
+	plain      []byte
+	ciphertext []byte
+}
+
+// openEncryptedBackup opens an encrypted backup file for reading. Close
+// closes the underlying file.
+func openEncryptedBackup(key []byte, path string) (*backupReader, error) {
+	aead, err := newBackupAEAD(key)
+	if err != nil {
+		return nil, err
+	}
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+
+	header := make([]byte, encHeaderSize)
+	if _, err := io.ReadFull(f, header); err != nil || string(header[:len(encMagic)]) != encMagic {
+		f.Close()
+		return nil, errors.New("not an encrypted backup file")
+	}
+	if !bytes.Equal(header[len(encMagic):len(encMagic)+encKeyIDSize], backupKeyID(key)) {
+		f.Close()
+		return nil, errors.New("backup was encrypted with a different key")
+	}
+	info, err := f.Stat()
+	if err != nil {
+		f.Close()
+		return nil, err
+	}
+
+	body := info.Size() - int64(encHeaderSize)
+	fullChunk := int64(encChunkSize + encTagSize)
+	chunks := (body + fullChunk - 1) / fullChunk
+	if chunks == 0 || body-(chunks-1)*fullChunk < encTagSize {
+		f.Close()
+		return nil, errors.New("encrypted backup file is truncated")
+	}
+	return &backupReader{
+		f:          f,
+		aead:       aead,
+		base:       header[len(encMagic)+encKeyIDSize:],
+		size:       body - chunks*encTagSize,
+		chunks:     chunks,
+		cached:     -1,
+		ciphertext: make([]byte, fullChunk),
+	}, nil
+}
+
+// Size returns the plaintext size
+func (r *backupReader) Size() int64 {
+	return r.size
+}
+
+func (r *backupReader) Read(p []byte) (int, error) {
+	if r.pos >= r.size {
+		return 0, io.EOF
+	}
+	i := r.pos / encChunkSize
+	if err := r.loadChunk(i); err != nil {
+		return 0, err
+	}
+	n := copy(p, r.plain[r.pos-i*encChunkSize:])
+	r.pos += int64(n)
+	return n, nil
+}
+
+// loadChunk decrypts chunk i into r.plain
+func (r *backupReader) loadChunk(i int64) error {
+	if r.cached == i {
+		return nil
+	}
+	fullChunk := int64(encChunkSize + encTagSize)
+	n, err := r.f.ReadAt(r.ciphertext, int64(encHeaderSize)+i*fullChunk)
+	if err != nil && err != io.EOF {
+		return err
+	}
+	aad := chunkAAD
+	if i == r.chunks-1 {
+		aad = lastChunkAAD
+	}
+	r.plain, err = r.aead.Open(r.plain[:0], chunkNonce(r.base, i), r.ciphertext[:n], aad)
+	if err != nil {
+		r.cached = -1
+		return fmt.Errorf("failed to decrypt backup: %w", err)
+	}
+	r.cached = i
+	return nil
+}
+
+func (r *backupReader) Seek(offset int64, whence int) (int64, error) {
+	switch whence {
+	case io.SeekStart:
+	case io.SeekCurrent:
+		offset += r.pos
+	case io.SeekEnd:
+		offset += r.size
+	default:
+		return 0, errors.New("invalid whence")
+	}
+	if offset < 0 {
+		return 0, errors.New("negative position")
+	}
+	r.pos = offset
+	return offset, nil
+}
+
+func (r *backupReader) Close() error {
+	return r.f.Close()
+}
@@ -14,6 +14,28 @@ type QueryResult struct {
 	Message  string          `json:"message,omitempty"`
 	Error    string          `json:"error,omitempty"`
 	RowCount int             `json:"rowCount"`
+	// Truncated is set when rows past the query row limit were dropped
+	Truncated bool `json:"truncated,omitempty"`
+	// Statement is the statement of a console script this result is for
+	Statement string `json:"statement,omitempty"`
+}
+
+// ReadOnlyQueryEngine is implemented by engines whose server can refuse
+// writes for a session, backing up the statement check of read-only queries
+type ReadOnlyQueryEngine interface {
+	ExecuteReadOnlyQuery(ctx context.Context, client runtime.Client, db *storage.DatabaseInstance, query string) (*QueryResult, error)
+}
+
+// ReadyQueryEngine is implemented by engines that check they accept queries
+// with something other than "SELECT 1"
+type ReadyQueryEngine interface {
+	ReadyQuery() string
+}
+
+// HealthcheckEngine is implemented by engines with a command that tells the
+// runtime whether the server is up, configured as the container healthcheck
+type HealthcheckEngine interface {
+	HealthcheckCmd(db *storage.DatabaseInstance) []string
 }
 
 // ConnectionStrings holds connection strings for various languages
@@ -27,6 +49,26 @@ type ConnectionStrings struct {
 	PHP    string `json:"php"`
 }
 
+// BackupOptions tune how an engine takes a backup. Engines ignore options
+// that don't apply to them.
+type BackupOptions struct {
+	// BinlogPosition records the binary log coordinates in MySQL/MariaDB
+	// dumps, for seeding a replica. Requires binary logging on the server.
+	BinlogPosition bool
+	// Tables limits the backup to these tables. Empty means the whole database.
+	Tables []string
+	// EncryptionKey encrypts the backup file as it is written. Engines create
+	// the file with createBackupFile so plaintext never reaches the disk.
+	EncryptionKey []byte
+}
+
+// RestoreOptions tune how an engine restores a backup
+type RestoreOptions struct {
+	// Tables limits the restore to these tables from the backup. Empty means
+	// everything in the backup.
+	Tables []string
+}
+
 // Engine defines the interface for database engine implementations
 // Each database type (PostgreSQL, MySQL, etc) implements this interface
 type Engine interface {
@@ -35,20 +77,34 @@ type Engine interface {
 	Image() string
 	DefaultPort() int
 	DataPath() string
+	// TmpfsPaths returns the paths besides DataPath the server writes to, such
+	// as its socket directory. They are mounted as tmpfs when the container's
+	// root filesystem is read-only.
+	TmpfsPaths() []string
 	Versions() []string
 
 	EnvVars(username, password, database string) []string
 	// ContainerCmd returns custom command/args to run the container (optional, nil = use image default)
-	ContainerCmd(password string) []string
+	ContainerCmd(db *storage.DatabaseInstance) []string
 
 	// Backup and restore
-	Backup(ctx context.Context, client runtime.Client, db *storage.DatabaseInstance, backupPath string) error
-	Restore(ctx context.Context, client runtime.Client, db *storage.DatabaseInstance, backupPath string) error
+	Backup(ctx context.Context, client runtime.Client, db *storage.DatabaseInstance, backupPath string, opts BackupOptions) error
+	Restore(ctx context.Context, client runtime.Client, db *storage.DatabaseInstance, backupPath string, opts RestoreOptions) error
 
 	ExecuteQuery(ctx context.Context, docker runtime.Client, db *storage.DatabaseInstance, query string) (*QueryResult, error)
 
+	// ChangeMarker returns a cheap indicator of the data's state, e.g. the
+	// WAL position, that changes whenever data is written. Backup itself must
+	// not change it. Scheduled backups are skipped while it stays the same.
+	ChangeMarker(ctx context.Context, client runtime.Client, db *storage.DatabaseInstance) (string, error)
+
 	ConnectionStrings(db *storage.DatabaseInstance) *ConnectionStrings
 
 	// CLICommand returns the command to execute a script via stdin
-	CLICommand(username, password, database string) []string
+	CLICommand(db *storage.DatabaseInstance) []string
+	// ExportCommand returns the command and env that write a plain-text SQL dump to stdout.
+	// A nil command means the engine has no SQL export.
+	ExportCommand(db *storage.DatabaseInstance) (cmd []string, env []string)
+	// ShellCommand returns the command to start an interactive CLI session
+	ShellCommand(db *storage.DatabaseInstance) []string
 }
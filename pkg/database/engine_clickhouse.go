@@ -0,0 +1,434 @@
+package database
+
+import (
+	"archive/tar"
+	"context"
+	"crypto/rand"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"github.com/sirrobot01/dbnest/pkg/runtime"
+	"github.com/sirrobot01/dbnest/pkg/storage"
+)
+
+func init() {
+	RegisterEngine(&ClickHouseEngine{})
+}
+
+// ClickHouseEngine implements the Engine interface for ClickHouse. The
+// published port is the native protocol (9000) that clickhouse-client and
+// the native drivers use; the HTTP interface on 8123 is reachable from the
+// container network.
+type ClickHouseEngine struct{}
+
+// clickhouseHTTPPort is the container port of ClickHouse's HTTP interface
+const clickhouseHTTPPort = 8123
+
+func (e *ClickHouseEngine) Name() string {
+	return "ClickHouse"
+}
+
+func (e *ClickHouseEngine) Type() string {
+	return "clickhouse"
+}
+
+func (e *ClickHouseEngine) Image() string {
+	return "clickhouse/clickhouse-server"
+}
+
+func (e *ClickHouseEngine) DefaultPort() int {
+	return 9000
+}
+
+func (e *ClickHouseEngine) DataPath() string {
+	return "/var/lib/clickhouse"
+}
+
+// TmpfsPaths includes users.d, where the entrypoint writes the configured user
+func (e *ClickHouseEngine) TmpfsPaths() []string {
+	return []string{"/var/log/clickhouse-server", "/etc/clickhouse-server/users.d", "/tmp"}
+}
+
+// HealthcheckCmd runs a trivial query with the native client
+func (e *ClickHouseEngine) HealthcheckCmd(db *storage.DatabaseInstance) []string {
+	return clickhouseClient(db, "--query", "SELECT 1")
+}
+
+func (e *ClickHouseEngine) Versions() []string {
+	return []string{"24.8", "24.3", "23.8"}
+}
+
+func (e *ClickHouseEngine) EnvVars(username, password, database string) []string {
+	return []string{
+		"CLICKHOUSE_USER=" + username,
+		"CLICKHOUSE_PASSWORD=" + password,
+		"CLICKHOUSE_DB=" + database,
+		// Let the user manage other users and grants with SQL
+		"CLICKHOUSE_DEFAULT_ACCESS_MANAGEMENT=1",
+	}
+}
+
+func (e *ClickHouseEngine) ContainerCmd(db *storage.DatabaseInstance) []string {
+	var args []string
+	if db.MaxConnections > 0 {
+		args = append(args, fmt.Sprintf("--max_connections=%d", db.MaxConnections))
+	}
+	for _, k := range configParamKeys(db.ConfigParams) {
+		args = append(args, fmt.Sprintf("--%s=%s", k, db.ConfigParams[k]))
+	}
+	if len(args) == 0 {
+		return nil // use image default
+	}
+	// The entrypoint passes these to clickhouse-server, which reads settings
+	// after "--" as overrides of its config file
+	return append([]string{"--"}, args...)
+}
+
+// clickhouseClient returns a clickhouse-client command for the database
+func clickhouseClient(db *storage.DatabaseInstance, args ...string) []string {
+	cmd := []string{
+		"clickhouse-client",
+		"--user", db.Username,
+		"--password", db.Password,
+		"--database", db.Database,
+	}
+	return append(cmd, args...)
+}
+
+// quoteClickHouseIdent quotes a table name for use in a query
+func quoteClickHouseIdent(name string) string {
+	return "`" + strings.NewReplacer(`\`, `\\`, "`", "\\`").Replace(name) + "`"
+}
+
+// Backup writes a tar archive holding, for each table, its CREATE statement
+// as <table>.sql and, unless it is a view, its rows in ClickHouse's Native
+// format as <table>.native. Views come after the tables they read from.
+func (e *ClickHouseEngine) Backup(ctx context.Context, dockerClient runtime.Client, db *storage.DatabaseInstance, backupPath string, opts BackupOptions) error {
+	listQuery := "SELECT name, engine FROM system.tables WHERE database = currentDatabase() AND NOT is_temporary" +
+		" AND name NOT LIKE '.inner%' ORDER BY engine LIKE '%View', name"
+	output, err := dockerClient.Exec(ctx, db.ContainerID, clickhouseClient(db, "--format", "TSV", "--query", listQuery), nil)
+	if err != nil {
+		return fmt.Errorf("failed to list tables: %w", err)
+	}
+
+	if err := os.MkdirAll(filepath.Dir(backupPath), 0755); err != nil {
+		return fmt.Errorf("failed to create backup directory: %w", err)
+	}
+	f, err := createBackupFile(backupPath, opts)
+	if err != nil {
+		return fmt.Errorf("failed to create backup file: %w", err)
+	}
+	defer f.Close()
+	archive := tar.NewWriter(f)
+
+	add := func(name, data string) error {
+		if err := archive.WriteHeader(&tar.Header{Name: name, Mode: 0644, Size: int64(len(data))}); err != nil {
+			return err
+		}
+		_, err := io.WriteString(archive, data)
+		return err
+	}
+
+	for _, line := range strings.Split(strings.TrimSpace(output), "\n") {
+		name, engine, ok := strings.Cut(line, "\t")
+		if !ok {
+			continue
+		}
+		name = unescapeMySQLBatch(name)
+		if len(opts.Tables) > 0 && !slices.Contains(opts.Tables, name) {
+			continue
+		}
+
+		create, err := dockerClient.Exec(ctx, db.ContainerID,
+			clickhouseClient(db, "--format", "TSVRaw", "--query", "SHOW CREATE TABLE "+quoteClickHouseIdent(name)), nil)
+		if err != nil {
+			return fmt.Errorf("failed to read schema of %s: %w", name, err)
+		}
+		if err := add(name+".sql", strings.TrimSpace(create)); err != nil {
+			return fmt.Errorf("failed to write backup file: %w", err)
+		}
+		if strings.HasSuffix(engine, "View") {
+			continue
+		}
+
+		// Native data is binary, so it is streamed rather than read as output.
+		// It goes through a temporary file because the tar header needs its
+		// size up front and a table can be far larger than memory.
+		data, err := spoolClickHouseTable(ctx, dockerClient, db, name, filepath.Dir(backupPath), opts)
+		if err != nil {
+			return err
+		}
+		err = archive.WriteHeader(&tar.Header{Name: name + ".native", Mode: 0644, Size: data.Size()})
+		if err == nil {
+			_, err = io.Copy(archive, data)
+		}
+		data.Close()
+		if err != nil {
+			return fmt.Errorf("failed to write backup file: %w", err)
+		}
+	}
+
+	if err := archive.Close(); err != nil {
+		return fmt.Errorf("failed to write backup file: %w", err)
+	}
+	return f.Close()
+}
+
+// spooledTable is a table dump held in a temporary file. Close removes it.
+type spooledTable struct {
+	io.ReadCloser
+	path string
+	size int64
+}
+
+func (t *spooledTable) Size() int64 {
+	return t.size
+}
+
+func (t *spooledTable) Close() error {
+	err := t.ReadCloser.Close()
+	os.Remove(t.path)
+	return err
+}
+
+// spoolClickHouseTable dumps a table's Native data to a temporary file in
+// dir. For encrypted backups the file is encrypted under a throwaway key, so
+// the plaintext doesn't reach the disk while it waits to be archived.
+func spoolClickHouseTable(ctx context.Context, dockerClient runtime.Client, db *storage.DatabaseInstance, name, dir string, opts BackupOptions) (*spooledTable, error) {
+	tmp, err := os.CreateTemp(dir, ".clickhouse-*.native")
+	if err != nil {
+		return nil, fmt.Errorf("failed to create temporary file: %w", err)
+	}
+	path := tmp.Name()
+	tmp.Close()
+
+	spoolOpts := BackupOptions{}
+	if len(opts.EncryptionKey) > 0 {
+		spoolOpts.EncryptionKey = make([]byte, BackupKeySize)
+		if _, err := rand.Read(spoolOpts.EncryptionKey); err != nil {
+			os.Remove(path)
+			return nil, err
+		}
+	}
+	w, err := createBackupFile(path, spoolOpts)
+	if err != nil {
+		os.Remove(path)
+		return nil, fmt.Errorf("failed to create temporary file: %w", err)
+	}
+	err = dockerClient.ExecStream(ctx, db.ContainerID,
+		clickhouseClient(db, "--format", "Native", "--query", "SELECT * FROM "+quoteClickHouseIdent(name)), nil, w)
+	if closeErr := w.Close(); err == nil && closeErr != nil {
+		err = fmt.Errorf("failed to write temporary file: %w", closeErr)
+	}
+	if err != nil {
+		os.Remove(path)
+		return nil, fmt.Errorf("failed to dump %s: %w", name, err)
+	}
+
+	if len(spoolOpts.EncryptionKey) > 0 {
+		r, err := openEncryptedBackup(spoolOpts.EncryptionKey, path)
+		if err != nil {
+			os.Remove(path)
+			return nil, err
+		}
+		return &spooledTable{ReadCloser: r, path: path, size: r.Size()}, nil
+	}
+	f, err := os.Open(path)
+	if err != nil {
+		os.Remove(path)
+		return nil, err
+	}
+	info, err := f.Stat()
+	if err != nil {
+		f.Close()
+		os.Remove(path)
+		return nil, err
+	}
+	return &spooledTable{ReadCloser: f, path: path, size: info.Size()}, nil
+}
+
+// Restore recreates each table in the archive Backup wrote, replacing any
+// table of the same name, and streams its rows back in through stdin
+func (e *ClickHouseEngine) Restore(ctx context.Context, dockerClient runtime.Client, db *storage.DatabaseInstance, backupPath string, opts RestoreOptions) error {
+	f, err := os.Open(backupPath)
+	if err != nil {
+		return fmt.Errorf("failed to read backup file: %w", err)
+	}
+	defer f.Close()
+
+	archive := tar.NewReader(f)
+	for {
+		header, err := archive.Next()
+		if errors.Is(err, io.EOF) {
+			return nil
+		}
+		if err != nil {
+			return fmt.Errorf("failed to read backup file: %w", err)
+		}
+		name, kind := header.Name, filepath.Ext(header.Name)
+		name = strings.TrimSuffix(name, kind)
+		if len(opts.Tables) > 0 && !slices.Contains(opts.Tables, name) {
+			continue
+		}
+
+		switch kind {
+		case ".sql":
+			create, err := io.ReadAll(archive)
+			if err != nil {
+				return fmt.Errorf("failed to read backup file: %w", err)
+			}
+			if output, err := dockerClient.Exec(ctx, db.ContainerID,
+				clickhouseClient(db, "--query", "DROP TABLE IF EXISTS "+quoteClickHouseIdent(name)), nil); err != nil {
+				return fmt.Errorf("failed to drop %s: %w, output: %s", name, err, output)
+			}
+			if output, err := dockerClient.Exec(ctx, db.ContainerID, clickhouseClient(db, "--query", string(create)), nil); err != nil {
+				return fmt.Errorf("failed to create %s: %w, output: %s", name, err, output)
+			}
+		case ".native":
+			insert := clickhouseClient(db, "--query", "INSERT INTO "+quoteClickHouseIdent(name)+" FORMAT Native")
+			if output, err := dockerClient.ExecWithStdin(ctx, db.ContainerID, insert, archive, nil); err != nil {
+				return fmt.Errorf("failed to restore %s: %w, output: %s", name, err, output)
+			}
+		}
+	}
+}
+
+// ChangeMarker summarises the database's active data parts. Inserts and
+// mutations change it, and so do background merges, which only costs an
+// extra backup.
+func (e *ClickHouseEngine) ChangeMarker(ctx context.Context, client runtime.Client, db *storage.DatabaseInstance) (string, error) {
+	query := "SELECT count(), sum(rows), max(modification_time) FROM system.parts WHERE database = currentDatabase() AND active"
+	output, err := client.Exec(ctx, db.ContainerID, clickhouseClient(db, "--format", "TSV", "--query", query), nil)
+	if err != nil {
+		return "", fmt.Errorf("failed to read data parts: %w", err)
+	}
+	parts := strings.TrimSpace(output)
+	if parts == "" {
+		return "", fmt.Errorf("empty data parts summary")
+	}
+	return "parts:" + strings.ReplaceAll(parts, "\t", ":"), nil
+}
+
+func (e *ClickHouseEngine) ExecuteQuery(ctx context.Context, dockerClient runtime.Client, db *storage.DatabaseInstance, query string) (*QueryResult, error) {
+	return e.executeQuery(ctx, dockerClient, db, query)
+}
+
+// ExecuteReadOnlyQuery runs query with the readonly setting, under which the
+// server refuses writes and setting changes
+func (e *ClickHouseEngine) ExecuteReadOnlyQuery(ctx context.Context, dockerClient runtime.Client, db *storage.DatabaseInstance, query string) (*QueryResult, error) {
+	return e.executeQuery(ctx, dockerClient, db, query, "--readonly=1")
+}
+
+func (e *ClickHouseEngine) executeQuery(ctx context.Context, dockerClient runtime.Client, db *storage.DatabaseInstance, query string, args ...string) (*QueryResult, error) {
+	// TSVWithNames escapes tabs and newlines in values and prints NULL as \N
+	cmd := clickhouseClient(db, append(args, "--format", "TSVWithNames", "--query", query)...)
+	output, err := dockerClient.Exec(ctx, db.ContainerID, cmd, nil)
+	if err != nil {
+		return &QueryResult{Error: fmt.Sprintf("Query failed: %v", err)}, nil
+	}
+	return parseClickHouseTSV(output), nil
+}
+
+// parseClickHouseTSV parses TSVWithNames output, whose first line holds the
+// column names
+func parseClickHouseTSV(output string) *QueryResult {
+	result := &QueryResult{
+		Columns: []string{},
+		Rows:    [][]interface{}{},
+	}
+
+	output = strings.TrimSuffix(output, "\n")
+	if output == "" {
+		result.Message = "Query executed successfully (no output)"
+		return result
+	}
+
+	lines := strings.Split(output, "\n")
+	for _, name := range strings.Split(lines[0], "\t") {
+		result.Columns = append(result.Columns, unescapeMySQLBatch(name))
+	}
+	for _, line := range lines[1:] {
+		fields := strings.Split(line, "\t")
+		row := make([]interface{}, len(fields))
+		for i, field := range fields {
+			if field != `\N` {
+				row[i] = unescapeMySQLBatch(field)
+			}
+		}
+		result.Rows = append(result.Rows, row)
+	}
+	result.RowCount = len(result.Rows)
+	return result
+}
+
+func (e *ClickHouseEngine) ConnectionStrings(db *storage.DatabaseInstance) *ConnectionStrings {
+	uri := fmt.Sprintf("clickhouse://%s:<password>@%s:%d/%s", db.Username, db.Host, db.Port, db.Database)
+
+	return &ConnectionStrings{
+		URI: uri,
+		Python: fmt.Sprintf(`from clickhouse_driver import Client
+client = Client(
+    host="%s",
+    port=%d,
+    user="%s",
+    password="<password>",
+    database="%s"
+)`, db.Host, db.Port, db.Username, db.Database),
+		Node: fmt.Sprintf(`const { createClient } = require('@clickhouse/client');
+// Uses the HTTP interface, reachable from the container network
+const client = createClient({
+    url: 'http://%s:%d',
+    username: '%s',
+    password: '<password>',
+    database: '%s'
+});`, db.Host, clickhouseHTTPPort, db.Username, db.Database),
+		Go: fmt.Sprintf(`import "github.com/ClickHouse/clickhouse-go/v2"
+
+conn, err := clickhouse.Open(&clickhouse.Options{
+    Addr: []string{"%s:%d"},
+    Auth: clickhouse.Auth{
+        Database: "%s",
+        Username: "%s",
+        Password: "<password>",
+    },
+})`, db.Host, db.Port, db.Database, db.Username),
+		Java: fmt.Sprintf(`// Uses the HTTP interface, reachable from the container network
+String url = "jdbc:clickhouse://%s:%d/%s";
+Connection conn = DriverManager.getConnection(url, "%s", "<password>");`,
+			db.Host, clickhouseHTTPPort, db.Database, db.Username),
+		Ruby: fmt.Sprintf(`require 'click_house'
+# Uses the HTTP interface, reachable from the container network
+ClickHouse.config do |config|
+  config.url = 'http://%s:%d'
+  config.username = '%s'
+  config.password = '<password>'
+  config.database = '%s'
+end`, db.Host, clickhouseHTTPPort, db.Username, db.Database),
+		PHP: fmt.Sprintf(`// Uses the HTTP interface, reachable from the container network
+$db = new ClickHouseDB\Client([
+    'host' => '%s',
+    'port' => %d,
+    'username' => '%s',
+    'password' => '<password>'
+]);
+$db->database('%s');`, db.Host, clickhouseHTTPPort, db.Username, db.Database),
+	}
+}
+
+func (e *ClickHouseEngine) CLICommand(db *storage.DatabaseInstance) []string {
+	return clickhouseClient(db, "--multiquery") // Read statements from stdin
+}
+
+func (e *ClickHouseEngine) ShellCommand(db *storage.DatabaseInstance) []string {
+	return clickhouseClient(db)
+}
+
+// ExportCommand returns nil: ClickHouse has no plain SQL dump tool
+func (e *ClickHouseEngine) ExportCommand(db *storage.DatabaseInstance) ([]string, []string) {
+	return nil, nil
+}
@@ -0,0 +1,352 @@
+package database
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"text/template"
+
+	"github.com/sirrobot01/dbnest/pkg/runtime"
+	"github.com/sirrobot01/dbnest/pkg/storage"
+)
+
+// CustomEngineSpec defines an engine in an engines file. Env vars, commands
+// and the URI are Go templates that can refer to {{.Username}},
+// {{.Password}}, {{.Database}}, {{.Host}} and {{.Port}}; the query command
+// also gets {{.Query}}. Commands run inside the database container.
+type CustomEngineSpec struct {
+	Type        string   `json:"type"` // e.g. "valkey"; must not clash with a built-in engine
+	Name        string   `json:"name"` // display name, defaults to the type
+	Image       string   `json:"image"`
+	Versions    []string `json:"versions"` // image tags offered, newest first; defaults to "latest"
+	DefaultPort int      `json:"defaultPort"`
+	DataPath    string   `json:"dataPath"`
+	// TmpfsPaths are the scratch paths kept writable with a read-only root filesystem
+	TmpfsPaths []string `json:"tmpfsPaths,omitempty"`
+
+	Env     []string `json:"env,omitempty"`     // "KEY=value" container env vars
+	Command []string `json:"command,omitempty"` // container command, empty for the image default
+
+	// BackupCommand writes a backup to stdout; RestoreCommand reads one from stdin
+	BackupCommand  []string `json:"backupCommand,omitempty"`
+	RestoreCommand []string `json:"restoreCommand,omitempty"`
+	// QueryCommand runs {{.Query}} and prints its result, shown as one row per line
+	QueryCommand []string `json:"queryCommand,omitempty"`
+	// ReadyQuery is run with QueryCommand until it succeeds, e.g. before seeding
+	ReadyQuery string `json:"readyQuery,omitempty"`
+	// CLICommand runs a script read from stdin, for seeding and imports
+	CLICommand []string `json:"cliCommand"`
+	// ShellCommand starts an interactive session; defaults to CLICommand
+	ShellCommand []string `json:"shellCommand,omitempty"`
+	URI          string   `json:"uri,omitempty"`
+}
+
+// customEngineData is what custom engine templates are executed with
+type customEngineData struct {
+	Username string
+	Password string
+	Database string
+	Host     string
+	Port     int
+	Query    string
+}
+
+// CustomEngine is an Engine driven by a CustomEngineSpec
+type CustomEngine struct {
+	spec CustomEngineSpec
+
+	env, command, backup, restore, query, cli, shell []*template.Template
+	uri                                              *template.Template
+}
+
+// customEngineTypeRegex matches custom engine type names
+var customEngineTypeRegex = regexp.MustCompile(`^[a-z][a-z0-9_-]*$`)
+
+// LoadCustomEngines reads a JSON array of CustomEngineSpec from path and
+// registers each engine. Nothing is registered if any definition is invalid.
+// It returns the registered engine types.
+func LoadCustomEngines(path string) ([]string, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read engines file: %w", err)
+	}
+	var specs []CustomEngineSpec
+	if err := json.Unmarshal(data, &specs); err != nil {
+		return nil, fmt.Errorf("invalid engines file %s: %w", path, err)
+	}
+
+	custom := make([]*CustomEngine, 0, len(specs))
+	seen := make(map[string]bool)
+	for _, spec := range specs {
+		engine, err := NewCustomEngine(spec)
+		if err != nil {
+			return nil, err
+		}
+		if _, err := GetEngine(spec.Type); err == nil || seen[spec.Type] {
+			return nil, fmt.Errorf("engine %s: type is already registered", spec.Type)
+		}
+		seen[spec.Type] = true
+		custom = append(custom, engine)
+	}
+
+	types := make([]string, 0, len(custom))
+	for _, engine := range custom {
+		RegisterEngine(engine)
+		types = append(types, engine.Type())
+	}
+	return types, nil
+}
+
+// NewCustomEngine validates a spec and parses its templates
+func NewCustomEngine(spec CustomEngineSpec) (*CustomEngine, error) {
+	if !customEngineTypeRegex.MatchString(spec.Type) {
+		return nil, fmt.Errorf("engine %q: type must be lowercase letters, digits, '-' or '_'", spec.Type)
+	}
+	if spec.Image == "" {
+		return nil, fmt.Errorf("engine %s: image is required", spec.Type)
+	}
+	if spec.DefaultPort < 1 || spec.DefaultPort > 65535 {
+		return nil, fmt.Errorf("engine %s: defaultPort must be between 1 and 65535", spec.Type)
+	}
+	for _, p := range append([]string{spec.DataPath}, spec.TmpfsPaths...) {
+		if !path.IsAbs(p) {
+			return nil, fmt.Errorf("engine %s: %q must be an absolute container path", spec.Type, p)
+		}
+	}
+	if len(spec.CLICommand) == 0 {
+		return nil, fmt.Errorf("engine %s: cliCommand is required", spec.Type)
+	}
+	for _, kv := range spec.Env {
+		if k, _, ok := strings.Cut(kv, "="); !ok || k == "" {
+			return nil, fmt.Errorf("engine %s: env entry %q must be KEY=value", spec.Type, kv)
+		}
+	}
+	if spec.Name == "" {
+		spec.Name = spec.Type
+	}
+	if len(spec.Versions) == 0 {
+		spec.Versions = []string{"latest"}
+	}
+
+	e := &CustomEngine{spec: spec}
+	var err error
+	for _, field := range []struct {
+		name string
+		args []string
+		dst  *[]*template.Template
+	}{
+		{"env", spec.Env, &e.env},
+		{"command", spec.Command, &e.command},
+		{"backupCommand", spec.BackupCommand, &e.backup},
+		{"restoreCommand", spec.RestoreCommand, &e.restore},
+		{"queryCommand", spec.QueryCommand, &e.query},
+		{"cliCommand", spec.CLICommand, &e.cli},
+		{"shellCommand", spec.ShellCommand, &e.shell},
+	} {
+		if *field.dst, err = parseCustomTemplates(spec.Type, field.name, field.args); err != nil {
+			return nil, err
+		}
+	}
+	if spec.URI != "" {
+		uri, err := parseCustomTemplates(spec.Type, "uri", []string{spec.URI})
+		if err != nil {
+			return nil, err
+		}
+		e.uri = uri[0]
+	}
+	if len(e.shell) == 0 {
+		e.shell = e.cli
+	}
+	return e, nil
+}
+
+// parseCustomTemplates parses a spec field's templates and runs them once,
+// so a reference to an unknown field fails at load time rather than later
+func parseCustomTemplates(engineType, field string, args []string) ([]*template.Template, error) {
+	tmpls := make([]*template.Template, len(args))
+	for i, arg := range args {
+		t, err := template.New(field).Parse(arg)
+		if err == nil {
+			err = t.Execute(io.Discard, customEngineData{})
+		}
+		if err != nil {
+			return nil, fmt.Errorf("engine %s: %s: %w", engineType, field, err)
+		}
+		tmpls[i] = t
+	}
+	return tmpls, nil
+}
+
+// expand executes templates for a database. Templates are checked when the
+// engine is loaded, so executing them can't fail.
+func (e *CustomEngine) expand(tmpls []*template.Template, data customEngineData) []string {
+	if len(tmpls) == 0 {
+		return nil
+	}
+	out := make([]string, len(tmpls))
+	for i, t := range tmpls {
+		var b strings.Builder
+		t.Execute(&b, data)
+		out[i] = b.String()
+	}
+	return out
+}
+
+// data returns the template data for a database
+func (e *CustomEngine) data(db *storage.DatabaseInstance) customEngineData {
+	return customEngineData{
+		Username: db.Username,
+		Password: db.Password,
+		Database: db.Database,
+		Host:     db.Host,
+		Port:     db.Port,
+	}
+}
+
+func (e *CustomEngine) Name() string {
+	return e.spec.Name
+}
+
+func (e *CustomEngine) Type() string {
+	return e.spec.Type
+}
+
+func (e *CustomEngine) Image() string {
+	return e.spec.Image
+}
+
+func (e *CustomEngine) DefaultPort() int {
+	return e.spec.DefaultPort
+}
+
+func (e *CustomEngine) DataPath() string {
+	return e.spec.DataPath
+}
+
+func (e *CustomEngine) TmpfsPaths() []string {
+	return e.spec.TmpfsPaths
+}
+
+func (e *CustomEngine) Versions() []string {
+	return e.spec.Versions
+}
+
+func (e *CustomEngine) EnvVars(username, password, database string) []string {
+	return e.expand(e.env, customEngineData{Username: username, Password: password, Database: database})
+}
+
+// ContainerCmd ignores maxConnections and configParams, which custom
+// engines have no way to apply
+func (e *CustomEngine) ContainerCmd(db *storage.DatabaseInstance) []string {
+	return e.expand(e.command, e.data(db))
+}
+
+func (e *CustomEngine) Backup(ctx context.Context, client runtime.Client, db *storage.DatabaseInstance, backupPath string, opts BackupOptions) error {
+	if len(e.backup) == 0 {
+		return fmt.Errorf("backups are not supported for %s", e.spec.Name)
+	}
+	if len(opts.Tables) > 0 {
+		return fmt.Errorf("%s backups cannot be limited to tables", e.spec.Name)
+	}
+
+	if err := os.MkdirAll(filepath.Dir(backupPath), 0755); err != nil {
+		return fmt.Errorf("failed to create backup directory: %w", err)
+	}
+	f, err := createBackupFile(backupPath, opts)
+	if err != nil {
+		return fmt.Errorf("failed to create backup file: %w", err)
+	}
+	defer f.Close()
+
+	if err := client.ExecStream(ctx, db.ContainerID, e.expand(e.backup, e.data(db)), nil, f); err != nil {
+		return fmt.Errorf("backup command failed: %w", err)
+	}
+	return f.Close()
+}
+
+func (e *CustomEngine) Restore(ctx context.Context, client runtime.Client, db *storage.DatabaseInstance, backupPath string, opts RestoreOptions) error {
+	if len(e.restore) == 0 {
+		return fmt.Errorf("restores are not supported for %s", e.spec.Name)
+	}
+	if len(opts.Tables) > 0 {
+		return fmt.Errorf("%s restores cannot be limited to tables", e.spec.Name)
+	}
+
+	f, err := os.Open(backupPath)
+	if err != nil {
+		return fmt.Errorf("failed to read backup file: %w", err)
+	}
+	defer f.Close()
+
+	output, err := client.ExecWithStdin(ctx, db.ContainerID, e.expand(e.restore, e.data(db)), f, nil)
+	if err != nil {
+		return fmt.Errorf("restore command failed: %w, output: %s", err, output)
+	}
+	return nil
+}
+
+// ExecuteQuery runs the query command and returns its output one line per row
+func (e *CustomEngine) ExecuteQuery(ctx context.Context, client runtime.Client, db *storage.DatabaseInstance, query string) (*QueryResult, error) {
+	if len(e.query) == 0 {
+		return &QueryResult{Error: fmt.Sprintf("queries are not supported for %s", e.spec.Name)}, nil
+	}
+	data := e.data(db)
+	data.Query = query
+	output, err := client.Exec(ctx, db.ContainerID, e.expand(e.query, data), nil)
+	if err != nil {
+		return &QueryResult{Error: fmt.Sprintf("Query failed: %v", err)}, nil
+	}
+
+	result := &QueryResult{Columns: []string{"output"}, Rows: [][]interface{}{}}
+	output = strings.TrimRight(output, "\n")
+	if output == "" {
+		result.Message = "Query executed successfully (no output)"
+		return result, nil
+	}
+	for _, line := range strings.Split(output, "\n") {
+		result.Rows = append(result.Rows, []interface{}{line})
+	}
+	result.RowCount = len(result.Rows)
+	return result, nil
+}
+
+// ReadyQuery returns the query WaitForReady runs
+func (e *CustomEngine) ReadyQuery() string {
+	return e.spec.ReadyQuery
+}
+
+// ChangeMarker is not supported, so scheduled backups always run
+func (e *CustomEngine) ChangeMarker(ctx context.Context, client runtime.Client, db *storage.DatabaseInstance) (string, error) {
+	return "", errors.New("change tracking is not supported for custom engines")
+}
+
+// ConnectionStrings only has the URI, when the spec defines one
+func (e *CustomEngine) ConnectionStrings(db *storage.DatabaseInstance) *ConnectionStrings {
+	strs := &ConnectionStrings{}
+	if e.uri != nil {
+		data := e.data(db)
+		data.Password = "<password>"
+		strs.URI = e.expand([]*template.Template{e.uri}, data)[0]
+	}
+	return strs
+}
+
+func (e *CustomEngine) CLICommand(db *storage.DatabaseInstance) []string {
+	return e.expand(e.cli, e.data(db))
+}
+
+func (e *CustomEngine) ShellCommand(db *storage.DatabaseInstance) []string {
+	return e.expand(e.shell, e.data(db))
+}
+
+// ExportCommand returns nil: custom engines have no SQL export
+func (e *CustomEngine) ExportCommand(db *storage.DatabaseInstance) ([]string, []string) {
+	return nil, nil
+}
@@ -1,11 +1,11 @@
 package database
 
 import (
+	"bytes"
 	"context"
 	"fmt"
 	"os"
 	"path/filepath"
-	"strings"
 
 	"github.com/sirrobot01/dbnest/pkg/runtime"
 	"github.com/sirrobot01/dbnest/pkg/storage"
@@ -39,6 +39,15 @@ func (e *MariaDBEngine) DataPath() string {
 	return "/var/lib/mysql"
 }
 
+func (e *MariaDBEngine) TmpfsPaths() []string {
+	return []string{"/run/mysqld", "/tmp"}
+}
+
+// HealthcheckCmd pings the server over TCP, like MySQL's
+func (e *MariaDBEngine) HealthcheckCmd(db *storage.DatabaseInstance) []string {
+	return []string{"mariadb-admin", "ping", "-h", "127.0.0.1", "--silent"}
+}
+
 func (e *MariaDBEngine) Versions() []string {
 	return []string{"11", "10.11", "10.6", "10.5"}
 }
@@ -52,17 +61,18 @@ func (e *MariaDBEngine) EnvVars(username, password, database string) []string {
 	}
 }
 
-func (e *MariaDBEngine) ContainerCmd(password string) []string {
-	return nil // use image default
+func (e *MariaDBEngine) ContainerCmd(db *storage.DatabaseInstance) []string {
+	// Leading flags make the entrypoint run the image's server binary
+	// (mariadbd or mysqld, depending on version) with them
+	args := mysqlServerArgs(db)
+	if len(args) == 0 {
+		return nil // use image default
+	}
+	return args
 }
 
-func (e *MariaDBEngine) Backup(ctx context.Context, dockerClient runtime.Client, db *storage.DatabaseInstance, backupPath string) error {
-	cmd := []string{
-		"mariadb-dump",
-		"-u", db.Username,
-		"-p" + db.Password,
-		db.Database,
-	}
+func (e *MariaDBEngine) Backup(ctx context.Context, dockerClient runtime.Client, db *storage.DatabaseInstance, backupPath string, opts BackupOptions) error {
+	cmd := mysqlDumpCommand(ctx, dockerClient, db, "mariadb-dump", "mariadb", "--master-data=2", opts)
 
 	if err := os.MkdirAll(filepath.Dir(backupPath), 0755); err != nil {
 		return fmt.Errorf("failed to create backup directory: %w", err)
@@ -73,18 +83,21 @@ func (e *MariaDBEngine) Backup(ctx context.Context, dockerClient runtime.Client,
 		return fmt.Errorf("mariadb-dump failed: %w", err)
 	}
 
-	if err := os.WriteFile(backupPath, []byte(output), 0644); err != nil {
+	if err := writeBackupFile(backupPath, output, opts); err != nil {
 		return fmt.Errorf("failed to write backup file: %w", err)
 	}
 
 	return nil
 }
 
-func (e *MariaDBEngine) Restore(ctx context.Context, dockerClient runtime.Client, db *storage.DatabaseInstance, backupPath string) error {
+func (e *MariaDBEngine) Restore(ctx context.Context, dockerClient runtime.Client, db *storage.DatabaseInstance, backupPath string, opts RestoreOptions) error {
 	data, err := os.ReadFile(backupPath)
 	if err != nil {
 		return fmt.Errorf("failed to read backup file: %w", err)
 	}
+	if len(opts.Tables) > 0 {
+		data = filterMySQLDump(data, opts.Tables)
+	}
 
 	cmd := []string{
 		"mariadb",
@@ -93,7 +106,7 @@ func (e *MariaDBEngine) Restore(ctx context.Context, dockerClient runtime.Client
 		db.Database,
 	}
 
-	output, err := dockerClient.ExecWithStdin(ctx, db.ContainerID, cmd, data, nil)
+	output, err := dockerClient.ExecWithStdin(ctx, db.ContainerID, cmd, bytes.NewReader(data), nil)
 	if err != nil {
 		return fmt.Errorf("mariadb restore failed: %w, output: %s", err, output)
 	}
@@ -101,12 +114,17 @@ func (e *MariaDBEngine) Restore(ctx context.Context, dockerClient runtime.Client
 	return nil
 }
 
+// ChangeMarker returns the current binary log position
+func (e *MariaDBEngine) ChangeMarker(ctx context.Context, client runtime.Client, db *storage.DatabaseInstance) (string, error) {
+	return binlogPosition(ctx, client, db, "mariadb", "SHOW MASTER STATUS")
+}
+
 func (e *MariaDBEngine) ExecuteQuery(ctx context.Context, dockerClient runtime.Client, db *storage.DatabaseInstance, query string) (*QueryResult, error) {
 	cmd := []string{
 		"mariadb",
 		"-u", db.Username,
 		"-p" + db.Password,
-		"-B", // Batch mode (tab-separated, includes headers)
+		"-B", // Batch mode (tab-separated, escaped values, includes headers)
 		db.Database,
 		"-e", query,
 	}
@@ -116,44 +134,13 @@ func (e *MariaDBEngine) ExecuteQuery(ctx context.Context, dockerClient runtime.C
 		return &QueryResult{Error: fmt.Sprintf("Query failed: %v", err)}, nil
 	}
 
-	result := &QueryResult{
-		Columns: []string{},
-		Rows:    [][]interface{}{},
-	}
-
-	lines := strings.Split(strings.TrimSpace(output), "\n")
-	if len(lines) == 0 {
-		result.Message = "Query executed successfully (no output)"
-		return result, nil
-	}
-
-	for i, line := range lines {
-		if line == "" {
-			continue
-		}
-		cols := strings.Split(line, "\t")
-
-		if i == 0 {
-			// First line is headers
-			for _, col := range cols {
-				result.Columns = append(result.Columns, col)
-			}
-		} else {
-			// Data rows
-			row := make([]interface{}, len(cols))
-			for j, col := range cols {
-				if col == "NULL" {
-					row[j] = nil
-				} else {
-					row[j] = col
-				}
-			}
-			result.Rows = append(result.Rows, row)
-		}
-	}
-	result.RowCount = len(result.Rows)
+	return parseMySQLBatch(output), nil
+}
 
-	return result, nil
+// ExecuteReadOnlyQuery runs query after making the session's transactions
+// read-only. The SET prints nothing, so the output is the query's alone.
+func (e *MariaDBEngine) ExecuteReadOnlyQuery(ctx context.Context, client runtime.Client, db *storage.DatabaseInstance, query string) (*QueryResult, error) {
+	return e.ExecuteQuery(ctx, client, db, "SET SESSION TRANSACTION READ ONLY; "+query)
 }
 
 func (e *MariaDBEngine) ConnectionStrings(db *storage.DatabaseInstance) *ConnectionStrings {
@@ -202,11 +189,30 @@ client = Mysql2::Client.new(
 	}
 }
 
-func (e *MariaDBEngine) CLICommand(username, password, database string) []string {
+func (e *MariaDBEngine) CLICommand(db *storage.DatabaseInstance) []string {
 	return []string{
 		"mariadb",
-		"-u", username,
-		"-p" + password,
-		database,
+		"-u", db.Username,
+		"-p" + db.Password,
+		db.Database,
+	}
+}
+
+func (e *MariaDBEngine) ShellCommand(db *storage.DatabaseInstance) []string {
+	return []string{
+		"mariadb",
+		"-u", db.Username,
+		"-p" + db.Password,
+		db.Database,
+	}
+}
+
+func (e *MariaDBEngine) ExportCommand(db *storage.DatabaseInstance) ([]string, []string) {
+	cmd := []string{
+		"mariadb-dump",
+		"-u", db.Username,
+		"-p" + db.Password,
+		db.Database,
 	}
+	return cmd, nil
 }
@@ -1,12 +1,14 @@
 package database
 
 import (
+	"bytes"
 	"context"
 	"fmt"
 	"os"
 	"path/filepath"
 	"strings"
 
+	"github.com/rs/zerolog/log"
 	"github.com/sirrobot01/dbnest/pkg/runtime"
 	"github.com/sirrobot01/dbnest/pkg/storage"
 )
@@ -38,6 +40,17 @@ func (e *MySQLEngine) DataPath() string {
 	return "/var/lib/mysql"
 }
 
+func (e *MySQLEngine) TmpfsPaths() []string {
+	return []string{"/var/run/mysqld", "/var/lib/mysql-files", "/tmp"}
+}
+
+// HealthcheckCmd pings the server over TCP, skipping the socket-only server
+// the entrypoint runs while initializing. The ping succeeds without
+// credentials once the server is up.
+func (e *MySQLEngine) HealthcheckCmd(db *storage.DatabaseInstance) []string {
+	return []string{"mysqladmin", "ping", "-h", "127.0.0.1", "--silent"}
+}
+
 func (e *MySQLEngine) Versions() []string {
 	return []string{"8.0", "8.4", "5.7"}
 }
@@ -51,17 +64,20 @@ func (e *MySQLEngine) EnvVars(username, password, database string) []string {
 	}
 }
 
-func (e *MySQLEngine) ContainerCmd(password string) []string {
-	return nil // use image default
+func (e *MySQLEngine) ContainerCmd(db *storage.DatabaseInstance) []string {
+	args := mysqlServerArgs(db)
+	if len(args) == 0 {
+		return nil // use image default
+	}
+	return append([]string{"mysqld"}, args...)
 }
 
-func (e *MySQLEngine) Backup(ctx context.Context, dockerClient runtime.Client, db *storage.DatabaseInstance, backupPath string) error {
-	cmd := []string{
-		"mysqldump",
-		"-u", db.Username,
-		"-p" + db.Password,
-		db.Database,
+func (e *MySQLEngine) Backup(ctx context.Context, dockerClient runtime.Client, db *storage.DatabaseInstance, backupPath string, opts BackupOptions) error {
+	binlogFlag := "--source-data=2"
+	if strings.HasPrefix(db.Version, "5.") {
+		binlogFlag = "--master-data=2" // renamed in 8.0.26
 	}
+	cmd := mysqlDumpCommand(ctx, dockerClient, db, "mysqldump", "mysql", binlogFlag, opts)
 
 	if err := os.MkdirAll(filepath.Dir(backupPath), 0755); err != nil {
 		return fmt.Errorf("failed to create backup directory: %w", err)
@@ -72,18 +88,136 @@ func (e *MySQLEngine) Backup(ctx context.Context, dockerClient runtime.Client, d
 		return fmt.Errorf("mysqldump failed: %w", err)
 	}
 
-	if err := os.WriteFile(backupPath, []byte(output), 0644); err != nil {
+	if err := writeBackupFile(backupPath, output, opts); err != nil {
 		return fmt.Errorf("failed to write backup file: %w", err)
 	}
 
 	return nil
 }
 
-func (e *MySQLEngine) Restore(ctx context.Context, dockerClient runtime.Client, db *storage.DatabaseInstance, backupPath string) error {
+// mysqlServerArgs returns the server flags for a MySQL/MariaDB database's
+// connection limit and config parameters
+func mysqlServerArgs(db *storage.DatabaseInstance) []string {
+	var args []string
+	if db.MaxConnections > 0 {
+		args = append(args, fmt.Sprintf("--max-connections=%d", db.MaxConnections))
+	}
+	for _, k := range configParamKeys(db.ConfigParams) {
+		args = append(args, fmt.Sprintf("--%s=%s", k, db.ConfigParams[k]))
+	}
+	return args
+}
+
+// mysqlDumpCommand builds a dump command that is consistent for a running
+// database. InnoDB tables are dumped inside one transaction without blocking
+// writers. A transaction can't make MyISAM (or other non-transactional) tables
+// consistent, so if the database has any, tables are read-locked for the
+// duration of the dump instead and writes wait until it finishes.
+func mysqlDumpCommand(ctx context.Context, client runtime.Client, db *storage.DatabaseInstance, dumpBin, clientBin, binlogFlag string, opts BackupOptions) []string {
+	user := db.Username
+	if opts.BinlogPosition {
+		// Reading binlog coordinates needs RELOAD and REPLICATION CLIENT,
+		// which only root has; root shares the database password
+		user = "root"
+	}
+	cmd := []string{dumpBin, "-u", user, "-p" + db.Password, "--quick"}
+
+	tables, err := nonTransactionalTables(ctx, client, db, clientBin)
+	switch {
+	case err != nil:
+		log.Warn().Err(err).Str("id", db.ID).Msg("Could not check table engines, assuming InnoDB for backup")
+		cmd = append(cmd, "--single-transaction")
+	case len(tables) > 0:
+		log.Warn().Str("id", db.ID).Strs("tables", tables).
+			Msg("Database has non-transactional tables, locking tables for the backup")
+		cmd = append(cmd, "--lock-tables")
+	default:
+		cmd = append(cmd, "--single-transaction")
+	}
+
+	if opts.BinlogPosition {
+		cmd = append(cmd, binlogFlag)
+	}
+	cmd = append(cmd, db.Database)
+	return append(cmd, opts.Tables...)
+}
+
+// nonTransactionalTables lists the tables in the database whose storage engine
+// doesn't support transactions, e.g. MyISAM
+func nonTransactionalTables(ctx context.Context, client runtime.Client, db *storage.DatabaseInstance, clientBin string) ([]string, error) {
+	cmd := []string{
+		clientBin,
+		"-u", db.Username,
+		"-p" + db.Password,
+		"-N", "-B",
+		db.Database,
+		"-e", "SELECT t.TABLE_NAME FROM information_schema.TABLES t " +
+			"JOIN information_schema.ENGINES e ON e.ENGINE = t.ENGINE " +
+			"WHERE t.TABLE_SCHEMA = DATABASE() AND t.TABLE_TYPE = 'BASE TABLE' " +
+			"AND e.TRANSACTIONS <> 'YES'",
+	}
+	output, err := client.Exec(ctx, db.ContainerID, cmd, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	var tables []string
+	for _, line := range strings.Split(output, "\n") {
+		line = strings.TrimSpace(line)
+		// Skip the client's "Using a password on the command line" warning
+		if line == "" || strings.Contains(line, "[Warning]") {
+			continue
+		}
+		tables = append(tables, line)
+	}
+	return tables, nil
+}
+
+// filterMySQLDump keeps only the given tables' sections of a mysqldump file,
+// along with the session settings before the first table and after the last.
+// Routines, events and other tables are dropped.
+func filterMySQLDump(dump []byte, tables []string) []byte {
+	keep := make(map[string]bool, len(tables))
+	for _, t := range tables {
+		keep[t] = true
+	}
+	sectionTable := func(line, prefix string) (string, bool) {
+		rest, ok := strings.CutPrefix(line, prefix)
+		if !ok {
+			return "", false
+		}
+		name, _, _ := strings.Cut(strings.TrimPrefix(rest, "`"), "`")
+		return name, true
+	}
+
+	var out bytes.Buffer
+	include := true
+	for _, line := range bytes.SplitAfter(dump, []byte("\n")) {
+		text := string(line)
+		if name, ok := sectionTable(text, "-- Table structure for table "); ok {
+			include = keep[name]
+		} else if name, ok := sectionTable(text, "-- Dumping data for table "); ok {
+			include = keep[name]
+		} else if strings.HasPrefix(text, "-- Dumping routines") || strings.HasPrefix(text, "-- Dumping events") {
+			include = false
+		} else if strings.HasPrefix(text, "/*!40103 SET TIME_ZONE=@OLD_TIME_ZONE */") {
+			include = true // trailer restoring session settings
+		}
+		if include {
+			out.Write(line)
+		}
+	}
+	return out.Bytes()
+}
+
+func (e *MySQLEngine) Restore(ctx context.Context, dockerClient runtime.Client, db *storage.DatabaseInstance, backupPath string, opts RestoreOptions) error {
 	data, err := os.ReadFile(backupPath)
 	if err != nil {
 		return fmt.Errorf("failed to read backup file: %w", err)
 	}
+	if len(opts.Tables) > 0 {
+		data = filterMySQLDump(data, opts.Tables)
+	}
 
 	cmd := []string{
 		"mysql",
@@ -92,7 +226,7 @@ func (e *MySQLEngine) Restore(ctx context.Context, dockerClient runtime.Client,
 		db.Database,
 	}
 
-	output, err := dockerClient.ExecWithStdin(ctx, db.ContainerID, cmd, data, nil)
+	output, err := dockerClient.ExecWithStdin(ctx, db.ContainerID, cmd, bytes.NewReader(data), nil)
 	if err != nil {
 		return fmt.Errorf("mysql restore failed: %w, output: %s", err, output)
 	}
@@ -100,12 +234,43 @@ func (e *MySQLEngine) Restore(ctx context.Context, dockerClient runtime.Client,
 	return nil
 }
 
+// ChangeMarker returns the current binary log position. SHOW MASTER STATUS
+// was replaced by SHOW BINARY LOG STATUS in 8.4.
+func (e *MySQLEngine) ChangeMarker(ctx context.Context, client runtime.Client, db *storage.DatabaseInstance) (string, error) {
+	query := "SHOW BINARY LOG STATUS"
+	if strings.HasPrefix(db.Version, "5.") || strings.HasPrefix(db.Version, "8.0") {
+		query = "SHOW MASTER STATUS"
+	}
+	return binlogPosition(ctx, client, db, "mysql", query)
+}
+
+// binlogPosition reads the binary log file and position from a status query.
+// It needs REPLICATION CLIENT, so runs as root like binlog-aware backups.
+func binlogPosition(ctx context.Context, client runtime.Client, db *storage.DatabaseInstance, clientBin, query string) (string, error) {
+	cmd := []string{clientBin, "-u", "root", "-p" + db.Password, "-N", "-B", "-e", query}
+	output, err := client.Exec(ctx, db.ContainerID, cmd, nil)
+	if err != nil {
+		return "", fmt.Errorf("failed to read binlog position: %w", err)
+	}
+	for _, line := range strings.Split(output, "\n") {
+		// Skip the client's "Using a password on the command line" warning
+		if strings.Contains(line, "[Warning]") {
+			continue
+		}
+		fields := strings.Split(strings.TrimSpace(line), "\t")
+		if len(fields) >= 2 && fields[0] != "" {
+			return "binlog:" + fields[0] + ":" + fields[1], nil
+		}
+	}
+	return "", fmt.Errorf("binary logging is disabled")
+}
+
 func (e *MySQLEngine) ExecuteQuery(ctx context.Context, client runtime.Client, db *storage.DatabaseInstance, query string) (*QueryResult, error) {
 	cmd := []string{
 		"mysql",
 		"-u", db.Username,
 		"-p" + db.Password,
-		"-B", // Batch mode (tab-separated, includes headers)
+		"-B", // Batch mode (tab-separated, escaped values, includes headers)
 		db.Database,
 		"-e", query,
 	}
@@ -115,44 +280,92 @@ func (e *MySQLEngine) ExecuteQuery(ctx context.Context, client runtime.Client, d
 		return &QueryResult{Error: fmt.Sprintf("Query failed: %v", err)}, nil
 	}
 
+	return parseMySQLBatch(output), nil
+}
+
+// parseMySQLBatch parses the client's batch output: tab-separated rows under
+// a header row. Tabs, newlines, backslashes and NUL bytes inside values are
+// backslash-escaped, so rows split safely before fields are unescaped. Batch
+// mode prints NULL and the string "NULL" the same way; both become nil.
+func parseMySQLBatch(output string) *QueryResult {
 	result := &QueryResult{
 		Columns: []string{},
 		Rows:    [][]interface{}{},
 	}
 
-	lines := strings.Split(strings.TrimSpace(output), "\n")
-	if len(lines) == 0 {
-		result.Message = "Query executed successfully (no output)"
-		return result, nil
-	}
-
-	for i, line := range lines {
-		if line == "" {
+	header := true
+	for _, line := range strings.Split(strings.TrimSuffix(output, "\n"), "\n") {
+		// Skip the client's "Using a password on the command line" warning
+		if header && (line == "" || strings.Contains(line, ": [Warning] ")) {
 			continue
 		}
-		cols := strings.Split(line, "\t")
-
-		if i == 0 {
-			// First line is headers
-			for _, col := range cols {
-				result.Columns = append(result.Columns, col)
+		fields := strings.Split(line, "\t")
+		if header {
+			for _, field := range fields {
+				result.Columns = append(result.Columns, unescapeMySQLBatch(field))
 			}
-		} else {
-			// Data rows
-			row := make([]interface{}, len(cols))
-			for j, col := range cols {
-				if col == "NULL" {
-					row[j] = nil
-				} else {
-					row[j] = col
-				}
+			header = false
+			continue
+		}
+
+		// Trailing empty values can be lost when the output is trimmed
+		row := make([]interface{}, max(len(fields), len(result.Columns)))
+		for i := range row {
+			switch {
+			case i >= len(fields):
+				row[i] = ""
+			case fields[i] == "NULL":
+				row[i] = nil
+			default:
+				row[i] = unescapeMySQLBatch(fields[i])
 			}
-			result.Rows = append(result.Rows, row)
 		}
+		result.Rows = append(result.Rows, row)
+	}
+	if header {
+		result.Message = "Query executed successfully (no output)"
 	}
 	result.RowCount = len(result.Rows)
+	return result
+}
 
-	return result, nil
+// unescapeMySQLBatch reverses the client's batch-mode escaping of a value.
+// ClickHouse escapes TSV values the same way, adding \b and \f.
+func unescapeMySQLBatch(value string) string {
+	if !strings.Contains(value, "\\") {
+		return value
+	}
+	var b strings.Builder
+	for i := 0; i < len(value); i++ {
+		if value[i] != '\\' || i == len(value)-1 {
+			b.WriteByte(value[i])
+			continue
+		}
+		i++
+		switch value[i] {
+		case 't':
+			b.WriteByte('\t')
+		case 'n':
+			b.WriteByte('\n')
+		case 'r':
+			b.WriteByte('\r')
+		case 'b':
+			b.WriteByte('\b')
+		case 'f':
+			b.WriteByte('\f')
+		case '0':
+			b.WriteByte(0)
+		default:
+			b.WriteByte(value[i])
+		}
+	}
+	return b.String()
+}
+
+// ExecuteReadOnlyQuery runs query after making the session's transactions
+// read-only. The SET prints nothing, so the output is the query's alone.
+func (e *MySQLEngine) ExecuteReadOnlyQuery(ctx context.Context, client runtime.Client, db *storage.DatabaseInstance, query string) (*QueryResult, error) {
+	return e.ExecuteQuery(ctx, client, db, "SET SESSION TRANSACTION READ ONLY; "+query)
 }
 
 func (e *MySQLEngine) ConnectionStrings(db *storage.DatabaseInstance) *ConnectionStrings {
@@ -201,11 +414,30 @@ client = Mysql2::Client.new(
 	}
 }
 
-func (e *MySQLEngine) CLICommand(username, password, database string) []string {
+func (e *MySQLEngine) CLICommand(db *storage.DatabaseInstance) []string {
+	return []string{
+		"mysql",
+		"-u", db.Username,
+		"-p" + db.Password,
+		db.Database,
+	}
+}
+
+func (e *MySQLEngine) ShellCommand(db *storage.DatabaseInstance) []string {
 	return []string{
 		"mysql",
-		"-u", username,
-		"-p" + password,
-		database,
+		"-u", db.Username,
+		"-p" + db.Password,
+		db.Database,
+	}
+}
+
+func (e *MySQLEngine) ExportCommand(db *storage.DatabaseInstance) ([]string, []string) {
+	cmd := []string{
+		"mysqldump",
+		"-u", db.Username,
+		"-p" + db.Password,
+		db.Database,
 	}
+	return cmd, nil
 }
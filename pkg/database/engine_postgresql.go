@@ -1,6 +1,7 @@
 package database
 
 import (
+	"bytes"
 	"context"
 	"fmt"
 	"os"
@@ -38,6 +39,18 @@ func (e *PostgreSQLEngine) DataPath() string {
 	return "/var/lib/postgresql/data"
 }
 
+// TmpfsPaths includes /backup, where Backup has pg_dump write its file
+func (e *PostgreSQLEngine) TmpfsPaths() []string {
+	return []string{"/var/run/postgresql", "/tmp", "/backup"}
+}
+
+// HealthcheckCmd checks the server accepts connections over TCP. The image's
+// entrypoint runs a temporary socket-only server while it initializes the
+// data directory, which this doesn't mistake for the real one.
+func (e *PostgreSQLEngine) HealthcheckCmd(db *storage.DatabaseInstance) []string {
+	return []string{"pg_isready", "-h", "127.0.0.1", "-U", db.Username, "-d", db.Database}
+}
+
 func (e *PostgreSQLEngine) Versions() []string {
 	return []string{"16", "15", "14", "13", "12"}
 }
@@ -50,11 +63,21 @@ func (e *PostgreSQLEngine) EnvVars(username, password, database string) []string
 	}
 }
 
-func (e *PostgreSQLEngine) ContainerCmd(password string) []string {
-	return nil // use image default
+func (e *PostgreSQLEngine) ContainerCmd(db *storage.DatabaseInstance) []string {
+	var args []string
+	if db.MaxConnections > 0 {
+		args = append(args, "-c", fmt.Sprintf("max_connections=%d", db.MaxConnections))
+	}
+	for _, k := range configParamKeys(db.ConfigParams) {
+		args = append(args, "-c", k+"="+db.ConfigParams[k])
+	}
+	if len(args) == 0 {
+		return nil // use image default
+	}
+	return append([]string{"postgres"}, args...)
 }
 
-func (e *PostgreSQLEngine) Backup(ctx context.Context, dockerClient runtime.Client, db *storage.DatabaseInstance, backupPath string) error {
+func (e *PostgreSQLEngine) Backup(ctx context.Context, dockerClient runtime.Client, db *storage.DatabaseInstance, backupPath string, opts BackupOptions) error {
 	// Use pg_dump to create a backup
 	cmd := []string{
 		"pg_dump",
@@ -63,6 +86,9 @@ func (e *PostgreSQLEngine) Backup(ctx context.Context, dockerClient runtime.Clie
 		"-F", "c", // Custom format (compressed)
 		"-f", "/backup/backup.dump",
 	}
+	for _, t := range opts.Tables {
+		cmd = append(cmd, "-t", t)
+	}
 
 	// Create backup directory on host
 	if err := os.MkdirAll(filepath.Dir(backupPath), 0755); err != nil {
@@ -81,14 +107,14 @@ func (e *PostgreSQLEngine) Backup(ctx context.Context, dockerClient runtime.Clie
 		return fmt.Errorf("failed to read backup: %w", err)
 	}
 
-	if err := os.WriteFile(backupPath, []byte(data), 0644); err != nil {
+	if err := writeBackupFile(backupPath, data, opts); err != nil {
 		return fmt.Errorf("failed to write backup file: %w", err)
 	}
 
 	return nil
 }
 
-func (e *PostgreSQLEngine) Restore(ctx context.Context, dockerClient runtime.Client, db *storage.DatabaseInstance, backupPath string) error {
+func (e *PostgreSQLEngine) Restore(ctx context.Context, dockerClient runtime.Client, db *storage.DatabaseInstance, backupPath string, opts RestoreOptions) error {
 	// Read backup file
 	data, err := os.ReadFile(backupPath)
 	if err != nil {
@@ -104,8 +130,16 @@ func (e *PostgreSQLEngine) Restore(ctx context.Context, dockerClient runtime.Cli
 		"--clean",
 		"--if-exists",
 	}
+	for _, t := range opts.Tables {
+		// pg_restore matches -t against bare table names; a schema goes in -n
+		if schema, table, ok := strings.Cut(t, "."); ok {
+			cmd = append(cmd, "-n", schema, "-t", table)
+		} else {
+			cmd = append(cmd, "-t", t)
+		}
+	}
 
-	output, err := dockerClient.ExecWithStdin(ctx, db.ContainerID, cmd, data, []string{"PGPASSWORD=" + db.Password})
+	output, err := dockerClient.ExecWithStdin(ctx, db.ContainerID, cmd, bytes.NewReader(data), []string{"PGPASSWORD=" + db.Password})
 	if err != nil {
 		return fmt.Errorf("pg_restore failed: %w, output: %s", err, output)
 	}
@@ -113,67 +147,90 @@ func (e *PostgreSQLEngine) Restore(ctx context.Context, dockerClient runtime.Cli
 	return nil
 }
 
+// ChangeMarker returns the current WAL insert position, which moves with any
+// write to the cluster
+func (e *PostgreSQLEngine) ChangeMarker(ctx context.Context, client runtime.Client, db *storage.DatabaseInstance) (string, error) {
+	cmd := []string{"psql", "-U", db.Username, "-d", db.Database, "-At", "-c", "SELECT pg_current_wal_lsn()"}
+	output, err := client.Exec(ctx, db.ContainerID, cmd, []string{"PGPASSWORD=" + db.Password})
+	if err != nil {
+		return "", fmt.Errorf("failed to read WAL position: %w", err)
+	}
+	lsn := strings.TrimSpace(output)
+	if lsn == "" {
+		return "", fmt.Errorf("empty WAL position")
+	}
+	return "wal:" + lsn, nil
+}
+
+// psql unaligned output separators. Control characters don't turn up in
+// ordinary values the way "|" and newlines do.
+const (
+	psqlFieldSep  = "\x1f" // ASCII unit separator
+	psqlRecordSep = "\x1e" // ASCII record separator
+	psqlNull      = "\x1a" // printed for NULL so it differs from an empty string
+)
+
 func (e *PostgreSQLEngine) ExecuteQuery(ctx context.Context, dockerClient runtime.Client, db *storage.DatabaseInstance, query string) (*QueryResult, error) {
+	return e.executeQuery(ctx, dockerClient, db, query, nil)
+}
+
+// ExecuteReadOnlyQuery runs query in a session whose transactions are read-only
+func (e *PostgreSQLEngine) ExecuteReadOnlyQuery(ctx context.Context, dockerClient runtime.Client, db *storage.DatabaseInstance, query string) (*QueryResult, error) {
+	return e.executeQuery(ctx, dockerClient, db, query, []string{"PGOPTIONS=-c default_transaction_read_only=on"})
+}
+
+func (e *PostgreSQLEngine) executeQuery(ctx context.Context, dockerClient runtime.Client, db *storage.DatabaseInstance, query string, env []string) (*QueryResult, error) {
 	// Use psql to execute query - include headers for column names
 	cmd := []string{
 		"psql",
 		"-U", db.Username,
 		"-d", db.Database,
+		"-X", // Ignore psqlrc
+		"-q", // No command tags such as "INSERT 0 1"
 		"-A", // Unaligned output
+		"-F", psqlFieldSep,
+		"-R", psqlRecordSep,
+		"-P", "footer=off",
+		"-P", "null=" + psqlNull,
 		"-c", query,
 	}
 
-	output, err := dockerClient.Exec(ctx, db.ContainerID, cmd, []string{"PGPASSWORD=" + db.Password})
+	output, err := dockerClient.Exec(ctx, db.ContainerID, cmd, append([]string{"PGPASSWORD=" + db.Password}, env...))
 	if err != nil {
 		return &QueryResult{Error: fmt.Sprintf("Query failed: %v", err)}, nil
 	}
+	return parsePsqlOutput(output), nil
+}
 
-	// Parse output into rows - first line is headers
+// parsePsqlOutput parses unaligned psql output written with the psql*
+// separators. The first record holds the column names.
+func parsePsqlOutput(output string) *QueryResult {
 	result := &QueryResult{
 		Columns: []string{},
 		Rows:    [][]interface{}{},
 	}
 
-	lines := strings.Split(strings.TrimSpace(output), "\n")
-	if len(lines) == 0 {
+	// psql ends the last record with a newline instead of the record separator
+	output = strings.TrimSuffix(output, "\n")
+	if output == "" {
 		result.Message = "Query executed successfully (no output)"
-		return result, nil
+		return result
 	}
 
-	// First line contains column headers (unless it's a row count like "(1 row)")
-	for i, line := range lines {
-		if line == "" {
-			continue
-		}
-		// Skip row count footer like "(1 row)" or "(5 rows)"
-		if strings.HasPrefix(line, "(") && strings.HasSuffix(line, ")") {
-			continue
-		}
-
-		cols := strings.Split(line, "|")
-
-		if i == 0 {
-			// First line is headers
-			for _, col := range cols {
-				result.Columns = append(result.Columns, strings.TrimSpace(col))
-			}
-		} else {
-			// Data rows
-			row := make([]interface{}, len(cols))
-			for j, col := range cols {
-				trimmed := strings.TrimSpace(col)
-				if trimmed == "" {
-					row[j] = nil
-				} else {
-					row[j] = trimmed
-				}
+	records := strings.Split(output, psqlRecordSep)
+	result.Columns = strings.Split(records[0], psqlFieldSep)
+	for _, record := range records[1:] {
+		fields := strings.Split(record, psqlFieldSep)
+		row := make([]interface{}, len(fields))
+		for i, field := range fields {
+			if field != psqlNull {
+				row[i] = field
 			}
-			result.Rows = append(result.Rows, row)
 		}
+		result.Rows = append(result.Rows, row)
 	}
 	result.RowCount = len(result.Rows)
-
-	return result, nil
+	return result
 }
 
 func (e *PostgreSQLEngine) ConnectionStrings(db *storage.DatabaseInstance) *ConnectionStrings {
@@ -222,11 +279,29 @@ conn = PG.connect(
 }
 
 // Helper to parse JSON output from psql
-func (e *PostgreSQLEngine) CLICommand(username, password, database string) []string {
+func (e *PostgreSQLEngine) CLICommand(db *storage.DatabaseInstance) []string {
 	return []string{
 		"psql",
-		"-U", username,
-		"-d", database,
+		"-U", db.Username,
+		"-d", db.Database,
 		"-f", "-", // Read from stdin
 	}
 }
+
+func (e *PostgreSQLEngine) ShellCommand(db *storage.DatabaseInstance) []string {
+	return []string{
+		"psql",
+		"-U", db.Username,
+		"-d", db.Database,
+	}
+}
+
+func (e *PostgreSQLEngine) ExportCommand(db *storage.DatabaseInstance) ([]string, []string) {
+	// Plain format (no -Fc) so the dump is readable SQL
+	cmd := []string{
+		"pg_dump",
+		"-U", db.Username,
+		"-d", db.Database,
+	}
+	return cmd, []string{"PGPASSWORD=" + db.Password}
+}
@@ -3,9 +3,12 @@ package database
 import (
 	"context"
 	"fmt"
+	"io"
 	"os"
 	"path/filepath"
+	"strconv"
 	"strings"
+	"time"
 
 	"github.com/sirrobot01/dbnest/pkg/runtime"
 	"github.com/sirrobot01/dbnest/pkg/storage"
@@ -15,6 +18,15 @@ func init() {
 	RegisterEngine(&RedisEngine{})
 }
 
+// Redis backup modes
+const (
+	RedisBackupRDB = "rdb" // Point-in-time RDB snapshot via BGSAVE
+	RedisBackupAOF = "aof" // Append-only file via BGREWRITEAOF; requires appendonly
+)
+
+// redisDatabases is the number of logical databases in a default Redis server
+const redisDatabases = 16
+
 // RedisEngine implements the Engine interface for Redis
 type RedisEngine struct{}
 
@@ -38,6 +50,15 @@ func (e *RedisEngine) DataPath() string {
 	return "/data"
 }
 
+func (e *RedisEngine) TmpfsPaths() []string {
+	return []string{"/tmp"}
+}
+
+// HealthcheckCmd sends PING, authenticated so it isn't refused with NOAUTH
+func (e *RedisEngine) HealthcheckCmd(db *storage.DatabaseInstance) []string {
+	return redisCLI(db, "PING")
+}
+
 func (e *RedisEngine) Versions() []string {
 	return []string{"7", "7.2", "6", "6.2"}
 }
@@ -48,14 +69,42 @@ func (e *RedisEngine) EnvVars(username, password, database string) []string {
 	return nil
 }
 
-func (e *RedisEngine) ContainerCmd(password string) []string {
-	if password != "" {
-		return []string{"redis-server", "--requirepass", password}
+func (e *RedisEngine) ContainerCmd(db *storage.DatabaseInstance) []string {
+	var args []string
+	if db.Password != "" {
+		args = append(args, "--requirepass", db.Password)
 	}
-	return nil
+	if db.RedisBackupMode == RedisBackupAOF {
+		args = append(args, "--appendonly", "yes")
+	}
+	for _, k := range configParamKeys(db.ConfigParams) {
+		args = append(args, "--"+k, db.ConfigParams[k])
+	}
+	if len(args) == 0 {
+		return nil
+	}
+	return append([]string{"redis-server"}, args...)
 }
 
-func (e *RedisEngine) Backup(ctx context.Context, dockerClient runtime.Client, db *storage.DatabaseInstance, backupPath string) error {
+func (e *RedisEngine) Backup(ctx context.Context, dockerClient runtime.Client, db *storage.DatabaseInstance, backupPath string, opts BackupOptions) error {
+	if db.RedisBackupMode == RedisBackupAOF {
+		return e.backupAOF(ctx, dockerClient, db, backupPath, opts)
+	}
+
+	// A clean dataset is already on disk. Saving it again would move the
+	// last save time that ChangeMarker reports without any data changing.
+	if info, err := redisPersistenceInfo(ctx, dockerClient, db); err == nil && info["rdb_changes_since_last_save"] == "0" {
+		if data, err := dockerClient.Exec(ctx, db.ContainerID, []string{"cat", "/data/dump.rdb"}, nil); err == nil && data != "" {
+			if err := os.MkdirAll(filepath.Dir(backupPath), 0755); err != nil {
+				return fmt.Errorf("failed to create backup directory: %w", err)
+			}
+			if err := writeBackupFile(backupPath, data, opts); err != nil {
+				return fmt.Errorf("failed to write backup file: %w", err)
+			}
+			return nil
+		}
+	}
+
 	// Trigger a background save
 	var authArgs []string
 	if db.Password != "" {
@@ -85,17 +134,191 @@ func (e *RedisEngine) Backup(ctx context.Context, dockerClient runtime.Client, d
 		return fmt.Errorf("failed to read dump.rdb: %w", err)
 	}
 
-	if err := os.WriteFile(backupPath, []byte(data), 0644); err != nil {
+	if err := writeBackupFile(backupPath, data, opts); err != nil {
+		return fmt.Errorf("failed to write backup file: %w", err)
+	}
+
+	return nil
+}
+
+// redisAOFArchiveCmd archives the AOF as a tar stream. Redis 7 keeps a
+// multi-part AOF in appendonlydir; older versions use a single appendonly.aof.
+const redisAOFArchiveCmd = "cd /data && if [ -d appendonlydir ]; then tar -cf - appendonlydir; else tar -cf - appendonly.aof; fi"
+
+// backupAOF compacts the append-only file with BGREWRITEAOF and archives it
+func (e *RedisEngine) backupAOF(ctx context.Context, dockerClient runtime.Client, db *storage.DatabaseInstance, backupPath string, opts BackupOptions) error {
+	if output, err := dockerClient.Exec(ctx, db.ContainerID, redisCLI(db, "BGREWRITEAOF"), nil); err != nil {
+		return fmt.Errorf("BGREWRITEAOF failed: %w, output: %s", err, output)
+	}
+	if err := waitForAOFRewrite(ctx, dockerClient, db, redisRestoreTimeout); err != nil {
+		return err
+	}
+
+	if err := os.MkdirAll(filepath.Dir(backupPath), 0755); err != nil {
+		return fmt.Errorf("failed to create backup directory: %w", err)
+	}
+
+	data, err := dockerClient.Exec(ctx, db.ContainerID, []string{"sh", "-c", redisAOFArchiveCmd}, nil)
+	if err != nil {
+		return fmt.Errorf("failed to archive AOF: %w", err)
+	}
+
+	if err := writeBackupFile(backupPath, data, opts); err != nil {
 		return fmt.Errorf("failed to write backup file: %w", err)
 	}
 
 	return nil
 }
 
-func (e *RedisEngine) Restore(ctx context.Context, dockerClient runtime.Client, db *storage.DatabaseInstance, backupPath string) error {
-	// For Redis, restoring requires stopping the server, replacing dump.rdb, and restarting
-	// This is complex in a container environment, so we provide a simple implementation
-	return fmt.Errorf("redis restore requires container restart - use Docker volume restore instead")
+// waitForAOFRewrite polls INFO persistence until no AOF rewrite is running
+func waitForAOFRewrite(ctx context.Context, dockerClient runtime.Client, db *storage.DatabaseInstance, timeout time.Duration) error {
+	deadline := time.Now().Add(timeout)
+	for {
+		output, err := dockerClient.Exec(ctx, db.ContainerID, redisCLI(db, "INFO", "persistence"), nil)
+		if err != nil {
+			return fmt.Errorf("failed to read persistence info: %w", err)
+		}
+		if !strings.Contains(output, "aof_rewrite_in_progress:1") && !strings.Contains(output, "aof_rewrite_scheduled:1") {
+			return nil
+		}
+		if time.Now().After(deadline) {
+			return fmt.Errorf("AOF rewrite did not finish within %s", timeout)
+		}
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(500 * time.Millisecond):
+		}
+	}
+}
+
+// Restore replaces the persisted dataset in the data volume and restarts
+// Redis so it loads it on boot. RDB snapshots and AOF archives are told apart
+// by the RDB magic header. Persistence is disabled first so the server can't
+// overwrite the restored files before it shuts down.
+func (e *RedisEngine) Restore(ctx context.Context, dockerClient runtime.Client, db *storage.DatabaseInstance, backupPath string, opts RestoreOptions) error {
+	f, err := os.Open(backupPath)
+	if err != nil {
+		return fmt.Errorf("failed to open backup file: %w", err)
+	}
+	defer f.Close()
+
+	magic := make([]byte, 5)
+	n, _ := io.ReadFull(f, magic)
+	if _, err := f.Seek(0, io.SeekStart); err != nil {
+		return fmt.Errorf("failed to read backup file: %w", err)
+	}
+	isRDB := string(magic[:n]) == "REDIS"
+
+	// Stage the backup in the data directory so the swap happens in place
+	var stagePath, swapCmd string
+	if isRDB {
+		stagePath = "/data/dump.rdb.restore"
+		swapCmd = "mv /data/dump.rdb.restore /data/dump.rdb"
+		if db.RedisBackupMode == RedisBackupAOF {
+			// With no AOF on disk, Redis rebuilds it from the snapshot on boot
+			swapCmd += " && rm -rf /data/appendonlydir /data/appendonly.aof"
+		}
+	} else {
+		if db.RedisBackupMode != RedisBackupAOF {
+			return fmt.Errorf("AOF backups can only be restored into databases using the %q backup mode", RedisBackupAOF)
+		}
+		stagePath = "/data/aof.restore.tar"
+		swapCmd = "cd /data && rm -rf appendonlydir appendonly.aof && tar -xf aof.restore.tar && rm -f aof.restore.tar"
+	}
+
+	stageCmd := []string{"sh", "-c", "cat > " + stagePath}
+	if output, err := dockerClient.ExecWithStdin(ctx, db.ContainerID, stageCmd, f, nil); err != nil {
+		return fmt.Errorf("failed to copy backup into container: %w, output: %s", err, output)
+	}
+
+	if output, err := dockerClient.Exec(ctx, db.ContainerID, redisCLI(db, "CONFIG", "SET", "save", ""), nil); err != nil {
+		return fmt.Errorf("failed to disable snapshotting: %w, output: %s", err, output)
+	}
+	if db.RedisBackupMode == RedisBackupAOF {
+		if output, err := dockerClient.Exec(ctx, db.ContainerID, redisCLI(db, "CONFIG", "SET", "appendonly", "no"), nil); err != nil {
+			return fmt.Errorf("failed to disable AOF: %w, output: %s", err, output)
+		}
+	}
+	if output, err := dockerClient.Exec(ctx, db.ContainerID, []string{"sh", "-c", swapCmd}, nil); err != nil {
+		return fmt.Errorf("failed to replace data files: %w, output: %s", err, output)
+	}
+
+	// SHUTDOWN drops the connection, so redis-cli reports an error even on success
+	dockerClient.Exec(ctx, db.ContainerID, redisCLI(db, "SHUTDOWN", "NOSAVE"), nil)
+
+	// The restart policy may already have brought it back; stop is a no-op then
+	if err := dockerClient.StopContainer(ctx, db.ContainerID, runtime.DefaultStopTimeout); err != nil {
+		return fmt.Errorf("failed to stop container: %w", err)
+	}
+	if err := dockerClient.StartContainer(ctx, db.ContainerID); err != nil {
+		return fmt.Errorf("failed to restart container: %w", err)
+	}
+
+	return waitForRedis(ctx, dockerClient, db, redisRestoreTimeout)
+}
+
+// redisRestoreTimeout bounds how long a restore waits for Redis to load the snapshot
+const redisRestoreTimeout = 2 * time.Minute
+
+// waitForRedis polls PING until Redis has finished loading its dataset
+func waitForRedis(ctx context.Context, dockerClient runtime.Client, db *storage.DatabaseInstance, timeout time.Duration) error {
+	deadline := time.Now().Add(timeout)
+	for {
+		output, err := dockerClient.Exec(ctx, db.ContainerID, redisCLI(db, "PING"), nil)
+		if err == nil {
+			if !strings.Contains(output, "LOADING") {
+				return nil
+			}
+			err = fmt.Errorf("still loading dataset")
+		}
+		if time.Now().After(deadline) {
+			return fmt.Errorf("redis did not become ready after restore: %v", err)
+		}
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(500 * time.Millisecond):
+		}
+	}
+}
+
+// redisPersistenceInfo returns the fields of INFO persistence, keyed by name
+func redisPersistenceInfo(ctx context.Context, dockerClient runtime.Client, db *storage.DatabaseInstance) (map[string]string, error) {
+	output, err := dockerClient.Exec(ctx, db.ContainerID, redisCLI(db, "INFO", "persistence"), nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read persistence info: %w", err)
+	}
+	info := make(map[string]string)
+	for _, line := range strings.Split(output, "\n") {
+		if k, v, ok := strings.Cut(strings.TrimSpace(line), ":"); ok {
+			info[k] = v
+		}
+	}
+	return info, nil
+}
+
+// ChangeMarker returns the last save time and the number of writes since.
+// Any write bumps the count, and a save that resets it moves the save time.
+func (e *RedisEngine) ChangeMarker(ctx context.Context, client runtime.Client, db *storage.DatabaseInstance) (string, error) {
+	info, err := redisPersistenceInfo(ctx, client, db)
+	if err != nil {
+		return "", err
+	}
+	lastSave, dirty := info["rdb_last_save_time"], info["rdb_changes_since_last_save"]
+	if lastSave == "" || dirty == "" {
+		return "", fmt.Errorf("unexpected INFO persistence reply")
+	}
+	return "save:" + lastSave + ":" + dirty, nil
+}
+
+// redisCLI builds a redis-cli invocation authenticated as the database's user
+func redisCLI(db *storage.DatabaseInstance, args ...string) []string {
+	cmd := []string{"redis-cli"}
+	if db.Password != "" {
+		cmd = append(cmd, "-a", db.Password, "--no-auth-warning")
+	}
+	return append(cmd, args...)
 }
 
 func (e *RedisEngine) ExecuteQuery(ctx context.Context, dockerClient runtime.Client, db *storage.DatabaseInstance, query string) (*QueryResult, error) {
@@ -110,6 +333,7 @@ func (e *RedisEngine) ExecuteQuery(ctx context.Context, dockerClient runtime.Cli
 	if db.Password != "" {
 		cmd = append(cmd, "-a", db.Password)
 	}
+	cmd = append(cmd, redisSelectArgs(db)...)
 	cmd = append(cmd, args...)
 
 	output, err := dockerClient.Exec(ctx, db.ContainerID, cmd, nil)
@@ -180,9 +404,9 @@ func parseRedisCommand(input string) []string {
 func (e *RedisEngine) ConnectionStrings(db *storage.DatabaseInstance) *ConnectionStrings {
 	var uri string
 	if db.Password != "" {
-		uri = fmt.Sprintf("redis://:%s@%s:%d", "<password>", db.Host, db.Port)
+		uri = fmt.Sprintf("redis://:%s@%s:%d/%d", "<password>", db.Host, db.Port, db.RedisDB)
 	} else {
-		uri = fmt.Sprintf("redis://%s:%d", db.Host, db.Port)
+		uri = fmt.Sprintf("redis://%s:%d/%d", db.Host, db.Port, db.RedisDB)
 	}
 
 	return &ConnectionStrings{
@@ -191,41 +415,67 @@ func (e *RedisEngine) ConnectionStrings(db *storage.DatabaseInstance) *Connectio
 r = redis.Redis(
     host='%s',
     port=%d,
+    db=%d,
     password='<password>',
     decode_responses=True
-)`, db.Host, db.Port),
+)`, db.Host, db.Port, db.RedisDB),
 		Node: fmt.Sprintf(`const Redis = require('ioredis');
 const redis = new Redis({
     host: '%s',
     port: %d,
+    db: %d,
     password: '<password>'
-});`, db.Host, db.Port),
+});`, db.Host, db.Port, db.RedisDB),
 		Go: fmt.Sprintf(`import "github.com/redis/go-redis/v9"
 rdb := redis.NewClient(&redis.Options{
     Addr:     "%s:%d",
     Password: "<password>",
-    DB:       0,
-})`, db.Host, db.Port),
+    DB:       %d,
+})`, db.Host, db.Port, db.RedisDB),
 		Java: fmt.Sprintf(`import redis.clients.jedis.Jedis;
 Jedis jedis = new Jedis("%s", %d);
-jedis.auth("<password>");`, db.Host, db.Port),
+jedis.auth("<password>");
+jedis.select(%d);`, db.Host, db.Port, db.RedisDB),
 		Ruby: fmt.Sprintf(`require 'redis'
 redis = Redis.new(
     host: '%s',
     port: %d,
+    db: %d,
     password: '<password>'
-)`, db.Host, db.Port),
+)`, db.Host, db.Port, db.RedisDB),
 		PHP: fmt.Sprintf(`$redis = new Redis();
 $redis->connect('%s', %d);
-$redis->auth('<password>');`, db.Host, db.Port),
+$redis->auth('<password>');
+$redis->select(%d);`, db.Host, db.Port, db.RedisDB),
 	}
 }
 
-func (e *RedisEngine) CLICommand(username, password, database string) []string {
+func (e *RedisEngine) CLICommand(db *storage.DatabaseInstance) []string {
 	cmd := []string{"redis-cli"}
-	if password != "" {
-		cmd = append(cmd, "-a", password)
+	if db.Password != "" {
+		cmd = append(cmd, "-a", db.Password)
 	}
+	cmd = append(cmd, redisSelectArgs(db)...)
 	cmd = append(cmd, "--pipe")
 	return cmd
 }
+
+func (e *RedisEngine) ShellCommand(db *storage.DatabaseInstance) []string {
+	cmd := []string{"redis-cli"}
+	if db.Password != "" {
+		cmd = append(cmd, "-a", db.Password, "--no-auth-warning")
+	}
+	return append(cmd, redisSelectArgs(db)...)
+}
+
+// redisSelectArgs selects the database's logical DB; index 0 is redis-cli's default
+func redisSelectArgs(db *storage.DatabaseInstance) []string {
+	if db.RedisDB == 0 {
+		return nil
+	}
+	return []string{"-n", strconv.Itoa(db.RedisDB)}
+}
+
+func (e *RedisEngine) ExportCommand(db *storage.DatabaseInstance) ([]string, []string) {
+	return nil, nil // Redis has no SQL representation
+}
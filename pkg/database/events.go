@@ -0,0 +1,139 @@
+package database
+
+import (
+	"sync"
+	"time"
+)
+
+// Provisioning stages, published in order as a new database is brought up.
+// StageQueued is only published when the database waits for a provisioning
+// slot. StageReady and StageError are terminal.
+const (
+	StageQueued   = "queued"
+	StagePulling  = "pulling image"
+	StageCreating = "creating container"
+	StageStarting = "starting"
+	StageSeeding  = "seeding"
+	StageReady    = "ready"
+	StageError    = "error"
+)
+
+// provisionHistoryRetention is how long a finished provisioning's events are
+// kept for clients that subscribe late
+const provisionHistoryRetention = time.Minute
+
+// ProvisionEvent is a provisioning milestone for a database
+type ProvisionEvent struct {
+	Stage   string    `json:"stage"`
+	Message string    `json:"message,omitempty"`
+	Time    time.Time `json:"time"`
+}
+
+// Terminal reports whether no further events follow this one
+func (e ProvisionEvent) Terminal() bool {
+	return e.Stage == StageReady || e.Stage == StageError
+}
+
+// provisionStream holds the events published so far for one database and
+// the channels of its current subscribers
+type provisionStream struct {
+	history     []ProvisionEvent
+	subscribers map[chan ProvisionEvent]struct{}
+	done        bool
+}
+
+// provisionHub is an in-memory pub/sub of provisioning events keyed by database ID
+type provisionHub struct {
+	mu      sync.Mutex
+	streams map[string]*provisionStream
+}
+
+func newProvisionHub() *provisionHub {
+	return &provisionHub{streams: make(map[string]*provisionStream)}
+}
+
+// publish records an event and fans it out to subscribers. A terminal event
+// closes all subscriptions; its history is dropped after a retention period.
+func (h *provisionHub) publish(id, stage, message string) {
+	event := ProvisionEvent{Stage: stage, Message: message, Time: time.Now()}
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	stream, ok := h.streams[id]
+	if !ok || stream.done {
+		// A new provisioning run (e.g. after a repair) starts a fresh history
+		stream = &provisionStream{subscribers: make(map[chan ProvisionEvent]struct{})}
+		h.streams[id] = stream
+	}
+	stream.history = append(stream.history, event)
+
+	for ch := range stream.subscribers {
+		select {
+		case ch <- event:
+		default:
+			// Slow subscriber; it can catch up from history on reconnect. The
+			// terminal event still has to arrive, so it replaces the oldest
+			// buffered one. Only publish sends, under h.mu, so there is room.
+			if event.Terminal() {
+				select {
+				case <-ch:
+				default:
+				}
+				ch <- event
+			}
+		}
+		if event.Terminal() {
+			close(ch)
+			delete(stream.subscribers, ch)
+		}
+	}
+
+	if event.Terminal() {
+		stream.done = true
+		time.AfterFunc(provisionHistoryRetention, func() {
+			h.mu.Lock()
+			defer h.mu.Unlock()
+			if h.streams[id] == stream {
+				delete(h.streams, id)
+			}
+		})
+	}
+}
+
+// subscribe returns the events published so far and, if provisioning is still
+// in progress, a channel of further events that is closed after the terminal
+// one. ok is false when there is no provisioning history for the database.
+func (h *provisionHub) subscribe(id string) (history []ProvisionEvent, events <-chan ProvisionEvent, cancel func(), ok bool) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	stream, ok := h.streams[id]
+	if !ok {
+		return nil, nil, func() {}, false
+	}
+	history = append([]ProvisionEvent(nil), stream.history...)
+	if stream.done {
+		return history, nil, func() {}, true
+	}
+
+	ch := make(chan ProvisionEvent, 16)
+	stream.subscribers[ch] = struct{}{}
+	cancel = func() {
+		h.mu.Lock()
+		defer h.mu.Unlock()
+		if _, ok := stream.subscribers[ch]; ok {
+			delete(stream.subscribers, ch)
+			close(ch)
+		}
+	}
+	return history, ch, cancel, true
+}
+
+// SubscribeProvisioning returns a database's provisioning events so far and a
+// channel of further events, closed once provisioning finishes. The channel is
+// nil if provisioning has already finished. Call cancel when done listening.
+// ok is false when no provisioning has been observed for the database.
+func (m *Manager) SubscribeProvisioning(id string) (history []ProvisionEvent, events <-chan ProvisionEvent, cancel func(), ok bool) {
+	return m.provisioning.subscribe(id)
+}
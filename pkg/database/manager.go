@@ -2,16 +2,22 @@ package database
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"io"
 	"net"
 	"os"
 	"path/filepath"
 	"regexp"
+	"slices"
+	"sort"
+	"strings"
 	"sync"
 	"time"
 
 	"github.com/google/uuid"
 	"github.com/rs/zerolog/log"
+	"github.com/sirrobot01/dbnest/pkg/notify"
 	"github.com/sirrobot01/dbnest/pkg/runtime"
 	"github.com/sirrobot01/dbnest/pkg/storage"
 )
@@ -21,14 +27,43 @@ type CreateRequest struct {
 	Name         string `json:"name"`
 	Engine       string `json:"engine"`
 	Version      string `json:"version"`
+	Image        string `json:"image,omitempty"`        // Optional custom image overriding the engine default
+	HostDataPath string `json:"hostDataPath,omitempty"` // Optional host directory to bind-mount as the data directory
 	Username     string `json:"username"`
 	Password     string `json:"password"` // Optional, auto-generated if empty
 	Database     string `json:"database"`
 	Port         int    `json:"port,omitempty"`
-	StorageLimit int64  `json:"storageLimit"`         // MB
-	MemoryLimit  int64  `json:"memoryLimit"`          // MB
-	Network      string `json:"network,omitempty"`    // Docker network name
-	ExposePort   *bool  `json:"exposePort,omitempty"` // Whether to expose port to host (default: true)
+	StorageLimit int64  `json:"storageLimit"`          // MB
+	MemoryLimit  int64  `json:"memoryLimit"`           // MB
+	Network      string `json:"network,omitempty"`     // Docker network name
+	ExposePort   *bool  `json:"exposePort,omitempty"`  // Whether to expose port to host (default: true)
+	BindAddress  string `json:"bindAddress,omitempty"` // Host IP to publish the port on (default: configured bind address)
+	// Server connection limit for PostgreSQL, MySQL and MariaDB (default: 100)
+	MaxConnections int `json:"maxConnections,omitempty"`
+
+	// Extra container env vars; engine-managed keys take precedence
+	ExtraEnv map[string]string `json:"extraEnv,omitempty"`
+	// Engine server settings, e.g. {"shared_buffers": "256MB"} or {"maxmemory-policy": "allkeys-lru"}
+	ConfigParams map[string]string `json:"configParams,omitempty"`
+	// Container restart policy, e.g. "no" or "on-failure:5"; defaults to the configured policy
+	RestartPolicy string `json:"restartPolicy,omitempty"`
+	// Numeric user and group the database runs as; defaults to the configured IDs, else the image's user
+	RunAsUser  int `json:"runAsUser,omitempty"`
+	RunAsGroup int `json:"runAsGroup,omitempty"`
+	// User namespace mode, e.g. "host" or podman's "auto"; defaults to the configured mode
+	UsernsMode string `json:"usernsMode,omitempty"`
+	// Run with a read-only root filesystem; defaults to the configured setting
+	ReadOnlyRootfs *bool `json:"readOnlyRootfs,omitempty"`
+	// Redis only: "rdb" (default) or "aof"
+	RedisBackupMode string `json:"redisBackupMode,omitempty"`
+	// Redis only: logical database index (0-15)
+	RedisDB int `json:"redisDb,omitempty"`
+	// Extra container labels, e.g. for Traefik or Prometheus; "dbnest." keys are reserved
+	Labels map[string]string `json:"labels,omitempty"`
+	// Pin the image to a registry digest ("sha256:...") instead of a mutable tag
+	ImageDigest string `json:"imageDigest,omitempty"`
+	// Set by Clone to record the source database; not accepted from clients
+	ClonedFrom string `json:"-"`
 
 	// Restore from backup
 	RestoreFromBackupID string `json:"restoreFromBackupId,omitempty"` // Optional backup to restore from
@@ -38,12 +73,28 @@ type CreateRequest struct {
 	SeedContent string `json:"seedContent,omitempty"` // URL or raw SQL content
 }
 
+// Bounds and default for a database's server connection limit
+const (
+	defaultMaxConnections = 100
+	minMaxConnections     = 1
+	maxMaxConnections     = 10000
+)
+
 // Manager handles database operations
 type Manager struct {
-	store          storage.Storage
-	client         runtime.Client // Interface type, not concrete
-	portLock       sync.Mutex     // Protects port allocation
-	metricsHistory *MetricsHistory
+	store           storage.Storage
+	client          runtime.Client // Interface type, not concrete
+	portLock        sync.Mutex     // Protects port allocation
+	reservedPorts   map[int]string // Ports of databases not yet started, by ID; guarded by portLock
+	metricsThrottle *metricsThrottle
+	notifier        *notify.Dispatcher
+	provisioning    *provisionHub
+	provisionMu     sync.Mutex
+	provisionRuns   map[string]*provisionRun // In-flight provisioning, by database ID; guarded by provisionMu
+	provisionQueue  provisionQueue
+	freeDiskSpace   func(path string) (int64, error) // checked before backups; replaced in tests
+	optsMu          sync.RWMutex
+	opts            Options
 }
 
 // validNameRegex matches alphanumeric names with underscores/hyphens
@@ -60,21 +111,303 @@ func sanitizeName(name string) (string, error) {
 	return name, nil
 }
 
+// imageRefRegex matches image references like "postgres", "timescale/timescaledb:latest-pg16",
+// "registry.example.com:5000/team/postgres:16" or "postgres@sha256:<digest>"
+var imageRefRegex = regexp.MustCompile(`^[a-z0-9]+(?:[._-][a-z0-9]+)*(?::[0-9]+)?(?:/[a-z0-9]+(?:(?:[._]|__|-+)[a-z0-9]+)*)*(?::[A-Za-z0-9_][A-Za-z0-9_.-]{0,127})?(?:@sha256:[a-f0-9]{64})?$`)
+
+// ValidateImage checks that a custom image is a plausible image reference
+func ValidateImage(ref string) error {
+	if len(ref) > 255 || !imageRefRegex.MatchString(ref) {
+		return fmt.Errorf("invalid image reference: %s", ref)
+	}
+	return nil
+}
+
+// imageDigestRegex matches a registry content digest
+var imageDigestRegex = regexp.MustCompile(`^sha256:[a-f0-9]{64}$`)
+
+// ValidateImageDigest checks that digest is a sha256 content digest and that
+// image (if set) doesn't already pin a different one
+func ValidateImageDigest(image, digest string) error {
+	if !imageDigestRegex.MatchString(digest) {
+		return fmt.Errorf("invalid image digest %q: expected sha256:<64 hex characters>", digest)
+	}
+	if strings.Contains(image, "@") {
+		return fmt.Errorf("imageDigest cannot be combined with an image that already includes a digest")
+	}
+	return nil
+}
+
+// ValidateVersion checks that version is one the engine supports. Empty and
+// "latest" are always accepted.
+func ValidateVersion(engine Engine, version string) error {
+	if version == "" || version == "latest" || slices.Contains(engine.Versions(), version) {
+		return nil
+	}
+	return fmt.Errorf("unsupported %s version %q (valid: %s, latest)", engine.Type(), version, strings.Join(engine.Versions(), ", "))
+}
+
+// ValidateHostDataPath checks that a bind-mount data path is an absolute path
+// to an existing directory inside the configured HostDataRoot, and returns it
+// with symlinks resolved
+func (m *Manager) ValidateHostDataPath(path string) (string, error) {
+	root := m.options().HostDataRoot
+	if root == "" {
+		return "", fmt.Errorf("host data paths are disabled; set --host-data-root to allow them")
+	}
+	if !filepath.IsAbs(path) {
+		return "", fmt.Errorf("host data path must be absolute: %s", path)
+	}
+	resolved, err := filepath.EvalSymlinks(path)
+	if err != nil {
+		return "", fmt.Errorf("host data path is not accessible: %w", err)
+	}
+	info, err := os.Stat(resolved)
+	if err != nil {
+		return "", fmt.Errorf("host data path is not accessible: %w", err)
+	}
+	if !info.IsDir() {
+		return "", fmt.Errorf("host data path is not a directory: %s", path)
+	}
+	resolvedRoot, err := filepath.EvalSymlinks(root)
+	if err != nil {
+		return "", fmt.Errorf("host data root is not accessible: %w", err)
+	}
+	if resolved == resolvedRoot || !pathWithin(resolvedRoot, resolved) {
+		return "", fmt.Errorf("host data path must be a directory inside %s", root)
+	}
+	return resolved, nil
+}
+
+// pathWithin reports whether the clean absolute path is dir or inside it
+func pathWithin(dir, path string) bool {
+	rel, err := filepath.Rel(dir, path)
+	return err == nil && rel != ".." && !strings.HasPrefix(rel, ".."+string(filepath.Separator))
+}
+
+// hostDataPathOwnerLocked returns the name of a database whose host data path
+// is path, or contains it or is inside it. Must be called with portLock held
+// so concurrent creates see each other.
+func (m *Manager) hostDataPathOwnerLocked(path string) string {
+	for _, db := range m.store.ListDatabases() {
+		if db.HostDataPath == "" {
+			continue
+		}
+		existing, err := filepath.EvalSymlinks(db.HostDataPath)
+		if err != nil {
+			existing = filepath.Clean(db.HostDataPath)
+		}
+		if pathWithin(existing, path) || pathWithin(path, existing) {
+			return db.Name
+		}
+	}
+	return ""
+}
+
+// envNameRegex matches POSIX environment variable names
+var envNameRegex = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*$`)
+
+// ValidateExtraEnv checks that extra env var names are valid
+func ValidateExtraEnv(env map[string]string) error {
+	for k := range env {
+		if !envNameRegex.MatchString(k) {
+			return fmt.Errorf("invalid environment variable name: %q", k)
+		}
+	}
+	return nil
+}
+
+// ValidateBindAddress checks that addr is an IPv4 or IPv6 address a port
+// can be published on
+func ValidateBindAddress(addr string) error {
+	if net.ParseIP(addr) == nil {
+		return fmt.Errorf("invalid bind address %q: expected an IPv4 or IPv6 address", addr)
+	}
+	return nil
+}
+
+// usernsModeRegex matches user namespace modes such as "host", "auto",
+// "keep-id" or "auto:size=65536"
+var usernsModeRegex = regexp.MustCompile(`^[a-z][a-z-]*(:[A-Za-z0-9=,._-]+)?$`)
+
+// ValidateRunAs checks the user, group and user namespace mode a database
+// container runs with. A zero user keeps the image's default user.
+func ValidateRunAs(uid, gid int, usernsMode string) error {
+	if uid < 0 || gid < 0 {
+		return fmt.Errorf("invalid run-as user %d:%d: IDs must not be negative", uid, gid)
+	}
+	if gid != 0 && uid == 0 {
+		return errors.New("a run-as group requires a run-as user")
+	}
+	if usernsMode != "" && !usernsModeRegex.MatchString(usernsMode) {
+		return fmt.Errorf("invalid user namespace mode %q", usernsMode)
+	}
+	return nil
+}
+
+// configParamRegex matches engine setting names such as "work_mem",
+// "pg_stat_statements.track" or "maxmemory-policy"
+var configParamRegex = regexp.MustCompile(`^[a-z][a-z0-9_.-]*$`)
+
+// managedConfigParams are settings DBnest sets itself, per engine
+var managedConfigParams = map[string][]string{
+	"postgresql": {"max_connections"},
+	"mysql":      {"max_connections"},
+	"mariadb":    {"max_connections"},
+	"redis":      {"requirepass", "appendonly"},
+	"clickhouse": {"max_connections"},
+}
+
+// ValidateConfigParams checks that server settings are well-formed and don't
+// override ones DBnest manages
+func ValidateConfigParams(engine string, params map[string]string) error {
+	for k, v := range params {
+		if !configParamRegex.MatchString(k) {
+			return fmt.Errorf("invalid config parameter name: %q", k)
+		}
+		if v == "" || strings.ContainsAny(v, "\x00\n\r") {
+			return fmt.Errorf("invalid value for config parameter %s", k)
+		}
+		// MySQL treats dashes and underscores in option names alike
+		name := k
+		if engine == "mysql" || engine == "mariadb" {
+			name = strings.ReplaceAll(k, "-", "_")
+		}
+		if slices.Contains(managedConfigParams[engine], name) {
+			if name == "max_connections" {
+				return fmt.Errorf("set max_connections with maxConnections, not configParams")
+			}
+			return fmt.Errorf("config parameter %s is managed by DBnest", k)
+		}
+	}
+	return nil
+}
+
+// configParamKeys returns the parameter names in a stable order so container
+// commands don't change between recreations
+func configParamKeys(params map[string]string) []string {
+	keys := make([]string, 0, len(params))
+	for k := range params {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// labelKeyRegex matches container label keys such as "traefik.http.routers.api.rule"
+var labelKeyRegex = regexp.MustCompile(`^[A-Za-z0-9][A-Za-z0-9._/-]*$`)
+
+// reservedLabelPrefix marks labels DBnest sets itself to track its containers
+const reservedLabelPrefix = "dbnest."
+
+// ValidateLabels checks that user labels are well-formed and don't use the reserved prefix
+func ValidateLabels(labels map[string]string) error {
+	for k := range labels {
+		if !labelKeyRegex.MatchString(k) {
+			return fmt.Errorf("invalid label key: %q", k)
+		}
+		if strings.HasPrefix(k, reservedLabelPrefix) {
+			return fmt.Errorf("label %q uses the reserved %q prefix", k, reservedLabelPrefix)
+		}
+	}
+	return nil
+}
+
+// mergeEnv appends extra variables to the engine's env, skipping any key the
+// engine already sets so credentials cannot be overridden
+func mergeEnv(engineEnv []string, extra map[string]string) []string {
+	if len(extra) == 0 {
+		return engineEnv
+	}
+	managed := make(map[string]bool, len(engineEnv))
+	for _, kv := range engineEnv {
+		k, _, _ := strings.Cut(kv, "=")
+		managed[k] = true
+	}
+
+	keys := make([]string, 0, len(extra))
+	for k := range extra {
+		if !managed[k] {
+			keys = append(keys, k)
+		}
+	}
+	sort.Strings(keys)
+
+	env := append([]string{}, engineEnv...)
+	for _, k := range keys {
+		env = append(env, k+"="+extra[k])
+	}
+	return env
+}
+
+// dataVolume returns the mount source for a database's data directory: its
+// bind-mounted host path if set, otherwise its named volume
+func dataVolume(db *storage.DatabaseInstance) string {
+	if db.HostDataPath != "" {
+		return db.HostDataPath
+	}
+	return fmt.Sprintf("dbnest-vol-%s", db.ID)
+}
+
+// resolveImage returns the image to run for a database. A custom image overrides
+// the engine default; version is applied as the tag unless the image already has
+// one. A digest replaces any tag, pinning the exact image.
+func resolveImage(engine Engine, image, version, digest string) string {
+	custom := image != ""
+	if !custom {
+		image = engine.Image()
+	}
+	if digest != "" {
+		if i := strings.LastIndex(image, ":"); i > strings.LastIndex(image, "/") {
+			image = image[:i]
+		}
+		return image + "@" + digest
+	}
+	if custom && (strings.Contains(image, "@") || strings.Contains(image[strings.LastIndex(image, "/")+1:], ":")) {
+		return image
+	}
+	if version != "" {
+		return fmt.Sprintf("%s:%s", image, version)
+	}
+	return image
+}
+
 // NewManager creates a new database manager
 func NewManager(store storage.Storage, dockerClient runtime.Client) *Manager {
 	return &Manager{
-		store:          store,
-		client:         dockerClient,
-		metricsHistory: NewMetricsHistory(),
+		store:           store,
+		client:          dockerClient,
+		metricsThrottle: newMetricsThrottle(),
+		notifier:        notify.NewDispatcher(store),
+		provisioning:    newProvisionHub(),
+		provisionRuns:   make(map[string]*provisionRun),
+		freeDiskSpace:   freeDiskSpace,
+		reservedPorts:   make(map[int]string),
+		opts:            DefaultOptions(),
 	}
 }
 
+// Notifier returns the dispatcher used for status change notifications
+func (m *Manager) Notifier() *notify.Dispatcher {
+	return m.notifier
+}
+
+// ErrPortUnavailable is returned when a requested host port is already taken
+var ErrPortUnavailable = errors.New("port unavailable")
+
 // findAvailablePortLocked finds an available port starting from the given port
 // Must be called with portLock held
 func (m *Manager) findAvailablePortLocked(startPort int) int {
 	usedPorts := make(map[int]bool)
 	for _, db := range m.store.ListDatabases() {
-		usedPorts[db.Port] = true
+		if db.ExposePort {
+			usedPorts[db.Port] = true
+		}
+	}
+	// A port is free on the host until its container binds it, so in-flight
+	// creates are excluded even if their record isn't visible in the store yet
+	for port := range m.reservedPorts {
+		usedPorts[port] = true
 	}
 
 	port := startPort
@@ -99,6 +432,30 @@ func (m *Manager) findAvailablePortLocked(startPort int) int {
 	return port // Return anyway, container will fail with clear error
 }
 
+// portOwnerLocked returns the name or ID of the database using or reserving
+// port, or "" if none does. Must be called with portLock held.
+func (m *Manager) portOwnerLocked(port int) string {
+	if id, ok := m.reservedPorts[port]; ok {
+		return id
+	}
+	for _, db := range m.store.ListDatabases() {
+		if db.ExposePort && db.Port == port {
+			return db.Name
+		}
+	}
+	return ""
+}
+
+// releasePort drops a database's port reservation once its container has
+// bound the port or provisioning has failed
+func (m *Manager) releasePort(port int, id string) {
+	m.portLock.Lock()
+	defer m.portLock.Unlock()
+	if m.reservedPorts[port] == id {
+		delete(m.reservedPorts, port)
+	}
+}
+
 // isPortAvailable checks if a port is available on the host
 func isPortAvailable(port int) bool {
 	ln, err := net.Listen("tcp", fmt.Sprintf(":%d", port))
@@ -119,51 +476,160 @@ func (m *Manager) Create(ctx context.Context, req *CreateRequest) (*storage.Data
 	return m.createDedicatedDatabase(ctx, req)
 }
 
-// createDedicatedDatabase creates a database with its own container
-// Returns immediately with status "creating", actual provisioning happens in background
-func (m *Manager) createDedicatedDatabase(ctx context.Context, req *CreateRequest) (*storage.DatabaseInstance, error) {
+// prepareCreate validates a create request and fills unset limits from the
+// configured defaults, returning the request's engine
+func (m *Manager) prepareCreate(req *CreateRequest) (Engine, error) {
 	// Get engine from registry
 	engine, err := GetEngine(req.Engine)
 	if err != nil {
 		return nil, fmt.Errorf("unsupported engine: %s", req.Engine)
 	}
 
-	// Generate ID
-	id := "db-" + uuid.New().String()[:8]
+	if req.Image != "" {
+		if err := ValidateImage(req.Image); err != nil {
+			return nil, err
+		}
+	}
 
-	// Lock port allocation - keep lock until DB is saved to prevent race condition
-	m.portLock.Lock()
-	port := req.Port
-	if port == 0 {
-		port = m.findAvailablePortLocked(engine.DefaultPort())
+	if req.ImageDigest != "" {
+		if err := ValidateImageDigest(req.Image, req.ImageDigest); err != nil {
+			return nil, err
+		}
 	}
 
-	// Create data directory with ABSOLUTE PATH
-	baseDataDir, err := filepath.Abs(m.store.DataDir())
-	if err != nil {
-		m.portLock.Unlock()
-		return nil, fmt.Errorf("failed to resolve data directory: %w", err)
+	if err := ValidateExtraEnv(req.ExtraEnv); err != nil {
+		return nil, err
 	}
-	dataDir := filepath.Join(baseDataDir, "databases", id)
-	if err := os.MkdirAll(dataDir, 0755); err != nil {
-		m.portLock.Unlock()
-		return nil, fmt.Errorf("failed to create data directory: %w", err)
+
+	if err := ValidateLabels(req.Labels); err != nil {
+		return nil, err
 	}
 
-	// Build image name with version
-	imageName := engine.Image()
-	if req.Version != "" {
-		imageName = fmt.Sprintf("%s:%s", engine.Image(), req.Version)
+	if err := ValidateConfigParams(engine.Type(), req.ConfigParams); err != nil {
+		return nil, err
+	}
+
+	if req.BindAddress != "" {
+		if err := ValidateBindAddress(req.BindAddress); err != nil {
+			return nil, err
+		}
+	}
+	if req.MaxConnections == 0 {
+		req.MaxConnections = defaultMaxConnections
+	}
+	if req.MaxConnections < minMaxConnections || req.MaxConnections > maxMaxConnections {
+		return nil, fmt.Errorf("maxConnections must be between %d and %d", minMaxConnections, maxMaxConnections)
+	}
+	if !req.exposed() && req.Port != 0 {
+		return nil, fmt.Errorf("port cannot be set when exposePort is false")
+	}
+
+	if _, _, err := runtime.ParseRestartPolicy(req.RestartPolicy); err != nil {
+		return nil, err
+	}
+	if err := ValidateRunAs(req.RunAsUser, req.RunAsGroup, req.UsernsMode); err != nil {
+		return nil, err
+	}
+
+	if req.RedisBackupMode != "" {
+		if engine.Type() != "redis" {
+			return nil, fmt.Errorf("redisBackupMode is only supported for Redis")
+		}
+		if req.RedisBackupMode != RedisBackupRDB && req.RedisBackupMode != RedisBackupAOF {
+			return nil, fmt.Errorf("redisBackupMode must be %q or %q", RedisBackupRDB, RedisBackupAOF)
+		}
+	}
+	if req.RedisDB != 0 {
+		if engine.Type() != "redis" {
+			return nil, fmt.Errorf("redisDb is only supported for Redis")
+		}
+		if req.RedisDB < 0 || req.RedisDB >= redisDatabases {
+			return nil, fmt.Errorf("redisDb must be between 0 and %d", redisDatabases-1)
+		}
+	}
+
+	if req.HostDataPath != "" {
+		// Overlap with other databases' paths is checked under portLock
+		resolved, err := m.ValidateHostDataPath(req.HostDataPath)
+		if err != nil {
+			return nil, err
+		}
+		req.HostDataPath = resolved
+	}
+
+	// Fill unset limits from the configured defaults
+	opts := m.options()
+	if req.MemoryLimit == 0 {
+		req.MemoryLimit = opts.DefaultMemoryLimit
+	}
+	if req.StorageLimit == 0 {
+		req.StorageLimit = opts.DefaultStorageLimit
+	}
+	if req.RestartPolicy == "" {
+		req.RestartPolicy = opts.DefaultRestartPolicy
+	}
+	if req.BindAddress == "" {
+		req.BindAddress = opts.DefaultBindAddress
+	}
+	if req.RunAsUser == 0 && req.RunAsGroup == 0 {
+		req.RunAsUser, req.RunAsGroup = opts.DefaultRunAsUser, opts.DefaultRunAsGroup
+	}
+	if req.UsernsMode == "" {
+		req.UsernsMode = opts.DefaultUsernsMode
+	}
+	if req.ReadOnlyRootfs == nil {
+		req.ReadOnlyRootfs = &opts.DefaultReadOnlyRootfs
 	}
 
-	// Create database record with "creating" status
-	db := &storage.DatabaseInstance{
+	return engine, nil
+}
+
+// exposed reports whether the database's port is published on the host.
+// Defaults to true if not specified.
+func (r *CreateRequest) exposed() bool {
+	return r.ExposePort == nil || *r.ExposePort
+}
+
+// containerName returns the name of a database's container, which is also
+// its hostname on the container network
+func containerName(id string) string {
+	return fmt.Sprintf("dbnest-%s", id)
+}
+
+// connectionHost returns the host clients connect to for a database published
+// on bindAddress: the address itself if it's a specific non-loopback one,
+// otherwise localhost
+func connectionHost(bindAddress string) string {
+	ip := net.ParseIP(bindAddress)
+	if ip == nil || ip.IsLoopback() || ip.IsUnspecified() {
+		return "localhost"
+	}
+	return bindAddress
+}
+
+// newInstance builds the stored record for a new database in "creating" status
+func (m *Manager) newInstance(id string, req *CreateRequest, port int) *storage.DatabaseInstance {
+	host := connectionHost(req.BindAddress)
+	if !req.exposed() {
+		// Only reachable from the container network, by container name
+		host = containerName(id)
+	}
+	return &storage.DatabaseInstance{
 		ID:             id,
 		Name:           req.Name,
 		Engine:         req.Engine,
 		Version:        req.Version,
+		Image:          req.Image,
+		HostDataPath:   req.HostDataPath,
+		ExtraEnv:       req.ExtraEnv,
+		ConfigParams:   req.ConfigParams,
+		RestartPolicy:  req.RestartPolicy,
+		RunAsUser:      req.RunAsUser,
+		RunAsGroup:     req.RunAsGroup,
+		UsernsMode:     req.UsernsMode,
+		ReadOnlyRootfs: *req.ReadOnlyRootfs,
 		Status:         "creating",
-		Host:           "localhost",
+		Host:           host,
 		Port:           port,
 		Username:       req.Username,
 		Password:       req.Password,
@@ -172,32 +638,223 @@ func (m *Manager) createDedicatedDatabase(ctx context.Context, req *CreateReques
 		StorageUsed:    0,
 		StorageLimit:   req.StorageLimit * 1024 * 1024, // Convert MB to bytes
 		MemoryLimit:    req.MemoryLimit * 1024 * 1024,
-		CPULimit:       1.0,
+		CPULimit:       m.options().DefaultCPULimit,
 		Connections:    0,
-		MaxConnections: 100,
-		ExposePort:     req.ExposePort == nil || *req.ExposePort, // Default to true if not specified
+		MaxConnections: req.MaxConnections,
+		ExposePort:     req.exposed(),
 		Network:        req.Network,
+		BindAddress:    req.BindAddress,
+
+		RedisBackupMode: req.RedisBackupMode,
+		RedisDB:         req.RedisDB,
+		ClonedFrom:      req.ClonedFrom,
+		Labels:          req.Labels,
+		ImageDigest:     req.ImageDigest,
+	}
+}
+
+// Plan validates a create request and returns the container configuration
+// that Create would use, without saving anything or calling the runtime.
+// The port is a preview; it is not reserved.
+func (m *Manager) Plan(req *CreateRequest) (*runtime.ContainerConfig, error) {
+	if req.Password == "" {
+		req.Password = uuid.New().String()[:16]
+	}
+
+	engine, err := m.prepareCreate(req)
+	if err != nil {
+		return nil, err
+	}
+
+	m.portLock.Lock()
+	if err := m.checkMemoryCapacity(req.MemoryLimit); err != nil {
+		m.portLock.Unlock()
+		return nil, err
+	}
+	if req.HostDataPath != "" {
+		if owner := m.hostDataPathOwnerLocked(req.HostDataPath); owner != "" {
+			m.portLock.Unlock()
+			return nil, fmt.Errorf("host data path overlaps the one used by database %s", owner)
+		}
+	}
+	port := req.Port
+	if !req.exposed() {
+		port = engine.DefaultPort() // the container port; nothing is bound on the host
+	} else if port == 0 {
+		port = m.findAvailablePortLocked(engine.DefaultPort())
+	}
+	m.portLock.Unlock()
+
+	db := m.newInstance("db-"+uuid.New().String()[:8], req, port)
+	cfg := containerConfig(db, engine, resolveImage(engine, req.Image, req.Version, req.ImageDigest))
+	cfg.Security = m.options().ContainerSecurity
+	return cfg, nil
+}
+
+// createDedicatedDatabase creates a database with its own container
+// Returns immediately with status "creating", actual provisioning happens in background
+func (m *Manager) createDedicatedDatabase(ctx context.Context, req *CreateRequest) (*storage.DatabaseInstance, error) {
+	engine, err := m.prepareCreate(req)
+	if err != nil {
+		return nil, err
+	}
+
+	// Generate ID
+	id := "db-" + uuid.New().String()[:8]
+
+	// Lock port allocation - keep lock until DB is saved to prevent race condition
+	m.portLock.Lock()
+
+	// Checked under the lock so concurrent creates see each other's memory limits
+	if err := m.checkMemoryCapacity(req.MemoryLimit); err != nil {
+		m.portLock.Unlock()
+		return nil, err
+	}
+	if req.HostDataPath != "" {
+		if owner := m.hostDataPathOwnerLocked(req.HostDataPath); owner != "" {
+			m.portLock.Unlock()
+			return nil, fmt.Errorf("host data path overlaps the one used by database %s", owner)
+		}
+	}
+
+	port := req.Port
+	if !req.exposed() {
+		port = engine.DefaultPort() // the container port; nothing is bound on the host
+	} else if port == 0 {
+		port = m.findAvailablePortLocked(engine.DefaultPort())
+	} else if owner := m.portOwnerLocked(port); owner != "" {
+		m.portLock.Unlock()
+		return nil, fmt.Errorf("%w: port %d is already used by database %s", ErrPortUnavailable, port, owner)
+	}
+
+	// Create data directory with ABSOLUTE PATH
+	baseDataDir, err := filepath.Abs(m.store.DataDir())
+	if err != nil {
+		m.portLock.Unlock()
+		return nil, fmt.Errorf("failed to resolve data directory: %w", err)
+	}
+	dataDir := filepath.Join(baseDataDir, "databases", id)
+	if err := os.MkdirAll(dataDir, 0755); err != nil {
+		m.portLock.Unlock()
+		return nil, fmt.Errorf("failed to create data directory: %w", err)
+	}
+
+	// Build image name with version
+	imageName := resolveImage(engine, req.Image, req.Version, req.ImageDigest)
+
+	// Create database record with "creating" status, or "queued" if as many
+	// databases as allowed are already being provisioned
+	db := m.newInstance(id, req, port)
+	admitted := m.provisionQueue.tryAcquire(m.options().ProvisionConcurrency)
+	if !admitted {
+		db.Status = "queued"
 	}
 
 	// Save to storage IMMEDIATELY (while still holding port lock)
 	if err := m.store.CreateDatabase(db); err != nil {
+		if admitted {
+			m.provisionQueue.release(m.options().ProvisionConcurrency)
+		}
 		m.portLock.Unlock()
 		return nil, fmt.Errorf("failed to save database: %w", err)
 	}
+	// Held until the container is started so the port can't be handed out again
+	if db.ExposePort {
+		m.reservedPorts[port] = id
+	}
 	m.portLock.Unlock() // Now safe to release lock
 
-	// Process container creation in background
-	go m.provisionDedicatedDatabase(db, imageName, dataDir, port, engine, req.SeedSource, req.SeedContent)
-
-	// Return immediately with "creating" status
+	// Process container creation in background. The first milestone is published
+	// here so clients subscribing right after this returns see the run.
+	if admitted {
+		m.provisioning.publish(db.ID, StagePulling, imageName)
+	} else {
+		m.provisioning.publish(db.ID, StageQueued, "")
+	}
+	// The request's logger is carried over so provisioning logs keep its
+	// request ID, but not its cancellation: only Delete stops provisioning.
+	provisionCtx, done := m.startProvisioning(log.Ctx(ctx).WithContext(context.Background()), db.ID)
+	go func() {
+		defer done()
+		m.provisionDedicatedDatabase(provisionCtx, db, imageName, dataDir, port, engine, req.SeedSource, req.SeedContent)
+	}()
+
+	// Return immediately with "creating" or "queued" status
 	return db, nil
 }
 
-// provisionDedicatedDatabase runs in background to pull image and create/start container
-func (m *Manager) provisionDedicatedDatabase(db *storage.DatabaseInstance, imageName, dataDir string, port int, engine Engine, seedSource, seedContent string) {
-	ctx := context.Background()
+// containerConfig builds the runtime container configuration for a database
+func containerConfig(db *storage.DatabaseInstance, engine Engine, imageName string) *runtime.ContainerConfig {
+	cfg := &runtime.ContainerConfig{
+		Name:  containerName(db.ID),
+		Image: imageName,
+		Cmd:   engine.ContainerCmd(db),
+		Env:   mergeEnv(engine.EnvVars(db.Username, db.Password, db.Database), db.ExtraEnv),
+		PortBindings: map[string]string{
+			fmt.Sprintf("%d/tcp", engine.DefaultPort()): fmt.Sprintf("%d", db.Port),
+		},
+		Volumes: map[string]string{
+			dataVolume(db): engine.DataPath(),
+		},
+		MemoryLimit:    db.MemoryLimit,
+		CPULimit:       db.CPULimit,
+		Labels:         containerLabels(db),
+		ExposePort:     db.ExposePort,
+		BindAddress:    db.BindAddress,
+		Network:        db.Network,
+		RestartPolicy:  db.RestartPolicy,
+		RunAsUser:      db.RunAsUser,
+		RunAsGroup:     db.RunAsGroup,
+		UsernsMode:     db.UsernsMode,
+		ReadOnlyRootfs: db.ReadOnlyRootfs,
+	}
+	if db.ReadOnlyRootfs {
+		cfg.Tmpfs = engine.TmpfsPaths()
+	}
+	if hc, ok := engine.(HealthcheckEngine); ok {
+		cfg.Healthcheck = &runtime.Healthcheck{
+			Cmd:         hc.HealthcheckCmd(db),
+			Interval:    10 * time.Second,
+			Timeout:     5 * time.Second,
+			StartPeriod: time.Minute,
+			Retries:     3,
+		}
+	}
+	return cfg
+}
+
+// containerLabels merges the database's user labels with the reserved labels
+// DBnest uses to find its containers
+func containerLabels(db *storage.DatabaseInstance) map[string]string {
+	labels := make(map[string]string, len(db.Labels)+2)
+	for k, v := range db.Labels {
+		labels[k] = v
+	}
+	labels["dbnest.managed"] = "true"
+	labels["dbnest.id"] = db.ID
+	return labels
+}
+
+// provisionDedicatedDatabase runs in background to pull image and create/start container.
+// A "queued" database first waits for a provisioning slot. ctx carries the
+// logger of the request that created the database, and is cancelled if the
+// database is deleted meanwhile.
+func (m *Manager) provisionDedicatedDatabase(ctx context.Context, db *storage.DatabaseInstance, imageName, dataDir string, port int, engine Engine, seedSource, seedContent string) {
+	defer m.releasePort(port, db.ID)
+
+	if db.Status == "queued" {
+		log.Ctx(ctx).Info().Str("id", db.ID).Msg("Waiting for a provisioning slot")
+		if err := m.provisionQueue.acquire(ctx, m.options().ProvisionConcurrency); err != nil {
+			m.provisioningCancelled(ctx, db, "")
+			return
+		}
+		db.Status = "creating"
+		m.store.UpdateDatabase(db)
+		m.provisioning.publish(db.ID, StagePulling, imageName)
+	}
+	defer m.provisionQueue.release(m.options().ProvisionConcurrency)
 
-	log.Info().
+	log.Ctx(ctx).Info().
 		Str("id", db.ID).
 		Str("name", db.Name).
 		Str("image", imageName).
@@ -205,66 +862,72 @@ func (m *Manager) provisionDedicatedDatabase(db *storage.DatabaseInstance, image
 		Msg("Starting database provisioning")
 
 	// Pull image (this can take a while for large images)
-	log.Info().Str("id", db.ID).Str("image", imageName).Msg("Pulling Docker image (this may take a few minutes)...")
-	if err := m.client.PullImage(ctx, imageName); err != nil {
-		log.Error().Err(err).Str("id", db.ID).Str("image", imageName).Msg("Failed to pull image")
+	log.Ctx(ctx).Info().Str("id", db.ID).Str("image", imageName).Msg("Pulling Docker image (this may take a few minutes)...")
+	if err := m.pullImage(ctx, db, imageName); err != nil {
+		if m.provisioningCancelled(ctx, db, "") {
+			return
+		}
+		log.Ctx(ctx).Error().Err(err).Str("id", db.ID).Str("image", imageName).Msg("Failed to pull image")
 		db.Status = "error"
 		db.ErrorMessage = fmt.Sprintf("Failed to pull image: %v", err)
 		m.store.UpdateDatabase(db)
+		m.provisioning.publish(db.ID, StageError, db.ErrorMessage)
 		return
 	}
-	log.Info().Str("id", db.ID).Str("image", imageName).Msg("Docker image pulled successfully")
+	log.Ctx(ctx).Info().Str("id", db.ID).Str("image", imageName).Msg("Docker image pulled successfully")
 
-	// Create container
-	log.Info().Str("id", db.ID).Msg("Creating Docker container")
-	containerCfg := &runtime.ContainerConfig{
-		Name:  fmt.Sprintf("dbnest-%s", db.ID),
-		Image: imageName,
-		Cmd:   engine.ContainerCmd(db.Password),
-		Env:   engine.EnvVars(db.Username, db.Password, db.Database),
-		PortBindings: map[string]string{
-			fmt.Sprintf("%d/tcp", engine.DefaultPort()): fmt.Sprintf("%d", port),
-		},
-		Volumes: map[string]string{
-			fmt.Sprintf("dbnest-vol-%s", db.ID): engine.DataPath(),
-		},
-		MemoryLimit: db.MemoryLimit,
-		CPULimit:    db.CPULimit,
-		Labels: map[string]string{
-			"dbnest.managed": "true",
-			"dbnest.id":      db.ID,
-		},
-		ExposePort: db.ExposePort,
-		Network:    db.Network,
+	// Record the exact image pulled so repairs recreate the same one
+	if digest, err := m.client.ImageDigest(ctx, imageName); err != nil {
+		log.Ctx(ctx).Warn().Err(err).Str("id", db.ID).Str("image", imageName).Msg("Failed to resolve image digest")
+	} else if digest != "" {
+		db.ImageDigest = digest
 	}
 
+	// Create container
+	log.Ctx(ctx).Info().Str("id", db.ID).Msg("Creating Docker container")
+	m.provisioning.publish(db.ID, StageCreating, "")
+	containerCfg := containerConfig(db, engine, imageName)
+	containerCfg.Security = m.options().ContainerSecurity
+
 	containerID, err := m.client.CreateContainer(ctx, containerCfg)
 	if err != nil {
-		log.Error().Err(err).Str("id", db.ID).Msg("Failed to create container")
+		if m.provisioningCancelled(ctx, db, "") {
+			return
+		}
+		log.Ctx(ctx).Error().Err(err).Str("id", db.ID).Msg("Failed to create container")
 		db.Status = "error"
 		db.ErrorMessage = fmt.Sprintf("Failed to create container: %v", err)
 		m.store.UpdateDatabase(db)
+		m.provisioning.publish(db.ID, StageError, db.ErrorMessage)
 		return
 	}
 
 	db.ContainerID = containerID
-	log.Info().Str("id", db.ID).Str("container_id", containerID[:12]).Msg("Container created")
+	log.Ctx(ctx).Info().Str("id", db.ID).Str("container_id", containerID[:12]).Msg("Container created")
 
 	// Start container
-	log.Info().Str("id", db.ID).Msg("Starting container")
+	log.Ctx(ctx).Info().Str("id", db.ID).Msg("Starting container")
+	m.provisioning.publish(db.ID, StageStarting, "")
 	if err := m.client.StartContainer(ctx, containerID); err != nil {
-		log.Error().Err(err).Str("id", db.ID).Msg("Failed to start container")
+		if m.provisioningCancelled(ctx, db, containerID) {
+			return
+		}
+		log.Ctx(ctx).Error().Err(err).Str("id", db.ID).Msg("Failed to start container")
 		db.Status = "error"
 		db.ErrorMessage = fmt.Sprintf("Failed to start container: %v", err)
 		m.store.UpdateDatabase(db)
+		m.provisioning.publish(db.ID, StageError, db.ErrorMessage)
 		return
 	}
 
+	if m.provisioningCancelled(ctx, db, containerID) {
+		return
+	}
 	db.Status = "running"
 	db.ErrorMessage = "" // Clear any previous error
 	m.store.UpdateDatabase(db)
 
-	log.Info().
+	log.Ctx(ctx).Info().
 		Str("id", db.ID).
 		Str("name", db.Name).
 		Int("port", port).
@@ -272,39 +935,165 @@ func (m *Manager) provisionDedicatedDatabase(db *storage.DatabaseInstance, image
 
 	// Apply data seeding if requested
 	if seedSource != "" && seedSource != "none" {
-		go m.applySeed(db, seedSource, seedContent)
+		m.provisioning.publish(db.ID, StageSeeding, seedSource)
+		m.applySeed(ctx, db, seedSource, seedContent)
+		return
 	}
+	m.provisioning.publish(db.ID, StageReady, "")
 }
 
-// applySeed runs in background to apply data seeding
-func (m *Manager) applySeed(db *storage.DatabaseInstance, source, content string) {
-	ctx := context.Background()
-	log.Info().Str("id", db.ID).Str("source", source).Msg("Starting data seeding")
+// provisionRun is a database's in-flight background provisioning
+type provisionRun struct {
+	cancel context.CancelFunc
+	done   chan struct{} // closed once provisioning has returned
+}
 
-	// Wait for database to be ready
-	// We'll try to connect periodically
-	maxRetries := 30
-	ready := false
-	engine, _ := GetEngine(db.Engine) // Error handled in caller
+// startProvisioning derives the context background provisioning of a
+// database runs with, which cancelProvisioning cancels. done must be called
+// once provisioning ends.
+func (m *Manager) startProvisioning(ctx context.Context, id string) (context.Context, func()) {
+	ctx, cancel := context.WithCancel(ctx)
+	run := &provisionRun{cancel: cancel, done: make(chan struct{})}
+	m.provisionMu.Lock()
+	m.provisionRuns[id] = run
+	m.provisionMu.Unlock()
+
+	return ctx, func() {
+		m.provisionMu.Lock()
+		delete(m.provisionRuns, id)
+		m.provisionMu.Unlock()
+		cancel()
+		close(run.done)
+	}
+}
 
-	for i := 0; i < maxRetries; i++ {
-		// Use a simple health check query via Exec
-		testQuery := "SELECT 1"
-		if db.Engine == "redis" {
-			testQuery = "PING"
+// cancelProvisioning stops a database's in-flight provisioning. It returns a
+// channel closed once provisioning has cleaned up and returned, or nil if
+// none was running.
+func (m *Manager) cancelProvisioning(id string) <-chan struct{} {
+	m.provisionMu.Lock()
+	defer m.provisionMu.Unlock()
+	run, ok := m.provisionRuns[id]
+	if !ok {
+		return nil
+	}
+	run.cancel()
+	return run.done
+}
+
+// provisioningCancelled reports whether provisioning was cancelled. If it
+// was, the database is being deleted, so instead of recording an error it
+// removes the container and volume created so far; Delete may have already
+// looked for them.
+func (m *Manager) provisioningCancelled(ctx context.Context, db *storage.DatabaseInstance, containerID string) bool {
+	if ctx.Err() == nil {
+		return false
+	}
+	log.Ctx(ctx).Info().Str("id", db.ID).Msg("Provisioning cancelled")
+
+	// ctx is done, so clean up without it
+	cleanupCtx := context.Background()
+	if containerID != "" {
+		if err := m.client.RemoveContainer(cleanupCtx, containerID, true); err != nil {
+			log.Ctx(ctx).Warn().Err(err).Str("id", db.ID).Msg("Failed to remove container of cancelled provisioning")
+		}
+	}
+	if db.HostDataPath == "" {
+		if err := m.client.DeleteVolume(cleanupCtx, dataVolume(db)); err != nil {
+			log.Ctx(ctx).Debug().Err(err).Str("id", db.ID).Msg("Failed to remove volume of cancelled provisioning")
 		}
+	}
+	m.provisioning.publish(db.ID, StageError, "Provisioning cancelled")
+	return true
+}
 
-		// We use the engine's ExecuteQuery which internally uses Exec/ExecWithStdin
-		_, err := engine.ExecuteQuery(ctx, m.client, db, testQuery)
-		if err == nil {
-			ready = true
-			break
+// FailInterruptedProvisioning marks databases a previous run left "queued"
+// or "creating" as failed. Their provisioning ended with that process, so
+// nothing would ever move them on. It must run on startup, before any
+// database is created.
+func (m *Manager) FailInterruptedProvisioning() {
+	for _, db := range m.store.ListDatabases() {
+		if db.Status != "queued" && db.Status != "creating" {
+			continue
 		}
-		time.Sleep(2 * time.Second)
+		log.Warn().Str("id", db.ID).Str("status", db.Status).Msg("Provisioning was interrupted by a restart")
+		oldStatus := db.Status
+		db.Status = "error"
+		db.ErrorMessage = "Provisioning was interrupted by a restart, delete and recreate the database"
+		if err := m.store.UpdateDatabase(db); err != nil {
+			log.Error().Err(err).Str("id", db.ID).Msg("Failed to update interrupted database")
+			continue
+		}
+		m.notifyStatusChange(db, oldStatus)
+	}
+}
+
+// WaitForReady waits until a database is running and answers a trivial query,
+// probing with exponential backoff until timeout or ctx is done. If the
+// database is in the store its latest record is used, so this also waits for
+// background provisioning to finish.
+func (m *Manager) WaitForReady(ctx context.Context, db *storage.DatabaseInstance, timeout time.Duration) error {
+	engine, err := GetEngine(db.Engine)
+	if err != nil {
+		return fmt.Errorf("unsupported engine: %s", db.Engine)
 	}
 
-	if !ready {
-		log.Error().Str("id", db.ID).Msg("Database not ready for seeding after timeout")
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	query := "SELECT 1"
+	if db.Engine == "redis" {
+		query = "PING"
+	} else if ready, ok := engine.(ReadyQueryEngine); ok && ready.ReadyQuery() != "" {
+		query = ready.ReadyQuery()
+	}
+
+	backoff := 250 * time.Millisecond
+	lastErr := fmt.Errorf("database is %s", db.Status)
+	for {
+		current := db
+		if stored, err := m.store.GetDatabase(db.ID); err == nil {
+			current = stored
+		}
+
+		switch {
+		case current.Status == "error" || current.Status == "oom-killed":
+			return fmt.Errorf("database failed: %s", current.ErrorMessage)
+		case !ContainerUp(current.Status) || current.ContainerID == "":
+			lastErr = fmt.Errorf("database is %s", current.Status)
+		default:
+			result, err := engine.ExecuteQuery(ctx, m.client, current, query)
+			if err == nil && result.Error == "" {
+				return nil
+			}
+			if err != nil {
+				lastErr = err
+			} else {
+				lastErr = errors.New(result.Error)
+			}
+		}
+
+		select {
+		case <-ctx.Done():
+			return fmt.Errorf("not ready after %s: %w", timeout, lastErr)
+		case <-time.After(backoff):
+		}
+		if backoff < 5*time.Second {
+			backoff *= 2
+		}
+	}
+}
+
+// applySeed applies data seeding at the end of provisioning
+func (m *Manager) applySeed(ctx context.Context, db *storage.DatabaseInstance, source, content string) {
+	log.Info().Str("id", db.ID).Str("source", source).Msg("Starting data seeding")
+
+	engine, _ := GetEngine(db.Engine) // Error handled in caller
+
+	// Wait for database to accept queries
+	if err := m.WaitForReady(ctx, db, time.Minute); err != nil {
+		log.Error().Err(err).Str("id", db.ID).Msg("Database not ready for seeding")
+		m.provisioning.publish(db.ID, StageError, fmt.Sprintf("Database not ready for seeding: %v", err))
 		return
 	}
 
@@ -323,6 +1112,7 @@ func (m *Manager) applySeed(db *storage.DatabaseInstance, source, content string
 
 	if sqlContent == "" {
 		log.Warn().Str("id", db.ID).Msg("Empty seed content")
+		m.provisioning.publish(db.ID, StageReady, "")
 		return
 	}
 
@@ -334,17 +1124,18 @@ func (m *Manager) applySeed(db *storage.DatabaseInstance, source, content string
 	// But for large SQL dump, we want to pipe it.
 	// Engine interface might need an `ExecuteScript` method, or we construct it here.
 
-	cmd := engine.CLICommand(db.Username, db.Password, db.Database)
+	cmd := engine.CLICommand(db)
 	// CLICommand returns something like ["psql", "-U", ...]
 	// We need to inject the SQL via stdin
 
-	output, err := m.client.ExecWithStdin(ctx, db.ContainerID, cmd, []byte(sqlContent), nil)
+	output, err := m.client.ExecWithStdin(ctx, db.ContainerID, cmd, strings.NewReader(sqlContent), nil)
 	if err != nil {
 		log.Error().Err(err).Str("id", db.ID).Msg("Failed to execute seed script")
-		// Ideally we should record this error somewhere visible to user
+		m.provisioning.publish(db.ID, StageError, fmt.Sprintf("Failed to execute seed script: %v", err))
 	} else {
 		log.Info().Str("id", db.ID).Msg("Data seeding completed successfully")
 		log.Debug().Str("id", db.ID).Str("output", output).Msg("Seed output")
+		m.provisioning.publish(db.ID, StageReady, "")
 	}
 }
 
@@ -358,15 +1149,37 @@ func (m *Manager) List() []*storage.DatabaseInstance {
 	return m.store.ListDatabases()
 }
 
+// PingRuntime checks the container runtime is reachable. A reconnecting
+// runtime client reconnects when the ping finds the connection gone.
+func (m *Manager) PingRuntime(ctx context.Context) error {
+	return m.client.Ping(ctx)
+}
+
 // SyncAllStatuses queries container runtime for actual status and updates any that differ.
-// This is called by the background status sync worker.
+// This is called by the background status sync worker. It stops early once
+// ctx is done rather than probing the remaining containers.
 func (m *Manager) SyncAllStatuses(ctx context.Context) {
 	databases := m.store.ListDatabases()
 	for _, db := range databases {
+		if ctx.Err() != nil {
+			return
+		}
 		m.syncStatus(ctx, db)
 	}
 }
 
+// oomKilledMessage is the error reported when the kernel killed a database for exceeding its memory limit
+const oomKilledMessage = "Container ran out of memory, increase memory limit"
+
+// unhealthyMessage is the error reported while a database's healthcheck is failing
+const unhealthyMessage = "Container healthcheck is failing"
+
+// ContainerUp reports whether a database's container is running, whether or
+// not its healthcheck has passed
+func ContainerUp(status string) bool {
+	return status == "running" || status == "starting" || status == "unhealthy"
+}
+
 // syncStatus queries the container runtime for actual container state and updates db.Status if needed
 func (m *Manager) syncStatus(ctx context.Context, db *storage.DatabaseInstance) {
 	// Skip if no container or still creating
@@ -375,13 +1188,19 @@ func (m *Manager) syncStatus(ctx context.Context, db *storage.DatabaseInstance)
 	}
 
 	actualStatus, err := m.client.GetContainerStatus(ctx, db.ContainerID)
+	if errors.Is(err, runtime.ErrUnavailable) {
+		// Nothing is known about the container while the runtime is down
+		return
+	}
 	if err != nil {
 		// If we can't query and it was running, mark as error
-		if db.Status == "running" {
+		if ContainerUp(db.Status) {
 			log.Debug().Err(err).Str("id", db.ID).Msg("Container not accessible")
+			oldStatus := db.Status
 			db.Status = "error"
 			db.ErrorMessage = "Container not accessible"
 			m.store.UpdateDatabase(db)
+			m.notifyStatusChange(db, oldStatus)
 		}
 		return
 	}
@@ -394,14 +1213,33 @@ func (m *Manager) syncStatus(ctx context.Context, db *storage.DatabaseInstance)
 			Str("new_status", actualStatus).
 			Msg("Container status changed externally")
 
+		oldStatus := db.Status
 		db.Status = actualStatus
-		if actualStatus == "running" {
+		switch actualStatus {
+		case "running", "starting":
 			db.ErrorMessage = ""
+		case "oom-killed":
+			db.ErrorMessage = oomKilledMessage
+		case "unhealthy":
+			db.ErrorMessage = unhealthyMessage
 		}
 		m.store.UpdateDatabase(db)
+		m.notifyStatusChange(db, oldStatus)
 	}
 }
 
+// notifyStatusChange sends a status transition to the configured webhooks
+func (m *Manager) notifyStatusChange(db *storage.DatabaseInstance, oldStatus string) {
+	m.notifier.Notify(notify.Event{
+		Type:         notify.EventStatusChanged,
+		DatabaseID:   db.ID,
+		DatabaseName: db.Name,
+		OldStatus:    oldStatus,
+		NewStatus:    db.Status,
+		Message:      db.ErrorMessage,
+	})
+}
+
 // Start starts a stopped database
 func (m *Manager) Start(ctx context.Context, id string) error {
 	db, err := m.store.GetDatabase(id)
@@ -421,8 +1259,9 @@ func (m *Manager) Start(ctx context.Context, id string) error {
 	return m.store.UpdateDatabase(db)
 }
 
-// Stop stops a running database
-func (m *Manager) Stop(ctx context.Context, id string) error {
+// Stop stops a running database, giving it up to timeout to shut down cleanly.
+// A zero or negative timeout kills the container immediately.
+func (m *Manager) Stop(ctx context.Context, id string, timeout time.Duration) error {
 	db, err := m.store.GetDatabase(id)
 	if err != nil {
 		return err
@@ -432,7 +1271,7 @@ func (m *Manager) Stop(ctx context.Context, id string) error {
 		return fmt.Errorf("no container associated with database")
 	}
 
-	if err := m.client.StopContainer(ctx, db.ContainerID); err != nil {
+	if err := m.client.StopContainer(ctx, db.ContainerID, timeout); err != nil {
 		return fmt.Errorf("failed to stop container: %w", err)
 	}
 
@@ -441,13 +1280,81 @@ func (m *Manager) Stop(ctx context.Context, id string) error {
 	return m.store.UpdateDatabase(db)
 }
 
-// Delete deletes a database and its container
-func (m *Manager) Delete(ctx context.Context, id string) error {
+// Pause freezes a running database's container without stopping it
+func (m *Manager) Pause(ctx context.Context, id string) error {
+	db, err := m.store.GetDatabase(id)
+	if err != nil {
+		return err
+	}
+
+	if db.ContainerID == "" {
+		return fmt.Errorf("no container associated with database")
+	}
+	if !ContainerUp(db.Status) {
+		return fmt.Errorf("database is not running")
+	}
+
+	if err := m.client.PauseContainer(ctx, db.ContainerID); err != nil {
+		return fmt.Errorf("failed to pause container: %w", err)
+	}
+
+	db.Status = "paused"
+	return m.store.UpdateDatabase(db)
+}
+
+// Resume unfreezes a paused database
+func (m *Manager) Resume(ctx context.Context, id string) error {
+	db, err := m.store.GetDatabase(id)
+	if err != nil {
+		return err
+	}
+
+	if db.ContainerID == "" {
+		return fmt.Errorf("no container associated with database")
+	}
+	if db.Status != "paused" {
+		return fmt.Errorf("database is not paused")
+	}
+
+	if err := m.client.ResumeContainer(ctx, db.ContainerID); err != nil {
+		return fmt.Errorf("failed to resume container: %w", err)
+	}
+
+	db.Status = "running"
+	return m.store.UpdateDatabase(db)
+}
+
+// DeleteOptions adjust what Delete removes along with a database
+type DeleteOptions struct {
+	// KeepBackups leaves the database's backups, records and files, in place
+	// so they can still be restored into another database
+	KeepBackups bool
+}
+
+// Delete deletes a database and its container, and its backups unless
+// opts.KeepBackups is set
+func (m *Manager) Delete(ctx context.Context, id string, opts DeleteOptions) error {
 	db, err := m.store.GetDatabase(id)
 	if err != nil {
 		return err
 	}
 
+	// A database still being created has provisioning running in the
+	// background, which would otherwise create its container and volume after
+	// they are removed here. It is cancelled, and removed only once it has
+	// stopped.
+	if done := m.cancelProvisioning(id); done != nil {
+		log.Ctx(ctx).Info().Str("id", id).Msg("Cancelling provisioning of deleted database")
+		select {
+		case <-done:
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+		if db, err = m.store.GetDatabase(id); err != nil {
+			return err
+		}
+	}
+
 	// Remove container if exists
 	if db.ContainerID != "" {
 		if err := m.client.RemoveContainer(ctx, db.ContainerID, true); err != nil {
@@ -455,11 +1362,13 @@ func (m *Manager) Delete(ctx context.Context, id string) error {
 		}
 	}
 
-	// Remove volume
-	volumeName := fmt.Sprintf("dbnest-vol-%s", id)
-	if err := m.client.DeleteVolume(ctx, volumeName); err != nil {
-		// Log but don't fail, volume might not exist
-		fmt.Printf("Warning: failed to remove volume %s: %v\n", volumeName, err)
+	// Remove volume. Bind-mounted host directories belong to the user and are kept.
+	if db.HostDataPath == "" {
+		volumeName := dataVolume(db)
+		if err := m.client.DeleteVolume(ctx, volumeName); err != nil {
+			// Log but don't fail, volume might not exist
+			fmt.Printf("Warning: failed to remove volume %s: %v\n", volumeName, err)
+		}
 	}
 
 	// Remove local data directory (if it exists)
@@ -469,6 +1378,23 @@ func (m *Manager) Delete(ctx context.Context, id string) error {
 		fmt.Printf("Warning: failed to remove data directory %s: %v\n", dataDir, err)
 	}
 
+	// Drop persisted metrics history
+	if err := m.store.DeleteMetricsPoints(id); err != nil {
+		log.Warn().Err(err).Str("id", id).Msg("Failed to remove metrics history")
+	}
+	m.metricsThrottle.forget(id)
+	if err := m.store.DeleteQueryHistory(id, ""); err != nil {
+		log.Warn().Err(err).Str("id", id).Msg("Failed to remove query history")
+	}
+
+	if !opts.KeepBackups {
+		for _, backup := range m.store.ListBackups(id) {
+			if err := m.DeleteBackup(backup.ID); err != nil {
+				log.Warn().Err(err).Str("id", id).Str("backup", backup.ID).Msg("Failed to remove backup")
+			}
+		}
+	}
+
 	return m.store.DeleteDatabase(id)
 }
 
@@ -487,43 +1413,52 @@ func (m *Manager) Clone(ctx context.Context, sourceID string, newName string) (*
 
 	// Create backup of source
 	log.Info().Str("source", sourceID).Str("name", newName).Msg("Creating backup for clone")
-	backup, err := m.CreateBackup(ctx, sourceID)
+	backup, err := m.CreateBackup(ctx, sourceID, nil)
 	if err != nil {
 		return nil, fmt.Errorf("failed to create backup: %w", err)
 	}
 
-	// Wait for backup to complete (poll status)
-	maxWait := 60 // seconds
-	for i := 0; i < maxWait; i++ {
-		backup, err = m.store.GetBackup(backup.ID)
-		if err != nil {
-			return nil, fmt.Errorf("failed to get backup status: %w", err)
-		}
-		if backup.Status == "completed" {
-			break
-		}
-		if backup.Status == "failed" {
-			return nil, fmt.Errorf("backup failed")
-		}
-		time.Sleep(time.Second)
-	}
-
-	if backup.Status != "completed" {
+	// Wait for backup to complete
+	waitCtx, cancel := context.WithTimeout(ctx, 60*time.Second)
+	backup, err = m.WaitForBackup(waitCtx, backup.ID)
+	cancel()
+	if errors.Is(err, context.DeadlineExceeded) {
 		return nil, fmt.Errorf("backup timed out")
 	}
+	if err != nil {
+		return nil, err
+	}
 
 	// Create new database with same settings
+	exposePort := source.ExposePort
+	readOnlyRootfs := source.ReadOnlyRootfs
 	req := &CreateRequest{
 		Name:                newName,
 		Engine:              source.Engine,
 		Version:             source.Version,
+		Image:               source.Image,
+		ExtraEnv:            source.ExtraEnv,
+		ConfigParams:        source.ConfigParams,
+		RestartPolicy:       source.RestartPolicy,
+		RunAsUser:           source.RunAsUser,
+		RunAsGroup:          source.RunAsGroup,
+		UsernsMode:          source.UsernsMode,
+		ReadOnlyRootfs:      &readOnlyRootfs,
+		RedisBackupMode:     source.RedisBackupMode,
+		RedisDB:             source.RedisDB,
+		Labels:              source.Labels,
+		ImageDigest:         source.ImageDigest,
 		Username:            source.Username,
 		Password:            uuid.New().String()[:16], // New password
 		Database:            source.Database,
 		StorageLimit:        source.StorageLimit / (1024 * 1024), // Convert back to MB
 		MemoryLimit:         source.MemoryLimit / (1024 * 1024),
 		Network:             source.Network,
+		ExposePort:          &exposePort,
+		BindAddress:         source.BindAddress,
+		MaxConnections:      source.MaxConnections,
 		RestoreFromBackupID: backup.ID,
+		ClonedFrom:          sourceID,
 	}
 
 	log.Info().Str("name", newName).Str("backup", backup.ID).Msg("Creating cloned database")
@@ -532,30 +1467,18 @@ func (m *Manager) Clone(ctx context.Context, sourceID string, newName string) (*
 		return nil, fmt.Errorf("failed to create clone: %w", err)
 	}
 
-	// Wait for container to be running then restore
-	// Wait for database to be running
-	containerWait := 120 // seconds
-	for i := 0; i < containerWait; i++ {
-		clone, err = m.store.GetDatabase(clone.ID)
-		if err != nil {
-			return nil, fmt.Errorf("failed to get clone status: %w", err)
-		}
-		if clone.Status == "running" {
-			break
-		}
-		if clone.Status == "error" {
-			return nil, fmt.Errorf("clone container failed: %s", clone.ErrorMessage)
-		}
-		time.Sleep(time.Second)
+	// Wait for the clone to be provisioned and accepting queries before restoring
+	if err := m.WaitForReady(ctx, clone, 2*time.Minute); err != nil {
+		return nil, fmt.Errorf("clone not ready: %w", err)
 	}
-
-	if clone.Status != "running" {
-		return nil, fmt.Errorf("clone timed out waiting for container")
+	clone, err = m.store.GetDatabase(clone.ID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get clone status: %w", err)
 	}
 
 	// Restore backup to clone
 	log.Info().Str("clone", clone.ID).Str("backup", backup.ID).Msg("Restoring backup to clone")
-	if err := m.RestoreBackup(ctx, backup.ID, clone.ID); err != nil {
+	if err := m.RestoreBackup(ctx, backup.ID, clone.ID, nil); err != nil {
 		log.Warn().Err(err).Msg("Failed to restore backup to clone")
 		// Don't fail - database was created, restore just didn't work
 	}
@@ -585,10 +1508,7 @@ func (m *Manager) Repair(ctx context.Context, id string) error {
 	}
 
 	// Build image name
-	imageName := engine.Image()
-	if db.Version != "" {
-		imageName = fmt.Sprintf("%s:%s", engine.Image(), db.Version)
-	}
+	imageName := resolveImage(engine, db.Image, db.Version, db.ImageDigest)
 
 	// Get data directory
 	baseDataDir, err := filepath.Abs(m.store.DataDir())
@@ -603,26 +1523,8 @@ func (m *Manager) Repair(ctx context.Context, id string) error {
 	}
 
 	// Create new container
-	containerCfg := &runtime.ContainerConfig{
-		Name:  fmt.Sprintf("dbnest-%s", db.ID),
-		Image: imageName,
-		Cmd:   engine.ContainerCmd(db.Password),
-		Env:   engine.EnvVars(db.Username, db.Password, db.Database),
-		PortBindings: map[string]string{
-			fmt.Sprintf("%d/tcp", engine.DefaultPort()): fmt.Sprintf("%d", db.Port),
-		},
-		Volumes: map[string]string{
-			fmt.Sprintf("dbnest-vol-%s", db.ID): engine.DataPath(),
-		},
-		MemoryLimit: db.MemoryLimit,
-		CPULimit:    db.CPULimit,
-		Labels: map[string]string{
-			"dbnest.managed": "true",
-			"dbnest.id":      db.ID,
-		},
-		ExposePort: db.ExposePort,
-		Network:    db.Network,
-	}
+	containerCfg := containerConfig(db, engine, imageName)
+	containerCfg.Security = m.options().ContainerSecurity
 
 	containerID, err := m.client.CreateContainer(ctx, containerCfg)
 	if err != nil {
@@ -641,23 +1543,28 @@ func (m *Manager) Repair(ctx context.Context, id string) error {
 	return m.store.UpdateDatabase(db)
 }
 
-// GetMetricsHistory returns historical metrics for a database
-func (m *Manager) GetMetricsHistory(dbID string) []MetricsPoint {
-	return m.metricsHistory.Get(dbID)
-}
-
-// RecordMetrics records a metrics point for a database
-func (m *Manager) RecordMetrics(dbID string, point MetricsPoint) {
-	m.metricsHistory.Record(dbID, point)
-}
-
 // GetContainerStats returns stats for a container
 func (m *Manager) GetContainerStats(ctx context.Context, containerID string) (*runtime.ContainerStats, error) {
 	return m.client.GetContainerStats(ctx, containerID)
 }
 
+// Bounds on how many log lines GetLogs fetches
+const (
+	DefaultLogTail = 200
+	MaxLogTail     = 10000
+)
+
+// LogOptions select which container log lines GetLogs returns
+type LogOptions struct {
+	Tail  int           // lines from the end, 0 means DefaultLogTail
+	Since time.Duration // only lines from this long ago, 0 means no limit
+	Grep  string        // keep lines containing this substring, applied after Tail
+	// Timestamps prefixes each line with the RFC3339 time it was written
+	Timestamps bool
+}
+
 // GetLogs returns the logs for a database container
-func (m *Manager) GetLogs(ctx context.Context, id string) (string, error) {
+func (m *Manager) GetLogs(ctx context.Context, id string, opts LogOptions) (string, error) {
 	db, err := m.store.GetDatabase(id)
 	if err != nil {
 		return "", err
@@ -667,16 +1574,105 @@ func (m *Manager) GetLogs(ctx context.Context, id string) (string, error) {
 		return "", fmt.Errorf("no container associated with database")
 	}
 
-	return m.client.GetContainerLogs(ctx, db.ContainerID, 200) // Fetch last 200 lines
+	tail := opts.Tail
+	if tail <= 0 {
+		tail = DefaultLogTail
+	}
+	var since time.Time
+	if opts.Since > 0 {
+		since = time.Now().Add(-opts.Since)
+	}
+	logs, err := m.client.GetContainerLogs(ctx, db.ContainerID, min(tail, MaxLogTail), since, opts.Timestamps)
+	if err != nil || opts.Grep == "" {
+		return logs, err
+	}
+
+	var b strings.Builder
+	for _, line := range strings.SplitAfter(logs, "\n") {
+		if strings.Contains(line, opts.Grep) {
+			b.WriteString(line)
+		}
+	}
+	return b.String(), nil
 }
 
-// UpdateResources updates the resource limits for a database
-func (m *Manager) UpdateResources(ctx context.Context, id string, memoryLimit int64, cpuLimit float64) (*storage.DatabaseInstance, error) {
+// Shell opens an interactive CLI session in a database container.
+// It blocks until the session ends or ctx is cancelled.
+func (m *Manager) Shell(ctx context.Context, id string, stdin io.Reader, stdout io.Writer, resize <-chan runtime.TerminalSize) error {
 	db, err := m.store.GetDatabase(id)
 	if err != nil {
+		return err
+	}
+
+	if db.ContainerID == "" {
+		return fmt.Errorf("no container associated with database")
+	}
+	if !ContainerUp(db.Status) {
+		return fmt.Errorf("database is not running")
+	}
+
+	engine, err := GetEngine(db.Engine)
+	if err != nil {
+		return err
+	}
+
+	cmd := engine.ShellCommand(db)
+	return m.client.ExecInteractive(ctx, db.ContainerID, cmd, stdin, stdout, resize)
+}
+
+// SetNetwork moves a database's container onto another network. An empty
+// name moves it back to the runtime's default network. The new network is
+// attached before the old one is detached so the container is never isolated.
+func (m *Manager) SetNetwork(ctx context.Context, id, network string) (*storage.DatabaseInstance, error) {
+	db, err := m.store.GetDatabase(id)
+	if err != nil {
+		return nil, err
+	}
+	if db.ContainerID == "" {
+		return nil, fmt.Errorf("no container associated with database")
+	}
+	if db.Network == network {
+		return db, nil
+	}
+
+	if err := m.client.ConnectNetwork(ctx, db.ContainerID, network); err != nil {
+		return nil, err
+	}
+	if err := m.client.DisconnectNetwork(ctx, db.ContainerID, db.Network); err != nil {
+		// Roll back so the container stays on exactly one network
+		if rbErr := m.client.DisconnectNetwork(ctx, db.ContainerID, network); rbErr != nil {
+			log.Warn().Err(rbErr).Str("db", id).Str("network", network).Msg("Failed to roll back network attach")
+		}
 		return nil, err
 	}
 
+	log.Info().Str("db", id).Str("from", db.Network).Str("to", network).Msg("Moved database to network")
+	db.Network = network
+	if err := m.store.UpdateDatabase(db); err != nil {
+		return nil, err
+	}
+	return db, nil
+}
+
+// UpdateResources updates the resource limits for a database and applies them to
+// its running container. restartRequired reports that the runtime saved the limits
+// but the container must be restarted before they take effect.
+func (m *Manager) UpdateResources(ctx context.Context, id string, memoryLimit int64, cpuLimit float64) (db *storage.DatabaseInstance, restartRequired bool, err error) {
+	db, err = m.store.GetDatabase(id)
+	if err != nil {
+		return nil, false, err
+	}
+
+	// Apply to the live container first; only persist limits the runtime accepted
+	if db.ContainerID != "" && ContainerUp(db.Status) {
+		err := m.client.UpdateContainerResources(ctx, db.ContainerID, memoryLimit, cpuLimit)
+		if errors.Is(err, runtime.ErrRestartRequired) {
+			restartRequired = true
+		} else if err != nil {
+			return nil, false, fmt.Errorf("failed to apply resource limits: %w", err)
+		}
+	}
+
 	if memoryLimit > 0 {
 		db.MemoryLimit = memoryLimit
 	}
@@ -685,7 +1681,7 @@ func (m *Manager) UpdateResources(ctx context.Context, id string, memoryLimit in
 	}
 
 	if err := m.store.UpdateDatabase(db); err != nil {
-		return nil, err
+		return nil, false, err
 	}
-	return db, nil
+	return db, restartRequired, nil
 }
@@ -1,56 +1,184 @@
 package database
 
 import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
 	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
 	"testing"
 	"time"
 
+	"github.com/sirrobot01/dbnest/pkg/notify"
 	"github.com/sirrobot01/dbnest/pkg/runtime"
 	"github.com/sirrobot01/dbnest/pkg/storage"
 )
 
 // MockDockerClient implements runtime.Client for testing
 type MockDockerClient struct {
-	LastContainerID string
-	LastExecCmd     []string
-	LastExecInput   string
+	LastContainerID    string
+	LastExecCmd        []string
+	LastExecInput      string
+	UpdateResourcesErr error
+	UpdateCalls        int
+	Containers         []runtime.ContainerInfo
+	RemovedContainers  []string
+	DeletedVolumes     []string
+	ExecFailures       int // number of upcoming Exec calls that fail
+	NetworkOps         []string
+
+	// ContainerStatus overrides the status reported by GetContainerStatus;
+	// ContainerStatusErr makes it fail instead
+	ContainerStatus    string
+	ContainerStatusErr error
+	// PullErrors are returned by upcoming PullImage calls, in order
+	PullErrors []error
+	PullCalls  int
+	// Hang names a call ("PullImage" or "StartContainer") that blocks until
+	// its context is done
+	Hang string
+	// Digest is reported by ImageDigest; LastImage is the image of the last created container
+	Digest    string
+	LastImage string
+	// ExecCmds and ExecEnvs record Exec commands and their environment;
+	// ExecOutput is returned by successful Exec calls
+	ExecCmds   [][]string
+	ExecEnvs   [][]string
+	ExecOutput string
+	// StreamOutput is written by ExecStream
+	StreamOutput string
+	// LogsOutput overrides GetContainerLogs output; the other Logs fields record the last call
+	LogsOutput     string
+	LogsTail       int
+	LogsSince      time.Time
+	LogsTimestamps bool
 }
 
 func (m *MockDockerClient) Close() error { return nil }
 func (m *MockDockerClient) Ping(ctx context.Context) error { return nil }
-func (m *MockDockerClient) PullImage(ctx context.Context, imageName string) error { return nil }
+func (m *MockDockerClient) Version(ctx context.Context) (string, error) {
+	return "27.0.0", nil
+}
+func (m *MockDockerClient) PullImage(ctx context.Context, imageName string) error {
+	m.PullCalls++
+	if m.Hang == "PullImage" {
+		<-ctx.Done()
+		return ctx.Err()
+	}
+	if len(m.PullErrors) > 0 {
+		err := m.PullErrors[0]
+		m.PullErrors = m.PullErrors[1:]
+		return err
+	}
+	return nil
+}
+func (m *MockDockerClient) ImageDigest(ctx context.Context, imageName string) (string, error) {
+	return m.Digest, nil
+}
 func (m *MockDockerClient) CreateContainer(ctx context.Context, cfg *runtime.ContainerConfig) (string, error) {
 	m.LastContainerID = "test-container-id"
+	m.LastImage = cfg.Image
 	return "test-container-id", nil
 }
-func (m *MockDockerClient) StartContainer(ctx context.Context, id string) error { return nil }
-func (m *MockDockerClient) StopContainer(ctx context.Context, id string) error { return nil }
-func (m *MockDockerClient) RemoveContainer(ctx context.Context, id string, force bool) error { return nil }
-func (m *MockDockerClient) GetContainerStatus(ctx context.Context, id string) (string, error) { return "running", nil }
+func (m *MockDockerClient) StartContainer(ctx context.Context, id string) error {
+	if m.Hang == "StartContainer" {
+		<-ctx.Done()
+		return ctx.Err()
+	}
+	return nil
+}
+func (m *MockDockerClient) StopContainer(ctx context.Context, id string, timeout time.Duration) error { return nil }
+func (m *MockDockerClient) RemoveContainer(ctx context.Context, id string, force bool) error {
+	m.RemovedContainers = append(m.RemovedContainers, id)
+	return nil
+}
+func (m *MockDockerClient) PauseContainer(ctx context.Context, id string) error  { return nil }
+func (m *MockDockerClient) ResumeContainer(ctx context.Context, id string) error { return nil }
+func (m *MockDockerClient) GetContainerStatus(ctx context.Context, id string) (string, error) {
+	if m.ContainerStatusErr != nil {
+		return "", m.ContainerStatusErr
+	}
+	if m.ContainerStatus != "" {
+		return m.ContainerStatus, nil
+	}
+	return "running", nil
+}
 func (m *MockDockerClient) GetContainerStats(ctx context.Context, id string) (*runtime.ContainerStats, error) {
 	return &runtime.ContainerStats{}, nil
 }
-func (m *MockDockerClient) GetContainerLogs(ctx context.Context, id string, tail int) (string, error) {
+func (m *MockDockerClient) GetContainerLogs(ctx context.Context, id string, tail int, since time.Time, timestamps bool) (string, error) {
+	m.LogsTail, m.LogsSince, m.LogsTimestamps = tail, since, timestamps
+	if m.LogsOutput != "" {
+		return m.LogsOutput, nil
+	}
 	return "test logs", nil
 }
-func (m *MockDockerClient) ListContainers(ctx context.Context) ([]string, error) { return []string{}, nil }
+func (m *MockDockerClient) ListContainers(ctx context.Context) ([]runtime.ContainerInfo, error) {
+	return m.Containers, nil
+}
 func (m *MockDockerClient) ListNetworks(ctx context.Context) ([]runtime.NetworkInfo, error) { return []runtime.NetworkInfo{}, nil }
 func (m *MockDockerClient) CreateNetwork(ctx context.Context, name string) (*runtime.NetworkInfo, error) {
 	return &runtime.NetworkInfo{ID: "test-net", Name: name}, nil
 }
 func (m *MockDockerClient) DeleteNetwork(ctx context.Context, id string) error { return nil }
+func (m *MockDockerClient) ConnectNetwork(ctx context.Context, id, network string) error {
+	m.NetworkOps = append(m.NetworkOps, "connect:"+network)
+	return nil
+}
+func (m *MockDockerClient) DisconnectNetwork(ctx context.Context, id, network string) error {
+	m.NetworkOps = append(m.NetworkOps, "disconnect:"+network)
+	return nil
+}
 func (m *MockDockerClient) ExecInContainer(ctx context.Context, id string, cmd []string) (string, error) { return "", nil }
-func (m *MockDockerClient) Exec(ctx context.Context, id string, cmd []string, env []string) (string, error) { return "", nil }
-func (m *MockDockerClient) ExecWithStdin(ctx context.Context, id string, cmd []string, stdin []byte, env []string) (string, error) {
+func (m *MockDockerClient) Exec(ctx context.Context, id string, cmd []string, env []string) (string, error) {
+	if m.ExecFailures > 0 {
+		m.ExecFailures--
+		return "", errors.New("connection refused")
+	}
+	m.ExecCmds = append(m.ExecCmds, cmd)
+	m.ExecEnvs = append(m.ExecEnvs, env)
+	return m.ExecOutput, nil
+}
+func (m *MockDockerClient) ExecWithStdin(ctx context.Context, id string, cmd []string, stdin io.Reader, env []string) (string, error) {
+	data, err := io.ReadAll(stdin)
+	if err != nil {
+		return "", err
+	}
 	m.LastExecCmd = cmd
-	m.LastExecInput = string(stdin)
+	m.LastExecInput = string(data)
 	return "", nil
 }
-func (m *MockDockerClient) UpdateContainerResources(ctx context.Context, id string, memoryLimit int64, cpuLimit float64) error { return nil }
-func (m *MockDockerClient) DeleteVolume(ctx context.Context, name string) error { return nil }
+func (m *MockDockerClient) ExecStream(ctx context.Context, id string, cmd []string, env []string, w io.Writer) error {
+	_, err := io.WriteString(w, m.StreamOutput)
+	return err
+}
+func (m *MockDockerClient) ExecInteractive(ctx context.Context, id string, cmd []string, stdin io.Reader, stdout io.Writer, resize <-chan runtime.TerminalSize) error {
+	m.LastExecCmd = cmd
+	return nil
+}
+func (m *MockDockerClient) UpdateContainerResources(ctx context.Context, id string, memoryLimit int64, cpuLimit float64) error {
+	m.UpdateCalls++
+	return m.UpdateResourcesErr
+}
+func (m *MockDockerClient) DeleteVolume(ctx context.Context, name string) error {
+	m.DeletedVolumes = append(m.DeletedVolumes, name)
+	return nil
+}
 
 
-func setupTestManager(t *testing.T) (*Manager, *storage.BoltStorage, func()) {
+// setupTestManager returns a manager over a fresh store and the given mock
+// runtime, or an empty mock when mockDocker is nil
+func setupTestManager(t *testing.T, mockDocker *MockDockerClient) (*Manager, *storage.BoltStorage, func()) {
 	t.Helper()
 
 	tmpDir := t.TempDir()
@@ -59,7 +187,9 @@ func setupTestManager(t *testing.T) (*Manager, *storage.BoltStorage, func()) {
 		t.Fatalf("failed to create test storage: %v", err)
 	}
 
-	mockDocker := &MockDockerClient{}
+	if mockDocker == nil {
+		mockDocker = &MockDockerClient{}
+	}
 	manager := NewManager(store, mockDocker)
 
 	cleanup := func() {
@@ -70,7 +200,7 @@ func setupTestManager(t *testing.T) (*Manager, *storage.BoltStorage, func()) {
 }
 
 func TestCreateDatabase(t *testing.T) {
-	manager, store, cleanup := setupTestManager(t)
+	manager, store, cleanup := setupTestManager(t, nil)
 	defer cleanup()
 
 	req := &CreateRequest{
@@ -111,7 +241,7 @@ func TestCreateDatabase(t *testing.T) {
 }
 
 func TestGetLogs(t *testing.T) {
-	manager, store, cleanup := setupTestManager(t)
+	manager, store, cleanup := setupTestManager(t, nil)
 	defer cleanup()
 
 	// Create a mock database in storage
@@ -127,7 +257,7 @@ func TestGetLogs(t *testing.T) {
 		t.Fatalf("failed to create database: %v", err)
 	}
 
-	logs, err := manager.GetLogs(context.Background(), "test-id")
+	logs, err := manager.GetLogs(context.Background(), "test-id", LogOptions{})
 	if err != nil {
 		t.Fatalf("failed to get logs: %v", err)
 	}
@@ -138,7 +268,7 @@ func TestGetLogs(t *testing.T) {
 }
 
 func TestGeneratePassword(t *testing.T) {
-	manager, _, cleanup := setupTestManager(t)
+	manager, _, cleanup := setupTestManager(t, nil)
 	defer cleanup()
 
 	req := &CreateRequest{
@@ -162,7 +292,7 @@ func TestGeneratePassword(t *testing.T) {
 }
 
 func TestUpdateResources(t *testing.T) {
-	manager, store, cleanup := setupTestManager(t)
+	manager, store, cleanup := setupTestManager(t, nil)
 	defer cleanup()
 
 	db := &storage.DatabaseInstance{
@@ -175,7 +305,7 @@ func TestUpdateResources(t *testing.T) {
 	}
 	store.CreateDatabase(db)
 
-	updatedDb, err := manager.UpdateResources(context.Background(), "test-update", 2048, 2.0)
+	updatedDb, _, err := manager.UpdateResources(context.Background(), "test-update", 2048, 2.0)
 	if err != nil {
 		t.Fatalf("failed to update resources: %v", err)
 	}
@@ -189,21 +319,9 @@ func TestUpdateResources(t *testing.T) {
 }
 
 func TestSeeding(t *testing.T) {
-	manager, store, cleanup := setupTestManager(t)
-	defer cleanup()
-	
-	// Access the mock client to check calls
-	// We need to verify that we are using the same instance as Manager
-	// The setupTestManager creates a new MockDockerClient locally but copies it by value? 
-	// No, it passes pointer &MockDockerClient{}. But we need to keep a reference.
-	// We need to modify setupTestManager to return the mock client too.
-	
-	// Re-implement setup here to get handle on mock
-	tmpDir := t.TempDir()
-	store, _ = storage.NewBoltStorage(tmpDir+"/test.db", tmpDir)
 	mockDocker := &MockDockerClient{}
-	manager = NewManager(store, mockDocker)
-	defer store.Close()
+	manager, _, cleanup := setupTestManager(t, mockDocker)
+	defer cleanup()
 
 	db := &storage.DatabaseInstance{
 		ID:          "seed-test-id",
@@ -219,7 +337,7 @@ func TestSeeding(t *testing.T) {
 	
 	// Executing applySeed directly (it's unexported but we are in package database)
 	// It should succeed immediately because MockDockerClient.Exec returns nil error
-	manager.applySeed(db, "text", seedContent)
+	manager.applySeed(context.Background(), db, "text", seedContent)
 
 	if mockDocker.LastExecInput != seedContent {
 		t.Errorf("expected seed content '%s', got '%s'", seedContent, mockDocker.LastExecInput)
@@ -254,7 +372,7 @@ func TestEngineCLICommands(t *testing.T) {
 			continue
 		}
 		
-		cmd := e.CLICommand("u", "p", "d")
+		cmd := e.CLICommand(&storage.DatabaseInstance{Username: "u", Password: "p", Database: "d"})
 		
 		if len(cmd) != len(tc.expect) {
 			t.Errorf("[%s] expected len %d, got %d: %v", tc.engine, len(tc.expect), len(cmd), cmd)
@@ -268,3 +386,2233 @@ func TestEngineCLICommands(t *testing.T) {
 		}
 	}
 }
+
+func TestEngineShellCommands(t *testing.T) {
+	tests := []struct {
+		engine string
+		expect []string
+	}{
+		{"postgresql", []string{"psql", "-U", "u", "-d", "d"}},
+		{"mysql", []string{"mysql", "-u", "u", "-pp", "d"}},
+		{"mariadb", []string{"mariadb", "-u", "u", "-pp", "d"}},
+		{"redis", []string{"redis-cli", "-a", "p", "--no-auth-warning"}},
+	}
+
+	for _, tc := range tests {
+		e, err := GetEngine(tc.engine)
+		if err != nil {
+			t.Errorf("failed to get engine %s: %v", tc.engine, err)
+			continue
+		}
+
+		cmd := e.ShellCommand(&storage.DatabaseInstance{Username: "u", Password: "p", Database: "d"})
+		if len(cmd) != len(tc.expect) {
+			t.Errorf("[%s] expected len %d, got %d: %v", tc.engine, len(tc.expect), len(cmd), cmd)
+			continue
+		}
+		for i := range cmd {
+			if cmd[i] != tc.expect[i] {
+				t.Errorf("[%s] arg %d: expected %s, got %s", tc.engine, i, tc.expect[i], cmd[i])
+			}
+		}
+	}
+}
+
+func TestImportGzipDump(t *testing.T) {
+	mockDocker := &MockDockerClient{}
+	manager, store, cleanup := setupTestManager(t, mockDocker)
+	defer cleanup()
+
+	db := &storage.DatabaseInstance{
+		ID:          "import-test-id",
+		Name:        "import-test-db",
+		Engine:      "postgresql",
+		Username:    "testuser",
+		Database:    "testdb",
+		ContainerID: "test-container-id",
+		Status:      "running",
+	}
+	if err := store.CreateDatabase(db); err != nil {
+		t.Fatalf("failed to create database: %v", err)
+	}
+
+	dump := "CREATE TABLE t (id int); INSERT INTO t VALUES (1);"
+
+	// Plain SQL is passed through untouched
+	result, err := manager.Import(context.Background(), db.ID, strings.NewReader(dump))
+	if err != nil {
+		t.Fatalf("plain import failed: %v", err)
+	}
+	if result.Compressed || mockDocker.LastExecInput != dump {
+		t.Errorf("unexpected plain import: compressed=%v input=%q", result.Compressed, mockDocker.LastExecInput)
+	}
+
+	// Gzip is detected and decompressed on the fly
+	var buf bytes.Buffer
+	gz := gzip.NewWriter(&buf)
+	gz.Write([]byte(dump))
+	gz.Close()
+
+	result, err = manager.Import(context.Background(), db.ID, &buf)
+	if err != nil {
+		t.Fatalf("gzip import failed: %v", err)
+	}
+	if !result.Compressed {
+		t.Error("expected gzip dump to be detected")
+	}
+	if mockDocker.LastExecInput != dump {
+		t.Errorf("expected decompressed input %q, got %q", dump, mockDocker.LastExecInput)
+	}
+	if result.Bytes != int64(len(dump)) {
+		t.Errorf("expected %d bytes imported, got %d", len(dump), result.Bytes)
+	}
+}
+
+func TestEngineExportCommands(t *testing.T) {
+	db := &storage.DatabaseInstance{Username: "u", Password: "p", Database: "d"}
+
+	tests := []struct {
+		engine string
+		expect []string
+	}{
+		{"postgresql", []string{"pg_dump", "-U", "u", "-d", "d"}},
+		{"mysql", []string{"mysqldump", "-u", "u", "-pp", "d"}},
+		{"mariadb", []string{"mariadb-dump", "-u", "u", "-pp", "d"}},
+		{"redis", nil},
+	}
+
+	for _, tc := range tests {
+		e, err := GetEngine(tc.engine)
+		if err != nil {
+			t.Errorf("failed to get engine %s: %v", tc.engine, err)
+			continue
+		}
+
+		cmd, _ := e.ExportCommand(db)
+		if len(cmd) != len(tc.expect) {
+			t.Errorf("[%s] expected %v, got %v", tc.engine, tc.expect, cmd)
+			continue
+		}
+		for i := range cmd {
+			if cmd[i] != tc.expect[i] {
+				t.Errorf("[%s] arg %d: expected %s, got %s", tc.engine, i, tc.expect[i], cmd[i])
+			}
+		}
+	}
+}
+
+func TestResolveImage(t *testing.T) {
+	engine, _ := GetEngine("postgresql")
+
+	tests := []struct {
+		image   string
+		version string
+		expect  string
+	}{
+		{"", "16", "postgres:16"},
+		{"", "", "postgres"},
+		{"timescale/timescaledb", "latest-pg16", "timescale/timescaledb:latest-pg16"},
+		{"timescale/timescaledb:2.14.2-pg16", "16", "timescale/timescaledb:2.14.2-pg16"},
+		{"registry.example.com:5000/team/postgres", "16", "registry.example.com:5000/team/postgres:16"},
+	}
+
+	for _, tc := range tests {
+		if got := resolveImage(engine, tc.image, tc.version, ""); got != tc.expect {
+			t.Errorf("resolveImage(%q, %q): expected %s, got %s", tc.image, tc.version, tc.expect, got)
+		}
+	}
+
+	for _, bad := range []string{"Postgres", "postgres:", "-postgres", "postgres; rm -rf /", "a//b"} {
+		if err := ValidateImage(bad); err == nil {
+			t.Errorf("expected %q to be rejected", bad)
+		}
+	}
+	if err := ValidateImage("registry.example.com:5000/team/postgres:16"); err != nil {
+		t.Errorf("expected valid image reference: %v", err)
+	}
+}
+
+func TestMetricsHistoryPersistence(t *testing.T) {
+	manager, _, cleanup := setupTestManager(t, nil)
+	defer cleanup()
+
+	manager.SetOptions(Options{MetricsInterval: time.Minute, MetricsRetention: time.Hour})
+
+	now := time.Now()
+	for _, ts := range []time.Time{
+		now.Add(-3 * time.Hour),
+		now.Add(-2 * time.Hour),
+		now.Add(-30 * time.Second),
+		now.Add(-10 * time.Second), // within MetricsInterval of the previous point, dropped
+	} {
+		manager.RecordMetrics("db-1", MetricsPoint{Timestamp: ts, CPUPercent: 1})
+	}
+
+	if got := len(manager.GetMetricsHistory("db-1", now.Add(-24*time.Hour))); got != 3 {
+		t.Fatalf("expected 3 stored points, got %d", got)
+	}
+	if got := len(manager.GetMetricsHistory("db-1", now.Add(-time.Hour))); got != 1 {
+		t.Errorf("expected 1 point in the last hour, got %d", got)
+	}
+
+	purged, err := manager.PurgeMetrics()
+	if err != nil {
+		t.Fatalf("purge failed: %v", err)
+	}
+	if purged != 2 {
+		t.Errorf("expected 2 points purged, got %d", purged)
+	}
+	if got := len(manager.GetMetricsHistory("db-1", now.Add(-24*time.Hour))); got != 1 {
+		t.Errorf("expected 1 point after purge, got %d", got)
+	}
+}
+
+func TestUpdateResourcesAppliesToRunningContainer(t *testing.T) {
+	mockDocker := &MockDockerClient{}
+	manager, store, cleanup := setupTestManager(t, mockDocker)
+	defer cleanup()
+
+	db := &storage.DatabaseInstance{
+		ID:          "test-live-update",
+		Name:        "test-live-update-db",
+		Engine:      "postgresql",
+		Status:      "running",
+		MemoryLimit: 1024,
+		CPULimit:    1.0,
+		ContainerID: "test-container-id",
+	}
+	store.CreateDatabase(db)
+
+	// Runtime failure: nothing is persisted
+	mockDocker.UpdateResourcesErr = errors.New("update failed")
+	if _, _, err := manager.UpdateResources(context.Background(), db.ID, 2048, 2.0); err == nil {
+		t.Fatal("expected error when runtime update fails")
+	}
+	stored, _ := store.GetDatabase(db.ID)
+	if stored.MemoryLimit != 1024 || stored.CPULimit != 1.0 {
+		t.Errorf("limits persisted despite runtime failure: %d, %f", stored.MemoryLimit, stored.CPULimit)
+	}
+
+	// Restart required: limits are persisted and flagged
+	mockDocker.UpdateResourcesErr = runtime.ErrRestartRequired
+	_, restartRequired, err := manager.UpdateResources(context.Background(), db.ID, 2048, 2.0)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !restartRequired {
+		t.Error("expected restartRequired to be reported")
+	}
+	stored, _ = store.GetDatabase(db.ID)
+	if stored.MemoryLimit != 2048 {
+		t.Errorf("expected memory limit 2048, got %d", stored.MemoryLimit)
+	}
+
+	if mockDocker.UpdateCalls != 2 {
+		t.Errorf("expected 2 runtime update calls, got %d", mockDocker.UpdateCalls)
+	}
+}
+
+func TestStatusChangeWebhook(t *testing.T) {
+	mockDocker := &MockDockerClient{}
+	manager, store, cleanup := setupTestManager(t, mockDocker)
+	defer cleanup()
+
+	received := make(chan notify.Event, 1)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var event notify.Event
+		json.NewDecoder(r.Body).Decode(&event)
+		received <- event
+	}))
+	defer srv.Close()
+
+	if _, err := manager.Notifier().AddWebhook(&notify.Webhook{URL: srv.URL}); err != nil {
+		t.Fatalf("failed to add webhook: %v", err)
+	}
+
+	// Mock runtime reports "running", so a stopped record transitions
+	store.CreateDatabase(&storage.DatabaseInstance{
+		ID:          "test-webhook",
+		Name:        "webhook-db",
+		Engine:      "postgresql",
+		Status:      "stopped",
+		ContainerID: "test-container-id",
+	})
+	manager.SyncAllStatuses(context.Background())
+
+	select {
+	case event := <-received:
+		if event.Type != notify.EventStatusChanged || event.DatabaseID != "test-webhook" {
+			t.Errorf("unexpected event: %+v", event)
+		}
+		if event.OldStatus != "stopped" || event.NewStatus != "running" {
+			t.Errorf("expected stopped -> running, got %s -> %s", event.OldStatus, event.NewStatus)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("webhook was not delivered")
+	}
+
+	// A container that can't be queried reports the stored status it left,
+	// not an assumed "running"
+	db, _ := store.GetDatabase("test-webhook")
+	db.Status = "unhealthy"
+	store.UpdateDatabase(db)
+	mockDocker.ContainerStatusErr = errors.New("no such container")
+	manager.SyncAllStatuses(context.Background())
+
+	select {
+	case event := <-received:
+		if event.OldStatus != "unhealthy" || event.NewStatus != "error" {
+			t.Errorf("expected unhealthy -> error, got %s -> %s", event.OldStatus, event.NewStatus)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("webhook was not delivered")
+	}
+}
+
+func TestSyncAllStatusesStopsWhenCancelled(t *testing.T) {
+	manager, store, cleanup := setupTestManager(t, nil)
+	defer cleanup()
+
+	// Mock runtime reports "running", so a sync would change this record
+	store.CreateDatabase(&storage.DatabaseInstance{
+		ID:          "test-cancelled-sync",
+		Name:        "cancelled-sync-db",
+		Engine:      "postgresql",
+		Status:      "stopped",
+		ContainerID: "test-container-id",
+	})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	manager.SyncAllStatuses(ctx)
+
+	if db, _ := store.GetDatabase("test-cancelled-sync"); db.Status != "stopped" {
+		t.Errorf("expected a cancelled sync to leave status stopped, got %s", db.Status)
+	}
+}
+
+func TestApplyRetention(t *testing.T) {
+	manager, store, cleanup := setupTestManager(t, nil)
+	defer cleanup()
+
+	store.CreateDatabase(&storage.DatabaseInstance{
+		ID:                   "test-retention",
+		Name:                 "retention-db",
+		Engine:               "postgresql",
+		BackupRetentionCount: 2,
+	})
+
+	base := time.Now()
+	for i := 0; i < 4; i++ {
+		store.CreateBackup(&storage.Backup{
+			ID:         fmt.Sprintf("bk-%d", i),
+			DatabaseID: "test-retention",
+			CreatedAt:  base.Add(time.Duration(i) * time.Minute),
+			Status:     "completed",
+		})
+	}
+	// Unfinished and failed backups neither count nor get deleted
+	store.CreateBackup(&storage.Backup{ID: "bk-running", DatabaseID: "test-retention", CreatedAt: base.Add(time.Hour), Status: "in-progress"})
+	store.CreateBackup(&storage.Backup{ID: "bk-failed", DatabaseID: "test-retention", CreatedAt: base.Add(-time.Hour), Status: "failed"})
+
+	deleted, err := manager.ApplyRetention("test-retention")
+	if err != nil {
+		t.Fatalf("ApplyRetention failed: %v", err)
+	}
+	if deleted != 2 {
+		t.Errorf("expected 2 backups deleted, got %d", deleted)
+	}
+
+	remaining := map[string]bool{}
+	for _, b := range store.ListBackups("test-retention") {
+		remaining[b.ID] = true
+	}
+	if len(remaining) != 4 || !remaining["bk-2"] || !remaining["bk-3"] || !remaining["bk-running"] || !remaining["bk-failed"] {
+		t.Errorf("expected the two newest completed backups and the unfinished ones to remain, got %v", remaining)
+	}
+}
+
+func TestRetentionAfterBackupCompletes(t *testing.T) {
+	mockDocker := &MockDockerClient{}
+	manager, store, cleanup := setupTestManager(t, mockDocker)
+	defer cleanup()
+
+	opts := DefaultOptions()
+	opts.BackupDir = t.TempDir()
+	manager.SetOptions(opts)
+
+	store.CreateDatabase(&storage.DatabaseInstance{ID: "db-ret", Name: "ret", Engine: "postgresql", Status: "running", ContainerID: "c-ret", BackupRetentionCount: 1})
+	store.CreateBackup(&storage.Backup{ID: "bk-old", DatabaseID: "db-ret", CreatedAt: time.Now().Add(-time.Hour), Status: "completed"})
+
+	wait := func(backup *storage.Backup) *storage.Backup {
+		t.Helper()
+		deadline := time.Now().Add(2 * time.Second)
+		for {
+			if stored, _ := store.GetBackup(backup.ID); stored.Status != "in-progress" {
+				return stored
+			}
+			if time.Now().After(deadline) {
+				t.Fatal("backup did not finish")
+			}
+			time.Sleep(10 * time.Millisecond)
+		}
+	}
+
+	// A failed backup leaves the last good one alone
+	mockDocker.ExecFailures = 10
+	backup, err := manager.CreateBackup(context.Background(), "db-ret", nil)
+	if err != nil {
+		t.Fatalf("CreateBackup failed: %v", err)
+	}
+	if stored := wait(backup); stored.Status != "failed" {
+		t.Fatalf("expected backup to fail, got %s", stored.Status)
+	}
+	if _, err := store.GetBackup("bk-old"); err != nil {
+		t.Error("expected a failed backup not to remove the previous one")
+	}
+
+	// A completed backup replaces it
+	mockDocker.ExecFailures = 0
+	backup, err = manager.CreateBackup(context.Background(), "db-ret", nil)
+	if err != nil {
+		t.Fatalf("CreateBackup failed: %v", err)
+	}
+	if stored := wait(backup); stored.Status != "completed" {
+		t.Fatalf("expected backup to complete, got %s: %s", stored.Status, stored.Error)
+	}
+	deadline := time.Now().Add(2 * time.Second)
+	for {
+		if _, err := store.GetBackup("bk-old"); err != nil {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatal("expected retention to remove the previous backup once the new one completed")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+func TestReconcile(t *testing.T) {
+	mockDocker := &MockDockerClient{
+		Containers: []runtime.ContainerInfo{
+			{ID: "c-live", Labels: map[string]string{"dbnest.managed": "true", "dbnest.id": "db-live"}},
+			{ID: "c-stale", Labels: map[string]string{"dbnest.managed": "true", "dbnest.id": "db-live"}},
+			{ID: "c-gone", Labels: map[string]string{"dbnest.managed": "true", "dbnest.id": "db-gone"}},
+			// Created by provisioning that hasn't recorded them yet
+			{ID: "c-new", Labels: map[string]string{"dbnest.managed": "true", "dbnest.id": "db-new"}},
+			{ID: "c-queued", Labels: map[string]string{"dbnest.managed": "true", "dbnest.id": "db-queued"}},
+		},
+	}
+	manager, store, cleanup := setupTestManager(t, mockDocker)
+	defer cleanup()
+
+	store.CreateDatabase(&storage.DatabaseInstance{ID: "db-live", Name: "live", Status: "running", ContainerID: "c-live"})
+	store.CreateDatabase(&storage.DatabaseInstance{ID: "db-lost", Name: "lost", Status: "running", ContainerID: "c-lost"})
+	store.CreateDatabase(&storage.DatabaseInstance{ID: "db-new", Name: "new", Status: "creating"})
+	store.CreateDatabase(&storage.DatabaseInstance{ID: "db-queued", Name: "queued", Status: "queued"})
+
+	report, err := manager.Reconcile(context.Background())
+	if err != nil {
+		t.Fatalf("Reconcile failed: %v", err)
+	}
+	if len(report.OrphanedContainers) != 2 {
+		t.Fatalf("expected 2 orphaned containers, got %+v", report.OrphanedContainers)
+	}
+	if len(report.MissingContainers) != 1 || report.MissingContainers[0].DatabaseID != "db-lost" {
+		t.Errorf("expected db-lost to be missing its container, got %+v", report.MissingContainers)
+	}
+
+	result, err := manager.CleanupOrphans(context.Background())
+	if err != nil {
+		t.Fatalf("CleanupOrphans failed: %v", err)
+	}
+	if len(result.RemovedContainers) != 2 {
+		t.Errorf("expected 2 containers removed, got %v", result.RemovedContainers)
+	}
+	// The stale container's database still exists, so only the deleted database's volume goes
+	if len(mockDocker.DeletedVolumes) != 1 || mockDocker.DeletedVolumes[0] != "dbnest-vol-db-gone" {
+		t.Errorf("unexpected volumes removed: %v", mockDocker.DeletedVolumes)
+	}
+}
+
+func TestCreateWithHostDataPath(t *testing.T) {
+	manager, _, cleanup := setupTestManager(t, nil)
+	defer cleanup()
+
+	root, err := filepath.EvalSymlinks(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	hostDir := filepath.Join(root, "a")
+	for _, dir := range []string{hostDir + "/nested", root + "/b"} {
+		if err := os.MkdirAll(dir, 0755); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := os.Symlink(hostDir, root+"/link"); err != nil {
+		t.Fatal(err)
+	}
+
+	req := &CreateRequest{
+		Name:         "bind-db",
+		Engine:       "postgresql",
+		Version:      "16",
+		Username:     "user",
+		Database:     "app",
+		HostDataPath: hostDir,
+	}
+	// Host data paths are disabled until a root is configured
+	if _, err := manager.Create(context.Background(), req); err == nil {
+		t.Fatal("expected error without a host data root")
+	}
+	opts := DefaultOptions()
+	opts.HostDataRoot = root
+	manager.SetOptions(opts)
+
+	db, err := manager.Create(context.Background(), req)
+	if err != nil {
+		t.Fatalf("Create failed: %v", err)
+	}
+	if dataVolume(db) != hostDir {
+		t.Errorf("expected bind mount source %s, got %s", hostDir, dataVolume(db))
+	}
+
+	// The same directory, or one overlapping it, cannot back two databases
+	req.Name = "bind-db-2"
+	for _, path := range []string{hostDir, hostDir + "/", hostDir + "/nested", root + "/link"} {
+		req.HostDataPath = path
+		if _, err := manager.Create(context.Background(), req); err == nil {
+			t.Errorf("expected error reusing host data path %q", path)
+		}
+	}
+
+	for _, path := range []string{"relative/path", hostDir + "/missing", root, "/etc", root + "/../"} {
+		req.HostDataPath = path
+		if _, err := manager.Create(context.Background(), req); err == nil {
+			t.Errorf("expected error for host data path %q", path)
+		}
+	}
+
+	req.HostDataPath = root + "/b"
+	if _, err := manager.Create(context.Background(), req); err != nil {
+		t.Errorf("expected a separate directory to be accepted: %v", err)
+	}
+}
+
+func TestCreateAppliesDefaultLimits(t *testing.T) {
+	manager, _, cleanup := setupTestManager(t, nil)
+	defer cleanup()
+
+	opts := DefaultOptions()
+	opts.DefaultMemoryLimit = 512
+	opts.DefaultCPULimit = 0.5
+	opts.DefaultStorageLimit = 2048
+	manager.SetOptions(opts)
+
+	db, err := manager.Create(context.Background(), &CreateRequest{
+		Name:     "defaults-db",
+		Engine:   "postgresql",
+		Version:  "16",
+		Username: "user",
+		Database: "app",
+	})
+	if err != nil {
+		t.Fatalf("Create failed: %v", err)
+	}
+	if db.MemoryLimit != 512*1024*1024 || db.StorageLimit != 2048*1024*1024 || db.CPULimit != 0.5 {
+		t.Errorf("defaults not applied: memory=%d storage=%d cpu=%f", db.MemoryLimit, db.StorageLimit, db.CPULimit)
+	}
+
+	// Explicit limits win over defaults
+	db, err = manager.Create(context.Background(), &CreateRequest{
+		Name:        "explicit-db",
+		Engine:      "postgresql",
+		Version:     "16",
+		Username:    "user",
+		Database:    "app",
+		MemoryLimit: 256,
+	})
+	if err != nil {
+		t.Fatalf("Create failed: %v", err)
+	}
+	if db.MemoryLimit != 256*1024*1024 {
+		t.Errorf("expected explicit memory limit, got %d", db.MemoryLimit)
+	}
+}
+
+func TestCreateRejectsMemoryOvercommit(t *testing.T) {
+	manager, _, cleanup := setupTestManager(t, nil)
+	defer cleanup()
+
+	opts := DefaultOptions()
+	opts.HostMemoryLimit = 4096
+	opts.MemoryOvercommitRatio = 1.5 // 6144 MB total
+	manager.SetOptions(opts)
+
+	create := func(name string, memoryMB int64) error {
+		_, err := manager.Create(context.Background(), &CreateRequest{
+			Name:        name,
+			Engine:      "postgresql",
+			Version:     "16",
+			Username:    "user",
+			Database:    "app",
+			MemoryLimit: memoryMB,
+		})
+		return err
+	}
+
+	if err := create("too-big", 8192); !errors.Is(err, ErrInsufficientResources) {
+		t.Errorf("expected ErrInsufficientResources for limit above host memory, got %v", err)
+	}
+	if err := create("first", 4096); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if err := create("second", 2048); err != nil {
+		t.Fatalf("unexpected error within overcommit ratio: %v", err)
+	}
+	if err := create("third", 1); !errors.Is(err, ErrInsufficientResources) {
+		t.Errorf("expected ErrInsufficientResources beyond overcommit ratio, got %v", err)
+	}
+}
+
+func TestMergeExtraEnv(t *testing.T) {
+	engine, _ := GetEngine("postgresql")
+	db := &storage.DatabaseInstance{
+		ID:       "test-env",
+		Username: "user",
+		Password: "secret",
+		Database: "app",
+		ExtraEnv: map[string]string{
+			"POSTGRES_PASSWORD":    "override",
+			"POSTGRES_INITDB_ARGS": "--data-checksums",
+		},
+	}
+
+	env := containerConfig(db, engine, "postgres:16").Env
+	var sawArgs bool
+	for _, kv := range env {
+		if kv == "POSTGRES_PASSWORD=override" {
+			t.Error("extra env must not override engine-managed credentials")
+		}
+		if kv == "POSTGRES_INITDB_ARGS=--data-checksums" {
+			sawArgs = true
+		}
+	}
+	if !sawArgs {
+		t.Errorf("expected extra env var in %v", env)
+	}
+
+	if err := ValidateExtraEnv(map[string]string{"BAD-NAME": "x"}); err == nil {
+		t.Error("expected error for invalid env var name")
+	}
+}
+
+func TestParseRestartPolicy(t *testing.T) {
+	tests := []struct {
+		policy  string
+		mode    string
+		retries int
+		wantErr bool
+	}{
+		{"", "unless-stopped", 0, false},
+		{"no", "no", 0, false},
+		{"always", "always", 0, false},
+		{"on-failure", "on-failure", 0, false},
+		{"on-failure:5", "on-failure", 5, false},
+		{"on-failure:x", "", 0, true},
+		{"always:3", "", 0, true},
+		{"sometimes", "", 0, true},
+	}
+
+	for _, tt := range tests {
+		mode, retries, err := runtime.ParseRestartPolicy(tt.policy)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("%q: unexpected error result: %v", tt.policy, err)
+			continue
+		}
+		if mode != tt.mode || retries != tt.retries {
+			t.Errorf("%q: expected %s/%d, got %s/%d", tt.policy, tt.mode, tt.retries, mode, retries)
+		}
+	}
+}
+
+func TestWaitForReady(t *testing.T) {
+	mockDocker := &MockDockerClient{ExecFailures: 2}
+	manager, store, cleanup := setupTestManager(t, mockDocker)
+	defer cleanup()
+
+	db := &storage.DatabaseInstance{
+		ID:          "test-ready",
+		Name:        "ready-db",
+		Engine:      "postgresql",
+		Status:      "running",
+		ContainerID: "test-container-id",
+	}
+	store.CreateDatabase(db)
+
+	// Ready after two failed probes (250ms + 500ms backoff)
+	start := time.Now()
+	if err := manager.WaitForReady(context.Background(), db, 10*time.Second); err != nil {
+		t.Fatalf("expected database to become ready: %v", err)
+	}
+	if elapsed := time.Since(start); elapsed > 3*time.Second {
+		t.Errorf("WaitForReady took too long: %s", elapsed)
+	}
+
+	// Never ready: times out
+	mockDocker.ExecFailures = 1000
+	if err := manager.WaitForReady(context.Background(), db, 500*time.Millisecond); err == nil {
+		t.Error("expected timeout error")
+	}
+
+	// Failed provisioning is reported immediately
+	db.Status = "error"
+	db.ErrorMessage = "pull failed"
+	store.UpdateDatabase(db)
+	start = time.Now()
+	if err := manager.WaitForReady(context.Background(), db, 10*time.Second); err == nil {
+		t.Error("expected error for failed database")
+	}
+	if time.Since(start) > time.Second {
+		t.Error("expected failed database to be reported without waiting")
+	}
+}
+
+func TestRedisRestore(t *testing.T) {
+	mockDocker := &MockDockerClient{}
+	manager, store, cleanup := setupTestManager(t, mockDocker)
+	defer cleanup()
+
+	backupFile := filepath.Join(t.TempDir(), "cache.rdb")
+	if err := os.WriteFile(backupFile, []byte("REDIS0011snapshot"), 0644); err != nil {
+		t.Fatalf("failed to write backup file: %v", err)
+	}
+	store.CreateDatabase(&storage.DatabaseInstance{ID: "db-redis", Name: "cache", Engine: "redis", Status: "running", ContainerID: "c-redis"})
+	store.CreateBackup(&storage.Backup{ID: "bk-redis", DatabaseID: "db-redis", Status: "completed", FilePath: backupFile})
+
+	if err := manager.RestoreBackup(context.Background(), "bk-redis", "db-redis", nil); err != nil {
+		t.Fatalf("RestoreBackup failed: %v", err)
+	}
+	if mockDocker.LastExecInput != "REDIS0011snapshot" {
+		t.Errorf("expected snapshot to be copied into the container, got %q", mockDocker.LastExecInput)
+	}
+	if got := strings.Join(mockDocker.LastExecCmd, " "); !strings.Contains(got, "/data/dump.rdb.restore") {
+		t.Errorf("expected snapshot to be staged in the data directory, got %q", got)
+	}
+}
+
+func TestRedisBackupMode(t *testing.T) {
+	manager, _, cleanup := setupTestManager(t, nil)
+	defer cleanup()
+
+	cfg, err := manager.Plan(&CreateRequest{Name: "cache", Engine: "redis", Version: "7", Password: "secret", RedisBackupMode: RedisBackupAOF})
+	if err != nil {
+		t.Fatalf("Plan failed: %v", err)
+	}
+	if got := strings.Join(cfg.Cmd, " "); got != "redis-server --requirepass secret --appendonly yes" {
+		t.Errorf("unexpected container command: %q", got)
+	}
+
+	if _, err := manager.Plan(&CreateRequest{Name: "cache2", Engine: "redis", Version: "7", RedisBackupMode: "snapshot"}); err == nil {
+		t.Error("expected error for unknown backup mode")
+	}
+	if _, err := manager.Plan(&CreateRequest{Name: "pg", Engine: "postgresql", Version: "16", RedisBackupMode: RedisBackupAOF}); err == nil {
+		t.Error("expected error for backup mode on a non-Redis engine")
+	}
+}
+
+func TestRedisDatabaseIndex(t *testing.T) {
+	manager, _, cleanup := setupTestManager(t, nil)
+	defer cleanup()
+
+	engine, _ := GetEngine("redis")
+	db := &storage.DatabaseInstance{Password: "p", Host: "localhost", Port: 6379, RedisDB: 3}
+
+	if got := strings.Join(engine.ShellCommand(db), " "); got != "redis-cli -a p --no-auth-warning -n 3" {
+		t.Errorf("unexpected shell command: %q", got)
+	}
+	if got := engine.ConnectionStrings(db).URI; got != "redis://:<password>@localhost:6379/3" {
+		t.Errorf("unexpected URI: %q", got)
+	}
+
+	if _, err := manager.Plan(&CreateRequest{Name: "cache", Engine: "redis", Version: "7", RedisDB: 16}); err == nil {
+		t.Error("expected error for out-of-range database index")
+	}
+	if _, err := manager.Plan(&CreateRequest{Name: "pg", Engine: "postgresql", Version: "16", RedisDB: 1}); err == nil {
+		t.Error("expected error for database index on a non-Redis engine")
+	}
+}
+
+func TestSetNetwork(t *testing.T) {
+	mockDocker := &MockDockerClient{}
+	manager, store, cleanup := setupTestManager(t, mockDocker)
+	defer cleanup()
+	store.CreateDatabase(&storage.DatabaseInstance{ID: "db-net", Name: "net", Status: "running", ContainerID: "c-net"})
+
+	db, err := manager.SetNetwork(context.Background(), "db-net", "dbnest-shared")
+	if err != nil {
+		t.Fatalf("SetNetwork failed: %v", err)
+	}
+	if db.Network != "dbnest-shared" {
+		t.Errorf("expected network to be updated, got %q", db.Network)
+	}
+	// Attach before detach so the container always has a network
+	if got := strings.Join(mockDocker.NetworkOps, ","); got != "connect:dbnest-shared,disconnect:" {
+		t.Errorf("unexpected network operations: %s", got)
+	}
+
+	mockDocker.NetworkOps = nil
+	if _, err := manager.SetNetwork(context.Background(), "db-net", "dbnest-shared"); err != nil {
+		t.Fatalf("SetNetwork failed: %v", err)
+	}
+	if len(mockDocker.NetworkOps) != 0 {
+		t.Errorf("expected no runtime calls when the network is unchanged, got %v", mockDocker.NetworkOps)
+	}
+	if stored, _ := store.GetDatabase("db-net"); stored.Network != "dbnest-shared" {
+		t.Errorf("expected stored network to be updated, got %q", stored.Network)
+	}
+}
+
+func TestContainerLabels(t *testing.T) {
+	manager, _, cleanup := setupTestManager(t, nil)
+	defer cleanup()
+
+	cfg, err := manager.Plan(&CreateRequest{
+		Name:    "routed",
+		Engine:  "postgresql",
+		Version: "16",
+		Labels:  map[string]string{"traefik.enable": "true", "prometheus.io/scrape": "true"},
+	})
+	if err != nil {
+		t.Fatalf("Plan failed: %v", err)
+	}
+	if cfg.Labels["traefik.enable"] != "true" || cfg.Labels["prometheus.io/scrape"] != "true" {
+		t.Errorf("expected user labels to be passed through, got %v", cfg.Labels)
+	}
+	if cfg.Labels["dbnest.managed"] != "true" {
+		t.Errorf("expected reserved labels to be kept, got %v", cfg.Labels)
+	}
+
+	for _, labels := range []map[string]string{
+		{"dbnest.id": "spoofed"},
+		{"bad key": "x"},
+	} {
+		if _, err := manager.Plan(&CreateRequest{Name: "bad", Engine: "postgresql", Version: "16", Labels: labels}); err == nil {
+			t.Errorf("expected labels %v to be rejected", labels)
+		}
+	}
+}
+
+func TestSyncStatusOOMKilled(t *testing.T) {
+	mockDocker := &MockDockerClient{ContainerStatus: "oom-killed"}
+	manager, store, cleanup := setupTestManager(t, mockDocker)
+	defer cleanup()
+	store.CreateDatabase(&storage.DatabaseInstance{ID: "db-oom", Name: "oom", Status: "running", ContainerID: "c-oom"})
+
+	manager.SyncAllStatuses(context.Background())
+
+	db, _ := store.GetDatabase("db-oom")
+	if db.Status != "oom-killed" {
+		t.Errorf("expected status oom-killed, got %q", db.Status)
+	}
+	if db.ErrorMessage != oomKilledMessage {
+		t.Errorf("expected actionable error message, got %q", db.ErrorMessage)
+	}
+
+	// Restarting clears the error
+	mockDocker.ContainerStatus = "running"
+	manager.SyncAllStatuses(context.Background())
+	if db, _ := store.GetDatabase("db-oom"); db.Status != "running" || db.ErrorMessage != "" {
+		t.Errorf("expected recovered database, got status %q error %q", db.Status, db.ErrorMessage)
+	}
+}
+
+func TestPullImageRetries(t *testing.T) {
+	mockDocker := &MockDockerClient{}
+	manager, store, cleanup := setupTestManager(t, mockDocker)
+	defer cleanup()
+	opts := DefaultOptions()
+	opts.PullAttempts = 3
+	opts.PullBackoff = time.Millisecond
+	manager.SetOptions(opts)
+
+	db := &storage.DatabaseInstance{ID: "db-pull", Name: "pull", Status: "creating"}
+	store.CreateDatabase(db)
+
+	// Transient failures are retried until the pull succeeds
+	mockDocker.PullErrors = []error{errors.New("connection reset by peer"), errors.New("i/o timeout")}
+	if err := manager.pullImage(context.Background(), db, "postgres:16"); err != nil {
+		t.Fatalf("expected pull to succeed after retries, got %v", err)
+	}
+	if mockDocker.PullCalls != 3 {
+		t.Errorf("expected 3 pull attempts, got %d", mockDocker.PullCalls)
+	}
+	if db.ErrorMessage != "" {
+		t.Errorf("expected retry message to be cleared, got %q", db.ErrorMessage)
+	}
+
+	// Attempts are capped
+	mockDocker.PullCalls = 0
+	mockDocker.PullErrors = []error{errors.New("timeout"), errors.New("timeout"), errors.New("timeout"), errors.New("timeout")}
+	if err := manager.pullImage(context.Background(), db, "postgres:16"); err == nil {
+		t.Fatal("expected pull to fail after max attempts")
+	}
+	if mockDocker.PullCalls != 3 {
+		t.Errorf("expected 3 pull attempts, got %d", mockDocker.PullCalls)
+	}
+
+	// A missing image is not retried
+	mockDocker.PullCalls = 0
+	mockDocker.PullErrors = []error{errors.New("manifest unknown: manifest unknown")}
+	if err := manager.pullImage(context.Background(), db, "postgres:nope"); err == nil {
+		t.Fatal("expected pull of a missing image to fail")
+	}
+	if mockDocker.PullCalls != 1 {
+		t.Errorf("expected permanent errors not to be retried, got %d attempts", mockDocker.PullCalls)
+	}
+}
+
+func TestBackupDir(t *testing.T) {
+	manager, store, cleanup := setupTestManager(t, nil)
+	defer cleanup()
+
+	backupDir := filepath.Join(t.TempDir(), "offsite")
+	opts := DefaultOptions()
+	opts.BackupDir = backupDir
+	manager.SetOptions(opts)
+
+	store.CreateDatabase(&storage.DatabaseInstance{ID: "db-bk", Name: "bk", Engine: "postgresql", Status: "running", ContainerID: "c-bk"})
+	backup, err := manager.CreateBackup(context.Background(), "db-bk", nil)
+	if err != nil {
+		t.Fatalf("CreateBackup failed: %v", err)
+	}
+
+	deadline := time.Now().Add(2 * time.Second)
+	for {
+		stored, _ := store.GetBackup(backup.ID)
+		if stored.Status != "in-progress" {
+			if filepath.Dir(stored.FilePath) != backupDir {
+				t.Errorf("expected backup in %s, got %s", backupDir, stored.FilePath)
+			}
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatal("backup did not finish")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	// Backups recorded under an old directory are found in the current one
+	moved := filepath.Join(backupDir, "old-bk-1.dump")
+	os.WriteFile(moved, []byte("dump"), 0644)
+	old := &storage.Backup{ID: "bk-1", FilePath: "/var/lib/dbnest/backups/old-bk-1.dump"}
+	if got := manager.BackupFilePath(old); got != moved {
+		t.Errorf("expected moved backup to resolve to %s, got %s", moved, got)
+	}
+}
+
+func TestImageDigestPinning(t *testing.T) {
+	digest := "sha256:" + strings.Repeat("ab", 32)
+	mockDocker := &MockDockerClient{Digest: digest}
+	manager, store, cleanup := setupTestManager(t, mockDocker)
+	defer cleanup()
+
+	cfg, err := manager.Plan(&CreateRequest{Name: "pinned", Engine: "postgresql", Version: "16", ImageDigest: digest})
+	if err != nil {
+		t.Fatalf("Plan failed: %v", err)
+	}
+	if cfg.Image != "postgres@"+digest {
+		t.Errorf("expected digest-pinned image, got %s", cfg.Image)
+	}
+	if got := resolveImage(nil, "registry.example.com:5000/team/postgres:16", "16", digest); got != "registry.example.com:5000/team/postgres@"+digest {
+		t.Errorf("expected the tag to be replaced by the digest, got %s", got)
+	}
+	for _, req := range []*CreateRequest{
+		{Name: "bad", Engine: "postgresql", ImageDigest: "sha256:nothex"},
+		{Name: "bad", Engine: "postgresql", Image: "postgres@" + digest, ImageDigest: digest},
+	} {
+		if _, err := manager.Plan(req); err == nil {
+			t.Errorf("expected %+v to be rejected", req)
+		}
+	}
+
+	// A tag-based database records the pulled digest, and repairs reuse it
+	db, err := manager.Create(context.Background(), &CreateRequest{Name: "tagged", Engine: "postgresql", Version: "16", Username: "u", Database: "d"})
+	if err != nil {
+		t.Fatalf("Create failed: %v", err)
+	}
+	time.Sleep(100 * time.Millisecond)
+	stored, _ := store.GetDatabase(db.ID)
+	if stored.ImageDigest != digest {
+		t.Fatalf("expected resolved digest to be stored, got %q", stored.ImageDigest)
+	}
+	if err := manager.Repair(context.Background(), db.ID); err != nil {
+		t.Fatalf("Repair failed: %v", err)
+	}
+	if mockDocker.LastImage != "postgres@"+digest {
+		t.Errorf("expected repair to use the pinned image, got %s", mockDocker.LastImage)
+	}
+}
+
+func TestMySQLBackupConsistency(t *testing.T) {
+	db := &storage.DatabaseInstance{
+		ID: "db-my", Engine: "mysql", Version: "8.0", ContainerID: "c1",
+		Username: "app", Password: "secret", Database: "shop",
+	}
+	engine := &MySQLEngine{}
+	backupPath := filepath.Join(t.TempDir(), "shop.dump")
+
+	dumpCmd := func(client *MockDockerClient) string {
+		return strings.Join(client.ExecCmds[len(client.ExecCmds)-1], " ")
+	}
+
+	// InnoDB only: dump in a single transaction
+	client := &MockDockerClient{}
+	if err := engine.Backup(context.Background(), client, db, backupPath, BackupOptions{}); err != nil {
+		t.Fatalf("Backup failed: %v", err)
+	}
+	if cmd := dumpCmd(client); !strings.Contains(cmd, "--single-transaction") || !strings.Contains(cmd, "--quick") {
+		t.Errorf("Expected transactional dump, got %q", cmd)
+	}
+
+	// MyISAM tables present: lock tables instead
+	client = &MockDockerClient{ExecOutput: "legacy_log"}
+	if err := engine.Backup(context.Background(), client, db, backupPath, BackupOptions{}); err != nil {
+		t.Fatalf("Backup failed: %v", err)
+	}
+	if cmd := dumpCmd(client); !strings.Contains(cmd, "--lock-tables") || strings.Contains(cmd, "--single-transaction") {
+		t.Errorf("Expected table-locking dump, got %q", cmd)
+	}
+
+	// Binlog coordinates are read as root
+	client = &MockDockerClient{}
+	if err := engine.Backup(context.Background(), client, db, backupPath, BackupOptions{BinlogPosition: true}); err != nil {
+		t.Fatalf("Backup failed: %v", err)
+	}
+	if cmd := dumpCmd(client); !strings.Contains(cmd, "-u root") || !strings.Contains(cmd, "--source-data=2") {
+		t.Errorf("Expected binlog position dump as root, got %q", cmd)
+	}
+}
+
+func TestSelectiveBackupTables(t *testing.T) {
+	if err := ValidateBackupTables("redis", []string{"users"}); err == nil {
+		t.Error("Expected redis table backup to be rejected")
+	}
+	if err := ValidateBackupTables("mysql", []string{"public.users"}); err == nil {
+		t.Error("Expected schema-qualified MySQL table to be rejected")
+	}
+	if err := ValidateBackupTables("postgresql", []string{"public.users", "orders"}); err != nil {
+		t.Errorf("Unexpected error: %v", err)
+	}
+	partial := &storage.Backup{ID: "bk-p", Tables: []string{"users"}}
+	if err := ValidateRestoreTables(partial, "mysql", []string{"orders"}); err == nil {
+		t.Error("Expected restoring a table missing from a partial backup to fail")
+	}
+
+	// pg_dump gets one -t per table
+	client := &MockDockerClient{}
+	db := &storage.DatabaseInstance{ID: "db-pg", Engine: "postgresql", ContainerID: "c1", Username: "app", Database: "shop"}
+	backupPath := filepath.Join(t.TempDir(), "shop.dump")
+	if err := (&PostgreSQLEngine{}).Backup(context.Background(), client, db, backupPath, BackupOptions{Tables: []string{"users", "orders"}}); err != nil {
+		t.Fatalf("Backup failed: %v", err)
+	}
+	if cmd := strings.Join(client.ExecCmds[0], " "); !strings.Contains(cmd, "-t users -t orders") {
+		t.Errorf("Expected table flags in pg_dump command, got %q", cmd)
+	}
+
+	dump := "SET NAMES utf8mb4;\n" +
+		"-- Table structure for table `orders`\nCREATE TABLE `orders` (id int);\n" +
+		"-- Dumping data for table `orders`\nINSERT INTO `orders` VALUES (1);\n" +
+		"-- Table structure for table `users`\nCREATE TABLE `users` (id int);\n" +
+		"-- Dumping data for table `users`\nINSERT INTO `users` VALUES (2);\n" +
+		"/*!40103 SET TIME_ZONE=@OLD_TIME_ZONE */;\n"
+	got := string(filterMySQLDump([]byte(dump), []string{"users"}))
+	if strings.Contains(got, "orders") || !strings.Contains(got, "INSERT INTO `users`") {
+		t.Errorf("Unexpected filtered dump:\n%s", got)
+	}
+	if !strings.HasPrefix(got, "SET NAMES") || !strings.Contains(got, "OLD_TIME_ZONE") {
+		t.Errorf("Expected session header and trailer to be kept:\n%s", got)
+	}
+}
+
+func TestGetSchema(t *testing.T) {
+	mock := &MockDockerClient{}
+	manager, store, cleanup := setupTestManager(t, mock)
+	defer cleanup()
+
+	store.CreateDatabase(&storage.DatabaseInstance{ID: "db-pg", Engine: "postgresql", Status: "running", ContainerID: "c-pg"})
+	mock.ExecOutput = "table_schema\x1ftable_name\x1ftable_type\x1epublic\x1fusers\x1fBASE TABLE\x1epublic\x1factive_users\x1fVIEW\n"
+	schema, err := manager.GetSchema(context.Background(), "db-pg", false)
+	if err != nil {
+		t.Fatalf("GetSchema failed: %v", err)
+	}
+	if len(schema.Tables) != 2 || schema.Tables[0].Name != "users" || schema.Tables[1].Type != "view" {
+		t.Errorf("Unexpected tables: %+v", schema.Tables)
+	}
+
+	store.CreateDatabase(&storage.DatabaseInstance{ID: "db-redis", Engine: "redis", Status: "running", ContainerID: "c-redis"})
+	mock.ExecOutput = "0\nuser:1:profile\nuser:2:profile\nsession:abc1\ncounter"
+	schema, err = manager.GetSchema(context.Background(), "db-redis", false)
+	if err != nil {
+		t.Fatalf("GetSchema failed: %v", err)
+	}
+	if len(schema.KeyPatterns) != 3 || schema.KeyPatterns[0] != (KeyPattern{Pattern: "user:*:profile", Count: 2}) || schema.Truncated {
+		t.Errorf("Unexpected key patterns: %+v", schema.KeyPatterns)
+	}
+}
+
+func TestConcurrentCreatesGetDistinctPorts(t *testing.T) {
+	manager, _, cleanup := setupTestManager(t, nil)
+	defer cleanup()
+
+	const n = 10
+	ports := make([]int, n)
+	errs := make([]error, n)
+	var wg sync.WaitGroup
+	for i := 0; i < n; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			db, err := manager.Create(context.Background(), &CreateRequest{Name: fmt.Sprintf("race%d", i), Engine: "postgresql"})
+			if err != nil {
+				errs[i] = err
+				return
+			}
+			ports[i] = db.Port
+		}(i)
+	}
+	wg.Wait()
+
+	seen := make(map[int]bool)
+	for i, port := range ports {
+		if errs[i] != nil {
+			t.Fatalf("Create failed: %v", errs[i])
+		}
+		if seen[port] {
+			t.Errorf("Port %d allocated twice", port)
+		}
+		seen[port] = true
+	}
+
+	// An explicit port already taken is rejected
+	if _, err := manager.Create(context.Background(), &CreateRequest{Name: "dup", Engine: "postgresql", Port: ports[0]}); err == nil {
+		t.Error("Expected create with a port already in use to fail")
+	}
+}
+
+func TestBindAddress(t *testing.T) {
+	manager, _, cleanup := setupTestManager(t, nil)
+	defer cleanup()
+
+	db, err := manager.Create(context.Background(), &CreateRequest{Name: "local", Engine: "postgresql"})
+	if err != nil {
+		t.Fatalf("Create failed: %v", err)
+	}
+	if db.BindAddress != "127.0.0.1" || db.Host != "localhost" {
+		t.Errorf("Expected default loopback binding, got %q (host %q)", db.BindAddress, db.Host)
+	}
+
+	db, err = manager.Create(context.Background(), &CreateRequest{Name: "lan", Engine: "postgresql", BindAddress: "fd00::5"})
+	if err != nil {
+		t.Fatalf("Create failed: %v", err)
+	}
+	if db.BindAddress != "fd00::5" || db.Host != "fd00::5" {
+		t.Errorf("Expected per-database IPv6 binding, got %q (host %q)", db.BindAddress, db.Host)
+	}
+	if cfg := containerConfig(db, &PostgreSQLEngine{}, "postgres"); cfg.BindAddress != "fd00::5" {
+		t.Errorf("Expected bind address in container config, got %q", cfg.BindAddress)
+	}
+
+	if _, err := manager.Create(context.Background(), &CreateRequest{Name: "bad", Engine: "postgresql", BindAddress: "localhost"}); err == nil {
+		t.Error("Expected a non-IP bind address to be rejected")
+	}
+}
+
+func TestUnexposedDatabase(t *testing.T) {
+	manager, _, cleanup := setupTestManager(t, nil)
+	defer cleanup()
+
+	expose := false
+	db, err := manager.Create(context.Background(), &CreateRequest{Name: "private", Engine: "postgresql", ExposePort: &expose})
+	if err != nil {
+		t.Fatalf("Create failed: %v", err)
+	}
+	if db.ExposePort || db.Host != "dbnest-"+db.ID || db.Port != 5432 {
+		t.Errorf("Expected network-only database on the container port, got host %q port %d", db.Host, db.Port)
+	}
+	if cfg := containerConfig(db, &PostgreSQLEngine{}, "postgres"); cfg.ExposePort {
+		t.Error("Expected container config not to publish the port")
+	}
+
+	// The container port isn't taken on the host, so an exposed database can still use it
+	manager.portLock.Lock()
+	owner := manager.portOwnerLocked(5432)
+	manager.portLock.Unlock()
+	if owner != "" {
+		t.Errorf("Expected port 5432 to be free on the host, owned by %q", owner)
+	}
+
+	if _, err := manager.Create(context.Background(), &CreateRequest{Name: "private2", Engine: "postgresql", ExposePort: &expose, Port: 15432}); err == nil {
+		t.Error("Expected a host port with exposePort false to be rejected")
+	}
+}
+
+func TestCredentialsFile(t *testing.T) {
+	db := &storage.DatabaseInstance{Name: "app", Engine: "postgresql", Host: "localhost", Port: 5433, Username: "app", Password: `p:w\d`, Database: "appdb"}
+	name, content, err := CredentialsFile(db)
+	if err != nil {
+		t.Fatalf("CredentialsFile failed: %v", err)
+	}
+	if name != "app.pgpass" || !strings.Contains(content, "localhost:5433:appdb:app:p\\:w\\\\d\n") {
+		t.Errorf("Unexpected pgpass %s:\n%s", name, content)
+	}
+
+	db.Engine = "mysql"
+	_, content, _ = CredentialsFile(db)
+	if !strings.Contains(content, "[client]\nhost=localhost\nport=5433\nuser=\"app\"\npassword=\"p:w\\\\d\"\n") {
+		t.Errorf("Unexpected my.cnf:\n%s", content)
+	}
+
+	db.Engine = "redis"
+	db.Password = "it's"
+	_, content, _ = CredentialsFile(db)
+	if !strings.Contains(content, `REDISCLI_AUTH='it'\''s'`) {
+		t.Errorf("Unexpected redis env file:\n%s", content)
+	}
+}
+
+func TestMaxConnections(t *testing.T) {
+	manager, _, cleanup := setupTestManager(t, nil)
+	defer cleanup()
+
+	cfg, err := manager.Plan(&CreateRequest{Name: "pg", Engine: "postgresql", MaxConnections: 250})
+	if err != nil {
+		t.Fatalf("Plan failed: %v", err)
+	}
+	if got := strings.Join(cfg.Cmd, " "); got != "postgres -c max_connections=250" {
+		t.Errorf("unexpected container command: %q", got)
+	}
+
+	cfg, err = manager.Plan(&CreateRequest{Name: "my", Engine: "mysql"})
+	if err != nil {
+		t.Fatalf("Plan failed: %v", err)
+	}
+	if got := strings.Join(cfg.Cmd, " "); got != "mysqld --max-connections=100" {
+		t.Errorf("expected default limit in container command, got %q", got)
+	}
+
+	if _, err := manager.Plan(&CreateRequest{Name: "pg2", Engine: "postgresql", MaxConnections: -5}); err == nil {
+		t.Error("expected error for out-of-range maxConnections")
+	}
+}
+
+func TestConfigParams(t *testing.T) {
+	manager, _, cleanup := setupTestManager(t, nil)
+	defer cleanup()
+
+	params := map[string]string{"work_mem": "16MB", "shared_buffers": "256MB"}
+	cfg, err := manager.Plan(&CreateRequest{Name: "pg", Engine: "postgresql", ConfigParams: params})
+	if err != nil {
+		t.Fatalf("Plan failed: %v", err)
+	}
+	if got := strings.Join(cfg.Cmd, " "); got != "postgres -c max_connections=100 -c shared_buffers=256MB -c work_mem=16MB" {
+		t.Errorf("unexpected container command: %q", got)
+	}
+
+	cfg, err = manager.Plan(&CreateRequest{Name: "my", Engine: "mysql", ConfigParams: map[string]string{"innodb_buffer_pool_size": "1G"}})
+	if err != nil {
+		t.Fatalf("Plan failed: %v", err)
+	}
+	if got := strings.Join(cfg.Cmd, " "); got != "mysqld --max-connections=100 --innodb_buffer_pool_size=1G" {
+		t.Errorf("unexpected container command: %q", got)
+	}
+
+	// Repairs rebuild the command from the stored instance
+	db := &storage.DatabaseInstance{Engine: "redis", ConfigParams: map[string]string{"maxmemory-policy": "allkeys-lru"}}
+	if got := strings.Join((&RedisEngine{}).ContainerCmd(db), " "); got != "redis-server --maxmemory-policy allkeys-lru" {
+		t.Errorf("unexpected redis command: %q", got)
+	}
+
+	for _, bad := range []map[string]string{
+		{"max-connections": "5"},
+		{"Bad Name": "1"},
+		{"work_mem": "1MB\n"},
+	} {
+		if _, err := manager.Plan(&CreateRequest{Name: "bad", Engine: "mysql", ConfigParams: bad}); err == nil {
+			t.Errorf("expected params %v to be rejected", bad)
+		}
+	}
+}
+
+func TestBackupEncryption(t *testing.T) {
+	manager, _, cleanup := setupTestManager(t, nil)
+	defer cleanup()
+	key := bytes.Repeat([]byte{7}, BackupKeySize)
+	opts := DefaultOptions()
+	opts.BackupEncryptionKey = key
+	manager.SetOptions(opts)
+
+	dir := t.TempDir()
+	plain := make([]byte, 2*encChunkSize+1234)
+	for i := range plain {
+		plain[i] = byte(i % 251)
+	}
+	path := filepath.Join(dir, "app.dump")
+	if err := writeBackupFile(path, string(plain), BackupOptions{EncryptionKey: key}); err != nil {
+		t.Fatalf("writeBackupFile failed: %v", err)
+	}
+	onDisk, _ := os.ReadFile(path)
+	if bytes.Contains(onDisk, plain[:64]) {
+		t.Error("Expected backup file to be encrypted on disk")
+	}
+
+	backup := &storage.Backup{ID: "bk-enc", FilePath: path, Encrypted: true}
+	r, size, _, err := manager.OpenBackup(backup)
+	if err != nil {
+		t.Fatalf("OpenBackup failed: %v", err)
+	}
+	defer r.Close()
+	if size != int64(len(plain)) {
+		t.Errorf("Expected plaintext size %d, got %d", len(plain), size)
+	}
+	got, err := io.ReadAll(r)
+	if err != nil || !bytes.Equal(got, plain) {
+		t.Fatalf("Decrypted backup doesn't match (err %v)", err)
+	}
+
+	// Seeking across a chunk boundary, as range downloads do
+	offset := int64(encChunkSize - 10)
+	r.Seek(offset, io.SeekStart)
+	part := make([]byte, 20)
+	if _, err := io.ReadFull(r, part); err != nil || !bytes.Equal(part, plain[offset:offset+20]) {
+		t.Errorf("Unexpected bytes after seek (err %v)", err)
+	}
+
+	tmp, err := manager.decryptBackupToTemp(backup, dir)
+	if err != nil {
+		t.Fatalf("decryptBackupToTemp failed: %v", err)
+	}
+	if restored, _ := os.ReadFile(tmp); !bytes.Equal(restored, plain) {
+		t.Error("Expected temporary restore file to hold the plaintext")
+	}
+
+	// Empty dumps still round trip
+	empty := filepath.Join(dir, "empty.dump")
+	if err := writeBackupFile(empty, "", BackupOptions{EncryptionKey: key}); err != nil {
+		t.Fatalf("writeBackupFile failed: %v", err)
+	}
+	if r, size, _, err := manager.OpenBackup(&storage.Backup{ID: "bk-empty", FilePath: empty, Encrypted: true}); err != nil || size != 0 {
+		t.Errorf("Expected empty plaintext, got size %d (err %v)", size, err)
+	} else {
+		r.Close()
+	}
+
+	opts.BackupEncryptionKey = bytes.Repeat([]byte{8}, BackupKeySize)
+	manager.SetOptions(opts)
+	if _, _, _, err := manager.OpenBackup(backup); err == nil || !strings.Contains(err.Error(), "different key") {
+		t.Errorf("Expected wrong key error, got %v", err)
+	}
+}
+
+func TestRestoreVerifiesChecksum(t *testing.T) {
+	manager, store, cleanup := setupTestManager(t, nil)
+	defer cleanup()
+
+	store.CreateDatabase(&storage.DatabaseInstance{ID: "db-1", Name: "app", Engine: "postgresql", ContainerID: "c1"})
+	path := filepath.Join(t.TempDir(), "app.dump")
+	os.WriteFile(path, []byte("-- dump"), 0644)
+	sum, err := fileChecksum(path)
+	if err != nil {
+		t.Fatalf("fileChecksum failed: %v", err)
+	}
+	backup := &storage.Backup{ID: "bk-1", DatabaseID: "db-1", Status: "completed", FilePath: path, Checksum: sum}
+	store.CreateBackup(backup)
+
+	if err := manager.RestoreBackup(context.Background(), "bk-1", "db-1", nil); err != nil {
+		t.Fatalf("Restore of intact backup failed: %v", err)
+	}
+
+	os.WriteFile(path, []byte("-- dumq"), 0644)
+	err = manager.RestoreBackup(context.Background(), "bk-1", "db-1", nil)
+	if !errors.Is(err, ErrBackupCorrupted) {
+		t.Errorf("Expected ErrBackupCorrupted, got %v", err)
+	}
+}
+
+func TestWaitForBackup(t *testing.T) {
+	manager, store, cleanup := setupTestManager(t, nil)
+	defer cleanup()
+
+	store.CreateBackup(&storage.Backup{ID: "bk-ok", Status: "completed"})
+	if backup, err := manager.WaitForBackup(context.Background(), "bk-ok"); err != nil || backup.ID != "bk-ok" {
+		t.Errorf("Expected completed backup, got %v (err %v)", backup, err)
+	}
+
+	store.CreateBackup(&storage.Backup{ID: "bk-bad", Status: "failed", Error: "pg_dump: connection refused"})
+	if _, err := manager.WaitForBackup(context.Background(), "bk-bad"); err == nil || !strings.Contains(err.Error(), "connection refused") {
+		t.Errorf("Expected failure reason in error, got %v", err)
+	}
+
+	store.CreateBackup(&storage.Backup{ID: "bk-slow", Status: "in-progress"})
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	if _, err := manager.WaitForBackup(ctx, "bk-slow"); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("Expected deadline exceeded, got %v", err)
+	}
+}
+
+func TestChangeMarker(t *testing.T) {
+	mock := &MockDockerClient{}
+	manager, _, cleanup := setupTestManager(t, mock)
+	defer cleanup()
+
+	pg := &storage.DatabaseInstance{ID: "db-pg", Engine: "postgresql", ContainerID: "c1", Username: "app", Database: "shop"}
+	mock.ExecOutput = "0/1A2B3C4\n"
+	if marker, err := manager.ChangeMarker(context.Background(), pg); err != nil || marker != "wal:0/1A2B3C4" {
+		t.Errorf("Unexpected PostgreSQL marker %q (err %v)", marker, err)
+	}
+
+	my := &storage.DatabaseInstance{ID: "db-my", Engine: "mysql", Version: "8.4", ContainerID: "c2", Password: "pw"}
+	mock.ExecOutput = "mysql: [Warning] Using a password on the command line interface can be insecure.\nbinlog.000002\t157\t\t\t\n"
+	if marker, err := manager.ChangeMarker(context.Background(), my); err != nil || marker != "binlog:binlog.000002:157" {
+		t.Errorf("Unexpected MySQL marker %q (err %v)", marker, err)
+	}
+	if cmd := strings.Join(mock.ExecCmds[len(mock.ExecCmds)-1], " "); !strings.Contains(cmd, "SHOW BINARY LOG STATUS") {
+		t.Errorf("Expected SHOW BINARY LOG STATUS on MySQL 8.4, got %q", cmd)
+	}
+	mock.ExecOutput = ""
+	if _, err := manager.ChangeMarker(context.Background(), my); err == nil {
+		t.Error("Expected an error when binary logging is disabled")
+	}
+
+	// A clean Redis dataset is backed up without BGSAVE, leaving the marker alone
+	redis := &storage.DatabaseInstance{ID: "db-redis", Engine: "redis", ContainerID: "c3"}
+	mock.ExecOutput = "# Persistence\r\nrdb_changes_since_last_save:0\r\nrdb_last_save_time:1700000000\r\n"
+	if marker, err := manager.ChangeMarker(context.Background(), redis); err != nil || marker != "save:1700000000:0" {
+		t.Errorf("Unexpected Redis marker %q (err %v)", marker, err)
+	}
+	mock.ExecCmds = nil
+	if err := (&RedisEngine{}).Backup(context.Background(), mock, redis, filepath.Join(t.TempDir(), "r.rdb"), BackupOptions{}); err != nil {
+		t.Fatalf("Backup failed: %v", err)
+	}
+	for _, cmd := range mock.ExecCmds {
+		if slices.Contains(cmd, "BGSAVE") {
+			t.Errorf("Expected no BGSAVE for a clean dataset, got %v", mock.ExecCmds)
+		}
+	}
+}
+
+func TestGetLogsFiltering(t *testing.T) {
+	mock := &MockDockerClient{}
+	manager, store, cleanup := setupTestManager(t, mock)
+	defer cleanup()
+
+	store.CreateDatabase(&storage.DatabaseInstance{ID: "db-1", ContainerID: "c1"})
+	mock.LogsOutput = "LOG: checkpoint starting\nERROR: relation \"users\" does not exist\nLOG: checkpoint complete\n"
+
+	logs, err := manager.GetLogs(context.Background(), "db-1", LogOptions{Grep: "checkpoint"})
+	if err != nil {
+		t.Fatalf("GetLogs failed: %v", err)
+	}
+	if logs != "LOG: checkpoint starting\nLOG: checkpoint complete\n" {
+		t.Errorf("Unexpected filtered logs: %q", logs)
+	}
+	if mock.LogsTail != DefaultLogTail || !mock.LogsSince.IsZero() {
+		t.Errorf("Expected default tail and no since, got %d and %v", mock.LogsTail, mock.LogsSince)
+	}
+
+	if _, err := manager.GetLogs(context.Background(), "db-1", LogOptions{Tail: 50, Since: time.Hour, Timestamps: true}); err != nil {
+		t.Fatalf("GetLogs failed: %v", err)
+	}
+	if mock.LogsTail != 50 || time.Since(mock.LogsSince) < 59*time.Minute || !mock.LogsTimestamps {
+		t.Errorf("Expected timestamped tail 50 from an hour ago, got %d, %v and %v", mock.LogsTail, mock.LogsSince, mock.LogsTimestamps)
+	}
+}
+
+func TestRunAsUser(t *testing.T) {
+	manager, _, cleanup := setupTestManager(t, nil)
+	defer cleanup()
+
+	opts := manager.options()
+	opts.DefaultRunAsUser, opts.DefaultRunAsGroup, opts.DefaultUsernsMode = 999, 999, "auto"
+	manager.SetOptions(opts)
+
+	db, err := manager.Create(context.Background(), &CreateRequest{Name: "defaults", Engine: "postgresql"})
+	if err != nil {
+		t.Fatalf("Create failed: %v", err)
+	}
+	cfg := containerConfig(db, &PostgreSQLEngine{}, "postgres")
+	if cfg.User() != "999:999" || cfg.UsernsMode != "auto" {
+		t.Errorf("Expected configured defaults, got user %q userns %q", cfg.User(), cfg.UsernsMode)
+	}
+
+	db, err = manager.Create(context.Background(), &CreateRequest{Name: "custom", Engine: "postgresql", RunAsUser: 70, UsernsMode: "host"})
+	if err != nil {
+		t.Fatalf("Create failed: %v", err)
+	}
+	if cfg := containerConfig(db, &PostgreSQLEngine{}, "postgres"); cfg.User() != "70" || cfg.UsernsMode != "host" {
+		t.Errorf("Expected per-database user, got user %q userns %q", cfg.User(), cfg.UsernsMode)
+	}
+
+	for _, req := range []*CreateRequest{
+		{Name: "negative", Engine: "postgresql", RunAsUser: -1},
+		{Name: "group-only", Engine: "postgresql", RunAsGroup: 5},
+		{Name: "bad-userns", Engine: "postgresql", RunAsUser: 70, UsernsMode: "host; rm"},
+	} {
+		if _, err := manager.Create(context.Background(), req); err == nil {
+			t.Errorf("Expected create %q to be rejected", req.Name)
+		}
+	}
+}
+
+func TestReadOnlyRootfs(t *testing.T) {
+	manager, _, cleanup := setupTestManager(t, nil)
+	defer cleanup()
+
+	db, err := manager.Create(context.Background(), &CreateRequest{Name: "writable", Engine: "postgresql"})
+	if err != nil {
+		t.Fatalf("Create failed: %v", err)
+	}
+	if cfg := containerConfig(db, &PostgreSQLEngine{}, "postgres"); cfg.ReadOnlyRootfs || len(cfg.Tmpfs) != 0 {
+		t.Errorf("Expected a writable root filesystem by default, got read-only %v tmpfs %v", cfg.ReadOnlyRootfs, cfg.Tmpfs)
+	}
+
+	readOnly := true
+	db, err = manager.Create(context.Background(), &CreateRequest{Name: "hardened", Engine: "mysql", ReadOnlyRootfs: &readOnly})
+	if err != nil {
+		t.Fatalf("Create failed: %v", err)
+	}
+	cfg := containerConfig(db, &MySQLEngine{}, "mysql")
+	if !cfg.ReadOnlyRootfs {
+		t.Error("Expected a read-only root filesystem")
+	}
+	if !slices.Contains(cfg.Tmpfs, "/var/run/mysqld") || !slices.Contains(cfg.Tmpfs, "/tmp") {
+		t.Errorf("Expected the socket directory and /tmp to stay writable, got %v", cfg.Tmpfs)
+	}
+	if cfg.Volumes[dataVolume(db)] != "/var/lib/mysql" {
+		t.Errorf("Expected the data volume to stay mounted, got %v", cfg.Volumes)
+	}
+}
+
+func TestContainerSecurity(t *testing.T) {
+	manager, _, cleanup := setupTestManager(t, nil)
+	defer cleanup()
+
+	cfg, err := manager.Plan(&CreateRequest{Name: "secure", Engine: "postgresql"})
+	if err != nil {
+		t.Fatalf("Plan failed: %v", err)
+	}
+	if !cfg.Security.NoNewPrivileges || !slices.Contains(cfg.Security.CapDrop, "NET_RAW") || slices.Contains(cfg.Security.CapDrop, "CHOWN") {
+		t.Errorf("Expected default hardening, got %+v", cfg.Security)
+	}
+
+	opts := manager.options()
+	opts.ContainerSecurity = runtime.SecurityOptions{SeccompProfile: runtime.SeccompUnconfined}
+	manager.SetOptions(opts)
+	cfg, err = manager.Plan(&CreateRequest{Name: "relaxed", Engine: "postgresql"})
+	if err != nil {
+		t.Fatalf("Plan failed: %v", err)
+	}
+	if cfg.Security.NoNewPrivileges || len(cfg.Security.CapDrop) != 0 || cfg.Security.SeccompProfile != runtime.SeccompUnconfined {
+		t.Errorf("Expected configured security options, got %+v", cfg.Security)
+	}
+}
+
+func TestPostgresQueryDelimiters(t *testing.T) {
+	mock := &MockDockerClient{}
+	db := &storage.DatabaseInstance{Engine: "postgresql", ContainerID: "c-pg"}
+
+	// Values with pipes and newlines, an empty string and a NULL
+	mock.ExecOutput = "id\x1fnote\x1fextra\x1e1\x1fa|b\x1f\x1e2\x1fline one\nline two\x1f\x1a\n"
+	result, err := (&PostgreSQLEngine{}).ExecuteQuery(context.Background(), mock, db, "SELECT * FROM notes")
+	if err != nil {
+		t.Fatalf("ExecuteQuery failed: %v", err)
+	}
+	if !slices.Equal(result.Columns, []string{"id", "note", "extra"}) || result.RowCount != 2 {
+		t.Fatalf("Unexpected result shape: %+v", result)
+	}
+	if result.Rows[0][1] != "a|b" || result.Rows[0][2] != "" {
+		t.Errorf("Expected pipe and empty values to round-trip, got %#v", result.Rows[0])
+	}
+	if result.Rows[1][1] != "line one\nline two" || result.Rows[1][2] != nil {
+		t.Errorf("Expected newline and NULL values to round-trip, got %#v", result.Rows[1])
+	}
+
+	mock.ExecOutput = ""
+	if result, _ := (&PostgreSQLEngine{}).ExecuteQuery(context.Background(), mock, db, "UPDATE notes SET note = ''"); result.Message == "" || len(result.Rows) != 0 {
+		t.Errorf("Expected a no-output message, got %+v", result)
+	}
+}
+
+func TestMySQLQueryEscaping(t *testing.T) {
+	mock := &MockDockerClient{}
+	db := &storage.DatabaseInstance{Engine: "mysql", ContainerID: "c-mysql"}
+
+	// Batch mode escapes tabs, newlines and backslashes inside values
+	mock.ExecOutput = "mysql: [Warning] Using a password on the command line interface can be insecure.\n" +
+		"id\tbody\tnote\n" +
+		"1\tcol a\\tcol b\\nnext line\tC:\\\\temp\n" +
+		"2\tNULL\t"
+	for _, engine := range []Engine{&MySQLEngine{}, &MariaDBEngine{}} {
+		result, err := engine.ExecuteQuery(context.Background(), mock, db, "SELECT * FROM notes")
+		if err != nil {
+			t.Fatalf("ExecuteQuery failed: %v", err)
+		}
+		if !slices.Equal(result.Columns, []string{"id", "body", "note"}) || result.RowCount != 2 {
+			t.Fatalf("%s: unexpected result shape: %+v", engine.Type(), result)
+		}
+		if result.Rows[0][1] != "col a\tcol b\nnext line" || result.Rows[0][2] != `C:\temp` {
+			t.Errorf("%s: expected escaped values to round-trip, got %#v", engine.Type(), result.Rows[0])
+		}
+		if result.Rows[1][1] != nil || result.Rows[1][2] != "" {
+			t.Errorf("%s: expected NULL and a trimmed trailing empty value, got %#v", engine.Type(), result.Rows[1])
+		}
+	}
+}
+
+func TestQueryRowLimit(t *testing.T) {
+	mock := &MockDockerClient{}
+	manager, store, cleanup := setupTestManager(t, mock)
+	defer cleanup()
+
+	opts := manager.options()
+	opts.QueryRowLimit = 3
+	manager.SetOptions(opts)
+
+	store.CreateDatabase(&storage.DatabaseInstance{ID: "db-pg", Engine: "postgresql", Status: "running", ContainerID: "c-pg"})
+	mock.ExecOutput = "n\x1e1\x1e2\x1e3\x1e4\n"
+	results, err := manager.Query(context.Background(), "db-pg", "SELECT n FROM generate_series(1, 100) n;", QueryOptions{})
+	if err != nil {
+		t.Fatalf("Query failed: %v", err)
+	}
+	if result := results[0]; !result.Truncated || result.RowCount != 3 || len(result.Rows) != 3 {
+		t.Errorf("Expected 3 rows and a truncation flag, got %+v", result)
+	}
+	if query := mock.ExecCmds[len(mock.ExecCmds)-1]; !strings.HasSuffix(query[len(query)-1], ") AS dbnest_limited LIMIT 4") {
+		t.Errorf("Expected the SELECT to be wrapped in a limit, got %q", query[len(query)-1])
+	}
+
+	// A lower per-query limit wins; a higher one is capped
+	if results, _ := manager.Query(context.Background(), "db-pg", "SELECT 1", QueryOptions{Limit: 2}); results[0].RowCount != 2 || !results[0].Truncated {
+		t.Errorf("Expected the request limit to apply, got %+v", results[0])
+	}
+	if results, _ := manager.Query(context.Background(), "db-pg", "SELECT 1", QueryOptions{Limit: 50}); results[0].RowCount != 3 {
+		t.Errorf("Expected the configured limit to cap the request, got %+v", results[0])
+	}
+
+	for _, query := range []string{"UPDATE t SET a = 1", "SELECT 1; SELECT 2", "SELECT * INTO copy FROM t", "WITH d AS (DELETE FROM t RETURNING *) SELECT * FROM d"} {
+		if got := limitQuery("postgresql", query, 10); got != query {
+			t.Errorf("Expected %q to run unwrapped, got %q", query, got)
+		}
+	}
+	if got := limitQuery("mysql", "SELECT 1", 10); got != "SELECT 1" {
+		t.Errorf("Expected MySQL queries to run unwrapped, got %q", got)
+	}
+}
+
+func TestReadOnlyQuery(t *testing.T) {
+	mock := &MockDockerClient{}
+	manager, store, cleanup := setupTestManager(t, mock)
+	defer cleanup()
+
+	store.CreateDatabase(&storage.DatabaseInstance{ID: "db-pg", Engine: "postgresql", Status: "running", ContainerID: "c-pg"})
+	store.CreateDatabase(&storage.DatabaseInstance{ID: "db-redis", Engine: "redis", Status: "running", ContainerID: "c-redis"})
+	readOnly := QueryOptions{ReadOnly: true}
+
+	for _, tt := range []struct {
+		db, query string
+		allowed   bool
+	}{
+		{"db-pg", "SELECT * FROM users", true},
+		{"db-pg", "-- recent\n  explain select 1;", true},
+		{"db-pg", "UPDATE users SET admin = true", false},
+		{"db-pg", "/* hidden */ DROP TABLE users", false},
+		{"db-pg", "SELECT 1; DELETE FROM users", false},
+		{"db-pg", "SELECT * INTO backup_users FROM users", false},
+		{"db-redis", "HGETALL user:1", true},
+		{"db-redis", "FLUSHALL", false},
+		{"db-redis", "set key value", false},
+	} {
+		_, err := manager.Query(context.Background(), tt.db, tt.query, readOnly)
+		if tt.allowed && err != nil {
+			t.Errorf("%q: expected to be allowed, got %v", tt.query, err)
+		}
+		if !tt.allowed && !errors.Is(err, ErrReadOnlyQuery) {
+			t.Errorf("%q: expected ErrReadOnlyQuery, got %v", tt.query, err)
+		}
+	}
+
+	// Postgres also runs the query in a read-only session
+	if _, err := manager.Query(context.Background(), "db-pg", "SELECT 1", readOnly); err != nil {
+		t.Fatalf("Query failed: %v", err)
+	}
+	if env := mock.ExecEnvs[len(mock.ExecEnvs)-1]; !slices.Contains(env, "PGOPTIONS=-c default_transaction_read_only=on") {
+		t.Errorf("Expected a read-only session, got env %v", env)
+	}
+	if _, err := manager.Query(context.Background(), "db-pg", "DELETE FROM users", QueryOptions{}); err != nil {
+		t.Errorf("Expected writes without ReadOnly to run, got %v", err)
+	}
+}
+
+func TestSplitStatements(t *testing.T) {
+	tests := []struct {
+		engine, script string
+		want           []string
+	}{
+		{"postgresql", "SELECT 1; SELECT 2;", []string{"SELECT 1", "SELECT 2"}},
+		{"postgresql", "INSERT INTO t VALUES ('a;b', 'it''s;'); -- done; really\nSELECT \"odd;name\" FROM t", []string{"INSERT INTO t VALUES ('a;b', 'it''s;')", "-- done; really\nSELECT \"odd;name\" FROM t"}},
+		{"postgresql", "CREATE FUNCTION f() RETURNS int AS $body$ BEGIN RETURN 1; END; $body$ LANGUAGE plpgsql; SELECT f()", []string{"CREATE FUNCTION f() RETURNS int AS $body$ BEGIN RETURN 1; END; $body$ LANGUAGE plpgsql", "SELECT f()"}},
+		{"postgresql", "DO $$ BEGIN PERFORM 1; END $$; SELECT $1::text", []string{"DO $$ BEGIN PERFORM 1; END $$", "SELECT $1::text"}},
+		{"postgresql", "SELECT E'a\\';b'; /* outer /* inner; */ still; */ SELECT 2", []string{"SELECT E'a\\';b'", "/* outer /* inner; */ still; */ SELECT 2"}},
+		{"mysql", "INSERT INTO t VALUES ('a\\';b'); # note; here\nSELECT `semi;colon` FROM t;;", []string{"INSERT INTO t VALUES ('a\\';b')", "# note; here\nSELECT `semi;colon` FROM t"}},
+		{"mysql", "SELECT 1; -- trailing comment", []string{"SELECT 1"}},
+	}
+	for _, tt := range tests {
+		if got := splitStatements(tt.engine, tt.script); !slices.Equal(got, tt.want) {
+			t.Errorf("splitStatements(%q):\n got %q\nwant %q", tt.script, got, tt.want)
+		}
+	}
+}
+
+func TestMultiStatementQuery(t *testing.T) {
+	mock := &MockDockerClient{}
+	manager, store, cleanup := setupTestManager(t, mock)
+	defer cleanup()
+
+	store.CreateDatabase(&storage.DatabaseInstance{ID: "db-pg", Engine: "postgresql", Status: "running", ContainerID: "c-pg"})
+	mock.ExecCmds = nil
+	results, err := manager.Query(context.Background(), "db-pg", "CREATE TABLE t (id int); INSERT INTO t VALUES (1); SELECT * FROM t", QueryOptions{})
+	if err != nil {
+		t.Fatalf("Query failed: %v", err)
+	}
+	if len(results) != 3 || len(mock.ExecCmds) != 3 || results[1].Statement != "INSERT INTO t VALUES (1)" {
+		t.Errorf("Expected one result and exec per statement, got %d results, %d execs: %+v", len(results), len(mock.ExecCmds), results)
+	}
+
+	// A failing statement stops the script
+	mock.ExecFailures = 1
+	results, err = manager.Query(context.Background(), "db-pg", "DROP TABLE missing; SELECT 1", QueryOptions{})
+	if err != nil {
+		t.Fatalf("Query failed: %v", err)
+	}
+	if len(results) != 1 || results[0].Error == "" {
+		t.Errorf("Expected the script to stop at the failed statement, got %+v", results)
+	}
+}
+
+func TestClickHouseEngine(t *testing.T) {
+	engine, err := GetEngine("clickhouse")
+	if err != nil {
+		t.Fatalf("clickhouse engine not registered: %v", err)
+	}
+	mock := &MockDockerClient{}
+	db := &storage.DatabaseInstance{Engine: "clickhouse", ContainerID: "c1", Username: "u", Password: "p", Database: "analytics", MaxConnections: 50}
+
+	if cmd := engine.ContainerCmd(db); !slices.Equal(cmd, []string{"--", "--max_connections=50"}) {
+		t.Errorf("Expected max_connections as a server override, got %v", cmd)
+	}
+
+	// TSV escapes tabs and newlines and prints NULL as \N
+	mock.ExecOutput = "id\tnote\n1\ta\\tb\n2\t\\N\n3\tline\\nbreak\n"
+	result, _ := engine.ExecuteQuery(context.Background(), mock, db, "SELECT id, note FROM events")
+	want := [][]interface{}{{"1", "a\tb"}, {"2", nil}, {"3", "line\nbreak"}}
+	if result.Error != "" || !slices.Equal(result.Columns, []string{"id", "note"}) || fmt.Sprint(result.Rows) != fmt.Sprint(want) {
+		t.Errorf("Unexpected result: %+v", result)
+	}
+	if _, err := engine.(ReadOnlyQueryEngine).ExecuteReadOnlyQuery(context.Background(), mock, db, "SELECT 1"); err != nil || !slices.Contains(mock.ExecCmds[len(mock.ExecCmds)-1], "--readonly=1") {
+		t.Errorf("Expected read-only queries to run with readonly=1, got %v", mock.ExecCmds[len(mock.ExecCmds)-1])
+	}
+
+	// Every exec returns the same output: one table listed, then its CREATE statement
+	mock.ExecOutput = "events\tMergeTree\n"
+	mock.StreamOutput = "\x01\x00native"
+	backupPath := filepath.Join(t.TempDir(), "backup.tar")
+	if err := engine.Backup(context.Background(), mock, db, backupPath, BackupOptions{}); err != nil {
+		t.Fatalf("Backup failed: %v", err)
+	}
+	mock.ExecCmds = nil
+	if err := engine.Restore(context.Background(), mock, db, backupPath, RestoreOptions{}); err != nil {
+		t.Fatalf("Restore failed: %v", err)
+	}
+	if len(mock.ExecCmds) != 2 || !strings.HasPrefix(mock.ExecCmds[0][len(mock.ExecCmds[0])-1], "DROP TABLE IF EXISTS `events`") {
+		t.Errorf("Expected the table to be dropped and recreated, got %v", mock.ExecCmds)
+	}
+	if mock.LastExecInput != "\x01\x00native" || !slices.Contains(mock.LastExecCmd, "INSERT INTO `events` FORMAT Native") {
+		t.Errorf("Expected the rows to be streamed into the table, got %q via %v", mock.LastExecInput, mock.LastExecCmd)
+	}
+
+	// Table data is spooled through a temporary file that doesn't outlive the backup
+	key := bytes.Repeat([]byte{7}, BackupKeySize)
+	encDir := t.TempDir()
+	if err := engine.Backup(context.Background(), mock, db, filepath.Join(encDir, "backup.tar"), BackupOptions{EncryptionKey: key}); err != nil {
+		t.Fatalf("Encrypted backup failed: %v", err)
+	}
+	if entries, _ := os.ReadDir(encDir); len(entries) != 1 {
+		t.Errorf("Expected only the backup file to remain, got %v", entries)
+	}
+	r, err := openEncryptedBackup(key, filepath.Join(encDir, "backup.tar"))
+	if err != nil {
+		t.Fatalf("openEncryptedBackup failed: %v", err)
+	}
+	archive := tar.NewReader(r)
+	var native string
+	for header, err := archive.Next(); err == nil; header, err = archive.Next() {
+		if header.Name == "events.native" {
+			data, _ := io.ReadAll(archive)
+			native = string(data)
+		}
+	}
+	r.Close()
+	if native != "\x01\x00native" {
+		t.Errorf("Expected the table data in the encrypted archive, got %q", native)
+	}
+
+	if got := splitStatements("clickhouse", `SELECT 'a\';b'; SELECT 2`); len(got) != 2 {
+		t.Errorf("Expected backslash escapes to be honoured, got %q", got)
+	}
+	if err := ValidateConfigParams("clickhouse", map[string]string{"max_connections": "10"}); err == nil {
+		t.Error("Expected max_connections to be managed by DBnest")
+	}
+}
+
+func TestCustomEngine(t *testing.T) {
+	dir := t.TempDir()
+	write := func(content string) string {
+		path := filepath.Join(dir, fmt.Sprintf("engines-%d.json", len(content)))
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+		return path
+	}
+
+	for _, bad := range []string{
+		`[{"type": "postgresql", "image": "x", "defaultPort": 1, "dataPath": "/d", "cliCommand": ["x"]}]`,
+		`[{"type": "kv", "image": "x", "defaultPort": 1, "dataPath": "data", "cliCommand": ["x"]}]`,
+		`[{"type": "kv", "image": "x", "defaultPort": 1, "dataPath": "/d", "cliCommand": ["{{.Secret}}"]}]`,
+		`[{"type": "kv", "image": "x", "defaultPort": 1, "dataPath": "/d"}]`,
+	} {
+		if _, err := LoadCustomEngines(write(bad)); err == nil {
+			t.Errorf("Expected %s to be rejected", bad)
+		}
+	}
+	if _, err := GetEngine("kv"); err == nil {
+		t.Fatal("Expected no engine to be registered from invalid definitions")
+	}
+
+	// Engines register globally, so drop this one again for -count runs
+	t.Cleanup(func() {
+		enginesMu.Lock()
+		delete(engines, "testkv")
+		enginesMu.Unlock()
+	})
+	types, err := LoadCustomEngines(write(`[{
+		"type": "testkv", "name": "TestKV", "image": "testkv/server", "defaultPort": 7000, "dataPath": "/data",
+		"env": ["KV_PASSWORD={{.Password}}"],
+		"backupCommand": ["kv-dump", "--db", "{{.Database}}"],
+		"queryCommand": ["kv-cli", "-a", "{{.Password}}", "{{.Query}}"],
+		"readyQuery": "PING",
+		"cliCommand": ["kv-cli", "-a", "{{.Password}}"],
+		"uri": "kv://{{.Username}}:{{.Password}}@{{.Host}}:{{.Port}}"
+	}]`))
+	if err != nil || !slices.Equal(types, []string{"testkv"}) {
+		t.Fatalf("LoadCustomEngines failed: %v %v", types, err)
+	}
+	engine, err := GetEngine("testkv")
+	if err != nil {
+		t.Fatalf("custom engine not registered: %v", err)
+	}
+
+	db := &storage.DatabaseInstance{Engine: "testkv", ContainerID: "c1", Username: "u", Password: "secret", Database: "d", Host: "localhost", Port: 7001}
+	if env := engine.EnvVars("u", "secret", "d"); !slices.Equal(env, []string{"KV_PASSWORD=secret"}) {
+		t.Errorf("Unexpected env: %v", env)
+	}
+	if uri := engine.ConnectionStrings(db).URI; uri != "kv://u:<password>@localhost:7001" {
+		t.Errorf("Unexpected URI: %s", uri)
+	}
+	if shell := engine.ShellCommand(db); !slices.Equal(shell, []string{"kv-cli", "-a", "secret"}) {
+		t.Errorf("Expected the shell to default to the CLI command, got %v", shell)
+	}
+
+	mock := &MockDockerClient{ExecOutput: "a\nb\n"}
+	result, _ := engine.ExecuteQuery(context.Background(), mock, db, "KEYS *")
+	if result.RowCount != 2 || !slices.Equal(mock.ExecCmds[0], []string{"kv-cli", "-a", "secret", "KEYS *"}) {
+		t.Errorf("Unexpected query result %+v from %v", result, mock.ExecCmds)
+	}
+
+	mock.StreamOutput = "dump"
+	backupPath := filepath.Join(dir, "backup.bin")
+	if err := engine.Backup(context.Background(), mock, db, backupPath, BackupOptions{}); err != nil {
+		t.Fatalf("Backup failed: %v", err)
+	}
+	if data, _ := os.ReadFile(backupPath); string(data) != "dump" {
+		t.Errorf("Expected the backup command's output in the file, got %q", data)
+	}
+	if err := engine.Restore(context.Background(), mock, db, backupPath, RestoreOptions{}); err == nil {
+		t.Error("Expected restore to fail without a restore command")
+	}
+}
+
+func TestBackupDiskSpaceCheck(t *testing.T) {
+	manager, store, cleanup := setupTestManager(t, nil)
+	defer cleanup()
+
+	opts := DefaultOptions()
+	opts.BackupDir = t.TempDir()
+	manager.SetOptions(opts)
+
+	var free int64
+	manager.freeDiskSpace = func(path string) (int64, error) { return free, nil }
+
+	store.CreateDatabase(&storage.DatabaseInstance{ID: "db-disk", Name: "disk", Engine: "postgresql", Status: "running", ContainerID: "c-disk", StorageUsed: 1000})
+	// The last completed backup is a better estimate than storage use when larger
+	store.CreateBackup(&storage.Backup{ID: "bk-old", DatabaseID: "db-disk", Status: "completed", Size: 5000, CreatedAt: time.Now()})
+
+	free = 4000
+	if _, err := manager.CreateBackup(context.Background(), "db-disk", nil); !errors.Is(err, ErrInsufficientDiskSpace) {
+		t.Fatalf("expected ErrInsufficientDiskSpace, got %v", err)
+	}
+	if n := len(store.ListBackups("db-disk")); n != 1 {
+		t.Errorf("expected no backup record for a refused backup, got %d backups", n)
+	}
+
+	// Let a started backup finish before the test's directories go away
+	wait := func(backup *storage.Backup) {
+		t.Helper()
+		deadline := time.Now().Add(2 * time.Second)
+		for {
+			if stored, _ := store.GetBackup(backup.ID); stored.Status != "in-progress" {
+				return
+			}
+			if time.Now().After(deadline) {
+				t.Fatal("backup did not finish")
+			}
+			time.Sleep(10 * time.Millisecond)
+		}
+	}
+
+	free = 6000
+	backup, err := manager.CreateBackup(context.Background(), "db-disk", nil)
+	if err != nil {
+		t.Fatalf("expected backup to start with enough space, got %v", err)
+	}
+	wait(backup)
+
+	// Not knowing the free space doesn't block backups
+	manager.freeDiskSpace = func(path string) (int64, error) { return 0, errors.New("statfs failed") }
+	backup, err = manager.CreateBackup(context.Background(), "db-disk", nil)
+	if err != nil {
+		t.Fatalf("expected backup to start when free space is unknown, got %v", err)
+	}
+	wait(backup)
+}
+
+func TestDeleteBackupRemovesFile(t *testing.T) {
+	manager, store, cleanup := setupTestManager(t, nil)
+	defer cleanup()
+
+	backupDir := t.TempDir()
+	opts := DefaultOptions()
+	opts.BackupDir = backupDir
+	manager.SetOptions(opts)
+
+	store.CreateDatabase(&storage.DatabaseInstance{ID: "db-del", Name: "del", Engine: "postgresql", BackupRetentionCount: 1})
+	var files []string
+	for i := 0; i < 3; i++ {
+		path := filepath.Join(backupDir, fmt.Sprintf("del-bk-%d.dump", i))
+		os.WriteFile(path, []byte("dump"), 0644)
+		files = append(files, path)
+		store.CreateBackup(&storage.Backup{
+			ID: fmt.Sprintf("bk-%d", i), DatabaseID: "db-del", Status: "completed",
+			FilePath: path, CreatedAt: time.Now().Add(time.Duration(i) * time.Minute),
+		})
+	}
+
+	if err := manager.DeleteBackup("bk-2"); err != nil {
+		t.Fatalf("DeleteBackup failed: %v", err)
+	}
+	if _, err := store.GetBackup("bk-2"); err == nil {
+		t.Error("expected backup record to be deleted")
+	}
+	if _, err := os.Stat(files[2]); !os.IsNotExist(err) {
+		t.Errorf("expected backup file to be removed, got %v", err)
+	}
+
+	// Retention removes the files of the backups it drops
+	if deleted, err := manager.ApplyRetention("db-del"); err != nil || deleted != 1 {
+		t.Fatalf("expected retention to delete 1 backup, got %d, %v", deleted, err)
+	}
+	if _, err := os.Stat(files[0]); !os.IsNotExist(err) {
+		t.Errorf("expected retained-out backup file to be removed, got %v", err)
+	}
+	if _, err := os.Stat(files[1]); err != nil {
+		t.Errorf("expected newest backup file to be kept, got %v", err)
+	}
+
+	if err := manager.DeleteBackup("bk-missing"); err == nil {
+		t.Error("expected an error deleting a missing backup")
+	}
+}
+
+func TestDeleteCascadesBackups(t *testing.T) {
+	manager, store, cleanup := setupTestManager(t, nil)
+	defer cleanup()
+
+	backupDir := t.TempDir()
+	for _, id := range []string{"db-gone", "db-kept"} {
+		store.CreateDatabase(&storage.DatabaseInstance{ID: id, Name: id, Engine: "postgresql", Status: "stopped"})
+		path := filepath.Join(backupDir, id+".dump")
+		os.WriteFile(path, []byte("dump"), 0644)
+		store.CreateBackup(&storage.Backup{ID: "bk-" + id, DatabaseID: id, Status: "completed", FilePath: path})
+	}
+
+	if err := manager.Delete(context.Background(), "db-gone", DeleteOptions{}); err != nil {
+		t.Fatalf("Delete failed: %v", err)
+	}
+	if backups := store.ListBackups("db-gone"); len(backups) != 0 {
+		t.Errorf("expected the database's backups to be deleted, got %d", len(backups))
+	}
+	if _, err := os.Stat(filepath.Join(backupDir, "db-gone.dump")); !os.IsNotExist(err) {
+		t.Errorf("expected backup file to be removed, got %v", err)
+	}
+
+	if err := manager.Delete(context.Background(), "db-kept", DeleteOptions{KeepBackups: true}); err != nil {
+		t.Fatalf("Delete failed: %v", err)
+	}
+	if backups := store.ListBackups("db-kept"); len(backups) != 1 {
+		t.Errorf("expected backups to be kept, got %d", len(backups))
+	}
+	if _, err := os.Stat(filepath.Join(backupDir, "db-kept.dump")); err != nil {
+		t.Errorf("expected backup file to be kept, got %v", err)
+	}
+}
+
+func TestContainerHealthcheck(t *testing.T) {
+	db := &storage.DatabaseInstance{ID: "db-hc", Username: "app", Database: "appdb", Password: "secret"}
+
+	cfg := containerConfig(db, &PostgreSQLEngine{}, "postgres:16")
+	if cfg.Healthcheck == nil || len(cfg.Healthcheck.Cmd) == 0 || cfg.Healthcheck.Cmd[0] != "pg_isready" {
+		t.Fatalf("expected a pg_isready healthcheck, got %+v", cfg.Healthcheck)
+	}
+	if cfg.Healthcheck.Interval <= 0 || cfg.Healthcheck.Retries <= 0 {
+		t.Errorf("expected healthcheck timings to be set, got %+v", cfg.Healthcheck)
+	}
+	if cfg := containerConfig(db, &RedisEngine{}, "redis:7"); cfg.Healthcheck == nil || !strings.Contains(strings.Join(cfg.Healthcheck.Cmd, " "), "-a secret") {
+		t.Errorf("expected an authenticated redis-cli healthcheck, got %+v", cfg.Healthcheck)
+	}
+	if cfg := containerConfig(db, &CustomEngine{}, "custom"); cfg.Healthcheck != nil {
+		t.Errorf("expected no healthcheck for a custom engine, got %+v", cfg.Healthcheck)
+	}
+}
+
+func TestSyncStatusUnhealthy(t *testing.T) {
+	mockDocker := &MockDockerClient{ContainerStatus: "unhealthy"}
+	manager, store, cleanup := setupTestManager(t, mockDocker)
+	defer cleanup()
+	store.CreateDatabase(&storage.DatabaseInstance{ID: "db-sick", Name: "sick", Status: "running", ContainerID: "c-sick"})
+
+	manager.SyncAllStatuses(context.Background())
+	db, _ := store.GetDatabase("db-sick")
+	if db.Status != "unhealthy" || db.ErrorMessage != unhealthyMessage {
+		t.Errorf("expected unhealthy database, got status %q error %q", db.Status, db.ErrorMessage)
+	}
+
+	// An unhealthy container is still up and can be paused
+	if err := manager.Pause(context.Background(), "db-sick"); err != nil {
+		t.Errorf("expected unhealthy database to pause, got %v", err)
+	}
+
+	mockDocker.ContainerStatus = "running"
+	manager.SyncAllStatuses(context.Background())
+	if db, _ := store.GetDatabase("db-sick"); db.Status != "running" || db.ErrorMessage != "" {
+		t.Errorf("expected recovered database, got status %q error %q", db.Status, db.ErrorMessage)
+	}
+}
+
+func TestSyncStatusStarting(t *testing.T) {
+	// A restarted container whose healthcheck hasn't passed yet
+	mockDocker := &MockDockerClient{ContainerStatus: "starting"}
+	manager, store, cleanup := setupTestManager(t, mockDocker)
+	defer cleanup()
+	store.CreateDatabase(&storage.DatabaseInstance{ID: "db-boot", Name: "boot", Status: "running", ContainerID: "c-boot"})
+
+	manager.SyncAllStatuses(context.Background())
+	if db, _ := store.GetDatabase("db-boot"); db.Status != "starting" {
+		t.Errorf("expected status starting, got %q", db.Status)
+	}
+	if _, err := manager.Query(context.Background(), "db-boot", "SELECT 1", QueryOptions{}); err == nil {
+		t.Error("expected queries to wait until the database is ready")
+	}
+
+	mockDocker.ContainerStatus = "running"
+	manager.SyncAllStatuses(context.Background())
+	if db, _ := store.GetDatabase("db-boot"); db.Status != "running" {
+		t.Errorf("expected status running once healthy, got %q", db.Status)
+	}
+}
+
+func TestDeleteDuringCreate(t *testing.T) {
+	for _, hang := range []string{"PullImage", "StartContainer"} {
+		t.Run(hang, func(t *testing.T) {
+			mockDocker := &MockDockerClient{Hang: hang}
+			manager, store, cleanup := setupTestManager(t, mockDocker)
+			defer cleanup()
+
+			db, err := manager.Create(context.Background(), &CreateRequest{
+				Name:     "doomed",
+				Engine:   "postgresql",
+				Version:  "16",
+				Username: "admin",
+				Database: "app",
+			})
+			if err != nil {
+				t.Fatalf("Create failed: %v", err)
+			}
+			history, events, cancel, _ := manager.SubscribeProvisioning(db.ID)
+			defer cancel()
+			if hang == "StartContainer" {
+				// Wait for the container to be created, which may have
+				// happened before subscribing
+				started := slices.ContainsFunc(history, func(event ProvisionEvent) bool { return event.Stage == StageStarting })
+				for !started {
+					event, ok := <-events
+					if !ok {
+						t.Fatal("provisioning ended before the container was created")
+					}
+					started = event.Stage == StageStarting
+				}
+			}
+
+			if err := manager.Delete(context.Background(), db.ID, DeleteOptions{}); err != nil {
+				t.Fatalf("Delete failed: %v", err)
+			}
+
+			if _, err := store.GetDatabase(db.ID); err == nil {
+				t.Error("expected the database record to be gone")
+			}
+			if mockDocker.LastContainerID != "" && !slices.Contains(mockDocker.RemovedContainers, mockDocker.LastContainerID) {
+				t.Errorf("container %s left behind", mockDocker.LastContainerID)
+			}
+			if hang == "StartContainer" && mockDocker.LastContainerID == "" {
+				t.Error("expected a container to have been created")
+			}
+			volume := dataVolume(db)
+			if n := len(mockDocker.DeletedVolumes); n == 0 || mockDocker.DeletedVolumes[n-1] != volume {
+				t.Errorf("expected volume %s to be removed last, got %v", volume, mockDocker.DeletedVolumes)
+			}
+			history, _, _, _ = manager.SubscribeProvisioning(db.ID)
+			if last := history[len(history)-1]; last.Stage != StageError || last.Message != "Provisioning cancelled" {
+				t.Errorf("expected provisioning to end cancelled, got %+v", last)
+			}
+		})
+	}
+}
+
+func TestProvisionHubDeliversTerminalEvent(t *testing.T) {
+	hub := newProvisionHub()
+	hub.publish("db-1", StagePulling, "")
+	_, events, cancel, _ := hub.subscribe("db-1")
+	defer cancel()
+
+	// Nobody reads while the buffer fills up
+	for i := 0; i < 32; i++ {
+		hub.publish("db-1", StageStarting, fmt.Sprint(i))
+	}
+	hub.publish("db-1", StageReady, "")
+
+	var last ProvisionEvent
+	for event := range events {
+		last = event
+	}
+	if last.Stage != StageReady {
+		t.Errorf("expected the stream to end with the ready event, got %+v", last)
+	}
+}
+
+func TestProvisionQueue(t *testing.T) {
+	manager, store, cleanup := setupTestManager(t, &MockDockerClient{Hang: "PullImage"})
+	defer cleanup()
+	opts := DefaultOptions()
+	opts.ProvisionConcurrency = 1
+	manager.SetOptions(opts)
+
+	create := func(name string) *storage.DatabaseInstance {
+		t.Helper()
+		db, err := manager.Create(context.Background(), &CreateRequest{
+			Name:     name,
+			Engine:   "postgresql",
+			Version:  "16",
+			Username: "admin",
+			Database: "app",
+		})
+		if err != nil {
+			t.Fatalf("Create failed: %v", err)
+		}
+		return db
+	}
+	first := create("first")
+	if first.Status != "creating" {
+		t.Fatalf("expected the first database to be creating, got %s", first.Status)
+	}
+	second := create("second")
+	if second.Status != "queued" {
+		t.Fatalf("expected the second database to be queued, got %s", second.Status)
+	}
+	history, events, cancel, _ := manager.SubscribeProvisioning(second.ID)
+	defer cancel()
+	if len(history) != 1 || history[0].Stage != StageQueued {
+		t.Fatalf("expected a queued event, got %+v", history)
+	}
+
+	// Finishing the first provisioning admits the second
+	if err := manager.Delete(context.Background(), first.ID, DeleteOptions{}); err != nil {
+		t.Fatalf("Delete failed: %v", err)
+	}
+	select {
+	case event := <-events:
+		if event.Stage != StagePulling {
+			t.Fatalf("expected the second database to start pulling, got %+v", event)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("the queued database was not admitted")
+	}
+	stored, err := store.GetDatabase(second.ID)
+	if err != nil {
+		t.Fatalf("GetDatabase failed: %v", err)
+	}
+	if stored.Status != "creating" {
+		t.Errorf("expected the admitted database to be creating, got %s", stored.Status)
+	}
+
+	if err := manager.Delete(context.Background(), second.ID, DeleteOptions{}); err != nil {
+		t.Fatalf("Delete failed: %v", err)
+	}
+
+	// Provisioning doesn't survive a restart, so records it left behind fail
+	store.CreateDatabase(&storage.DatabaseInstance{ID: "db-queued", Engine: "postgresql", Status: "queued"})
+	store.CreateDatabase(&storage.DatabaseInstance{ID: "db-creating", Engine: "postgresql", Status: "creating", ContainerID: "c-creating"})
+	store.CreateDatabase(&storage.DatabaseInstance{ID: "db-running", Engine: "postgresql", Status: "running", ContainerID: "c-running"})
+	NewManager(store, &MockDockerClient{}).FailInterruptedProvisioning()
+	for id, want := range map[string]string{"db-queued": "error", "db-creating": "error", "db-running": "running"} {
+		if db, _ := store.GetDatabase(id); db.Status != want {
+			t.Errorf("expected %s to be %s after a restart, got %s", id, want, db.Status)
+		}
+	}
+}
+
+func TestQueryWhileContainerUp(t *testing.T) {
+	mock := &MockDockerClient{}
+	manager, store, cleanup := setupTestManager(t, mock)
+	defer cleanup()
+	mock.ExecOutput = "n\x1e1\n"
+
+	for _, status := range []string{"running", "starting", "unhealthy"} {
+		store.CreateDatabase(&storage.DatabaseInstance{ID: "db-" + status, Engine: "postgresql", Status: status, ContainerID: "c-" + status})
+		if _, err := manager.Query(context.Background(), "db-"+status, "SELECT 1", QueryOptions{}); err != nil {
+			t.Errorf("expected a %s database to be queried, got %v", status, err)
+		}
+	}
+	store.CreateDatabase(&storage.DatabaseInstance{ID: "db-stopped", Engine: "postgresql", Status: "stopped", ContainerID: "c-stopped"})
+	if _, err := manager.Query(context.Background(), "db-stopped", "SELECT 1", QueryOptions{}); err == nil {
+		t.Error("expected a stopped database to be refused")
+	}
+}
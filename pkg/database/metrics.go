@@ -3,83 +3,63 @@ package database
 import (
 	"sync"
 	"time"
-)
 
-const (
-	// MaxHistoryPoints is the maximum number of metrics points to keep per database
-	MaxHistoryPoints = 60 // 1 hour at 1-minute intervals
+	"github.com/rs/zerolog/log"
+	"github.com/sirrobot01/dbnest/pkg/storage"
 )
 
 // MetricsPoint represents a single metrics snapshot
-type MetricsPoint struct {
-	Timestamp     time.Time `json:"timestamp"`
-	CPUPercent    float64   `json:"cpuPercent"`
-	MemoryUsage   int64     `json:"memoryUsage"`
-	MemoryLimit   int64     `json:"memoryLimit"`
-	MemoryPercent float64   `json:"memoryPercent"`
-	StorageUsed   int64     `json:"storageUsed"`
-	Connections   int       `json:"connections"`
-	NetworkRx     int64     `json:"networkRx"`
-	NetworkTx     int64     `json:"networkTx"`
-}
+type MetricsPoint = storage.MetricsPoint
 
-// MetricsHistory stores historical metrics for databases
-type MetricsHistory struct {
-	mu      sync.RWMutex
-	history map[string][]MetricsPoint // database ID -> metrics points
+// metricsThrottle tracks when each database last had a point persisted
+type metricsThrottle struct {
+	mu   sync.Mutex
+	last map[string]time.Time // database ID -> last persisted timestamp
 }
 
-// NewMetricsHistory creates a new metrics history store
-func NewMetricsHistory() *MetricsHistory {
-	return &MetricsHistory{
-		history: make(map[string][]MetricsPoint),
+func newMetricsThrottle() *metricsThrottle {
+	return &metricsThrottle{
+		last: make(map[string]time.Time),
 	}
 }
 
-// Record adds a new metrics point for a database
-func (mh *MetricsHistory) Record(dbID string, point MetricsPoint) {
-	mh.mu.Lock()
-	defer mh.mu.Unlock()
+// allow reports whether a point at ts should be persisted, given the minimum interval
+func (mt *metricsThrottle) allow(dbID string, ts time.Time, interval time.Duration) bool {
+	mt.mu.Lock()
+	defer mt.mu.Unlock()
 
-	points := mh.history[dbID]
-	
-	// Add new point
-	points = append(points, point)
-	
-	// Keep only the last MaxHistoryPoints
-	if len(points) > MaxHistoryPoints {
-		points = points[len(points)-MaxHistoryPoints:]
+	if last, ok := mt.last[dbID]; ok && ts.Sub(last) < interval {
+		return false
 	}
-	
-	mh.history[dbID] = points
+	mt.last[dbID] = ts
+	return true
 }
 
-// Get returns the metrics history for a database
-func (mh *MetricsHistory) Get(dbID string) []MetricsPoint {
-	mh.mu.RLock()
-	defer mh.mu.RUnlock()
+// forget drops throttle state for a database
+func (mt *metricsThrottle) forget(dbID string) {
+	mt.mu.Lock()
+	defer mt.mu.Unlock()
+	delete(mt.last, dbID)
+}
 
-	points := mh.history[dbID]
-	if points == nil {
-		return []MetricsPoint{}
-	}
-	
-	// Return a copy to avoid race conditions
-	result := make([]MetricsPoint, len(points))
-	copy(result, points)
-	return result
+// GetMetricsHistory returns persisted metrics for a database recorded since the given time
+func (m *Manager) GetMetricsHistory(dbID string, since time.Time) []*MetricsPoint {
+	return m.store.ListMetricsPoints(dbID, since)
 }
 
-// Delete removes the metrics history for a database
-func (mh *MetricsHistory) Delete(dbID string) {
-	mh.mu.Lock()
-	defer mh.mu.Unlock()
-	delete(mh.history, dbID)
+// RecordMetrics persists a metrics point for a database, at most once per MetricsInterval
+func (m *Manager) RecordMetrics(dbID string, point MetricsPoint) {
+	if !m.metricsThrottle.allow(dbID, point.Timestamp, m.options().MetricsInterval) {
+		return
+	}
+	if err := m.store.AddMetricsPoint(dbID, &point); err != nil {
+		log.Error().Err(err).Str("db", dbID).Msg("Failed to persist metrics point")
+	}
 }
 
-// Clear removes all metrics history
-func (mh *MetricsHistory) Clear() {
-	mh.mu.Lock()
-	defer mh.mu.Unlock()
-	mh.history = make(map[string][]MetricsPoint)
+// PurgeMetrics removes metrics points older than the retention period
+// and returns how many were removed
+func (m *Manager) PurgeMetrics() (int, error) {
+	cutoff := time.Now().Add(-m.options().MetricsRetention)
+	return m.store.DeleteMetricsBefore(cutoff)
 }
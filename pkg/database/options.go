@@ -0,0 +1,112 @@
+package database
+
+import (
+	"time"
+
+	"github.com/sirrobot01/dbnest/pkg/runtime"
+)
+
+// Options holds tunable database manager settings
+type Options struct {
+	// MetricsInterval is the minimum spacing between persisted metrics
+	// points for a database; samples arriving faster are not stored
+	MetricsInterval time.Duration
+	// MetricsRetention is how long metrics points are kept before purging
+	MetricsRetention time.Duration
+
+	// Limits applied to new databases whose create request leaves them unset.
+	// Zero memory or storage means unlimited.
+	DefaultMemoryLimit  int64   // MB
+	DefaultCPULimit     float64 // cores
+	DefaultStorageLimit int64   // MB
+
+	// MemoryOvercommitRatio caps the sum of all databases' memory limits at
+	// this multiple of host memory. Zero disables the capacity check.
+	MemoryOvercommitRatio float64
+	// HostMemoryLimit overrides the detected host memory (MB). Zero reads /proc/meminfo.
+	HostMemoryLimit int64
+
+	// HostDataRoot is the host directory bind-mounted data paths must be
+	// inside. Empty disables host data paths.
+	HostDataRoot string
+
+	// DefaultRestartPolicy is the container restart policy for new databases
+	// that don't set one
+	DefaultRestartPolicy string
+
+	// DefaultBindAddress is the host IP new databases publish their port on
+	// when the create request doesn't set one. Empty means all interfaces.
+	DefaultBindAddress string
+
+	// DefaultRunAsUser and DefaultRunAsGroup are the numeric IDs new
+	// databases run as when the create request doesn't set them. Zero keeps
+	// the image's default user.
+	DefaultRunAsUser  int
+	DefaultRunAsGroup int
+	// DefaultUsernsMode is the user namespace mode for new databases. Empty
+	// means the runtime default.
+	DefaultUsernsMode string
+	// DefaultReadOnlyRootfs runs new databases with a read-only root
+	// filesystem unless the create request says otherwise
+	DefaultReadOnlyRootfs bool
+
+	// QueryRowLimit caps the rows a console query returns. Zero means no limit.
+	QueryRowLimit int
+
+	// ContainerSecurity is applied to every database container DBnest creates
+	ContainerSecurity runtime.SecurityOptions
+
+	// PullAttempts is how many times an image pull is tried before provisioning
+	// fails; PullBackoff is the delay before the first retry, doubling after each
+	PullAttempts int
+	PullBackoff  time.Duration
+	// ProvisionConcurrency caps how many databases are provisioned at once;
+	// further creates are queued. Zero means no limit.
+	ProvisionConcurrency int
+
+	// BackupDir is where new backups are written. Empty means <data dir>/backups.
+	BackupDir string
+	// BackupBinlogPosition records binary log coordinates in MySQL/MariaDB
+	// backups so they can seed a replica
+	BackupBinlogPosition bool
+	// BackupEncryptionKey, when set, encrypts new backups at rest with
+	// AES-256-GCM. It must be BackupKeySize bytes.
+	BackupEncryptionKey []byte
+}
+
+// DefaultOptions returns the default manager settings
+func DefaultOptions() Options {
+	return Options{
+		MetricsInterval:  time.Minute,
+		MetricsRetention: 7 * 24 * time.Hour,
+		DefaultCPULimit:  1.0,
+
+		MemoryOvercommitRatio: 1.0,
+		DefaultRestartPolicy:  runtime.DefaultRestartPolicy,
+		DefaultBindAddress:    "127.0.0.1",
+		ContainerSecurity: runtime.SecurityOptions{
+			NoNewPrivileges: true,
+			CapDrop:         runtime.DefaultCapDrop,
+		},
+
+		QueryRowLimit: 1000,
+
+		PullAttempts:         4,
+		PullBackoff:          2 * time.Second,
+		ProvisionConcurrency: 3,
+	}
+}
+
+// SetOptions replaces the manager settings. Safe to call while running.
+func (m *Manager) SetOptions(opts Options) {
+	m.optsMu.Lock()
+	defer m.optsMu.Unlock()
+	m.opts = opts
+}
+
+// options returns a snapshot of the current manager settings
+func (m *Manager) options() Options {
+	m.optsMu.RLock()
+	defer m.optsMu.RUnlock()
+	return m.opts
+}
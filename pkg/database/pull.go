@@ -0,0 +1,75 @@
+package database
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/rs/zerolog/log"
+	"github.com/sirrobot01/dbnest/pkg/storage"
+)
+
+// maxPullBackoff caps the delay between image pull attempts
+const maxPullBackoff = time.Minute
+
+// permanentPullErrors are substrings of registry errors that retrying won't fix
+var permanentPullErrors = []string{
+	"not found",
+	"manifest unknown",
+	"does not exist",
+	"unauthorized",
+	"access denied",
+	"authentication required",
+	"invalid reference format",
+}
+
+// isPermanentPullError reports whether a pull failure should not be retried,
+// e.g. a missing image or rejected credentials
+func isPermanentPullError(err error) bool {
+	if errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+		return true
+	}
+	msg := strings.ToLower(err.Error())
+	for _, s := range permanentPullErrors {
+		if strings.Contains(msg, s) {
+			return true
+		}
+	}
+	return false
+}
+
+// pullImage pulls an image, retrying transient failures with exponential
+// backoff. Retry progress is recorded in the database's error message.
+func (m *Manager) pullImage(ctx context.Context, db *storage.DatabaseInstance, imageName string) error {
+	opts := m.options()
+	attempts := max(opts.PullAttempts, 1)
+	backoff := opts.PullBackoff
+
+	for attempt := 1; ; attempt++ {
+		err := m.client.PullImage(ctx, imageName)
+		if err == nil {
+			if attempt > 1 {
+				db.ErrorMessage = ""
+				m.store.UpdateDatabase(db)
+			}
+			return nil
+		}
+		if attempt >= attempts || isPermanentPullError(err) {
+			return err
+		}
+
+		log.Warn().Err(err).Str("id", db.ID).Str("image", imageName).
+			Int("attempt", attempt).Dur("retry_in", backoff).Msg("Image pull failed, retrying")
+		db.ErrorMessage = fmt.Sprintf("Image pull failed (attempt %d/%d), retrying in %s: %v", attempt, attempts, backoff, err)
+		m.store.UpdateDatabase(db)
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(backoff):
+		}
+		backoff = min(backoff*2, maxPullBackoff)
+	}
+}
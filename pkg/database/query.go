@@ -0,0 +1,265 @@
+package database
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// ErrReadOnlyQuery is returned when a read-only query would change data
+var ErrReadOnlyQuery = errors.New("only read queries are allowed")
+
+// QueryOptions adjust how Query runs a console query
+type QueryOptions struct {
+	// Limit caps the rows returned, 0 means the configured QueryRowLimit.
+	// It can lower the configured limit but not raise it.
+	Limit int
+	// ReadOnly refuses statements that change data or schema
+	ReadOnly bool
+}
+
+// Query runs a console query against a database. SQL scripts are split into
+// statements that run one at a time, each with its own result, and a failing
+// statement stops the ones after it. Each statement runs in its own session,
+// so a script can't rely on a transaction spanning statements. A result cut
+// short by the row limit is marked Truncated.
+func (m *Manager) Query(ctx context.Context, databaseID, query string, opts QueryOptions) ([]*QueryResult, error) {
+	db, err := m.store.GetDatabase(databaseID)
+	if err != nil {
+		return nil, err
+	}
+	if !ContainerUp(db.Status) || db.ContainerID == "" {
+		return nil, fmt.Errorf("database is not running")
+	}
+	engine, err := GetEngine(db.Engine)
+	if err != nil {
+		return nil, fmt.Errorf("unsupported engine: %s", db.Engine)
+	}
+
+	// Custom engines' queries are passed through whole, and can't be checked
+	// for writes
+	_, custom := engine.(*CustomEngine)
+	if custom && opts.ReadOnly {
+		return nil, ErrReadOnlyQuery
+	}
+	statements := []string{query}
+	if db.Engine != "redis" && !custom {
+		if statements = splitStatements(db.Engine, query); len(statements) == 0 {
+			return nil, errors.New("query has no statements")
+		}
+	}
+
+	execute := engine.ExecuteQuery
+	if opts.ReadOnly {
+		// Check the whole script before any of it runs
+		for _, stmt := range statements {
+			if err := checkReadOnly(db.Engine, stmt); err != nil {
+				return nil, err
+			}
+		}
+		if ro, ok := engine.(ReadOnlyQueryEngine); ok {
+			execute = ro.ExecuteReadOnlyQuery
+		}
+	}
+
+	limit := opts.Limit
+	if max := m.options().QueryRowLimit; max > 0 && (limit <= 0 || limit > max) {
+		limit = max
+	}
+	results := make([]*QueryResult, 0, len(statements))
+	for _, stmt := range statements {
+		result, err := execute(ctx, m.client, db, limitQuery(db.Engine, stmt, limit))
+		if err != nil {
+			return nil, err
+		}
+		truncateResult(result, limit)
+		result.Statement = stmt
+		results = append(results, result)
+		if result.Error != "" {
+			break
+		}
+	}
+	return results, nil
+}
+
+// splitStatements splits an SQL script on the semicolons that end its
+// statements, skipping those inside quoted strings, identifiers and
+// comments, and PostgreSQL dollar-quoted bodies. Empty and comment-only
+// statements are dropped.
+func splitStatements(engine, script string) []string {
+	// ClickHouse quotes strings and identifiers the way MySQL does
+	mysql := engine == "mysql" || engine == "mariadb" || engine == "clickhouse"
+	var statements []string
+	start := 0
+	add := func(end int) {
+		stmt := strings.TrimSpace(script[start:end])
+		if sqlCommentRegex.ReplaceAllString(stmt, "") != "" {
+			statements = append(statements, stmt)
+		}
+	}
+
+	for i := 0; i < len(script); i++ {
+		switch c := script[i]; {
+		case c == ';':
+			add(i)
+			start = i + 1
+		case c == '\'' || c == '"' || (c == '`' && mysql):
+			// MySQL strings and PostgreSQL E'' strings take backslash escapes
+			escapes := mysql && c != '`' || c == '\'' && i > 0 && (script[i-1] == 'E' || script[i-1] == 'e') && (i == 1 || !isIdentByte(script[i-2]))
+			i = skipQuoted(script, i, escapes)
+		case c == '-' && strings.HasPrefix(script[i:], "--"), c == '#' && mysql:
+			if end := strings.IndexByte(script[i:], '\n'); end >= 0 {
+				i += end
+			} else {
+				i = len(script)
+			}
+		case c == '/' && strings.HasPrefix(script[i:], "/*"):
+			i = skipBlockComment(script, i, !mysql)
+		case c == '$' && !mysql && (i == 0 || !isIdentByte(script[i-1])):
+			i = skipDollarQuoted(script, i)
+		}
+	}
+	add(len(script))
+	return statements
+}
+
+// skipQuoted returns the index of the quote closing the one at script[i]. A
+// doubled quote is an escaped quote, as is a backslashed one when escapes
+// is set.
+func skipQuoted(script string, i int, escapes bool) int {
+	quote := script[i]
+	for i++; i < len(script); i++ {
+		switch {
+		case escapes && script[i] == '\\':
+			i++
+		case script[i] == quote:
+			if i+1 < len(script) && script[i+1] == quote {
+				i++
+				continue
+			}
+			return i
+		}
+	}
+	return len(script)
+}
+
+// skipBlockComment returns the index of the end of the comment opening at
+// script[i]. PostgreSQL comments nest.
+func skipBlockComment(script string, i int, nested bool) int {
+	depth := 0
+	for ; i < len(script)-1; i++ {
+		switch {
+		case script[i] == '/' && script[i+1] == '*':
+			if depth == 0 || nested {
+				depth++
+			}
+			i++
+		case script[i] == '*' && script[i+1] == '/':
+			i++
+			if depth--; depth == 0 {
+				return i
+			}
+		}
+	}
+	return len(script)
+}
+
+// skipDollarQuoted returns the index of the end of the $tag$ body opening at
+// script[i], or i when the $ doesn't open one (e.g. a $1 parameter)
+func skipDollarQuoted(script string, i int) int {
+	end := strings.IndexByte(script[i+1:], '$')
+	if end < 0 {
+		return i
+	}
+	tag := script[i : i+end+2]
+	for j := 1; j < len(tag)-1; j++ {
+		if !isIdentByte(tag[j]) || (j == 1 && tag[j] >= '0' && tag[j] <= '9') {
+			return i
+		}
+	}
+	if close := strings.Index(script[i+len(tag):], tag); close >= 0 {
+		return i + len(tag) + close + len(tag) - 1
+	}
+	return len(script)
+}
+
+func isIdentByte(c byte) bool {
+	return c == '_' || c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c >= '0' && c <= '9' || c >= 0x80
+}
+
+// readOnlySQLKeywords are the statements a read-only SQL query may start with
+var readOnlySQLKeywords = map[string]bool{
+	"select": true, "show": true, "explain": true, "describe": true, "desc": true,
+	"with": true, "values": true, "table": true,
+}
+
+// readOnlyRedisCommands are the Redis commands a read-only query may run
+var readOnlyRedisCommands = map[string]bool{
+	"GET": true, "MGET": true, "GETRANGE": true, "STRLEN": true, "EXISTS": true,
+	"TYPE": true, "TTL": true, "PTTL": true, "KEYS": true, "SCAN": true,
+	"RANDOMKEY": true, "DBSIZE": true, "INFO": true, "PING": true, "ECHO": true, "TIME": true,
+	"HGET": true, "HMGET": true, "HGETALL": true, "HKEYS": true, "HVALS": true,
+	"HLEN": true, "HEXISTS": true, "HSTRLEN": true, "HSCAN": true,
+	"LRANGE": true, "LLEN": true, "LINDEX": true, "LPOS": true,
+	"SMEMBERS": true, "SISMEMBER": true, "SMISMEMBER": true, "SCARD": true, "SRANDMEMBER": true, "SSCAN": true,
+	"ZRANGE": true, "ZRANGEBYSCORE": true, "ZRANGEBYLEX": true, "ZREVRANGE": true, "ZREVRANGEBYSCORE": true,
+	"ZSCORE": true, "ZMSCORE": true, "ZCARD": true, "ZCOUNT": true, "ZRANK": true, "ZREVRANK": true, "ZSCAN": true,
+	"XRANGE": true, "XREVRANGE": true, "XLEN": true, "BITCOUNT": true, "GETBIT": true, "PFCOUNT": true,
+}
+
+var (
+	sqlCommentRegex = regexp.MustCompile(`(?s)^\s*(--[^\n]*\n?|/\*.*?\*/|\s)*`)
+	sqlWordRegex    = regexp.MustCompile(`^[A-Za-z]+`)
+)
+
+// checkReadOnly refuses a statement unless it only reads. PostgreSQL and
+// MySQL statements also run in read-only transactions, so the server catches
+// writes the check lets through, such as a data-modifying WITH.
+func checkReadOnly(engine, query string) error {
+	if engine == "redis" {
+		args := parseRedisCommand(query)
+		if len(args) == 0 || !readOnlyRedisCommands[strings.ToUpper(args[0])] {
+			return ErrReadOnlyQuery
+		}
+		return nil
+	}
+
+	stmt := sqlCommentRegex.ReplaceAllString(query, "")
+	if !readOnlySQLKeywords[strings.ToLower(sqlWordRegex.FindString(stmt))] || intoRegex.MatchString(stmt) {
+		return ErrReadOnlyQuery
+	}
+	return nil
+}
+
+var (
+	selectRegex = regexp.MustCompile(`(?i)^select\b`)
+	intoRegex   = regexp.MustCompile(`(?i)\binto\b`)
+)
+
+// limitQuery wraps a single Postgres SELECT in a subquery that stops after
+// limit+1 rows, so a huge table isn't read out in full; the extra row tells
+// truncateResult there was more. Other queries, and SELECTs that can't run
+// as a subquery (SELECT INTO), are returned unchanged and truncated after
+// they run.
+func limitQuery(engine, query string, limit int) string {
+	if engine != "postgresql" || limit <= 0 {
+		return query
+	}
+	trimmed := strings.TrimRight(strings.TrimSpace(query), "; \t\n")
+	if !selectRegex.MatchString(trimmed) || strings.Contains(trimmed, ";") || intoRegex.MatchString(trimmed) {
+		return query
+	}
+	// The newlines keep a trailing -- comment from swallowing the parenthesis
+	return fmt.Sprintf("SELECT * FROM (\n%s\n) AS dbnest_limited LIMIT %d", trimmed, limit+1)
+}
+
+// truncateResult cuts result down to limit rows, marking it Truncated
+func truncateResult(result *QueryResult, limit int) {
+	if limit > 0 && len(result.Rows) > limit {
+		result.Rows = result.Rows[:limit]
+		result.RowCount = limit
+		result.Truncated = true
+	}
+}
@@ -0,0 +1,74 @@
+package database
+
+import (
+	"context"
+	"slices"
+	"sync"
+)
+
+// provisionQueue limits how many databases are provisioned at once.
+// Provisions waiting for a slot are admitted in the order they queued. A
+// limit of zero or less means no limit; a raised limit admits more waiting
+// provisions as running ones finish.
+type provisionQueue struct {
+	mu      sync.Mutex
+	running int
+	waiting []chan struct{} // closed when handed a slot
+}
+
+// tryAcquire takes a slot if one is free and nothing is waiting for one
+func (q *provisionQueue) tryAcquire(limit int) bool {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	if limit > 0 && (q.running >= limit || len(q.waiting) > 0) {
+		return false
+	}
+	q.running++
+	return true
+}
+
+// acquire waits for a slot until ctx is done
+func (q *provisionQueue) acquire(ctx context.Context, limit int) error {
+	q.mu.Lock()
+	if limit <= 0 || (q.running < limit && len(q.waiting) == 0) {
+		q.running++
+		q.mu.Unlock()
+		return nil
+	}
+	ready := make(chan struct{})
+	q.waiting = append(q.waiting, ready)
+	q.mu.Unlock()
+
+	select {
+	case <-ready:
+		return nil
+	case <-ctx.Done():
+		q.mu.Lock()
+		defer q.mu.Unlock()
+		if i := slices.Index(q.waiting, ready); i >= 0 {
+			q.waiting = slices.Delete(q.waiting, i, i+1)
+		} else {
+			// Handed a slot just as ctx ended; pass it on
+			q.running--
+			q.admitLocked(limit)
+		}
+		return ctx.Err()
+	}
+}
+
+// release frees a slot taken by tryAcquire or acquire
+func (q *provisionQueue) release(limit int) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	q.running--
+	q.admitLocked(limit)
+}
+
+// admitLocked hands free slots to waiting provisions. Must be called with mu held.
+func (q *provisionQueue) admitLocked(limit int) {
+	for len(q.waiting) > 0 && (limit <= 0 || q.running < limit) {
+		close(q.waiting[0])
+		q.waiting = q.waiting[1:]
+		q.running++
+	}
+}
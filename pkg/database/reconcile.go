@@ -0,0 +1,136 @@
+package database
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/rs/zerolog/log"
+	"github.com/sirrobot01/dbnest/pkg/storage"
+)
+
+// OrphanedContainer is a managed container on the runtime that no stored database owns
+type OrphanedContainer struct {
+	ContainerID string `json:"containerId"`
+	Name        string `json:"name"`
+	State       string `json:"state"`
+	DatabaseID  string `json:"databaseId,omitempty"` // From the dbnest.id label, if present
+	// Superseded is true when the database still exists but uses a different
+	// container (e.g. left behind by a repair). Its volume is still in use.
+	Superseded bool `json:"superseded"`
+}
+
+// MissingContainer is a stored database whose container no longer exists
+type MissingContainer struct {
+	DatabaseID  string `json:"databaseId"`
+	Name        string `json:"name"`
+	ContainerID string `json:"containerId"`
+	Status      string `json:"status"`
+}
+
+// ReconcileReport lists drift between the store and the container runtime
+type ReconcileReport struct {
+	OrphanedContainers []OrphanedContainer `json:"orphanedContainers"`
+	MissingContainers  []MissingContainer  `json:"missingContainers"`
+}
+
+// CleanupResult reports what a reconcile cleanup removed
+type CleanupResult struct {
+	RemovedContainers []string `json:"removedContainers"`
+	RemovedVolumes    []string `json:"removedVolumes"`
+	Errors            []string `json:"errors,omitempty"`
+}
+
+// Reconcile cross-references runtime containers against stored databases
+func (m *Manager) Reconcile(ctx context.Context) (*ReconcileReport, error) {
+	containers, err := m.client.ListContainers(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list containers: %w", err)
+	}
+
+	databases := make(map[string]string)  // database ID -> container ID
+	provisioning := make(map[string]bool) // databases whose container may not be recorded yet
+	for _, db := range m.store.ListDatabases() {
+		databases[db.ID] = db.ContainerID
+		if provisioningStatus(db) {
+			provisioning[db.ID] = true
+		}
+	}
+
+	report := &ReconcileReport{
+		OrphanedContainers: []OrphanedContainer{},
+		MissingContainers:  []MissingContainer{},
+	}
+
+	seen := make(map[string]bool)
+	for _, c := range containers {
+		seen[c.ID] = true
+		dbID := c.Labels["dbnest.id"]
+		containerID, exists := databases[dbID]
+		if exists && (containerID == c.ID || provisioning[dbID]) {
+			continue
+		}
+		report.OrphanedContainers = append(report.OrphanedContainers, OrphanedContainer{
+			ContainerID: c.ID,
+			Name:        c.Name,
+			State:       c.State,
+			DatabaseID:  dbID,
+			Superseded:  exists,
+		})
+	}
+
+	for _, db := range m.store.ListDatabases() {
+		// Databases still provisioning may not have a container yet
+		if provisioningStatus(db) || seen[db.ContainerID] {
+			continue
+		}
+		report.MissingContainers = append(report.MissingContainers, MissingContainer{
+			DatabaseID:  db.ID,
+			Name:        db.Name,
+			ContainerID: db.ContainerID,
+			Status:      db.Status,
+		})
+	}
+
+	return report, nil
+}
+
+// provisioningStatus reports whether a database may still be provisioning,
+// in which case its container can exist before it is recorded
+func provisioningStatus(db *storage.DatabaseInstance) bool {
+	return db.ContainerID == "" || db.Status == "creating" || db.Status == "queued"
+}
+
+// CleanupOrphans removes orphaned containers and the volumes of databases that
+// no longer exist. Stored databases with missing containers are left for the
+// user to repair or delete.
+func (m *Manager) CleanupOrphans(ctx context.Context) (*CleanupResult, error) {
+	report, err := m.Reconcile(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	result := &CleanupResult{
+		RemovedContainers: []string{},
+		RemovedVolumes:    []string{},
+	}
+	for _, orphan := range report.OrphanedContainers {
+		if err := m.client.RemoveContainer(ctx, orphan.ContainerID, true); err != nil {
+			result.Errors = append(result.Errors, fmt.Sprintf("container %s: %v", orphan.ContainerID, err))
+			continue
+		}
+		result.RemovedContainers = append(result.RemovedContainers, orphan.ContainerID)
+		log.Info().Str("container", orphan.ContainerID).Str("db", orphan.DatabaseID).Msg("Removed orphaned container")
+
+		if orphan.DatabaseID == "" || orphan.Superseded {
+			continue
+		}
+		volumeName := fmt.Sprintf("dbnest-vol-%s", orphan.DatabaseID)
+		if err := m.client.DeleteVolume(ctx, volumeName); err != nil {
+			result.Errors = append(result.Errors, fmt.Sprintf("volume %s: %v", volumeName, err))
+			continue
+		}
+		result.RemovedVolumes = append(result.RemovedVolumes, volumeName)
+	}
+
+	return result, nil
+}
@@ -0,0 +1,70 @@
+package database
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// ErrInsufficientResources is returned when a database would not fit on the host
+var ErrInsufficientResources = errors.New("insufficient host resources")
+
+// hostMemoryMB returns total host memory in MB from /proc/meminfo, or 0 if unknown
+func hostMemoryMB() int64 {
+	f, err := os.Open("/proc/meminfo")
+	if err != nil {
+		return 0
+	}
+	defer f.Close()
+
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) >= 2 && fields[0] == "MemTotal:" {
+			kb, err := strconv.ParseInt(fields[1], 10, 64)
+			if err != nil {
+				return 0
+			}
+			return kb / 1024
+		}
+	}
+	return 0
+}
+
+// checkMemoryCapacity verifies that a new database with the given memory limit
+// (MB) fits on the host: the limit itself must not exceed host memory, and the
+// sum of all databases' limits must stay within host memory times the
+// overcommit ratio. Databases without a memory limit are not counted.
+func (m *Manager) checkMemoryCapacity(memoryLimitMB int64) error {
+	opts := m.options()
+	if opts.MemoryOvercommitRatio <= 0 || memoryLimitMB <= 0 {
+		return nil
+	}
+
+	hostMB := opts.HostMemoryLimit
+	if hostMB <= 0 {
+		hostMB = hostMemoryMB()
+	}
+	if hostMB <= 0 {
+		// Host memory unknown (e.g. not Linux); nothing to check against
+		return nil
+	}
+
+	if memoryLimitMB > hostMB {
+		return fmt.Errorf("%w: memory limit %d MB exceeds host memory %d MB", ErrInsufficientResources, memoryLimitMB, hostMB)
+	}
+
+	var allocatedMB int64
+	for _, db := range m.store.ListDatabases() {
+		allocatedMB += db.MemoryLimit / (1024 * 1024)
+	}
+	capacityMB := int64(float64(hostMB) * opts.MemoryOvercommitRatio)
+	if allocatedMB+memoryLimitMB > capacityMB {
+		return fmt.Errorf("%w: %d MB already allocated, %d MB requested, capacity is %d MB (%.2gx of %d MB host memory)",
+			ErrInsufficientResources, allocatedMB, memoryLimitMB, capacityMB, opts.MemoryOvercommitRatio, hostMB)
+	}
+	return nil
+}
@@ -0,0 +1,242 @@
+package database
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/sirrobot01/dbnest/pkg/storage"
+)
+
+// Limits on how much of a Redis keyspace is sampled for key patterns
+const (
+	redisSchemaScanCount = 1000
+	redisSchemaMaxKeys   = 10000
+)
+
+// Schema describes the objects in a database: tables for SQL engines, key
+// patterns for Redis
+type Schema struct {
+	Engine      string        `json:"engine"`
+	Tables      []SchemaTable `json:"tables,omitempty"`
+	KeyPatterns []KeyPattern  `json:"keyPatterns,omitempty"`
+	// Truncated is set when only part of a Redis keyspace was sampled
+	Truncated bool `json:"truncated,omitempty"`
+}
+
+// SchemaTable is a table or view
+type SchemaTable struct {
+	Schema  string         `json:"schema,omitempty"` // PostgreSQL only
+	Name    string         `json:"name"`
+	Type    string         `json:"type"` // "table" or "view"
+	Columns []SchemaColumn `json:"columns,omitempty"`
+}
+
+// SchemaColumn is a column of a table
+type SchemaColumn struct {
+	Name     string `json:"name"`
+	Type     string `json:"type"`
+	Nullable bool   `json:"nullable"`
+}
+
+// KeyPattern groups Redis keys sharing a prefix, e.g. "user:*"
+type KeyPattern struct {
+	Pattern string `json:"pattern"`
+	Count   int    `json:"count"`
+}
+
+// schemaQueries returns the introspection queries listing a SQL database's
+// tables and columns, or false if the engine has none. Both select schema,
+// table name and then table type or column name, type and nullability.
+func schemaQueries(engine string) (tables, columns string, ok bool) {
+	switch engine {
+	case "postgresql":
+		filter := "table_schema NOT IN ('pg_catalog', 'information_schema')"
+		return "SELECT table_schema, table_name, table_type FROM information_schema.tables WHERE " + filter +
+				" ORDER BY table_schema, table_name",
+			"SELECT table_schema, table_name, column_name, data_type, is_nullable FROM information_schema.columns WHERE " + filter +
+				" ORDER BY table_schema, table_name, ordinal_position",
+			true
+	case "mysql", "mariadb":
+		return "SELECT '' AS table_schema, table_name, table_type FROM information_schema.tables WHERE table_schema = DATABASE()" +
+				" ORDER BY table_name",
+			"SELECT '' AS table_schema, table_name, column_name, column_type, is_nullable FROM information_schema.columns WHERE table_schema = DATABASE()" +
+				" ORDER BY table_name, ordinal_position",
+			true
+	case "clickhouse":
+		return "SELECT '' AS table_schema, name, engine FROM system.tables WHERE database = currentDatabase() AND NOT is_temporary" +
+				" ORDER BY name",
+			"SELECT '' AS table_schema, table, name, type, if(startsWith(type, 'Nullable('), 'YES', 'NO') FROM system.columns WHERE database = currentDatabase()" +
+				" ORDER BY table, position",
+			true
+	}
+	return "", "", false
+}
+
+// GetSchema lists a running database's tables (with their columns if
+// columns is set) or, for Redis, the key patterns in a sample of its keyspace
+func (m *Manager) GetSchema(ctx context.Context, databaseID string, columns bool) (*Schema, error) {
+	db, err := m.store.GetDatabase(databaseID)
+	if err != nil {
+		return nil, err
+	}
+	if db.ContainerID == "" {
+		return nil, fmt.Errorf("no container associated with database")
+	}
+	engine, err := GetEngine(db.Engine)
+	if err != nil {
+		return nil, fmt.Errorf("unsupported engine: %s", db.Engine)
+	}
+
+	if db.Engine == "redis" {
+		return m.redisSchema(ctx, engine, db)
+	}
+
+	tablesQuery, columnsQuery, ok := schemaQueries(db.Engine)
+	if !ok {
+		return nil, fmt.Errorf("schema listing not supported for %s", engine.Name())
+	}
+
+	schema := &Schema{Engine: db.Engine, Tables: []SchemaTable{}}
+	rows, err := m.schemaRows(ctx, engine, db, tablesQuery)
+	if err != nil {
+		return nil, err
+	}
+	index := make(map[string]int, len(rows))
+	for _, row := range rows {
+		if len(row) < 3 {
+			continue
+		}
+		kind := "table"
+		if strings.Contains(strings.ToUpper(row[2]), "VIEW") {
+			kind = "view"
+		}
+		index[row[0]+"."+row[1]] = len(schema.Tables)
+		schema.Tables = append(schema.Tables, SchemaTable{Schema: row[0], Name: row[1], Type: kind})
+	}
+
+	if !columns {
+		return schema, nil
+	}
+	rows, err = m.schemaRows(ctx, engine, db, columnsQuery)
+	if err != nil {
+		return nil, err
+	}
+	for _, row := range rows {
+		if len(row) < 5 {
+			continue
+		}
+		i, ok := index[row[0]+"."+row[1]]
+		if !ok {
+			continue
+		}
+		schema.Tables[i].Columns = append(schema.Tables[i].Columns, SchemaColumn{
+			Name:     row[2],
+			Type:     row[3],
+			Nullable: strings.EqualFold(row[4], "YES"),
+		})
+	}
+	return schema, nil
+}
+
+// schemaRows runs an introspection query and returns its rows as strings
+func (m *Manager) schemaRows(ctx context.Context, engine Engine, db *storage.DatabaseInstance, query string) ([][]string, error) {
+	result, err := engine.ExecuteQuery(ctx, m.client, db, query)
+	if err != nil {
+		return nil, err
+	}
+	if result.Error != "" {
+		return nil, errors.New(result.Error)
+	}
+
+	rows := make([][]string, 0, len(result.Rows))
+	for _, row := range result.Rows {
+		values := make([]string, len(row))
+		for i, v := range row {
+			if v != nil {
+				values[i] = fmt.Sprint(v)
+			}
+		}
+		rows = append(rows, values)
+	}
+	return rows, nil
+}
+
+// redisSchema samples the keyspace with SCAN and groups keys by prefix
+func (m *Manager) redisSchema(ctx context.Context, engine Engine, db *storage.DatabaseInstance) (*Schema, error) {
+	counts := make(map[string]int)
+	seen := 0
+	cursor := "0"
+	for {
+		result, err := engine.ExecuteQuery(ctx, m.client, db, fmt.Sprintf("SCAN %s COUNT %d", cursor, redisSchemaScanCount))
+		if err != nil {
+			return nil, err
+		}
+		if result.Error != "" {
+			return nil, errors.New(result.Error)
+		}
+
+		// The first line of the reply is the next cursor, the rest are keys
+		var lines []string
+		if len(result.Rows) > 0 {
+			for _, row := range result.Rows {
+				lines = append(lines, fmt.Sprint(row[0]))
+			}
+		} else {
+			lines = []string{result.Message}
+		}
+		cursor = strings.TrimSpace(lines[0])
+		if _, err := strconv.ParseUint(cursor, 10, 64); err != nil {
+			return nil, fmt.Errorf("unexpected SCAN reply: %s", lines[0])
+		}
+		for _, key := range lines[1:] {
+			if key == "" {
+				continue
+			}
+			counts[keyPattern(key)]++
+			seen++
+		}
+
+		if cursor == "0" || seen >= redisSchemaMaxKeys {
+			break
+		}
+	}
+
+	schema := &Schema{Engine: db.Engine, KeyPatterns: []KeyPattern{}, Truncated: cursor != "0"}
+	for pattern, count := range counts {
+		schema.KeyPatterns = append(schema.KeyPatterns, KeyPattern{Pattern: pattern, Count: count})
+	}
+	sort.Slice(schema.KeyPatterns, func(i, j int) bool {
+		a, b := schema.KeyPatterns[i], schema.KeyPatterns[j]
+		if a.Count != b.Count {
+			return a.Count > b.Count
+		}
+		return a.Pattern < b.Pattern
+	})
+	return schema, nil
+}
+
+// keyPattern generalises a key by replacing its ":"-separated segments that
+// contain digits with "*", so "user:42:profile" groups under "user:*:profile".
+// If no segment is replaced the last one is, so "cache:home" becomes "cache:*".
+// Keys without a separator are their own pattern.
+func keyPattern(key string) string {
+	segments := strings.Split(key, ":")
+	if len(segments) == 1 {
+		return key
+	}
+	replaced := false
+	for i, s := range segments {
+		if strings.ContainsAny(s, "0123456789") {
+			segments[i] = "*"
+			replaced = true
+		}
+	}
+	if !replaced {
+		segments[len(segments)-1] = "*"
+	}
+	return strings.Join(segments, ":")
+}
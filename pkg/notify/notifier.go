@@ -0,0 +1,128 @@
+package notify
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+)
+
+// Webhook types
+const (
+	TypeWebhook = "webhook" // Raw event JSON
+	TypeSlack   = "slack"   // Slack incoming webhook
+	TypeDiscord = "discord" // Discord channel webhook
+)
+
+// Notifier delivers a single event to one destination. New destinations
+// (e.g. email) implement this and add a case to newNotifier.
+type Notifier interface {
+	Send(ctx context.Context, event Event) error
+}
+
+// newNotifier returns the notifier for a webhook's type
+func newNotifier(hook *Webhook, client *http.Client) (Notifier, error) {
+	switch hook.Type {
+	case TypeWebhook, "": // Webhooks saved before types existed
+		return &webhookNotifier{url: hook.URL, client: client}, nil
+	case TypeSlack:
+		return &slackNotifier{url: hook.URL, channel: hook.Channel, client: client}, nil
+	case TypeDiscord:
+		return &discordNotifier{url: hook.URL, client: client}, nil
+	default:
+		return nil, fmt.Errorf("unsupported webhook type: %s", hook.Type)
+	}
+}
+
+// webhookNotifier posts the event itself as JSON
+type webhookNotifier struct {
+	url    string
+	client *http.Client
+}
+
+func (n *webhookNotifier) Send(ctx context.Context, event Event) error {
+	return postJSON(ctx, n.client, n.url, event)
+}
+
+// slackNotifier posts a Slack incoming webhook message
+type slackNotifier struct {
+	url     string
+	channel string
+	client  *http.Client
+}
+
+func (n *slackNotifier) Send(ctx context.Context, event Event) error {
+	payload := map[string]string{
+		"username": "DBnest",
+		"text":     formatMessage(event, "*"),
+	}
+	// Only honoured by legacy webhooks; app webhooks post to their own channel
+	if n.channel != "" {
+		payload["channel"] = n.channel
+	}
+	return postJSON(ctx, n.client, n.url, payload)
+}
+
+// discordNotifier posts a Discord webhook message. Discord webhooks are bound
+// to a single channel, so there is no channel override.
+type discordNotifier struct {
+	url    string
+	client *http.Client
+}
+
+func (n *discordNotifier) Send(ctx context.Context, event Event) error {
+	return postJSON(ctx, n.client, n.url, map[string]string{
+		"username": "DBnest",
+		"content":  formatMessage(event, "**"),
+	})
+}
+
+// formatMessage renders an event as a one-line chat message, using bold to
+// wrap the database name
+func formatMessage(event Event, bold string) string {
+	name := bold + event.DatabaseName + bold
+	var msg string
+	switch event.Type {
+	case EventStatusChanged:
+		msg = fmt.Sprintf("Database %s changed status: %s → %s", name, event.OldStatus, event.NewStatus)
+		if event.NewStatus == "error" || event.NewStatus == "oom-killed" {
+			msg = ":red_circle: " + msg
+		}
+	case EventBackupSucceeded:
+		msg = fmt.Sprintf(":white_check_mark: Scheduled backup of %s succeeded (%s)", name, event.BackupID)
+	case EventBackupFailed:
+		msg = fmt.Sprintf(":x: Scheduled backup of %s failed", name)
+	default:
+		msg = fmt.Sprintf("Database %s: %s", name, event.Type)
+	}
+	if event.Message != "" {
+		msg += ": " + event.Message
+	}
+	return msg
+}
+
+// postJSON posts v as JSON and treats any non-2xx response as a failure
+func postJSON(ctx context.Context, client *http.Client, url string, v interface{}) error {
+	body, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "DBnest-Webhook")
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return fmt.Errorf("unexpected status %d", resp.StatusCode)
+	}
+	return nil
+}
@@ -0,0 +1,188 @@
+package notify
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/rs/zerolog/log"
+	"github.com/sirrobot01/dbnest/pkg/storage"
+)
+
+// Event types
+const (
+	EventStatusChanged   = "status_changed"
+	EventBackupSucceeded = "backup_succeeded"
+	EventBackupFailed    = "backup_failed"
+)
+
+// webhooksSetting is the settings key holding the JSON-encoded webhook list
+const webhooksSetting = "webhooks"
+
+// Event describes something that happened to a database
+type Event struct {
+	Type         string    `json:"event"`
+	DatabaseID   string    `json:"databaseId"`
+	DatabaseName string    `json:"databaseName"`
+	OldStatus    string    `json:"oldStatus,omitempty"`
+	NewStatus    string    `json:"newStatus,omitempty"`
+	BackupID     string    `json:"backupId,omitempty"`
+	Message      string    `json:"message,omitempty"`
+	Timestamp    time.Time `json:"timestamp"`
+}
+
+// Webhook is a notification target. Type selects how events are formatted
+// and delivered; see newNotifier for the supported types.
+type Webhook struct {
+	ID        string    `json:"id"`
+	Type      string    `json:"type"`
+	URL       string    `json:"url"`
+	Channel   string    `json:"channel,omitempty"` // Slack channel override
+	Events    []string  `json:"events,omitempty"`  // Event types to send; empty means all
+	CreatedAt time.Time `json:"createdAt"`
+}
+
+// wants reports whether the webhook subscribes to an event type
+func (h *Webhook) wants(eventType string) bool {
+	if len(h.Events) == 0 {
+		return true
+	}
+	for _, e := range h.Events {
+		if e == eventType {
+			return true
+		}
+	}
+	return false
+}
+
+// Dispatcher delivers events to the configured webhooks
+type Dispatcher struct {
+	store   storage.Storage
+	client  *http.Client
+	mu      sync.Mutex // Serializes read-modify-write of the webhook list
+	retries int
+	backoff time.Duration
+}
+
+// NewDispatcher creates a dispatcher that reads webhooks from the settings bucket
+func NewDispatcher(store storage.Storage) *Dispatcher {
+	return &Dispatcher{
+		store:   store,
+		client:  &http.Client{Timeout: 10 * time.Second},
+		retries: 3,
+		backoff: 2 * time.Second,
+	}
+}
+
+// Webhooks returns the configured webhooks
+func (d *Dispatcher) Webhooks() []*Webhook {
+	value, err := d.store.GetSetting(webhooksSetting)
+	if err != nil {
+		// Not set yet
+		return []*Webhook{}
+	}
+	var hooks []*Webhook
+	if err := json.Unmarshal([]byte(value), &hooks); err != nil {
+		log.Error().Err(err).Msg("Failed to decode webhook settings")
+		return []*Webhook{}
+	}
+	return hooks
+}
+
+// AddWebhook validates and stores a new webhook. An empty type defaults to a
+// generic JSON webhook.
+func (d *Dispatcher) AddWebhook(hook *Webhook) (*Webhook, error) {
+	u, err := url.Parse(hook.URL)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return nil, fmt.Errorf("invalid webhook URL: must be an absolute http or https URL")
+	}
+	if hook.Type == "" {
+		hook.Type = TypeWebhook
+	}
+	if _, err := newNotifier(hook, d.client); err != nil {
+		return nil, err
+	}
+	for _, e := range hook.Events {
+		if e != EventStatusChanged && e != EventBackupSucceeded && e != EventBackupFailed {
+			return nil, fmt.Errorf("unknown event type: %s", e)
+		}
+	}
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	hook.ID = "wh-" + uuid.New().String()[:8]
+	hook.URL = u.String()
+	hook.CreatedAt = time.Now()
+	hooks := append(d.Webhooks(), hook)
+	if err := d.save(hooks); err != nil {
+		return nil, err
+	}
+	return hook, nil
+}
+
+// DeleteWebhook removes a webhook by ID
+func (d *Dispatcher) DeleteWebhook(id string) error {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	hooks := d.Webhooks()
+	for i, hook := range hooks {
+		if hook.ID == id {
+			return d.save(append(hooks[:i], hooks[i+1:]...))
+		}
+	}
+	return fmt.Errorf("webhook not found: %s", id)
+}
+
+func (d *Dispatcher) save(hooks []*Webhook) error {
+	data, err := json.Marshal(hooks)
+	if err != nil {
+		return err
+	}
+	return d.store.SetSetting(webhooksSetting, string(data))
+}
+
+// Notify sends an event to every subscribed webhook in the background.
+// Delivery failures are retried and then logged; they never block the caller.
+func (d *Dispatcher) Notify(event Event) {
+	if event.Timestamp.IsZero() {
+		event.Timestamp = time.Now()
+	}
+
+	for _, hook := range d.Webhooks() {
+		if !hook.wants(event.Type) {
+			continue
+		}
+		n, err := newNotifier(hook, d.client)
+		if err != nil {
+			log.Warn().Err(err).Str("webhook", hook.ID).Msg("Skipping misconfigured webhook")
+			continue
+		}
+		go d.deliver(hook.ID, n, event)
+	}
+}
+
+// deliver sends an event through a notifier, retrying with exponential backoff
+func (d *Dispatcher) deliver(hookID string, n Notifier, event Event) {
+	backoff := d.backoff
+	var err error
+	for attempt := 1; attempt <= d.retries; attempt++ {
+		ctx, cancel := context.WithTimeout(context.Background(), d.client.Timeout)
+		err = n.Send(ctx, event)
+		cancel()
+		if err == nil {
+			return
+		}
+		if attempt < d.retries {
+			time.Sleep(backoff)
+			backoff *= 2
+		}
+	}
+	log.Warn().Err(err).Str("webhook", hookID).Int("attempts", d.retries).Msg("Webhook delivery failed")
+}
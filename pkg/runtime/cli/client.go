@@ -5,10 +5,13 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
+	"io"
+	"net"
 	"os/exec"
 	"regexp"
 	"strconv"
 	"strings"
+	"time"
 
 	"github.com/sirrobot01/dbnest/pkg/runtime/types"
 )
@@ -16,8 +19,9 @@ import (
 // Client implements the types.Client interface using container runtime CLIs.
 // Supports docker, podman, and nerdctl (containerd).
 type Client struct {
-	binary  string // Runtime binary: "docker", "podman", or "nerdctl"
-	network string
+	binary     string // Runtime binary: "docker", "podman", or "nerdctl"
+	network    string
+	registries []types.RegistryAuth
 }
 
 // Verify Client implements types.Client interface
@@ -82,30 +86,114 @@ func (c *Client) Ping(ctx context.Context) error {
 	return err
 }
 
+// Version returns the version of the daemon behind the CLI
+func (c *Client) Version(ctx context.Context) (string, error) {
+	// podman info nests its version; docker and nerdctl report the server's
+	format := "{{.ServerVersion}}"
+	if c.binary == "podman" {
+		format = "{{.Version.Version}}"
+	}
+	return c.runCommand(ctx, "info", "--format", format)
+}
+
+// SetRegistryAuth sets the credentials used when pulling from private registries
+func (c *Client) SetRegistryAuth(auths []types.RegistryAuth) {
+	c.registries = auths
+}
+
 // PullImage pulls a container image
 func (c *Client) PullImage(ctx context.Context, imageName string) error {
+	auth := types.FindRegistryAuth(c.registries, imageName)
+	if auth == nil {
+		_, err := c.runCommand(ctx, "pull", imageName)
+		return err
+	}
+
+	// Log in first rather than passing credentials to pull, which would put
+	// the password on the command line
+	if err := c.login(ctx, auth); err != nil {
+		return err
+	}
 	_, err := c.runCommand(ctx, "pull", imageName)
 	return err
 }
 
+// ImageDigest returns the registry digest of a local image
+func (c *Client) ImageDigest(ctx context.Context, imageName string) (string, error) {
+	output, err := c.runCommand(ctx, "image", "inspect", "--format", "{{range .RepoDigests}}{{.}} {{end}}", imageName)
+	if err != nil {
+		return "", err
+	}
+	return types.PickRepoDigest(imageName, strings.Fields(output)), nil
+}
+
+// login authenticates against a registry, passing the password on stdin
+func (c *Client) login(ctx context.Context, auth *types.RegistryAuth) error {
+	cmd := exec.CommandContext(ctx, c.binary, "login", "--username", auth.Username, "--password-stdin", auth.Server)
+	cmd.Stdin = strings.NewReader(auth.Password)
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+
+	if err := cmd.Run(); err != nil {
+		return fmt.Errorf("%s login to %s failed: %w, stderr: %s", c.binary, auth.Server, err, stderr.String())
+	}
+	return nil
+}
+
 // CreateContainer creates a new container
 func (c *Client) CreateContainer(ctx context.Context, cfg *types.ContainerConfig) (string, error) {
 	args := []string{"create", "--name", cfg.Name}
 
-	args = append(args, "--network", c.network)
+	args = append(args, "--network", c.networkName(cfg.Network))
 
 	for _, env := range cfg.Env {
 		args = append(args, "-e", env)
 	}
 
-	for containerPort, hostPort := range cfg.PortBindings {
-		args = append(args, "-p", fmt.Sprintf("%s:%s", hostPort, containerPort))
+	// Unexposed containers are reachable only from the container network
+	if cfg.ExposePort {
+		for containerPort, hostPort := range cfg.PortBindings {
+			publish := fmt.Sprintf("%s:%s", hostPort, containerPort)
+			if cfg.BindAddress != "" {
+				// IPv6 addresses are bracketed to separate them from the ports
+				publish = net.JoinHostPort(cfg.BindAddress, publish)
+			}
+			args = append(args, "-p", publish)
+		}
 	}
 
 	for hostPath, containerPath := range cfg.Volumes {
 		args = append(args, "-v", fmt.Sprintf("%s:%s", hostPath, containerPath))
 	}
 
+	if user := cfg.User(); user != "" {
+		if err := c.chownVolumes(ctx, cfg); err != nil {
+			return "", err
+		}
+		args = append(args, "--user", user)
+	}
+	if cfg.UsernsMode != "" {
+		args = append(args, "--userns", cfg.UsernsMode)
+	}
+	if cfg.ReadOnlyRootfs {
+		args = append(args, "--read-only")
+	}
+	for _, path := range cfg.Tmpfs {
+		args = append(args, "--tmpfs", path)
+	}
+	if cfg.Security.NoNewPrivileges {
+		args = append(args, "--security-opt", "no-new-privileges")
+	}
+	if cfg.Security.SeccompProfile != "" {
+		args = append(args, "--security-opt", "seccomp="+cfg.Security.SeccompProfile)
+	}
+	for _, capability := range cfg.Security.CapDrop {
+		args = append(args, "--cap-drop", capability)
+	}
+	if hc := cfg.Healthcheck; hc != nil {
+		args = append(args, healthcheckArgs(hc)...)
+	}
+
 	if cfg.MemoryLimit > 0 {
 		args = append(args, "--memory", fmt.Sprintf("%d", cfg.MemoryLimit))
 	}
@@ -117,7 +205,14 @@ func (c *Client) CreateContainer(ctx context.Context, cfg *types.ContainerConfig
 		args = append(args, "--label", fmt.Sprintf("%s=%s", k, v))
 	}
 
-	args = append(args, "--restart", "unless-stopped")
+	if _, _, err := types.ParseRestartPolicy(cfg.RestartPolicy); err != nil {
+		return "", err
+	}
+	restartPolicy := cfg.RestartPolicy
+	if restartPolicy == "" {
+		restartPolicy = types.DefaultRestartPolicy
+	}
+	args = append(args, "--restart", restartPolicy)
 	args = append(args, cfg.Image)
 
 	// Append command args if specified
@@ -132,6 +227,33 @@ func (c *Client) CreateContainer(ctx context.Context, cfg *types.ContainerConfig
 	return containerID, nil
 }
 
+// chownVolumes hands the container's named volumes to its user by running
+// chown in a throwaway root container from the same image. Bind-mounted host
+// directories belong to the user and are left alone.
+func (c *Client) chownVolumes(ctx context.Context, cfg *types.ContainerConfig) error {
+	args := []string{"run", "--rm", "--user", "0:0", "--network", "none", "--entrypoint", "chown"}
+	if cfg.UsernsMode != "" {
+		args = append(args, "--userns", cfg.UsernsMode)
+	}
+	targets := []string{"-R", cfg.User()}
+	for hostPath, containerPath := range cfg.Volumes {
+		if !types.IsNamedVolume(hostPath) {
+			continue
+		}
+		args = append(args, "-v", fmt.Sprintf("%s:%s", hostPath, containerPath))
+		targets = append(targets, containerPath)
+	}
+	if len(targets) == 2 {
+		return nil
+	}
+	args = append(append(args, cfg.Image), targets...)
+
+	if _, err := c.runCommand(ctx, args...); err != nil {
+		return fmt.Errorf("failed to fix volume permissions: %w", err)
+	}
+	return nil
+}
+
 // StartContainer starts a container
 func (c *Client) StartContainer(ctx context.Context, containerID string) error {
 	_, err := c.runCommand(ctx, "start", containerID)
@@ -139,8 +261,24 @@ func (c *Client) StartContainer(ctx context.Context, containerID string) error {
 }
 
 // StopContainer stops a container
-func (c *Client) StopContainer(ctx context.Context, containerID string) error {
-	_, err := c.runCommand(ctx, "stop", "-t", "10", containerID)
+func (c *Client) StopContainer(ctx context.Context, containerID string, timeout time.Duration) error {
+	if timeout <= 0 {
+		_, err := c.runCommand(ctx, "kill", containerID)
+		return err
+	}
+	_, err := c.runCommand(ctx, "stop", "-t", strconv.Itoa(int(timeout.Seconds())), containerID)
+	return err
+}
+
+// PauseContainer freezes all processes in a container
+func (c *Client) PauseContainer(ctx context.Context, containerID string) error {
+	_, err := c.runCommand(ctx, "pause", containerID)
+	return err
+}
+
+// ResumeContainer unfreezes a paused container
+func (c *Client) ResumeContainer(ctx context.Context, containerID string) error {
+	_, err := c.runCommand(ctx, "unpause", containerID)
 	return err
 }
 
@@ -155,9 +293,33 @@ func (c *Client) RemoveContainer(ctx context.Context, containerID string, force
 	return err
 }
 
+// healthcheckArgs returns the run flags for a healthcheck. --health-cmd is
+// run by a shell, so the command's arguments are quoted.
+func healthcheckArgs(hc *types.Healthcheck) []string {
+	quoted := make([]string, len(hc.Cmd))
+	for i, arg := range hc.Cmd {
+		quoted[i] = "'" + strings.ReplaceAll(arg, "'", `'\''`) + "'"
+	}
+	args := []string{"--health-cmd", strings.Join(quoted, " ")}
+	if hc.Interval > 0 {
+		args = append(args, "--health-interval", hc.Interval.String())
+	}
+	if hc.Timeout > 0 {
+		args = append(args, "--health-timeout", hc.Timeout.String())
+	}
+	if hc.StartPeriod > 0 {
+		args = append(args, "--health-start-period", hc.StartPeriod.String())
+	}
+	if hc.Retries > 0 {
+		args = append(args, "--health-retries", strconv.Itoa(hc.Retries))
+	}
+	return args
+}
+
 // GetContainerStatus returns the container's running status
 func (c *Client) GetContainerStatus(ctx context.Context, containerID string) (string, error) {
-	output, err := c.runCommand(ctx, "inspect", "--format", "{{.State.Status}}", containerID)
+	output, err := c.runCommand(ctx, "inspect", "--format",
+		"{{.State.Status}} {{.State.OOMKilled}} {{if .State.Health}}{{.State.Health.Status}}{{end}}", containerID)
 	if err != nil {
 		if strings.Contains(err.Error(), "No such") {
 			return "error", nil
@@ -165,10 +327,22 @@ func (c *Client) GetContainerStatus(ctx context.Context, containerID string) (st
 		return "", err
 	}
 
-	switch output {
+	// The health field is empty for containers without a healthcheck
+	fields := append(strings.Fields(output), "", "", "")
+	status, oomKilled, health := fields[0], fields[1], fields[2]
+	if oomKilled == "true" && status != "running" && status != "paused" {
+		return "oom-killed", nil
+	}
+
+	switch status {
 	case "running":
+		if health == "starting" || health == "unhealthy" {
+			return health, nil
+		}
 		return "running", nil
-	case "paused", "exited", "dead":
+	case "paused":
+		return "paused", nil
+	case "exited", "dead":
 		return "stopped", nil
 	case "restarting", "created":
 		return "creating", nil
@@ -256,19 +430,27 @@ func parseBytes(s string) int64 {
 	return int64(value * multiplier)
 }
 
-// GetContainerLogs retrieves the last N lines of container logs
-func (c *Client) GetContainerLogs(ctx context.Context, containerID string, tail int) (string, error) {
+// GetContainerLogs retrieves the last N lines of container logs, optionally
+// only those written after since
+func (c *Client) GetContainerLogs(ctx context.Context, containerID string, tail int, since time.Time, timestamps bool) (string, error) {
 	if tail <= 0 {
 		tail = 100
 	}
-	return c.runCommand(ctx, "logs", "--tail", fmt.Sprintf("%d", tail), containerID)
+	args := []string{"logs", "--tail", fmt.Sprintf("%d", tail)}
+	if !since.IsZero() {
+		args = append(args, "--since", since.UTC().Format(time.RFC3339))
+	}
+	if timestamps {
+		args = append(args, "--timestamps")
+	}
+	return c.runCommand(ctx, append(args, containerID)...)
 }
 
 // ListContainers lists all DBNest-managed containers
-func (c *Client) ListContainers(ctx context.Context) ([]string, error) {
-	output, err := c.runCommand(ctx, "ps", "-a",
+func (c *Client) ListContainers(ctx context.Context) ([]types.ContainerInfo, error) {
+	output, err := c.runCommand(ctx, "ps", "-a", "--no-trunc",
 		"--filter", "label=dbnest.managed=true",
-		"--format", "{{.ID}}")
+		"--format", "{{json .}}")
 	if err != nil {
 		return nil, err
 	}
@@ -276,7 +458,40 @@ func (c *Client) ListContainers(ctx context.Context) ([]string, error) {
 	if output == "" {
 		return nil, nil
 	}
-	return strings.Split(output, "\n"), nil
+
+	var result []types.ContainerInfo
+	for _, line := range strings.Split(output, "\n") {
+		// docker/nerdctl render Names and Labels as strings, podman as a list and a map
+		var ps struct {
+			ID     string          `json:"ID"`
+			Names  json.RawMessage `json:"Names"`
+			State  string          `json:"State"`
+			Labels json.RawMessage `json:"Labels"`
+		}
+		if err := json.Unmarshal([]byte(line), &ps); err != nil {
+			return nil, fmt.Errorf("failed to parse container list: %w", err)
+		}
+
+		info := types.ContainerInfo{ID: ps.ID, State: ps.State, Labels: map[string]string{}}
+		var names []string
+		if err := json.Unmarshal(ps.Names, &names); err == nil && len(names) > 0 {
+			info.Name = names[0]
+		} else {
+			json.Unmarshal(ps.Names, &info.Name)
+		}
+		var labels string
+		if err := json.Unmarshal(ps.Labels, &labels); err == nil {
+			for _, kv := range strings.Split(labels, ",") {
+				if k, v, ok := strings.Cut(kv, "="); ok {
+					info.Labels[k] = v
+				}
+			}
+		} else {
+			json.Unmarshal(ps.Labels, &info.Labels)
+		}
+		result = append(result, info)
+	}
+	return result, nil
 }
 
 // ListNetworks returns all available networks
@@ -328,6 +543,30 @@ func (c *Client) DeleteNetwork(ctx context.Context, networkID string) error {
 	return nil
 }
 
+// ConnectNetwork attaches a container to a network
+func (c *Client) ConnectNetwork(ctx context.Context, containerID, network string) error {
+	if _, err := c.runCommand(ctx, "network", "connect", c.networkName(network), containerID); err != nil {
+		return fmt.Errorf("failed to connect to network: %w", err)
+	}
+	return nil
+}
+
+// DisconnectNetwork detaches a container from a network
+func (c *Client) DisconnectNetwork(ctx context.Context, containerID, network string) error {
+	if _, err := c.runCommand(ctx, "network", "disconnect", c.networkName(network), containerID); err != nil {
+		return fmt.Errorf("failed to disconnect from network: %w", err)
+	}
+	return nil
+}
+
+// networkName resolves an optional network name, defaulting to the DBNest network
+func (c *Client) networkName(name string) string {
+	if name == "" {
+		return c.network
+	}
+	return name
+}
+
 // ExecInContainer executes a command in a container
 func (c *Client) ExecInContainer(ctx context.Context, containerID string, cmd []string) (string, error) {
 	args := append([]string{"exec", containerID}, cmd...)
@@ -346,7 +585,7 @@ func (c *Client) Exec(ctx context.Context, containerID string, cmd []string, env
 }
 
 // ExecWithStdin executes a command with stdin input
-func (c *Client) ExecWithStdin(ctx context.Context, containerID string, cmd []string, stdin []byte, env []string) (string, error) {
+func (c *Client) ExecWithStdin(ctx context.Context, containerID string, cmd []string, stdin io.Reader, env []string) (string, error) {
 	args := []string{"exec", "-i"}
 	for _, e := range env {
 		args = append(args, "-e", e)
@@ -355,7 +594,7 @@ func (c *Client) ExecWithStdin(ctx context.Context, containerID string, cmd []st
 	args = append(args, cmd...)
 
 	execCmd := exec.CommandContext(ctx, c.binary, args...)
-	execCmd.Stdin = bytes.NewReader(stdin)
+	execCmd.Stdin = stdin
 	var stdout, stderr bytes.Buffer
 	execCmd.Stdout = &stdout
 	execCmd.Stderr = &stderr
@@ -366,6 +605,31 @@ func (c *Client) ExecWithStdin(ctx context.Context, containerID string, cmd []st
 	return strings.TrimSpace(stdout.String()), nil
 }
 
+// ExecStream executes a command and streams its stdout to w
+func (c *Client) ExecStream(ctx context.Context, containerID string, cmd []string, env []string, w io.Writer) error {
+	args := []string{"exec"}
+	for _, e := range env {
+		args = append(args, "-e", e)
+	}
+	args = append(args, containerID)
+	args = append(args, cmd...)
+
+	execCmd := exec.CommandContext(ctx, c.binary, args...)
+	var stderr bytes.Buffer
+	execCmd.Stdout = w
+	execCmd.Stderr = &stderr
+
+	if err := execCmd.Run(); err != nil {
+		return fmt.Errorf("%s exec failed: %w, stderr: %s", c.binary, err, stderr.String())
+	}
+	return nil
+}
+
+// ExecInteractive is not supported in CLI mode (requires a pseudo-terminal)
+func (c *Client) ExecInteractive(ctx context.Context, containerID string, cmd []string, stdin io.Reader, stdout io.Writer, resize <-chan types.TerminalSize) error {
+	return fmt.Errorf("interactive shell not supported in %s CLI mode; use SDK mode with a socket", c.binary)
+}
+
 // UpdateContainerResources updates memory and CPU limits for a running container
 func (c *Client) UpdateContainerResources(ctx context.Context, containerID string, memoryLimit int64, cpuLimit float64) error {
 	args := []string{"update"}
@@ -4,6 +4,7 @@ import (
 	"context"
 	"fmt"
 	"io"
+	"io/fs"
 	"os"
 	"path/filepath"
 	"strings"
@@ -13,9 +14,13 @@ import (
 	"github.com/containerd/containerd"
 	"github.com/containerd/containerd/cio"
 	"github.com/containerd/containerd/containers"
+	"github.com/containerd/containerd/contrib/seccomp"
 	"github.com/containerd/containerd/namespaces"
 	"github.com/containerd/containerd/oci"
+	"github.com/containerd/containerd/remotes/docker"
+	"github.com/containerd/containerd/remotes/docker/config"
 	"github.com/opencontainers/runtime-spec/specs-go"
+	"github.com/rs/zerolog/log"
 	"github.com/sirrobot01/dbnest/pkg/runtime/types"
 )
 
@@ -26,8 +31,9 @@ const (
 
 // Client wraps the containerd SDK client
 type Client struct {
-	cli     *containerd.Client
-	network string
+	cli        *containerd.Client
+	network    string
+	registries []types.RegistryAuth
 }
 
 // Verify Client implements types.Client interface
@@ -64,6 +70,20 @@ func (c *Client) Ping(ctx context.Context) error {
 	return err
 }
 
+// Version returns the containerd daemon version
+func (c *Client) Version(ctx context.Context) (string, error) {
+	v, err := c.cli.Version(c.ctx(ctx))
+	if err != nil {
+		return "", err
+	}
+	return v.Version, nil
+}
+
+// SetRegistryAuth sets the credentials used when pulling from private registries
+func (c *Client) SetRegistryAuth(auths []types.RegistryAuth) {
+	c.registries = auths
+}
+
 // PullImage pulls a container image
 func (c *Client) PullImage(ctx context.Context, imageName string) error {
 	// Normalize image name for containerd
@@ -71,10 +91,21 @@ func (c *Client) PullImage(ctx context.Context, imageName string) error {
 	normalizedName := normalizeImageName(imageName)
 
 	// Use native snapshotter which works better in Docker-in-Docker environments
-	_, err := c.cli.Pull(c.ctx(ctx), normalizedName,
+	opts := []containerd.RemoteOpt{
 		containerd.WithPullUnpack,
 		containerd.WithPullSnapshotter("native"),
-	)
+	}
+	if auth := types.FindRegistryAuth(c.registries, normalizedName); auth != nil {
+		opts = append(opts, containerd.WithResolver(docker.NewResolver(docker.ResolverOptions{
+			Hosts: config.ConfigureHosts(ctx, config.HostOptions{
+				Credentials: func(host string) (string, string, error) {
+					return auth.Username, auth.Password, nil
+				},
+			}),
+		})))
+	}
+
+	_, err := c.cli.Pull(c.ctx(ctx), normalizedName, opts...)
 	if err != nil {
 		return fmt.Errorf("failed to pull image %s: %w", imageName, err)
 	}
@@ -98,10 +129,28 @@ func normalizeImageName(name string) string {
 	return "docker.io/" + name
 }
 
+// ImageDigest returns the digest of a local image's manifest (or index)
+func (c *Client) ImageDigest(ctx context.Context, imageName string) (string, error) {
+	if _, digest, found := strings.Cut(imageName, "@"); found {
+		return digest, nil
+	}
+	image, err := c.cli.GetImage(c.ctx(ctx), normalizeImageName(imageName))
+	if err != nil {
+		return "", fmt.Errorf("image %s not found: %w", imageName, err)
+	}
+	return image.Target().Digest.String(), nil
+}
+
 // CreateContainer creates a new container
 func (c *Client) CreateContainer(ctx context.Context, cfg *types.ContainerConfig) (string, error) {
 	ctx = c.ctx(ctx)
 
+	// containerd has no daemon-managed restarts; tasks stay down once they exit
+	if cfg.RestartPolicy != "" && cfg.RestartPolicy != "no" {
+		log.Warn().Str("policy", cfg.RestartPolicy).Str("container", cfg.Name).
+			Msg("Restart policies are not supported by containerd; container will not be restarted automatically")
+	}
+
 	// Get image (use normalized name)
 	imageName := normalizeImageName(cfg.Image)
 	image, err := c.cli.GetImage(ctx, imageName)
@@ -120,13 +169,22 @@ func (c *Client) CreateContainer(ctx context.Context, cfg *types.ContainerConfig
 		specOpts = append(specOpts, oci.WithProcessArgs(cfg.Cmd...))
 	}
 
+	if cfg.RunAsUser != 0 {
+		specOpts = append(specOpts, oci.WithUIDGID(uint32(cfg.RunAsUser), uint32(cfg.RunAsGroup)))
+	}
+	if cfg.UsernsMode != "" {
+		log.Warn().Str("userns", cfg.UsernsMode).Str("container", cfg.Name).
+			Msg("User namespace modes are not supported by containerd; running in the host user namespace")
+	}
+
 	// Add mounts
 	for hostPath, containerPath := range cfg.Volumes {
 		source := hostPath
+		named := types.IsNamedVolume(source)
 		
 		// If source doesn't start with / or ., assume it's a named volume
 		// Emulate named volumes for containerd by using a standard host directory
-		if !strings.HasPrefix(source, "/") && !strings.HasPrefix(source, ".") {
+		if named {
 			source = filepath.Join("/var/lib/dbnest/volumes", hostPath)
 			// Ensure directory exists
 			if err := os.MkdirAll(source, 0755); err != nil {
@@ -134,6 +192,15 @@ func (c *Client) CreateContainer(ctx context.Context, cfg *types.ContainerConfig
 			}
 		}
 
+		// Volumes are host directories here, so hand them to the container's
+		// user directly. Bind-mounted host directories belong to the user and
+		// are left alone.
+		if named && cfg.RunAsUser != 0 {
+			if err := chownTree(source, cfg.RunAsUser, cfg.RunAsGroup); err != nil {
+				return "", fmt.Errorf("failed to fix volume permissions on %s: %w", source, err)
+			}
+		}
+
 		specOpts = append(specOpts, oci.WithMounts([]specs.Mount{
 			{
 				Type:        "bind",
@@ -144,6 +211,21 @@ func (c *Client) CreateContainer(ctx context.Context, cfg *types.ContainerConfig
 		}))
 	}
 
+	if cfg.ReadOnlyRootfs {
+		specOpts = append(specOpts, oci.WithRootFSReadonly())
+	}
+	specOpts = append(specOpts, securitySpecOpts(cfg.Security)...)
+	for _, path := range cfg.Tmpfs {
+		specOpts = append(specOpts, oci.WithMounts([]specs.Mount{
+			{
+				Type:        "tmpfs",
+				Source:      "tmpfs",
+				Destination: path,
+				Options:     []string{"nosuid", "nodev", "mode=1777"},
+			},
+		}))
+	}
+
 	// Add resource limits
 	if cfg.MemoryLimit > 0 || cfg.CPULimit > 0 {
 		specOpts = append(specOpts, func(_ context.Context, _ oci.Client, _ *containers.Container, s *oci.Spec) error {
@@ -189,6 +271,42 @@ func (c *Client) CreateContainer(ctx context.Context, cfg *types.ContainerConfig
 	return container.ID(), nil
 }
 
+// securitySpecOpts converts security options to spec options. Unlike Docker,
+// containerd applies no seccomp profile by default, so its default profile is
+// set unless another is configured.
+func securitySpecOpts(sec types.SecurityOptions) []oci.SpecOpts {
+	var opts []oci.SpecOpts
+	if sec.NoNewPrivileges {
+		opts = append(opts, oci.WithNoNewPrivileges)
+	}
+	if len(sec.CapDrop) > 0 {
+		caps := make([]string, len(sec.CapDrop))
+		for i, capability := range sec.CapDrop {
+			caps[i] = "CAP_" + strings.TrimPrefix(strings.ToUpper(capability), "CAP_")
+		}
+		opts = append(opts, oci.WithDroppedCapabilities(caps))
+	}
+	// The default profile depends on the capabilities, so it must come after them
+	switch sec.SeccompProfile {
+	case "":
+		opts = append(opts, seccomp.WithDefaultProfile())
+	case types.SeccompUnconfined:
+	default:
+		opts = append(opts, seccomp.WithProfile(sec.SeccompProfile))
+	}
+	return opts
+}
+
+// chownTree changes the owner of root and everything below it
+func chownTree(root string, uid, gid int) error {
+	return filepath.WalkDir(root, func(path string, _ fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		return os.Lchown(path, uid, gid)
+	})
+}
+
 // StartContainer starts a container
 func (c *Client) StartContainer(ctx context.Context, containerID string) error {
 	ctx = c.ctx(ctx)
@@ -212,7 +330,7 @@ func (c *Client) StartContainer(ctx context.Context, containerID string) error {
 }
 
 // StopContainer stops a container
-func (c *Client) StopContainer(ctx context.Context, containerID string) error {
+func (c *Client) StopContainer(ctx context.Context, containerID string, timeout time.Duration) error {
 	ctx = c.ctx(ctx)
 
 	container, err := c.cli.LoadContainer(ctx, containerID)
@@ -225,27 +343,68 @@ func (c *Client) StopContainer(ctx context.Context, containerID string) error {
 		return nil // No running task
 	}
 
-	// Send SIGTERM
-	if err := task.Kill(ctx, syscall.SIGTERM); err != nil {
-		return fmt.Errorf("failed to kill task: %w", err)
-	}
-
 	// Wait for exit with timeout
 	exitCh, err := task.Wait(ctx)
 	if err != nil {
 		return err
 	}
 
-	select {
-	case <-exitCh:
-	case <-time.After(10 * time.Second):
-		task.Kill(ctx, syscall.SIGKILL)
+	if timeout <= 0 {
+		// Forced stop: skip the grace period
+		if err := task.Kill(ctx, syscall.SIGKILL); err != nil {
+			return fmt.Errorf("failed to kill task: %w", err)
+		}
+		<-exitCh
+	} else {
+		// Send SIGTERM
+		if err := task.Kill(ctx, syscall.SIGTERM); err != nil {
+			return fmt.Errorf("failed to kill task: %w", err)
+		}
+
+		select {
+		case <-exitCh:
+		case <-time.After(timeout):
+			task.Kill(ctx, syscall.SIGKILL)
+			<-exitCh
+		}
 	}
 
 	_, err = task.Delete(ctx)
 	return err
 }
 
+// PauseContainer freezes the container's running task
+func (c *Client) PauseContainer(ctx context.Context, containerID string) error {
+	ctx = c.ctx(ctx)
+
+	container, err := c.cli.LoadContainer(ctx, containerID)
+	if err != nil {
+		return fmt.Errorf("container not found: %w", err)
+	}
+
+	task, err := container.Task(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("no running task: %w", err)
+	}
+	return task.Pause(ctx)
+}
+
+// ResumeContainer unfreezes the container's paused task
+func (c *Client) ResumeContainer(ctx context.Context, containerID string) error {
+	ctx = c.ctx(ctx)
+
+	container, err := c.cli.LoadContainer(ctx, containerID)
+	if err != nil {
+		return fmt.Errorf("container not found: %w", err)
+	}
+
+	task, err := container.Task(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("no running task: %w", err)
+	}
+	return task.Resume(ctx)
+}
+
 // RemoveContainer removes a container
 func (c *Client) RemoveContainer(ctx context.Context, containerID string, force bool) error {
 	ctx = c.ctx(ctx)
@@ -288,9 +447,11 @@ func (c *Client) GetContainerStatus(ctx context.Context, containerID string) (st
 	switch status.Status {
 	case containerd.Running:
 		return "running", nil
-	case containerd.Created, containerd.Pausing:
+	case containerd.Created:
 		return "creating", nil
-	case containerd.Stopped, containerd.Paused:
+	case containerd.Paused, containerd.Pausing:
+		return "paused", nil
+	case containerd.Stopped:
 		return "stopped", nil
 	default:
 		return "error", nil
@@ -331,14 +492,14 @@ func (c *Client) GetContainerStats(ctx context.Context, containerID string) (*ty
 }
 
 // GetContainerLogs retrieves the last N lines of container logs
-func (c *Client) GetContainerLogs(ctx context.Context, containerID string, tail int) (string, error) {
+func (c *Client) GetContainerLogs(ctx context.Context, containerID string, tail int, since time.Time, timestamps bool) (string, error) {
 	// containerd doesn't store logs like Docker
 	// Applications should use a logging driver
 	return "", fmt.Errorf("containerd does not support log retrieval directly; use a logging driver")
 }
 
 // ListContainers lists all DBNest-managed containers
-func (c *Client) ListContainers(ctx context.Context) ([]string, error) {
+func (c *Client) ListContainers(ctx context.Context) ([]types.ContainerInfo, error) {
 	ctx = c.ctx(ctx)
 
 	containers, err := c.cli.Containers(ctx, "labels.\"dbnest.managed\"==true")
@@ -346,11 +507,22 @@ func (c *Client) ListContainers(ctx context.Context) ([]string, error) {
 		return nil, err
 	}
 
-	var ids []string
+	var result []types.ContainerInfo
 	for _, container := range containers {
-		ids = append(ids, container.ID())
+		labels, err := container.Labels(ctx)
+		if err != nil {
+			return nil, err
+		}
+		// containerd container IDs are the names given at creation
+		state, _ := c.GetContainerStatus(ctx, container.ID())
+		result = append(result, types.ContainerInfo{
+			ID:     container.ID(),
+			Name:   container.ID(),
+			State:  state,
+			Labels: labels,
+		})
 	}
-	return ids, nil
+	return result, nil
 }
 
 // ListNetworks returns all available networks
@@ -379,6 +551,16 @@ func (c *Client) DeleteNetwork(ctx context.Context, networkID string) error {
 	return nil
 }
 
+// ConnectNetwork is not supported; containerd networking is configured through CNI
+func (c *Client) ConnectNetwork(ctx context.Context, containerID, network string) error {
+	return fmt.Errorf("network attach is not supported by the containerd runtime")
+}
+
+// DisconnectNetwork is not supported; containerd networking is configured through CNI
+func (c *Client) DisconnectNetwork(ctx context.Context, containerID, network string) error {
+	return fmt.Errorf("network detach is not supported by the containerd runtime")
+}
+
 // ExecInContainer executes a command in a container
 func (c *Client) ExecInContainer(ctx context.Context, containerID string, cmd []string) (string, error) {
 	return c.Exec(ctx, containerID, cmd, nil)
@@ -432,7 +614,7 @@ func (c *Client) Exec(ctx context.Context, containerID string, cmd []string, env
 }
 
 // ExecWithStdin executes a command with stdin input
-func (c *Client) ExecWithStdin(ctx context.Context, containerID string, cmd []string, stdin []byte, env []string) (string, error) {
+func (c *Client) ExecWithStdin(ctx context.Context, containerID string, cmd []string, stdin io.Reader, env []string) (string, error) {
 	ctx = c.ctx(ctx)
 
 	container, err := c.cli.LoadContainer(ctx, containerID)
@@ -446,15 +628,14 @@ func (c *Client) ExecWithStdin(ctx context.Context, containerID string, cmd []st
 	}
 
 	var stdout, stderr strings.Builder
-	stdinReader := strings.NewReader(string(stdin))
-	
+
 	execID := fmt.Sprintf("exec-%d", time.Now().UnixNano())
 	process, err := task.Exec(ctx, execID, &specs.Process{
 		Args: cmd,
 		Env:  env,
 		Cwd:  "/",
 	}, cio.NewCreator(
-		cio.WithStreams(io.NopCloser(stdinReader), &stdout, &stderr),
+		cio.WithStreams(io.NopCloser(stdin), &stdout, &stderr),
 	))
 	if err != nil {
 		return "", fmt.Errorf("failed to exec: %w", err)
@@ -475,11 +656,108 @@ func (c *Client) ExecWithStdin(ctx context.Context, containerID string, cmd []st
 	return strings.TrimSpace(stdout.String()), nil
 }
 
+// ExecStream executes a command and streams its stdout to w
+func (c *Client) ExecStream(ctx context.Context, containerID string, cmd []string, env []string, w io.Writer) error {
+	ctx = c.ctx(ctx)
+
+	container, err := c.cli.LoadContainer(ctx, containerID)
+	if err != nil {
+		return fmt.Errorf("container not found: %w", err)
+	}
+
+	task, err := container.Task(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("no running task: %w", err)
+	}
+
+	var stderr strings.Builder
+
+	execID := fmt.Sprintf("exec-%d", time.Now().UnixNano())
+	process, err := task.Exec(ctx, execID, &specs.Process{
+		Args: cmd,
+		Env:  env,
+		Cwd:  "/",
+	}, cio.NewCreator(
+		cio.WithStreams(nil, w, &stderr),
+	))
+	if err != nil {
+		return fmt.Errorf("failed to exec: %w", err)
+	}
+	defer process.Delete(ctx)
+
+	exitCh, err := process.Wait(ctx)
+	if err != nil {
+		return err
+	}
+
+	if err := process.Start(ctx); err != nil {
+		return fmt.Errorf("failed to start exec: %w", err)
+	}
+
+	status := <-exitCh
+	if code, _, err := status.Result(); err != nil {
+		return err
+	} else if code != 0 {
+		return fmt.Errorf("command exited with code %d: %s", code, strings.TrimSpace(stderr.String()))
+	}
+	return nil
+}
+
+// ExecInteractive is not supported for containerd
+func (c *Client) ExecInteractive(ctx context.Context, containerID string, cmd []string, stdin io.Reader, stdout io.Writer, resize <-chan types.TerminalSize) error {
+	return fmt.Errorf("interactive shell not supported with containerd")
+}
+
 // UpdateContainerResources updates memory and CPU limits for a running container
 func (c *Client) UpdateContainerResources(ctx context.Context, containerID string, memoryLimit int64, cpuLimit float64) error {
-	// containerd doesn't support live resource updates easily
-	// This would require updating the container spec and restarting
-	return fmt.Errorf("live resource updates not supported with containerd; restart container with new limits")
+	ctx = c.ctx(ctx)
+
+	container, err := c.cli.LoadContainer(ctx, containerID)
+	if err != nil {
+		return fmt.Errorf("container not found: %w", err)
+	}
+
+	// Persist the new limits in the container spec so future tasks use them
+	spec, err := container.Spec(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to load container spec: %w", err)
+	}
+	if spec.Linux == nil {
+		spec.Linux = &specs.Linux{}
+	}
+	if spec.Linux.Resources == nil {
+		spec.Linux.Resources = &specs.LinuxResources{}
+	}
+	resources := spec.Linux.Resources
+	if memoryLimit > 0 {
+		if resources.Memory == nil {
+			resources.Memory = &specs.LinuxMemory{}
+		}
+		resources.Memory.Limit = &memoryLimit
+	}
+	if cpuLimit > 0 {
+		if resources.CPU == nil {
+			resources.CPU = &specs.LinuxCPU{}
+		}
+		period := uint64(100000)
+		quota := int64(cpuLimit * float64(period))
+		resources.CPU.Period = &period
+		resources.CPU.Quota = &quota
+	}
+
+	if err := container.Update(ctx, containerd.UpdateContainerOpts(containerd.WithSpec(spec))); err != nil {
+		return fmt.Errorf("failed to update container spec: %w", err)
+	}
+
+	// Apply to the running task's cgroup, if any
+	task, err := container.Task(ctx, nil)
+	if err != nil {
+		return nil // Not running; limits apply on next start
+	}
+	if err := task.Update(ctx, containerd.WithResources(resources)); err != nil {
+		return fmt.Errorf("%w: %v", types.ErrRestartRequired, err)
+	}
+	return nil
 }
 
 // DeleteVolume removes a volume (emulated for containerd)
@@ -1,38 +1,58 @@
 package docker
 
 import (
+	"bytes"
 	"context"
 	"encoding/json"
 	"fmt"
 	"io"
+	"os"
 	"strings"
+	"time"
 
 	"github.com/docker/docker/api/types/container"
 	"github.com/docker/docker/api/types/image"
 	"github.com/docker/docker/api/types/mount"
 	"github.com/docker/docker/api/types/network"
+	"github.com/docker/docker/api/types/registry"
 	"github.com/docker/docker/client"
+	"github.com/docker/docker/pkg/stdcopy"
 	"github.com/docker/go-connections/nat"
 	"github.com/sirrobot01/dbnest/pkg/runtime/types"
 )
 
 // Client wraps the Docker SDK client
 type Client struct {
-	cli     *client.Client
-	network string
+	cli        *client.Client
+	network    string
+	registries []types.RegistryAuth
 }
 
 // Verify Client implements types.Client interface
 var _ types.Client = (*Client)(nil)
 
-// NewClient creates a new Docker SDK client
-func NewClient(socketPath string, networkName string) (*Client, error) {
-	host := "unix://" + socketPath
+// NewClient creates a new Docker SDK client for host, a unix socket path or a
+// unix://, tcp:// or ssh:// URL. tls applies to tcp:// daemons served over TLS.
+func NewClient(host string, networkName string, tls types.TLSConfig) (*Client, error) {
+	opts := []client.Opt{client.WithAPIVersionNegotiation()}
+	switch {
+	case strings.HasPrefix(host, "ssh://"):
+		dial, err := sshDialer(host)
+		if err != nil {
+			return nil, err
+		}
+		// Requests still need an HTTP host, but connections go through ssh
+		opts = append(opts, client.WithHost("http://docker.example.com"), client.WithDialContext(dial))
+	case strings.Contains(host, "://"):
+		opts = append(opts, client.WithHost(host))
+	default:
+		opts = append(opts, client.WithHost("unix://"+host))
+	}
+	if tls.Enabled() {
+		opts = append(opts, client.WithTLSClientConfig(tls.CA, tls.Cert, tls.Key))
+	}
 
-	cli, err := client.NewClientWithOpts(
-		client.WithHost(host),
-		client.WithAPIVersionNegotiation(),
-	)
+	cli, err := client.NewClientWithOpts(opts...)
 	if err != nil {
 		return nil, fmt.Errorf("failed to create Docker client: %w", err)
 	}
@@ -62,6 +82,15 @@ func (c *Client) Ping(ctx context.Context) error {
 	return err
 }
 
+// Version returns the Docker daemon version
+func (c *Client) Version(ctx context.Context) (string, error) {
+	v, err := c.cli.ServerVersion(ctx)
+	if err != nil {
+		return "", err
+	}
+	return v.Version, nil
+}
+
 // ensureNetwork creates the DBNest network if it doesn't exist
 func (c *Client) ensureNetwork(ctx context.Context) error {
 	networks, err := c.cli.NetworkList(ctx, network.ListOptions{})
@@ -84,9 +113,27 @@ func (c *Client) ensureNetwork(ctx context.Context) error {
 	return err
 }
 
+// SetRegistryAuth sets the credentials used when pulling from private registries
+func (c *Client) SetRegistryAuth(auths []types.RegistryAuth) {
+	c.registries = auths
+}
+
 // PullImage pulls a Docker image
 func (c *Client) PullImage(ctx context.Context, imageName string) error {
-	reader, err := c.cli.ImagePull(ctx, imageName, image.PullOptions{})
+	opts := image.PullOptions{}
+	if auth := types.FindRegistryAuth(c.registries, imageName); auth != nil {
+		encoded, err := registry.EncodeAuthConfig(registry.AuthConfig{
+			Username:      auth.Username,
+			Password:      auth.Password,
+			ServerAddress: auth.Server,
+		})
+		if err != nil {
+			return fmt.Errorf("failed to encode registry auth: %w", err)
+		}
+		opts.RegistryAuth = encoded
+	}
+
+	reader, err := c.cli.ImagePull(ctx, imageName, opts)
 	if err != nil {
 		return fmt.Errorf("failed to pull image %s: %w", imageName, err)
 	}
@@ -95,16 +142,32 @@ func (c *Client) PullImage(ctx context.Context, imageName string) error {
 	return err
 }
 
+// ImageDigest returns the registry digest of a local image
+func (c *Client) ImageDigest(ctx context.Context, imageName string) (string, error) {
+	info, err := c.cli.ImageInspect(ctx, imageName)
+	if err != nil {
+		return "", fmt.Errorf("failed to inspect image %s: %w", imageName, err)
+	}
+	return types.PickRepoDigest(imageName, info.RepoDigests), nil
+}
+
 // CreateContainer creates a new container
 func (c *Client) CreateContainer(ctx context.Context, cfg *types.ContainerConfig) (string, error) {
 	exposedPorts := nat.PortSet{}
 	portBindings := nat.PortMap{}
 
+	hostIP := cfg.BindAddress
+	if hostIP == "" {
+		hostIP = "0.0.0.0"
+	}
 	for containerPort, hostPort := range cfg.PortBindings {
 		port := nat.Port(containerPort)
 		exposedPorts[port] = struct{}{}
+		if !cfg.ExposePort {
+			continue // reachable only from the container network
+		}
 		portBindings[port] = []nat.PortBinding{
-			{HostIP: "0.0.0.0", HostPort: hostPort},
+			{HostIP: hostIP, HostPort: hostPort},
 		}
 	}
 
@@ -112,7 +175,7 @@ func (c *Client) CreateContainer(ctx context.Context, cfg *types.ContainerConfig
 	for source, containerPath := range cfg.Volumes {
 		// Determine mount type: named volume vs bind mount
 		mountType := mount.TypeBind
-		if !strings.HasPrefix(source, "/") && !strings.HasPrefix(source, ".") {
+		if types.IsNamedVolume(source) {
 			// Named volume (e.g., "dbnest-vol-xxx")
 			mountType = mount.TypeVolume
 		}
@@ -123,21 +186,57 @@ func (c *Client) CreateContainer(ctx context.Context, cfg *types.ContainerConfig
 		})
 	}
 
+	if cfg.User() != "" {
+		if err := c.chownVolumes(ctx, cfg, mounts); err != nil {
+			return "", err
+		}
+	}
+
 	containerCfg := &container.Config{
 		Image:        cfg.Image,
 		Cmd:          cfg.Cmd,
 		Env:          cfg.Env,
 		ExposedPorts: exposedPorts,
 		Labels:       cfg.Labels,
+		User:         cfg.User(),
+	}
+	if hc := cfg.Healthcheck; hc != nil {
+		containerCfg.Healthcheck = &container.HealthConfig{
+			Test:        append([]string{"CMD"}, hc.Cmd...),
+			Interval:    hc.Interval,
+			Timeout:     hc.Timeout,
+			StartPeriod: hc.StartPeriod,
+			Retries:     hc.Retries,
+		}
+	}
+
+	restartMode, maxRetries, err := types.ParseRestartPolicy(cfg.RestartPolicy)
+	if err != nil {
+		return "", err
 	}
 
 	hostCfg := &container.HostConfig{
-		PortBindings:  portBindings,
-		Mounts:        mounts,
-		NetworkMode:   container.NetworkMode(c.network),
-		RestartPolicy: container.RestartPolicy{Name: "unless-stopped"},
+		PortBindings:   portBindings,
+		Mounts:         mounts,
+		NetworkMode:    container.NetworkMode(c.networkName(cfg.Network)),
+		UsernsMode:     container.UsernsMode(cfg.UsernsMode),
+		ReadonlyRootfs: cfg.ReadOnlyRootfs,
+		RestartPolicy: container.RestartPolicy{
+			Name:              container.RestartPolicyMode(restartMode),
+			MaximumRetryCount: maxRetries,
+		},
 	}
 
+	if hostCfg.SecurityOpt, err = securityOpts(cfg.Security); err != nil {
+		return "", err
+	}
+	hostCfg.CapDrop = cfg.Security.CapDrop
+	if len(cfg.Tmpfs) > 0 {
+		hostCfg.Tmpfs = make(map[string]string, len(cfg.Tmpfs))
+		for _, path := range cfg.Tmpfs {
+			hostCfg.Tmpfs[path] = ""
+		}
+	}
 	if cfg.MemoryLimit > 0 {
 		hostCfg.Memory = cfg.MemoryLimit
 	}
@@ -153,15 +252,96 @@ func (c *Client) CreateContainer(ctx context.Context, cfg *types.ContainerConfig
 	return resp.ID, nil
 }
 
+// securityOpts converts security options to Docker's SecurityOpt form. The
+// daemon takes a seccomp profile's JSON rather than its path.
+func securityOpts(sec types.SecurityOptions) ([]string, error) {
+	var opts []string
+	if sec.NoNewPrivileges {
+		opts = append(opts, "no-new-privileges:true")
+	}
+	switch sec.SeccompProfile {
+	case "":
+	case types.SeccompUnconfined:
+		opts = append(opts, "seccomp="+types.SeccompUnconfined)
+	default:
+		data, err := os.ReadFile(sec.SeccompProfile)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read seccomp profile: %w", err)
+		}
+		var profile bytes.Buffer
+		if err := json.Compact(&profile, data); err != nil {
+			return nil, fmt.Errorf("invalid seccomp profile %s: %w", sec.SeccompProfile, err)
+		}
+		opts = append(opts, "seccomp="+profile.String())
+	}
+	return opts, nil
+}
+
+// chownVolumes hands the container's named volumes to its user by running
+// chown in a throwaway root container from the same image. Named volumes start
+// out owned by the image's user, which an arbitrary UID can't write to. Bind
+// mounts are host directories that belong to the user and are left alone.
+func (c *Client) chownVolumes(ctx context.Context, cfg *types.ContainerConfig, mounts []mount.Mount) error {
+	var volumes []mount.Mount
+	for _, m := range mounts {
+		if m.Type == mount.TypeVolume {
+			volumes = append(volumes, m)
+		}
+	}
+	if len(volumes) == 0 {
+		return nil
+	}
+	cmd := []string{"-R", cfg.User()}
+	for _, m := range volumes {
+		cmd = append(cmd, m.Target)
+	}
+
+	resp, err := c.cli.ContainerCreate(ctx,
+		&container.Config{Image: cfg.Image, User: "0:0", Entrypoint: []string{"chown"}, Cmd: cmd},
+		&container.HostConfig{Mounts: volumes, NetworkMode: "none", UsernsMode: container.UsernsMode(cfg.UsernsMode)},
+		nil, nil, "")
+	if err != nil {
+		return fmt.Errorf("failed to create volume permissions container: %w", err)
+	}
+	defer c.cli.ContainerRemove(context.Background(), resp.ID, container.RemoveOptions{Force: true})
+
+	waitCh, errCh := c.cli.ContainerWait(ctx, resp.ID, container.WaitConditionNextExit)
+	if err := c.cli.ContainerStart(ctx, resp.ID, container.StartOptions{}); err != nil {
+		return fmt.Errorf("failed to fix volume permissions: %w", err)
+	}
+	select {
+	case result := <-waitCh:
+		if result.StatusCode != 0 {
+			return fmt.Errorf("failed to fix volume permissions: chown exited with %d", result.StatusCode)
+		}
+		return nil
+	case err := <-errCh:
+		return fmt.Errorf("failed to fix volume permissions: %w", err)
+	}
+}
+
 // StartContainer starts a container
 func (c *Client) StartContainer(ctx context.Context, containerID string) error {
 	return c.cli.ContainerStart(ctx, containerID, container.StartOptions{})
 }
 
 // StopContainer stops a container
-func (c *Client) StopContainer(ctx context.Context, containerID string) error {
-	timeout := 10
-	return c.cli.ContainerStop(ctx, containerID, container.StopOptions{Timeout: &timeout})
+func (c *Client) StopContainer(ctx context.Context, containerID string, timeout time.Duration) error {
+	if timeout <= 0 {
+		return c.cli.ContainerKill(ctx, containerID, "SIGKILL")
+	}
+	seconds := int(timeout.Seconds())
+	return c.cli.ContainerStop(ctx, containerID, container.StopOptions{Timeout: &seconds})
+}
+
+// PauseContainer freezes all processes in a container
+func (c *Client) PauseContainer(ctx context.Context, containerID string) error {
+	return c.cli.ContainerPause(ctx, containerID)
+}
+
+// ResumeContainer unfreezes a paused container
+func (c *Client) ResumeContainer(ctx context.Context, containerID string) error {
+	return c.cli.ContainerUnpause(ctx, containerID)
 }
 
 // RemoveContainer removes a container
@@ -182,16 +362,29 @@ func (c *Client) GetContainerStatus(ctx context.Context, containerID string) (st
 		return "", err
 	}
 
+	// A paused container also reports Running, so check Paused first
+	if info.State.Paused {
+		return "paused", nil
+	}
 	if info.State.Running {
+		if info.State.Health != nil {
+			switch info.State.Health.Status {
+			case container.Starting:
+				return "starting", nil
+			case container.Unhealthy:
+				return "unhealthy", nil
+			}
+		}
 		return "running", nil
 	}
-	if info.State.Paused {
-		return "stopped", nil
-	}
 	if info.State.Restarting {
 		return "creating", nil
 	}
-	if info.State.Dead || info.State.OOMKilled {
+	// The kernel OOM killer is reported separately so users know to raise the memory limit
+	if info.State.OOMKilled {
+		return "oom-killed", nil
+	}
+	if info.State.Dead {
 		return "error", nil
 	}
 	return "stopped", nil
@@ -265,8 +458,9 @@ func (c *Client) GetContainerStats(ctx context.Context, containerID string) (*ty
 	}, nil
 }
 
-// GetContainerLogs retrieves the last N lines of container logs
-func (c *Client) GetContainerLogs(ctx context.Context, containerID string, tail int) (string, error) {
+// GetContainerLogs retrieves the last N lines of container logs, optionally
+// only those written after since
+func (c *Client) GetContainerLogs(ctx context.Context, containerID string, tail int, since time.Time, timestamps bool) (string, error) {
 	if tail <= 0 {
 		tail = 100
 	}
@@ -274,6 +468,10 @@ func (c *Client) GetContainerLogs(ctx context.Context, containerID string, tail
 		ShowStdout: true,
 		ShowStderr: true,
 		Tail:       fmt.Sprintf("%d", tail),
+		Timestamps: timestamps,
+	}
+	if !since.IsZero() {
+		options.Since = since.UTC().Format(time.RFC3339)
 	}
 	reader, err := c.cli.ContainerLogs(ctx, containerID, options)
 	if err != nil {
@@ -289,19 +487,29 @@ func (c *Client) GetContainerLogs(ctx context.Context, containerID string, tail
 }
 
 // ListContainers lists all DBNest-managed containers
-func (c *Client) ListContainers(ctx context.Context) ([]string, error) {
+func (c *Client) ListContainers(ctx context.Context) ([]types.ContainerInfo, error) {
 	containers, err := c.cli.ContainerList(ctx, container.ListOptions{All: true})
 	if err != nil {
 		return nil, err
 	}
 
-	var ids []string
+	var result []types.ContainerInfo
 	for _, ctr := range containers {
-		if ctr.Labels["dbnest.managed"] == "true" {
-			ids = append(ids, ctr.ID)
+		if ctr.Labels["dbnest.managed"] != "true" {
+			continue
 		}
+		var name string
+		if len(ctr.Names) > 0 {
+			name = strings.TrimPrefix(ctr.Names[0], "/")
+		}
+		result = append(result, types.ContainerInfo{
+			ID:     ctr.ID,
+			Name:   name,
+			State:  ctr.State,
+			Labels: ctr.Labels,
+		})
 	}
-	return ids, nil
+	return result, nil
 }
 
 // ListNetworks returns all available Docker networks
@@ -347,6 +555,30 @@ func (c *Client) DeleteNetwork(ctx context.Context, networkID string) error {
 	return nil
 }
 
+// ConnectNetwork attaches a container to a network
+func (c *Client) ConnectNetwork(ctx context.Context, containerID, networkName string) error {
+	if err := c.cli.NetworkConnect(ctx, c.networkName(networkName), containerID, nil); err != nil {
+		return fmt.Errorf("failed to connect to network: %w", err)
+	}
+	return nil
+}
+
+// DisconnectNetwork detaches a container from a network
+func (c *Client) DisconnectNetwork(ctx context.Context, containerID, networkName string) error {
+	if err := c.cli.NetworkDisconnect(ctx, c.networkName(networkName), containerID, false); err != nil {
+		return fmt.Errorf("failed to disconnect from network: %w", err)
+	}
+	return nil
+}
+
+// networkName resolves an optional network name, defaulting to the DBNest network
+func (c *Client) networkName(name string) string {
+	if name == "" {
+		return c.network
+	}
+	return name
+}
+
 // ExecInContainer executes a command in a container
 func (c *Client) ExecInContainer(ctx context.Context, containerID string, cmd []string) (string, error) {
 	exec, err := c.cli.ContainerExecCreate(ctx, containerID, container.ExecOptions{
@@ -399,7 +631,7 @@ func (c *Client) Exec(ctx context.Context, containerID string, cmd []string, env
 }
 
 // ExecWithStdin executes a command with stdin input
-func (c *Client) ExecWithStdin(ctx context.Context, containerID string, cmd []string, stdin []byte, env []string) (string, error) {
+func (c *Client) ExecWithStdin(ctx context.Context, containerID string, cmd []string, stdin io.Reader, env []string) (string, error) {
 	exec, err := c.cli.ContainerExecCreate(ctx, containerID, container.ExecOptions{
 		Cmd:          cmd,
 		Env:          env,
@@ -417,19 +649,107 @@ func (c *Client) ExecWithStdin(ctx context.Context, containerID string, cmd []st
 	}
 	defer resp.Close()
 
-	if _, err := resp.Conn.Write(stdin); err != nil {
-		return "", fmt.Errorf("failed to write stdin: %w", err)
-	}
-	resp.CloseWrite()
+	// Stream stdin while reading output so large inputs can't fill the
+	// output buffer and deadlock the exec
+	writeErr := make(chan error, 1)
+	go func() {
+		_, err := io.Copy(resp.Conn, stdin)
+		resp.CloseWrite()
+		writeErr <- err
+	}()
 
 	output, err := io.ReadAll(resp.Reader)
 	if err != nil {
 		return "", err
 	}
+	if err := <-writeErr; err != nil {
+		return strings.TrimSpace(string(output)), fmt.Errorf("failed to write stdin: %w", err)
+	}
 
 	return strings.TrimSpace(string(output)), nil
 }
 
+// ExecStream executes a command and streams its stdout to w
+func (c *Client) ExecStream(ctx context.Context, containerID string, cmd []string, env []string, w io.Writer) error {
+	exec, err := c.cli.ContainerExecCreate(ctx, containerID, container.ExecOptions{
+		Cmd:          cmd,
+		Env:          env,
+		AttachStdout: true,
+		AttachStderr: true,
+	})
+	if err != nil {
+		return err
+	}
+
+	resp, err := c.cli.ContainerExecAttach(ctx, exec.ID, container.ExecAttachOptions{})
+	if err != nil {
+		return err
+	}
+	defer resp.Close()
+
+	// Without a TTY, stdout and stderr are multiplexed on one stream
+	var stderr bytes.Buffer
+	if _, err := stdcopy.StdCopy(w, &stderr, resp.Reader); err != nil {
+		return err
+	}
+
+	inspect, err := c.cli.ContainerExecInspect(ctx, exec.ID)
+	if err != nil {
+		return err
+	}
+	if inspect.ExitCode != 0 {
+		return fmt.Errorf("command exited with code %d: %s", inspect.ExitCode, strings.TrimSpace(stderr.String()))
+	}
+	return nil
+}
+
+// ExecInteractive runs a TTY-enabled command and bridges it to stdin/stdout
+func (c *Client) ExecInteractive(ctx context.Context, containerID string, cmd []string, stdin io.Reader, stdout io.Writer, resize <-chan types.TerminalSize) error {
+	exec, err := c.cli.ContainerExecCreate(ctx, containerID, container.ExecOptions{
+		Cmd:          cmd,
+		Tty:          true,
+		AttachStdin:  true,
+		AttachStdout: true,
+		AttachStderr: true,
+	})
+	if err != nil {
+		return err
+	}
+
+	resp, err := c.cli.ContainerExecAttach(ctx, exec.ID, container.ExecAttachOptions{Tty: true})
+	if err != nil {
+		return err
+	}
+	defer resp.Close()
+
+	// Apply terminal resizes until the session ends
+	go func() {
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case size, ok := <-resize:
+				if !ok {
+					return
+				}
+				c.cli.ContainerExecResize(ctx, exec.ID, container.ResizeOptions{
+					Height: size.Rows,
+					Width:  size.Cols,
+				})
+			}
+		}
+	}()
+
+	go func() {
+		io.Copy(resp.Conn, stdin)
+		resp.CloseWrite()
+	}()
+
+	// With a TTY, output is a raw stream (no stdout/stderr multiplexing)
+	_, err = io.Copy(stdout, resp.Reader)
+	return err
+}
+
 // UpdateContainerResources updates memory and CPU limits for a running container
 func (c *Client) UpdateContainerResources(ctx context.Context, containerID string, memoryLimit int64, cpuLimit float64) error {
 	updateConfig := container.UpdateConfig{
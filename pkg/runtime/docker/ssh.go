@@ -0,0 +1,130 @@
+package docker
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"net"
+	"net/url"
+	"os/exec"
+	"strings"
+	"sync"
+	"time"
+)
+
+// sshDialer returns a dialer that reaches the Docker daemon of an ssh:// host
+// by running "docker system dial-stdio" there, as the docker CLI does. It
+// uses the ssh client's own config and keys, and never prompts.
+func sshDialer(host string) (func(ctx context.Context, network, addr string) (net.Conn, error), error) {
+	u, args, err := sshArgs(host)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := exec.LookPath("ssh"); err != nil {
+		return nil, fmt.Errorf("ssh hosts need the ssh client installed: %w", err)
+	}
+
+	return func(ctx context.Context, network, addr string) (net.Conn, error) {
+		// Not tied to ctx: the connection outlives the dial
+		return newCommandConn(exec.Command("ssh", args...), u.Host)
+	}, nil
+}
+
+// sshArgs parses an ssh:// host into the ssh arguments that run
+// "docker system dial-stdio" on it
+func sshArgs(host string) (*url.URL, []string, error) {
+	u, err := url.Parse(host)
+	if err != nil || u.Scheme != "ssh" || u.Hostname() == "" {
+		return nil, nil, fmt.Errorf("invalid ssh host: %s", host)
+	}
+
+	args := []string{"-o", "BatchMode=yes"}
+	if u.User != nil {
+		args = append(args, "-l", u.User.Username())
+	}
+	if port := u.Port(); port != "" {
+		args = append(args, "-p", port)
+	}
+	args = append(args, "--", u.Hostname(), "docker", "system", "dial-stdio")
+	return u, args, nil
+}
+
+// commandConn is a net.Conn over a command's stdin and stdout
+type commandConn struct {
+	cmd    *exec.Cmd
+	stdin  io.WriteCloser
+	stdout io.ReadCloser
+	stderr lockedBuffer
+	addr   sshAddr
+}
+
+func newCommandConn(cmd *exec.Cmd, host string) (*commandConn, error) {
+	c := &commandConn{cmd: cmd, addr: sshAddr(host)}
+	var err error
+	if c.stdin, err = cmd.StdinPipe(); err != nil {
+		return nil, err
+	}
+	if c.stdout, err = cmd.StdoutPipe(); err != nil {
+		return nil, err
+	}
+	cmd.Stderr = &c.stderr
+	if err := cmd.Start(); err != nil {
+		return nil, fmt.Errorf("failed to start ssh: %w", err)
+	}
+	return c, nil
+}
+
+// Read reads the daemon's output. When ssh exits with an error, e.g. the host
+// refused the key, its message is returned instead of a bare EOF.
+func (c *commandConn) Read(p []byte) (int, error) {
+	n, err := c.stdout.Read(p)
+	if err == io.EOF {
+		if msg := strings.TrimSpace(c.stderr.String()); msg != "" {
+			return n, fmt.Errorf("ssh: %s", msg)
+		}
+	}
+	return n, err
+}
+
+func (c *commandConn) Write(p []byte) (int, error) {
+	return c.stdin.Write(p)
+}
+
+// Close ends the ssh session
+func (c *commandConn) Close() error {
+	c.stdin.Close()
+	c.cmd.Process.Kill()
+	c.cmd.Wait()
+	return nil
+}
+
+func (c *commandConn) LocalAddr() net.Addr                { return c.addr }
+func (c *commandConn) RemoteAddr() net.Addr               { return c.addr }
+func (c *commandConn) SetDeadline(t time.Time) error      { return nil }
+func (c *commandConn) SetReadDeadline(t time.Time) error  { return nil }
+func (c *commandConn) SetWriteDeadline(t time.Time) error { return nil }
+
+// sshAddr is the address of an ssh connection, the host it goes to
+type sshAddr string
+
+func (a sshAddr) Network() string { return "ssh" }
+func (a sshAddr) String() string  { return string(a) }
+
+// lockedBuffer is a bytes.Buffer safe to write from the command while read
+type lockedBuffer struct {
+	mu  sync.Mutex
+	buf bytes.Buffer
+}
+
+func (b *lockedBuffer) Write(p []byte) (int, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buf.Write(p)
+}
+
+func (b *lockedBuffer) String() string {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buf.String()
+}
@@ -0,0 +1,44 @@
+package docker
+
+import (
+	"slices"
+	"testing"
+)
+
+func TestSSHArgs(t *testing.T) {
+	dial := []string{"docker", "system", "dial-stdio"}
+	tests := []struct {
+		host     string
+		wantArgs []string // nil means the host is rejected
+	}{
+		{"ssh://remote", append([]string{"-o", "BatchMode=yes", "--", "remote"}, dial...)},
+		{"ssh://me@remote", append([]string{"-o", "BatchMode=yes", "-l", "me", "--", "remote"}, dial...)},
+		{"ssh://me@remote:2222", append([]string{"-o", "BatchMode=yes", "-l", "me", "-p", "2222", "--", "remote"}, dial...)},
+		// After "--" a host that looks like an option is still only a host
+		{"ssh://-oProxyCommand=x", append([]string{"-o", "BatchMode=yes", "--", "-oProxyCommand=x"}, dial...)},
+		{"ssh://", nil},
+		{"ssh://%zz", nil},
+		{"tcp://remote:2376", nil},
+		{"remote", nil},
+	}
+	for _, tt := range tests {
+		_, args, err := sshArgs(tt.host)
+		if tt.wantArgs == nil {
+			if err == nil {
+				t.Errorf("sshArgs(%q): expected an error, got %v", tt.host, args)
+			}
+			continue
+		}
+		if err != nil || !slices.Equal(args, tt.wantArgs) {
+			t.Errorf("sshArgs(%q) = %v, %v; expected %v", tt.host, args, err, tt.wantArgs)
+		}
+	}
+}
+
+func TestSSHDialerRejectsInvalidHosts(t *testing.T) {
+	for _, host := range []string{"ssh://", "ssh://%zz", "tcp://remote:2376"} {
+		if _, err := sshDialer(host); err == nil {
+			t.Errorf("expected sshDialer(%q) to fail", host)
+		}
+	}
+}
@@ -5,12 +5,14 @@ import (
 	"fmt"
 	"os"
 	"os/exec"
+	"strings"
 	"time"
 
 	"github.com/rs/zerolog/log"
 	"github.com/sirrobot01/dbnest/pkg/runtime/cli"
 	"github.com/sirrobot01/dbnest/pkg/runtime/containerd"
 	"github.com/sirrobot01/dbnest/pkg/runtime/docker"
+	"github.com/sirrobot01/dbnest/pkg/runtime/podman"
 )
 
 // RuntimeBinary maps runtime names to CLI binaries
@@ -23,14 +25,18 @@ var RuntimeBinary = map[string]string{
 // DefaultSockets maps runtime names to default socket paths
 var DefaultSockets = map[string]string{
 	"docker":     "/var/run/docker.sock",
+	"podman":     podman.DefaultSocket,
 	"containerd": "/run/containerd/containerd.sock",
 }
 
 // New creates a new container runtime client.
 // runtime: "docker", "podman", or "containerd"
-// If socketPath is provided and matches the runtime, uses SDK mode.
+// If socketPath is provided and matches the runtime, uses SDK mode. Docker and
+// podman also take a unix://, tcp:// or ssh:// URL for it, with tls applying
+// to tcp:// daemons; containerd only takes a local socket.
 // Otherwise uses CLI mode with the appropriate binary.
-func New(runtime, socketPath, networkName string) (Client, error) {
+// registries holds credentials used when pulling from private registries.
+func New(runtime, socketPath string, tls TLSConfig, networkName string, registries []RegistryAuth) (Client, error) {
 	// Default to docker
 	if runtime == "" {
 		runtime = "docker"
@@ -45,19 +51,21 @@ func New(runtime, socketPath, networkName string) (Client, error) {
 	if socketPath != "" {
 		switch runtime {
 		case "docker":
-			return newDockerSDKClient(socketPath, networkName)
+			return newDockerSDKClient(socketPath, tls, networkName, registries)
+		case "podman":
+			return newPodmanSDKClient(socketPath, tls, networkName, registries)
 		case "containerd":
-			return newContainerdSDKClient(socketPath, networkName)
+			return newContainerdSDKClient(socketPath, networkName, registries)
 		}
 	}
 
 	// Fall back to CLI mode
-	return newCLIClient(runtime, networkName)
+	return newCLIClient(runtime, networkName, registries)
 }
 
 // newDockerSDKClient validates socket and creates Docker SDK client
-func newDockerSDKClient(socketPath, networkName string) (Client, error) {
-	if err := validateSocket(socketPath); err != nil {
+func newDockerSDKClient(socketPath string, tls TLSConfig, networkName string, registries []RegistryAuth) (Client, error) {
+	if err := validateHost(socketPath); err != nil {
 		return nil, err
 	}
 
@@ -67,10 +75,11 @@ func newDockerSDKClient(socketPath, networkName string) (Client, error) {
 		Str("socket", socketPath).
 		Msg("Initializing container runtime")
 
-	client, err := docker.NewClient(socketPath, networkName)
+	client, err := docker.NewClient(socketPath, networkName, tls)
 	if err != nil {
 		return nil, err
 	}
+	client.SetRegistryAuth(registries)
 
 	if err := pingWithTimeout(client, socketPath, "docker"); err != nil {
 		client.Close()
@@ -85,8 +94,44 @@ func newDockerSDKClient(socketPath, networkName string) (Client, error) {
 	return client, nil
 }
 
+// newPodmanSDKClient validates socket and creates podman SDK client
+func newPodmanSDKClient(socketPath string, tls TLSConfig, networkName string, registries []RegistryAuth) (Client, error) {
+	if err := validateHost(socketPath); err != nil {
+		return nil, err
+	}
+
+	log.Info().
+		Str("runtime", "podman").
+		Str("mode", "SDK").
+		Str("socket", socketPath).
+		Msg("Initializing container runtime")
+
+	client, err := podman.NewClient(socketPath, networkName, tls)
+	if err != nil {
+		return nil, err
+	}
+	client.SetRegistryAuth(registries)
+
+	if err := pingWithTimeout(client, socketPath, "podman"); err != nil {
+		client.Close()
+		return nil, err
+	}
+
+	log.Info().
+		Str("runtime", "podman").
+		Str("socket", socketPath).
+		Msg("Container runtime connected successfully")
+
+	return client, nil
+}
+
 // newContainerdSDKClient validates socket and creates containerd SDK client
-func newContainerdSDKClient(socketPath, networkName string) (Client, error) {
+func newContainerdSDKClient(socketPath, networkName string, registries []RegistryAuth) (Client, error) {
+	path, local := localSocket(socketPath)
+	if !local {
+		return nil, fmt.Errorf("containerd needs a local socket, got %s", socketPath)
+	}
+	socketPath = path
 	if err := validateSocket(socketPath); err != nil {
 		return nil, err
 	}
@@ -101,6 +146,7 @@ func newContainerdSDKClient(socketPath, networkName string) (Client, error) {
 	if err != nil {
 		return nil, err
 	}
+	client.SetRegistryAuth(registries)
 
 	if err := pingWithTimeout(client, socketPath, "containerd"); err != nil {
 		client.Close()
@@ -116,7 +162,7 @@ func newContainerdSDKClient(socketPath, networkName string) (Client, error) {
 }
 
 // newCLIClient validates binary and creates CLI client
-func newCLIClient(runtime, networkName string) (Client, error) {
+func newCLIClient(runtime, networkName string, registries []RegistryAuth) (Client, error) {
 	binary := RuntimeBinary[runtime]
 
 	binaryPath, err := exec.LookPath(binary)
@@ -134,6 +180,7 @@ func newCLIClient(runtime, networkName string) (Client, error) {
 	if err != nil {
 		return nil, err
 	}
+	client.SetRegistryAuth(registries)
 
 	if err := pingWithTimeout(client, "", runtime); err != nil {
 		return nil, err
@@ -147,6 +194,24 @@ func newCLIClient(runtime, networkName string) (Client, error) {
 	return client, nil
 }
 
+// localSocket returns the path of a unix socket given as a path or unix://
+// URL, and false for remote hosts
+func localSocket(host string) (string, bool) {
+	if path, ok := strings.CutPrefix(host, "unix://"); ok {
+		return path, true
+	}
+	return host, !strings.Contains(host, "://")
+}
+
+// validateHost checks a local socket exists; remote hosts are checked by
+// connecting to them
+func validateHost(host string) error {
+	if path, local := localSocket(host); local {
+		return validateSocket(path)
+	}
+	return nil
+}
+
 // validateSocket checks if socket path exists and is accessible
 func validateSocket(socketPath string) error {
 	info, err := os.Stat(socketPath)
@@ -0,0 +1,59 @@
+package runtime
+
+import (
+	"net"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestLocalSocket(t *testing.T) {
+	tests := []struct {
+		host      string
+		wantPath  string
+		wantLocal bool
+	}{
+		{"/var/run/docker.sock", "/var/run/docker.sock", true},
+		{"unix:///var/run/docker.sock", "/var/run/docker.sock", true},
+		{"tcp://remote:2376", "tcp://remote:2376", false},
+		{"ssh://me@remote", "ssh://me@remote", false},
+	}
+	for _, tt := range tests {
+		path, local := localSocket(tt.host)
+		if path != tt.wantPath || local != tt.wantLocal {
+			t.Errorf("localSocket(%q) = %q, %v; expected %q, %v", tt.host, path, local, tt.wantPath, tt.wantLocal)
+		}
+	}
+}
+
+func TestValidateHost(t *testing.T) {
+	dir := t.TempDir()
+	sock := filepath.Join(dir, "docker.sock")
+	listener, err := net.Listen("unix", sock)
+	if err != nil {
+		t.Fatalf("failed to create socket: %v", err)
+	}
+	defer listener.Close()
+
+	tests := []struct {
+		host    string
+		wantErr string
+	}{
+		{sock, ""},
+		{"unix://" + sock, ""},
+		{filepath.Join(dir, "missing.sock"), "socket not found"},
+		{"unix://" + filepath.Join(dir, "missing.sock"), "socket not found"},
+		// Remote hosts are only checked by connecting
+		{"tcp://remote:2376", ""},
+		{"ssh://me@remote", ""},
+	}
+	for _, tt := range tests {
+		err := validateHost(tt.host)
+		switch {
+		case tt.wantErr == "" && err != nil:
+			t.Errorf("validateHost(%q) failed: %v", tt.host, err)
+		case tt.wantErr != "" && (err == nil || !strings.Contains(err.Error(), tt.wantErr)):
+			t.Errorf("validateHost(%q): expected %q error, got %v", tt.host, tt.wantErr, err)
+		}
+	}
+}
@@ -6,10 +6,34 @@ import (
 	"github.com/sirrobot01/dbnest/pkg/runtime/types"
 )
 
+// ErrRestartRequired is re-exported from runtime/types
+var ErrRestartRequired = types.ErrRestartRequired
+
+// DefaultStopTimeout is re-exported from runtime/types
+const DefaultStopTimeout = types.DefaultStopTimeout
+
+// DefaultRestartPolicy is re-exported from runtime/types
+const DefaultRestartPolicy = types.DefaultRestartPolicy
+
+// DefaultCapDrop is re-exported from runtime/types
+var DefaultCapDrop = types.DefaultCapDrop
+
+// SeccompUnconfined is re-exported from runtime/types
+const SeccompUnconfined = types.SeccompUnconfined
+
+// ParseRestartPolicy is re-exported from runtime/types
+var ParseRestartPolicy = types.ParseRestartPolicy
+
 // Re-export types for external users
 type (
 	Client          = types.Client
 	ContainerConfig = types.ContainerConfig
+	SecurityOptions = types.SecurityOptions
+	Healthcheck     = types.Healthcheck
 	ContainerStats  = types.ContainerStats
 	NetworkInfo     = types.NetworkInfo
+	ContainerInfo   = types.ContainerInfo
+	TerminalSize    = types.TerminalSize
+	RegistryAuth    = types.RegistryAuth
+	TLSConfig       = types.TLSConfig
 )
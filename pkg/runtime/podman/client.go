@@ -0,0 +1,37 @@
+// Package podman is the SDK client for the podman service socket.
+//
+// The podman service (podman system service) serves the Docker-compatible
+// REST API next to its own libpod API, so this client drives it with the
+// Docker SDK rather than the podman Go bindings, which pull in podman's
+// storage and image libraries and need cgo. Calls go straight over the socket
+// like the Docker client's, so stats and logs work without spawning podman.
+package podman
+
+import (
+	"fmt"
+
+	"github.com/sirrobot01/dbnest/pkg/runtime/docker"
+	"github.com/sirrobot01/dbnest/pkg/runtime/types"
+)
+
+// DefaultSocket is the rootful podman service socket. Rootless services
+// listen on $XDG_RUNTIME_DIR/podman/podman.sock.
+const DefaultSocket = "/run/podman/podman.sock"
+
+// Client talks to the podman service through its Docker-compatible API
+type Client struct {
+	*docker.Client
+}
+
+// Verify Client implements types.Client interface
+var _ types.Client = (*Client)(nil)
+
+// NewClient creates a podman client for the service listening on host, a
+// socket path or URL as for docker.NewClient
+func NewClient(host string, networkName string, tls types.TLSConfig) (*Client, error) {
+	c, err := docker.NewClient(host, networkName, tls)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create podman client: %w", err)
+	}
+	return &Client{Client: c}, nil
+}
@@ -0,0 +1,116 @@
+package podman
+
+import (
+	"context"
+	"encoding/json"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/sirrobot01/dbnest/pkg/runtime/types"
+)
+
+// fakeService answers the few Docker-compatible API calls the client makes,
+// as the podman service does, and records the networks it is asked to create
+type fakeService struct {
+	mu       sync.Mutex
+	networks []string
+}
+
+func (f *fakeService) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("API-Version", "1.41")
+	w.Header().Set("Content-Type", "application/json")
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	switch {
+	case strings.HasSuffix(r.URL.Path, "/_ping"):
+		w.Write([]byte("OK"))
+	case strings.HasSuffix(r.URL.Path, "/version"):
+		json.NewEncoder(w).Encode(map[string]string{"Version": "5.2.0", "ApiVersion": "1.41"})
+	case strings.HasSuffix(r.URL.Path, "/networks") && r.Method == http.MethodGet:
+		list := []map[string]string{{"Name": "podman"}}
+		for _, name := range f.networks {
+			list = append(list, map[string]string{"Name": name})
+		}
+		json.NewEncoder(w).Encode(list)
+	case strings.HasSuffix(r.URL.Path, "/networks/create"):
+		var req struct{ Name string }
+		json.NewDecoder(r.Body).Decode(&req)
+		f.networks = append(f.networks, req.Name)
+		w.WriteHeader(http.StatusCreated)
+		json.NewEncoder(w).Encode(map[string]string{"Id": "net-" + req.Name})
+	default:
+		http.NotFound(w, r)
+	}
+}
+
+// serveUnix serves f on a unix socket in a temporary directory
+func serveUnix(t *testing.T, f *fakeService) string {
+	t.Helper()
+	sock := filepath.Join(t.TempDir(), "podman.sock")
+	listener, err := net.Listen("unix", sock)
+	if err != nil {
+		t.Fatalf("failed to listen on %s: %v", sock, err)
+	}
+	server := &httptest.Server{Listener: listener, Config: &http.Server{Handler: f}}
+	server.Start()
+	t.Cleanup(server.Close)
+	return sock
+}
+
+func TestNewClientResolvesSocket(t *testing.T) {
+	service := &fakeService{}
+	sock := serveUnix(t, service)
+	tcp := httptest.NewServer(service)
+	defer tcp.Close()
+
+	for _, host := range []string{
+		sock,             // a bare path is a unix socket
+		"unix://" + sock, // so is a unix:// URL
+		"tcp://" + strings.TrimPrefix(tcp.URL, "http://"), // and a remote service
+	} {
+		t.Run(host, func(t *testing.T) {
+			client, err := NewClient(host, "dbnest", types.TLSConfig{})
+			if err != nil {
+				t.Fatalf("NewClient(%q) failed: %v", host, err)
+			}
+			defer client.Close()
+
+			ctx := context.Background()
+			if err := client.Ping(ctx); err != nil {
+				t.Errorf("Ping failed: %v", err)
+			}
+			if v, err := client.Version(ctx); err != nil || v != "5.2.0" {
+				t.Errorf("expected the service version, got %q (%v)", v, err)
+			}
+		})
+	}
+
+	// The network is created once and found afterwards
+	service.mu.Lock()
+	defer service.mu.Unlock()
+	if len(service.networks) != 1 || service.networks[0] != "dbnest" {
+		t.Errorf("expected the dbnest network to be created once, got %v", service.networks)
+	}
+}
+
+func TestNewClientErrors(t *testing.T) {
+	missing := filepath.Join(t.TempDir(), "missing.sock")
+	for _, host := range []string{missing, "unix://" + missing} {
+		if _, err := NewClient(host, "dbnest", types.TLSConfig{}); err == nil {
+			t.Errorf("expected NewClient(%q) to fail without a service", host)
+		}
+	}
+
+	if _, err := NewClient("ssh://", "dbnest", types.TLSConfig{}); err == nil {
+		t.Error("expected an invalid ssh host to be rejected")
+	}
+	_, err := NewClient("tcp://remote:2376", "dbnest", types.TLSConfig{CA: "/nonexistent/ca.pem"})
+	if err == nil || !strings.Contains(err.Error(), "failed to create podman client") {
+		t.Errorf("expected a wrapped constructor error for unreadable TLS files, got %v", err)
+	}
+}
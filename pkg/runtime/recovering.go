@@ -0,0 +1,398 @@
+package runtime
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/containerd/containerd/errdefs"
+	dockerclient "github.com/docker/docker/client"
+	"github.com/rs/zerolog/log"
+)
+
+// ErrUnavailable is returned by a RecoveringClient while the container
+// runtime can't be reached
+var ErrUnavailable = errors.New("container runtime unavailable")
+
+// Reconnection attempts start right away and back off, doubling, up to
+// maxReconnectBackoff between attempts
+const (
+	minReconnectBackoff = time.Second
+	maxReconnectBackoff = 30 * time.Second
+)
+
+// RecoveringClient is a Client that survives its runtime going away. It can
+// start without the runtime, and drops a connection that fails with a
+// connection error. Until it is connected again every operation fails with
+// ErrUnavailable while the connection is retried in the background, so
+// DBnest keeps serving stored data while the daemon is down and picks it up
+// once it's back.
+type RecoveringClient struct {
+	connect func() (Client, error)
+
+	mu           sync.RWMutex
+	client       Client // nil while unavailable
+	reconnecting bool
+
+	closeOnce sync.Once
+	done      chan struct{}
+}
+
+// Verify RecoveringClient implements Client
+var _ Client = (*RecoveringClient)(nil)
+
+// NewRecovering returns a client connected with connect, typically a call to
+// New. If connect fails the client starts unavailable and keeps retrying.
+func NewRecovering(connect func() (Client, error)) *RecoveringClient {
+	c := &RecoveringClient{
+		connect: connect,
+		done:    make(chan struct{}),
+	}
+
+	client, err := connect()
+	if err == nil {
+		c.client = client
+		return c
+	}
+	log.Warn().Err(err).Msg("Container runtime unavailable, starting in degraded mode")
+	c.reconnecting = true
+	go c.retryConnect()
+	return c
+}
+
+// reconnect drops stale, a connection that stopped working, and reconnects in
+// the background. It does nothing if stale was already replaced.
+func (c *RecoveringClient) reconnect(stale Client) {
+	c.mu.Lock()
+	if c.client != stale || c.reconnecting {
+		c.mu.Unlock()
+		return
+	}
+	c.client = nil
+	c.reconnecting = true
+	c.mu.Unlock()
+
+	log.Warn().Msg("Lost connection to container runtime, reconnecting")
+	stale.Close()
+	go c.retryConnect()
+}
+
+// retryConnect retries the connection, backing off between attempts, until
+// it succeeds or the client is closed
+func (c *RecoveringClient) retryConnect() {
+	backoff := minReconnectBackoff
+	for {
+		client, err := c.connect()
+		if err == nil {
+			c.mu.Lock()
+			c.client = client
+			c.reconnecting = false
+			c.mu.Unlock()
+			log.Info().Msg("Container runtime available, leaving degraded mode")
+			return
+		}
+		log.Debug().Err(err).Dur("retry_in", backoff).Msg("Container runtime still unavailable")
+
+		select {
+		case <-c.done:
+			return
+		case <-time.After(backoff):
+		}
+		backoff = min(backoff*2, maxReconnectBackoff)
+	}
+}
+
+// check passes err through, unless it says the runtime behind client can no
+// longer be reached. Then it reconnects and returns the error as
+// ErrUnavailable, so callers treat it like any other outage.
+func (c *RecoveringClient) check(client Client, err error) error {
+	if isConnectionError(err) {
+		log.Debug().Err(err).Msg("Container runtime connection error")
+		c.reconnect(client)
+		return fmt.Errorf("%w: %v", ErrUnavailable, err)
+	}
+	return err
+}
+
+// cliConnectionErrors are the messages runtime CLIs print when their daemon
+// is down
+var cliConnectionErrors = []string{
+	"Cannot connect to the Docker daemon",
+	"Cannot connect to Podman",
+	"cannot access containerd socket",
+}
+
+// isConnectionError reports whether err means the runtime's daemon couldn't
+// be reached, as opposed to an operation failing
+func isConnectionError(err error) bool {
+	if err == nil || errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+		return false
+	}
+	if dockerclient.IsErrConnectionFailed(err) || errdefs.IsUnavailable(errdefs.FromGRPC(err)) {
+		return true
+	}
+	var opErr *net.OpError
+	if errors.As(err, &opErr) && opErr.Op == "dial" {
+		return true
+	}
+	for _, msg := range cliConnectionErrors {
+		if strings.Contains(err.Error(), msg) {
+			return true
+		}
+	}
+	return false
+}
+
+// Available reports whether the runtime is connected
+func (c *RecoveringClient) Available() bool {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.client != nil
+}
+
+// current returns the connected runtime, or ErrUnavailable
+func (c *RecoveringClient) current() (Client, error) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	if c.client == nil {
+		return nil, ErrUnavailable
+	}
+	return c.client, nil
+}
+
+// Close stops any reconnection attempts and closes the runtime connection
+func (c *RecoveringClient) Close() error {
+	c.closeOnce.Do(func() { close(c.done) })
+	if client, err := c.current(); err == nil {
+		return client.Close()
+	}
+	return nil
+}
+
+// The remaining methods forward to the connected runtime, reconnecting on
+// connection errors
+
+func (c *RecoveringClient) Ping(ctx context.Context) error {
+	client, err := c.current()
+	if err != nil {
+		return err
+	}
+	return c.check(client, client.Ping(ctx))
+}
+
+func (c *RecoveringClient) Version(ctx context.Context) (string, error) {
+	client, err := c.current()
+	if err != nil {
+		return "", err
+	}
+	out, err := client.Version(ctx)
+	return out, c.check(client, err)
+}
+
+func (c *RecoveringClient) PullImage(ctx context.Context, imageName string) error {
+	client, err := c.current()
+	if err != nil {
+		return err
+	}
+	return c.check(client, client.PullImage(ctx, imageName))
+}
+
+func (c *RecoveringClient) ImageDigest(ctx context.Context, imageName string) (string, error) {
+	client, err := c.current()
+	if err != nil {
+		return "", err
+	}
+	out, err := client.ImageDigest(ctx, imageName)
+	return out, c.check(client, err)
+}
+
+func (c *RecoveringClient) CreateContainer(ctx context.Context, cfg *ContainerConfig) (string, error) {
+	client, err := c.current()
+	if err != nil {
+		return "", err
+	}
+	out, err := client.CreateContainer(ctx, cfg)
+	return out, c.check(client, err)
+}
+
+func (c *RecoveringClient) StartContainer(ctx context.Context, containerID string) error {
+	client, err := c.current()
+	if err != nil {
+		return err
+	}
+	return c.check(client, client.StartContainer(ctx, containerID))
+}
+
+func (c *RecoveringClient) StopContainer(ctx context.Context, containerID string, timeout time.Duration) error {
+	client, err := c.current()
+	if err != nil {
+		return err
+	}
+	return c.check(client, client.StopContainer(ctx, containerID, timeout))
+}
+
+func (c *RecoveringClient) RemoveContainer(ctx context.Context, containerID string, force bool) error {
+	client, err := c.current()
+	if err != nil {
+		return err
+	}
+	return c.check(client, client.RemoveContainer(ctx, containerID, force))
+}
+
+func (c *RecoveringClient) PauseContainer(ctx context.Context, containerID string) error {
+	client, err := c.current()
+	if err != nil {
+		return err
+	}
+	return c.check(client, client.PauseContainer(ctx, containerID))
+}
+
+func (c *RecoveringClient) ResumeContainer(ctx context.Context, containerID string) error {
+	client, err := c.current()
+	if err != nil {
+		return err
+	}
+	return c.check(client, client.ResumeContainer(ctx, containerID))
+}
+
+func (c *RecoveringClient) GetContainerStatus(ctx context.Context, containerID string) (string, error) {
+	client, err := c.current()
+	if err != nil {
+		return "", err
+	}
+	out, err := client.GetContainerStatus(ctx, containerID)
+	return out, c.check(client, err)
+}
+
+func (c *RecoveringClient) GetContainerStats(ctx context.Context, containerID string) (*ContainerStats, error) {
+	client, err := c.current()
+	if err != nil {
+		return nil, err
+	}
+	out, err := client.GetContainerStats(ctx, containerID)
+	return out, c.check(client, err)
+}
+
+func (c *RecoveringClient) GetContainerLogs(ctx context.Context, containerID string, tail int, since time.Time, timestamps bool) (string, error) {
+	client, err := c.current()
+	if err != nil {
+		return "", err
+	}
+	out, err := client.GetContainerLogs(ctx, containerID, tail, since, timestamps)
+	return out, c.check(client, err)
+}
+
+func (c *RecoveringClient) ListContainers(ctx context.Context) ([]ContainerInfo, error) {
+	client, err := c.current()
+	if err != nil {
+		return nil, err
+	}
+	out, err := client.ListContainers(ctx)
+	return out, c.check(client, err)
+}
+
+func (c *RecoveringClient) ListNetworks(ctx context.Context) ([]NetworkInfo, error) {
+	client, err := c.current()
+	if err != nil {
+		return nil, err
+	}
+	out, err := client.ListNetworks(ctx)
+	return out, c.check(client, err)
+}
+
+func (c *RecoveringClient) CreateNetwork(ctx context.Context, name string) (*NetworkInfo, error) {
+	client, err := c.current()
+	if err != nil {
+		return nil, err
+	}
+	out, err := client.CreateNetwork(ctx, name)
+	return out, c.check(client, err)
+}
+
+func (c *RecoveringClient) DeleteNetwork(ctx context.Context, networkID string) error {
+	client, err := c.current()
+	if err != nil {
+		return err
+	}
+	return c.check(client, client.DeleteNetwork(ctx, networkID))
+}
+
+func (c *RecoveringClient) ConnectNetwork(ctx context.Context, containerID, network string) error {
+	client, err := c.current()
+	if err != nil {
+		return err
+	}
+	return c.check(client, client.ConnectNetwork(ctx, containerID, network))
+}
+
+func (c *RecoveringClient) DisconnectNetwork(ctx context.Context, containerID, network string) error {
+	client, err := c.current()
+	if err != nil {
+		return err
+	}
+	return c.check(client, client.DisconnectNetwork(ctx, containerID, network))
+}
+
+func (c *RecoveringClient) ExecInContainer(ctx context.Context, containerID string, cmd []string) (string, error) {
+	client, err := c.current()
+	if err != nil {
+		return "", err
+	}
+	out, err := client.ExecInContainer(ctx, containerID, cmd)
+	return out, c.check(client, err)
+}
+
+func (c *RecoveringClient) Exec(ctx context.Context, containerID string, cmd []string, env []string) (string, error) {
+	client, err := c.current()
+	if err != nil {
+		return "", err
+	}
+	out, err := client.Exec(ctx, containerID, cmd, env)
+	return out, c.check(client, err)
+}
+
+func (c *RecoveringClient) ExecWithStdin(ctx context.Context, containerID string, cmd []string, stdin io.Reader, env []string) (string, error) {
+	client, err := c.current()
+	if err != nil {
+		return "", err
+	}
+	out, err := client.ExecWithStdin(ctx, containerID, cmd, stdin, env)
+	return out, c.check(client, err)
+}
+
+func (c *RecoveringClient) ExecStream(ctx context.Context, containerID string, cmd []string, env []string, w io.Writer) error {
+	client, err := c.current()
+	if err != nil {
+		return err
+	}
+	return c.check(client, client.ExecStream(ctx, containerID, cmd, env, w))
+}
+
+func (c *RecoveringClient) ExecInteractive(ctx context.Context, containerID string, cmd []string, stdin io.Reader, stdout io.Writer, resize <-chan TerminalSize) error {
+	client, err := c.current()
+	if err != nil {
+		return err
+	}
+	return c.check(client, client.ExecInteractive(ctx, containerID, cmd, stdin, stdout, resize))
+}
+
+func (c *RecoveringClient) UpdateContainerResources(ctx context.Context, containerID string, memoryLimit int64, cpuLimit float64) error {
+	client, err := c.current()
+	if err != nil {
+		return err
+	}
+	return c.check(client, client.UpdateContainerResources(ctx, containerID, memoryLimit, cpuLimit))
+}
+
+func (c *RecoveringClient) DeleteVolume(ctx context.Context, name string) error {
+	client, err := c.current()
+	if err != nil {
+		return err
+	}
+	return c.check(client, client.DeleteVolume(ctx, name))
+}
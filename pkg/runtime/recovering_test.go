@@ -0,0 +1,85 @@
+package runtime
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+)
+
+// fakeClient is a Client whose Ping returns pingErr. Other methods are not
+// implemented.
+type fakeClient struct {
+	Client
+	pingErr error
+}
+
+func (f *fakeClient) Ping(ctx context.Context) error { return f.pingErr }
+func (f *fakeClient) Close() error                   { return nil }
+
+func TestRecoveringClientReconnects(t *testing.T) {
+	var mu sync.Mutex
+	var connects int
+	// The second connection fails like a runtime CLI whose daemon has stopped
+	down := &fakeClient{pingErr: errors.New("docker info failed: exit status 1, stderr: Cannot connect to the Docker daemon at unix:///var/run/docker.sock")}
+	results := []Client{nil, down, &fakeClient{}}
+	connect := func() (Client, error) {
+		mu.Lock()
+		defer mu.Unlock()
+		client := results[min(connects, len(results)-1)]
+		connects++
+		if client == nil {
+			return nil, errors.New("socket not found")
+		}
+		return client, nil
+	}
+
+	waitAvailable := func(c *RecoveringClient) {
+		t.Helper()
+		deadline := time.Now().Add(3 * time.Second)
+		for !c.Available() {
+			if time.Now().After(deadline) {
+				t.Fatal("runtime did not become available")
+			}
+			time.Sleep(10 * time.Millisecond)
+		}
+	}
+
+	// Starts unavailable and connects in the background
+	c := NewRecovering(connect)
+	defer c.Close()
+	if err := c.Ping(context.Background()); !errors.Is(err, ErrUnavailable) {
+		t.Fatalf("expected ErrUnavailable before connecting, got %v", err)
+	}
+	waitAvailable(c)
+
+	// The first connection's daemon is down: a ping drops it and reconnects,
+	// and its error is reported as the runtime being unavailable
+	if err := c.Ping(context.Background()); !errors.Is(err, ErrUnavailable) {
+		t.Fatalf("expected ErrUnavailable from a stopped daemon, got %v", err)
+	}
+	waitAvailable(c)
+	if err := c.Ping(context.Background()); err != nil {
+		t.Fatalf("expected the reconnected client to work, got %v", err)
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+	if connects != 3 {
+		t.Errorf("expected 3 connection attempts, got %d", connects)
+	}
+}
+
+func TestRecoveringClientPassesOperationErrors(t *testing.T) {
+	failure := errors.New("no such container")
+	c := NewRecovering(func() (Client, error) { return &fakeClient{pingErr: failure}, nil })
+	defer c.Close()
+
+	if err := c.Ping(context.Background()); err != failure {
+		t.Errorf("expected the operation's error, got %v", err)
+	}
+	if !c.Available() {
+		t.Error("expected an operation error to keep the connection")
+	}
+}
@@ -0,0 +1,82 @@
+package types
+
+import "strings"
+
+// DefaultRegistry is the registry used for image references without a registry host
+const DefaultRegistry = "docker.io"
+
+// RegistryAuth holds credentials for a private image registry
+type RegistryAuth struct {
+	Server   string // Registry host, e.g. "registry.example.com:5000" or "docker.io"
+	Username string
+	Password string
+}
+
+// RegistryHost returns the registry host an image reference is pulled from
+func RegistryHost(imageName string) string {
+	first, _, found := strings.Cut(imageName, "/")
+	if !found {
+		return DefaultRegistry
+	}
+	// Only a first component that looks like a host names a registry
+	if strings.ContainsAny(first, ".:") || first == "localhost" {
+		return first
+	}
+	return DefaultRegistry
+}
+
+// normalizeRegistry maps Docker Hub aliases and URLs to a bare host
+func normalizeRegistry(server string) string {
+	server = strings.TrimPrefix(server, "https://")
+	server = strings.TrimPrefix(server, "http://")
+	server, _, _ = strings.Cut(server, "/")
+	switch server {
+	case "", "index.docker.io", "registry-1.docker.io":
+		return DefaultRegistry
+	}
+	return server
+}
+
+// FindRegistryAuth returns the credentials matching the registry of imageName, or nil
+func FindRegistryAuth(auths []RegistryAuth, imageName string) *RegistryAuth {
+	host := RegistryHost(imageName)
+	for i := range auths {
+		if normalizeRegistry(auths[i].Server) == host {
+			return &auths[i]
+		}
+	}
+	return nil
+}
+
+// imageRepository strips the tag and digest from an image reference
+func imageRepository(imageName string) string {
+	repo, _, _ := strings.Cut(imageName, "@")
+	if i := strings.LastIndex(repo, ":"); i > strings.LastIndex(repo, "/") {
+		repo = repo[:i]
+	}
+	return repo
+}
+
+// PickRepoDigest returns the digest ("sha256:...") for imageName from an image's
+// repo digests ("repo@sha256:..."), preferring the one for the same repository.
+// A reference that already pins a digest returns that digest.
+func PickRepoDigest(imageName string, repoDigests []string) string {
+	if _, digest, found := strings.Cut(imageName, "@"); found {
+		return digest
+	}
+	repo := imageRepository(imageName)
+	var first string
+	for _, rd := range repoDigests {
+		name, digest, found := strings.Cut(rd, "@")
+		if !found {
+			continue
+		}
+		if name == repo || strings.TrimPrefix(name, DefaultRegistry+"/library/") == repo || strings.TrimPrefix(name, DefaultRegistry+"/") == repo {
+			return digest
+		}
+		if first == "" {
+			first = digest
+		}
+	}
+	return first
+}
@@ -0,0 +1,81 @@
+package types
+
+import "testing"
+
+func TestRegistryHost(t *testing.T) {
+	tests := []struct {
+		image string
+		want  string
+	}{
+		{"postgres", "docker.io"},
+		{"postgres:16", "docker.io"},
+		{"library/postgres:16", "docker.io"},
+		{"myorg/app:1.0", "docker.io"},
+		{"docker.io/library/redis:7", "docker.io"},
+		{"localhost/app", "localhost"},
+		{"localhost:5000/x", "localhost:5000"},
+		{"ghcr.io/org/img:latest", "ghcr.io"},
+		{"registry.example.com:5000/team/db:1", "registry.example.com:5000"},
+	}
+	for _, tt := range tests {
+		if got := RegistryHost(tt.image); got != tt.want {
+			t.Errorf("RegistryHost(%q) = %q; expected %q", tt.image, got, tt.want)
+		}
+	}
+}
+
+func TestNormalizeRegistry(t *testing.T) {
+	tests := []struct {
+		server string
+		want   string
+	}{
+		{"", "docker.io"},
+		{"docker.io", "docker.io"},
+		{"index.docker.io", "docker.io"},
+		{"registry-1.docker.io", "docker.io"},
+		{"https://index.docker.io/v1/", "docker.io"},
+		{"localhost:5000", "localhost:5000"},
+		{"http://localhost:5000", "localhost:5000"},
+		{"https://ghcr.io/", "ghcr.io"},
+		{"registry.example.com:5000", "registry.example.com:5000"},
+	}
+	for _, tt := range tests {
+		if got := normalizeRegistry(tt.server); got != tt.want {
+			t.Errorf("normalizeRegistry(%q) = %q; expected %q", tt.server, got, tt.want)
+		}
+	}
+}
+
+func TestFindRegistryAuth(t *testing.T) {
+	auths := []RegistryAuth{
+		{Server: "https://index.docker.io/v1/", Username: "hub"},
+		{Server: "localhost:5000", Username: "local"},
+		{Server: "registry.example.com:5000", Username: "corp"},
+	}
+
+	tests := []struct {
+		image string
+		want  string // expected username, or "" for no match
+	}{
+		{"postgres:16", "hub"},
+		{"myorg/app", "hub"},
+		{"localhost:5000/x", "local"},
+		{"localhost/x", ""},
+		{"registry.example.com:5000/db:1", "corp"},
+		{"registry.example.com/db:1", ""},
+		{"ghcr.io/org/img", ""},
+	}
+	for _, tt := range tests {
+		got := FindRegistryAuth(auths, tt.image)
+		switch {
+		case tt.want == "" && got != nil:
+			t.Errorf("FindRegistryAuth(%q) = %q; expected no match", tt.image, got.Username)
+		case tt.want != "" && (got == nil || got.Username != tt.want):
+			t.Errorf("FindRegistryAuth(%q) = %v; expected %q", tt.image, got, tt.want)
+		}
+	}
+
+	if got := FindRegistryAuth(nil, "postgres"); got != nil {
+		t.Errorf("expected no match without credentials, got %v", got)
+	}
+}
@@ -2,7 +2,15 @@
 // This package exists to avoid import cycles between runtime and its sub-packages.
 package types
 
-import "context"
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+)
 
 // Client defines the container runtime operations interface.
 // Implementations: docker.Client, containerd.Client, cli.Client
@@ -10,31 +18,56 @@ type Client interface {
 	// Lifecycle
 	Close() error
 	Ping(ctx context.Context) error
+	// Version returns the version of the runtime daemon
+	Version(ctx context.Context) (string, error)
 
 	// Image operations
 	PullImage(ctx context.Context, imageName string) error
+	// ImageDigest returns the registry digest ("sha256:...") of a local image,
+	// or "" if it has none (e.g. it was built locally)
+	ImageDigest(ctx context.Context, imageName string) (string, error)
 
 	// Container operations
 	CreateContainer(ctx context.Context, cfg *ContainerConfig) (string, error)
 	StartContainer(ctx context.Context, containerID string) error
-	StopContainer(ctx context.Context, containerID string) error
+	// StopContainer sends SIGTERM and waits up to timeout before killing the container.
+	// A zero or negative timeout sends SIGKILL immediately.
+	StopContainer(ctx context.Context, containerID string, timeout time.Duration) error
 	RemoveContainer(ctx context.Context, containerID string, force bool) error
+	PauseContainer(ctx context.Context, containerID string) error
+	ResumeContainer(ctx context.Context, containerID string) error
 
 	// Container inspection
+	// GetContainerStatus returns "running", "paused", "creating", "stopped",
+	// "oom-killed" or "error". A running container with a healthcheck is
+	// "starting" until the check first passes and "unhealthy" while it fails.
 	GetContainerStatus(ctx context.Context, containerID string) (string, error)
 	GetContainerStats(ctx context.Context, containerID string) (*ContainerStats, error)
-	GetContainerLogs(ctx context.Context, containerID string, tail int) (string, error)
-	ListContainers(ctx context.Context) ([]string, error)
+	// GetContainerLogs returns the last tail lines of a container's output,
+	// limited to lines written after since unless it is zero. With timestamps
+	// each line is prefixed with its RFC3339 time.
+	GetContainerLogs(ctx context.Context, containerID string, tail int, since time.Time, timestamps bool) (string, error)
+	ListContainers(ctx context.Context) ([]ContainerInfo, error)
 
 	// Network operations
 	ListNetworks(ctx context.Context) ([]NetworkInfo, error)
 	CreateNetwork(ctx context.Context, name string) (*NetworkInfo, error)
 	DeleteNetwork(ctx context.Context, networkID string) error
+	// ConnectNetwork and DisconnectNetwork attach or detach a container.
+	// An empty network name means the runtime's default network.
+	ConnectNetwork(ctx context.Context, containerID, network string) error
+	DisconnectNetwork(ctx context.Context, containerID, network string) error
 
 	// Container interaction
 	ExecInContainer(ctx context.Context, containerID string, cmd []string) (string, error)
 	Exec(ctx context.Context, containerID string, cmd []string, env []string) (string, error)
-	ExecWithStdin(ctx context.Context, containerID string, cmd []string, stdin []byte, env []string) (string, error)
+	ExecWithStdin(ctx context.Context, containerID string, cmd []string, stdin io.Reader, env []string) (string, error)
+	// ExecStream runs cmd and streams its stdout to w as it is produced.
+	// A non-zero exit status is returned as an error including stderr.
+	ExecStream(ctx context.Context, containerID string, cmd []string, env []string, w io.Writer) error
+	// ExecInteractive runs cmd with a TTY, streaming stdin/stdout until the process exits or ctx is done.
+	// Terminal size changes received on resize are applied to the TTY where supported.
+	ExecInteractive(ctx context.Context, containerID string, cmd []string, stdin io.Reader, stdout io.Writer, resize <-chan TerminalSize) error
 
 	// Resource management
 	UpdateContainerResources(ctx context.Context, containerID string, memoryLimit int64, cpuLimit float64) error
@@ -43,6 +76,27 @@ type Client interface {
 	DeleteVolume(ctx context.Context, name string) error
 }
 
+// TLSConfig holds the certificate files for a remote runtime daemon served
+// over TLS. CA verifies the daemon, Cert and Key identify DBnest to it. An
+// empty TLSConfig means plain connections.
+type TLSConfig struct {
+	CA   string
+	Cert string
+	Key  string
+}
+
+// Enabled reports whether any TLS files are set
+func (t TLSConfig) Enabled() bool {
+	return t.CA != "" || t.Cert != "" || t.Key != ""
+}
+
+// DefaultStopTimeout is the grace period given to a container to shut down
+const DefaultStopTimeout = 10 * time.Second
+
+// ErrRestartRequired is returned by UpdateContainerResources when the new limits
+// were saved but cannot be applied to the running container until it restarts
+var ErrRestartRequired = errors.New("container restart required to apply resource limits")
+
 // NetworkInfo holds information about a container network
 type NetworkInfo struct {
 	ID     string `json:"id"`
@@ -50,19 +104,141 @@ type NetworkInfo struct {
 	Driver string `json:"driver"`
 }
 
+// ContainerInfo describes a DBnest-managed container found on the runtime
+type ContainerInfo struct {
+	ID     string            `json:"id"`
+	Name   string            `json:"name"`
+	State  string            `json:"state"`
+	Labels map[string]string `json:"labels"`
+}
+
 // ContainerConfig holds configuration for creating a container
 type ContainerConfig struct {
-	Name         string
-	Image        string
-	Cmd          []string          // command/args to run (optional, overrides image default)
-	Env          []string
-	PortBindings map[string]string // containerPort/proto -> hostPort
-	Volumes      map[string]string // hostPath -> containerPath
-	MemoryLimit  int64             // bytes
-	CPULimit     float64           // cores
-	Labels       map[string]string
-	Network      string // network name (optional)
-	ExposePort   bool   // whether to bind port to host
+	Name         string            `json:"name"`
+	Image        string            `json:"image"`
+	Cmd          []string          `json:"cmd,omitempty"` // command/args to run (optional, overrides image default)
+	Env          []string          `json:"env"`
+	PortBindings map[string]string `json:"portBindings"` // containerPort/proto -> hostPort
+	Volumes      map[string]string `json:"volumes"`      // hostPath -> containerPath
+	MemoryLimit  int64             `json:"memoryLimit"`  // bytes
+	CPULimit     float64           `json:"cpuLimit"`     // cores
+	Labels       map[string]string `json:"labels"`
+	Network      string            `json:"network,omitempty"` // network name (optional)
+	ExposePort   bool              `json:"exposePort"`        // whether to bind port to host
+	// BindAddress is the host IP (v4 or v6) ports are published on. Empty means
+	// all IPv4 interfaces.
+	BindAddress string `json:"bindAddress,omitempty"`
+	// RestartPolicy is "no", "always", "unless-stopped" or "on-failure[:max-retries]".
+	// Empty means DefaultRestartPolicy.
+	RestartPolicy string `json:"restartPolicy,omitempty"`
+	// RunAsUser and RunAsGroup are the numeric IDs the container's processes
+	// run as. Zero RunAsUser keeps the image's user; named volumes are chowned
+	// to the IDs before the container is created so the database can write its
+	// data. Bind-mounted host directories are left as they are.
+	RunAsUser  int `json:"runAsUser,omitempty"`
+	RunAsGroup int `json:"runAsGroup,omitempty"`
+	// UsernsMode is the user namespace mode, e.g. "host" to opt out of the
+	// daemon's remapping or podman's "auto". Empty means the runtime default.
+	UsernsMode string `json:"usernsMode,omitempty"`
+	// ReadOnlyRootfs mounts the image's root filesystem read-only. Volumes
+	// and Tmpfs paths stay writable.
+	ReadOnlyRootfs bool `json:"readOnlyRootfs,omitempty"`
+	// Tmpfs lists container paths mounted as empty in-memory filesystems
+	Tmpfs []string `json:"tmpfs,omitempty"`
+	// Security hardens the container's processes
+	Security SecurityOptions `json:"security"`
+	// Healthcheck lets the runtime track whether the database is serving.
+	// Nil leaves the image's healthcheck, if any. containerd has no
+	// healthchecks and ignores it.
+	Healthcheck *Healthcheck `json:"healthcheck,omitempty"`
+}
+
+// Healthcheck is a command the runtime runs inside a container to tell
+// whether it's healthy. Zero durations and retries use the runtime defaults.
+type Healthcheck struct {
+	// Cmd is run without a shell and exits 0 when the container is healthy
+	Cmd      []string      `json:"cmd"`
+	Interval time.Duration `json:"interval,omitempty"`
+	Timeout  time.Duration `json:"timeout,omitempty"`
+	// StartPeriod gives the server time to start; failures during it don't
+	// count towards Retries
+	StartPeriod time.Duration `json:"startPeriod,omitempty"`
+	// Retries is how many failures in a row mark the container unhealthy
+	Retries int `json:"retries,omitempty"`
+}
+
+// SeccompUnconfined disables seccomp filtering when used as a seccomp profile
+const SeccompUnconfined = "unconfined"
+
+// SecurityOptions restricts what a container's processes may do
+type SecurityOptions struct {
+	// NoNewPrivileges stops processes gaining privileges through setuid binaries
+	NoNewPrivileges bool `json:"noNewPrivileges,omitempty"`
+	// SeccompProfile is the host path of a JSON seccomp profile, or
+	// SeccompUnconfined. Empty means the runtime's default profile.
+	SeccompProfile string `json:"seccompProfile,omitempty"`
+	// CapDrop lists Linux capabilities removed from the runtime's default
+	// set, without the "CAP_" prefix, e.g. "NET_RAW"
+	CapDrop []string `json:"capDrop,omitempty"`
+}
+
+// DefaultCapDrop lists default runtime capabilities database servers don't
+// use. CHOWN, DAC_OVERRIDE, FOWNER, SETUID and SETGID are kept because image
+// entrypoints fix their data directory's ownership and then drop to the
+// database user.
+var DefaultCapDrop = []string{"AUDIT_WRITE", "MKNOD", "NET_BIND_SERVICE", "NET_RAW", "SETFCAP", "SETPCAP", "SYS_CHROOT"}
+
+// IsNamedVolume reports whether a Volumes source names a runtime-managed
+// volume rather than a host path to bind-mount
+func IsNamedVolume(source string) bool {
+	return !strings.HasPrefix(source, "/") && !strings.HasPrefix(source, ".")
+}
+
+// User returns the "uid[:gid]" the container runs as, or "" for the image default
+func (c *ContainerConfig) User() string {
+	if c.RunAsUser == 0 {
+		return ""
+	}
+	if c.RunAsGroup == 0 {
+		return strconv.Itoa(c.RunAsUser)
+	}
+	return fmt.Sprintf("%d:%d", c.RunAsUser, c.RunAsGroup)
+}
+
+// DefaultRestartPolicy is used when a container config has no restart policy
+const DefaultRestartPolicy = "unless-stopped"
+
+// ParseRestartPolicy splits a restart policy into its mode and maximum retry
+// count (only meaningful for on-failure; 0 means unlimited)
+func ParseRestartPolicy(policy string) (mode string, maxRetries int, err error) {
+	if policy == "" {
+		return DefaultRestartPolicy, 0, nil
+	}
+	mode, retries, hasRetries := strings.Cut(policy, ":")
+	switch mode {
+	case "no", "always", "unless-stopped":
+		if hasRetries {
+			return "", 0, fmt.Errorf("restart policy %q does not take a retry count", mode)
+		}
+		return mode, 0, nil
+	case "on-failure":
+		if !hasRetries {
+			return mode, 0, nil
+		}
+		n, err := strconv.Atoi(retries)
+		if err != nil || n < 0 {
+			return "", 0, fmt.Errorf("invalid retry count in restart policy: %q", policy)
+		}
+		return mode, n, nil
+	default:
+		return "", 0, fmt.Errorf("invalid restart policy: %q (use no, always, unless-stopped or on-failure[:N])", policy)
+	}
+}
+
+// TerminalSize is the size of an interactive terminal
+type TerminalSize struct {
+	Cols uint `json:"cols"`
+	Rows uint `json:"rows"`
 }
 
 // ContainerStats holds container resource statistics
@@ -0,0 +1,34 @@
+package scheduler
+
+import "time"
+
+// Options holds tunable scheduler settings
+type Options struct {
+	// BackupAttempts is how many times a scheduled backup is tried before it
+	// is recorded as failed; BackupBackoff is the delay before the first
+	// retry, doubling after each
+	BackupAttempts int
+	BackupBackoff  time.Duration
+}
+
+// DefaultOptions returns the default scheduler settings
+func DefaultOptions() Options {
+	return Options{
+		BackupAttempts: 3,
+		BackupBackoff:  time.Minute,
+	}
+}
+
+// SetOptions replaces the scheduler settings. Safe to call while running.
+func (s *Scheduler) SetOptions(opts Options) {
+	s.optsMu.Lock()
+	defer s.optsMu.Unlock()
+	s.opts = opts
+}
+
+// options returns a snapshot of the current scheduler settings
+func (s *Scheduler) options() Options {
+	s.optsMu.RLock()
+	defer s.optsMu.RUnlock()
+	return s.opts
+}
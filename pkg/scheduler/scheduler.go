@@ -2,7 +2,6 @@ package scheduler
 
 import (
 	"context"
-	"sort"
 	"sync"
 	"sync/atomic"
 	"time"
@@ -10,6 +9,7 @@ import (
 	"github.com/robfig/cron/v3"
 	"github.com/rs/zerolog/log"
 	"github.com/sirrobot01/dbnest/pkg/database"
+	"github.com/sirrobot01/dbnest/pkg/notify"
 	"github.com/sirrobot01/dbnest/pkg/storage"
 )
 
@@ -22,8 +22,13 @@ type Scheduler struct {
 	jobIDs   map[string]cron.EntryID // databaseID -> cronEntryID
 	stopChan chan struct{}
 	syncing  atomic.Bool // Guards against overlapping status sync runs
+	optsMu   sync.RWMutex
+	opts     Options
 }
 
+// maxBackupBackoff caps the delay between scheduled backup attempts
+const maxBackupBackoff = 30 * time.Minute
+
 // New creates a new scheduler
 func New(store storage.Storage, manager *database.Manager) *Scheduler {
 	return &Scheduler{
@@ -32,6 +37,7 @@ func New(store storage.Storage, manager *database.Manager) *Scheduler {
 		cron:     cron.New(cron.WithSeconds()),
 		jobIDs:   make(map[string]cron.EntryID),
 		stopChan: make(chan struct{}),
+		opts:     DefaultOptions(),
 	}
 }
 
@@ -49,14 +55,31 @@ func (s *Scheduler) Start() error {
 		return err
 	}
 
+	// Add container runtime ping, which notices a restarted daemon (every 30 seconds)
+	if _, err := s.cron.AddFunc("@every 30s", s.pingRuntime); err != nil {
+		return err
+	}
+
+	// Add expired session cleanup job (hourly)
+	if _, err := s.cron.AddFunc("@every 1h", s.purgeExpiredSessions); err != nil {
+		return err
+	}
+
+	// Add metrics history retention job (hourly)
+	if _, err := s.cron.AddFunc("@every 1h", s.purgeOldMetrics); err != nil {
+		return err
+	}
+
 	// Start cron
 	s.cron.Start()
 
 	// Run backup schedule sync loop (every 5 minutes)
 	go s.syncLoop()
 
-	// Do initial status sync
+	// Do initial status sync and cleanup
 	go s.syncContainerStatus()
+	go s.purgeExpiredSessions()
+	go s.purgeOldMetrics()
 
 	return nil
 }
@@ -97,10 +120,49 @@ func (s *Scheduler) syncContainerStatus() {
 
 	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
 	defer cancel()
+	// Stop probing containers when the scheduler stops
+	go func() {
+		select {
+		case <-s.stopChan:
+			cancel()
+		case <-ctx.Done():
+		}
+	}()
 
 	s.manager.SyncAllStatuses(ctx)
 }
 
+// pingRuntime pings the container runtime so a lost connection is noticed,
+// and reconnected, even when nothing else is using it
+func (s *Scheduler) pingRuntime() {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	if err := s.manager.PingRuntime(ctx); err != nil {
+		log.Debug().Err(err).Msg("Container runtime ping failed")
+	}
+}
+
+// purgeExpiredSessions removes expired sessions so the sessions bucket stays bounded
+func (s *Scheduler) purgeExpiredSessions() {
+	count, err := s.store.DeleteExpiredSessions()
+	if err != nil {
+		log.Error().Err(err).Msg("Failed to purge expired sessions")
+		return
+	}
+	log.Debug().Int("count", count).Msg("Purged expired sessions")
+}
+
+// purgeOldMetrics trims metrics history beyond the retention period
+func (s *Scheduler) purgeOldMetrics() {
+	count, err := s.manager.PurgeMetrics()
+	if err != nil {
+		log.Error().Err(err).Msg("Failed to purge old metrics")
+		return
+	}
+	log.Debug().Int("count", count).Msg("Purged old metrics points")
+}
+
 // syncSchedules syncs the cron jobs with database backup settings
 func (s *Scheduler) syncSchedules() error {
 	s.mu.Lock()
@@ -162,9 +224,19 @@ func (s *Scheduler) syncSchedules() error {
 	return nil
 }
 
-// runBackup executes a backup for a database and applies retention policy
+// runBackup executes a backup for a database, retrying failed attempts, and
+// applies retention policy. The outcome is recorded on the database.
 func (s *Scheduler) runBackup(databaseID string) {
-	ctx := context.Background()
+	// Abandon waits and retries when the scheduler stops
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go func() {
+		select {
+		case <-s.stopChan:
+			cancel()
+		case <-ctx.Done():
+		}
+	}()
 	log.Info().Str("db", databaseID).Msg("Running scheduled backup")
 
 	// Get database to check if still enabled
@@ -179,57 +251,138 @@ func (s *Scheduler) runBackup(databaseID string) {
 		return
 	}
 
-	if db.Status != "running" {
-		log.Debug().Str("db", databaseID).Str("status", db.Status).Msg("Database not running, skipping backup")
+	// A stopped or paused database is skipped rather than failed, so it doesn't
+	// record a failure and notify on every run until it's started again
+	if !database.ContainerUp(db.Status) {
+		log.Info().Str("db", databaseID).Str("status", db.Status).Msg("Database not running, skipping backup")
 		return
 	}
 
-	// Create backup
-	backup, err := s.manager.CreateBackup(ctx, databaseID)
+	// The marker is read before the backup, so writes made while it runs
+	// show up as a change next time
+	var marker string
+	if db.BackupSkipUnchanged {
+		marker, err = s.manager.ChangeMarker(ctx, db)
+		switch {
+		case err != nil:
+			log.Warn().Err(err).Str("db", databaseID).Msg("Could not read change marker, backing up anyway")
+		case marker == db.LastChangeMarker:
+			log.Info().Str("db", databaseID).Str("marker", marker).Msg("Database unchanged since last backup, skipping")
+			return
+		}
+	}
+
+	backup, attempts, err := s.backupWithRetry(ctx, databaseID)
 	if err != nil {
-		log.Error().Err(err).Str("db", databaseID).Msg("Failed to create scheduled backup")
+		log.Error().Err(err).Str("db", databaseID).Int("attempts", attempts).Msg("Scheduled backup failed")
+		s.recordBackupResult(databaseID, storage.BackupResult{Error: err.Error(), Attempts: attempts}, "")
+		s.notifyBackup(db, notify.EventBackupFailed, "", err.Error())
 		return
 	}
 
-	log.Info().Str("db", databaseID).Str("backup", backup.ID).Msg("Scheduled backup created")
+	log.Info().Str("db", databaseID).Str("backup", backup.ID).Msg("Scheduled backup completed")
+	s.recordBackupResult(databaseID, storage.BackupResult{Success: true, BackupID: backup.ID, Attempts: attempts}, marker)
+	s.notifyBackup(db, notify.EventBackupSucceeded, backup.ID, "")
+}
 
-	// Update last backup time
-	now := time.Now()
-	db.LastBackupAt = &now
-	if err := s.store.UpdateDatabase(db); err != nil {
-		log.Error().Err(err).Str("db", databaseID).Msg("Failed to update last backup time")
+// backupWithRetry creates a backup and waits for it to finish, retrying with
+// backoff until it succeeds or the configured attempts run out. It returns
+// the completed backup and how many attempts were made.
+func (s *Scheduler) backupWithRetry(ctx context.Context, databaseID string) (*storage.Backup, int, error) {
+	opts := s.options()
+	attempts := max(opts.BackupAttempts, 1)
+	backoff := opts.BackupBackoff
+
+	for attempt := 1; ; attempt++ {
+		var finished *storage.Backup
+		backup, err := s.manager.CreateBackup(ctx, databaseID, nil)
+		if err == nil {
+			finished, err = s.manager.WaitForBackup(ctx, backup.ID)
+		}
+		if err == nil {
+			s.recordAttempts(finished, attempt)
+			return finished, attempt, nil
+		}
+		if attempt >= attempts || ctx.Err() != nil {
+			s.recordAttempts(finished, attempt)
+			return nil, attempt, err
+		}
+
+		// A run keeps one backup record, its last attempt's, rather than a
+		// failed record for every retry
+		if backup != nil {
+			if err := s.manager.DeleteBackup(backup.ID); err != nil {
+				log.Warn().Err(err).Str("db", databaseID).Str("backup", backup.ID).Msg("Failed to remove failed backup attempt")
+			}
+		}
+
+		log.Warn().Err(err).Str("db", databaseID).
+			Int("attempt", attempt).Dur("retry_in", backoff).Msg("Scheduled backup failed, retrying")
+
+		select {
+		case <-ctx.Done():
+			return nil, attempt, err
+		case <-time.After(backoff):
+		}
+		backoff = min(backoff*2, maxBackupBackoff)
 	}
+}
 
-	// Apply retention policy
-	go s.applyRetention(databaseID)
+// recordAttempts stores how many attempts a scheduled run took on its
+// finished backup record. backup is nil when the last attempt never finished.
+func (s *Scheduler) recordAttempts(backup *storage.Backup, attempts int) {
+	if backup == nil || (backup.Status != "completed" && backup.Status != "failed") {
+		return
+	}
+	backup.Attempts = attempts
+	if err := s.store.UpdateBackup(backup); err != nil {
+		log.Error().Err(err).Str("backup", backup.ID).Msg("Failed to record backup attempts")
+	}
 }
 
-// applyRetention removes old backups beyond the retention count
-func (s *Scheduler) applyRetention(databaseID string) {
+// recordBackupResult stores the outcome of a scheduled backup run on the
+// database, re-reading it so changes made during the run are kept. marker is
+// the change marker read before a successful backup, if any.
+func (s *Scheduler) recordBackupResult(databaseID string, result storage.BackupResult, marker string) {
 	db, err := s.store.GetDatabase(databaseID)
-	if err != nil || db.BackupRetentionCount <= 0 {
+	if err != nil {
+		log.Error().Err(err).Str("db", databaseID).Msg("Failed to record backup result")
 		return
 	}
 
-	backups := s.store.ListBackups(databaseID)
-	if len(backups) <= db.BackupRetentionCount {
-		return
+	result.At = time.Now()
+	db.LastBackup = &result
+	if result.Success {
+		db.LastBackupAt = &result.At
+		db.LastChangeMarker = marker
+	}
+	if err := s.store.UpdateDatabase(db); err != nil {
+		log.Error().Err(err).Str("db", databaseID).Msg("Failed to record backup result")
 	}
+}
 
-	// Sort by creation time (newest first)
-	sort.Slice(backups, func(i, j int) bool {
-		return backups[i].CreatedAt.After(backups[j].CreatedAt)
+// notifyBackup reports the outcome of a scheduled backup
+func (s *Scheduler) notifyBackup(db *storage.DatabaseInstance, eventType, backupID, message string) {
+	s.manager.Notifier().Notify(notify.Event{
+		Type:         eventType,
+		DatabaseID:   db.ID,
+		DatabaseName: db.Name,
+		BackupID:     backupID,
+		Message:      message,
 	})
+}
 
-	// Delete old backups beyond retention count
-	for i := db.BackupRetentionCount; i < len(backups); i++ {
-		backup := backups[i]
-		if err := s.store.DeleteBackup(backup.ID); err != nil {
-			log.Error().Err(err).Str("backup", backup.ID).Msg("Failed to delete old backup")
-		} else {
-			log.Debug().Str("backup", backup.ID).Str("db", databaseID).Msg("Deleted old backup (retention policy)")
-		}
+// Resync drops every backup job and rebuilds them from the stored database
+// settings, picking up schedules that changed outside the API
+func (s *Scheduler) Resync() error {
+	s.mu.Lock()
+	for dbID, entryID := range s.jobIDs {
+		s.cron.Remove(entryID)
+		delete(s.jobIDs, dbID)
 	}
+	s.mu.Unlock()
+
+	return s.syncSchedules()
 }
 
 // RefreshSchedule forces a refresh of a specific database's schedule
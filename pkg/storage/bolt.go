@@ -1,6 +1,8 @@
 package storage
 
 import (
+	"bytes"
+	"encoding/binary"
 	"fmt"
 	"time"
 
@@ -14,6 +16,16 @@ var (
 	usersBucket     = []byte("users")
 	sessionsBucket  = []byte("sessions")
 	settingsBucket  = []byte("settings")
+	apiKeysBucket   = []byte("apikeys")
+	metricsBucket   = []byte("metrics") // nested bucket per database, keyed by timestamp
+	auditBucket     = []byte("audit")   // keyed by timestamp + event ID
+	// Nested buckets per database then per user, keyed by timestamp + entry ID
+	queryHistoryBucket = []byte("query_history")
+
+	// Secondary indexes, maintained alongside the records they point to
+	usernameIndexBucket = []byte("users_by_username") // username -> user ID
+	tokenIndexBucket    = []byte("sessions_by_token") // token -> session ID
+	keyHashIndexBucket  = []byte("apikeys_by_hash")   // key hash -> API key ID
 )
 
 // BoltStorage implements Storage interface using BoltDB
@@ -31,7 +43,7 @@ func NewBoltStorage(path string, dataDir string) (*BoltStorage, error) {
 
 	// Create buckets
 	err = db.Update(func(tx *bolt.Tx) error {
-	for _, bucket := range [][]byte{databasesBucket, backupsBucket, usersBucket, sessionsBucket, settingsBucket} {
+	for _, bucket := range [][]byte{databasesBucket, backupsBucket, usersBucket, sessionsBucket, settingsBucket, apiKeysBucket, metricsBucket, auditBucket, queryHistoryBucket} {
 			if _, err := tx.CreateBucketIfNotExists(bucket); err != nil {
 				return err
 			}
@@ -43,9 +55,99 @@ func NewBoltStorage(path string, dataDir string) (*BoltStorage, error) {
 		return nil, fmt.Errorf("failed to create buckets: %w", err)
 	}
 
+	if err := db.Update(rebuildIndexes); err != nil {
+		db.Close()
+		return nil, fmt.Errorf("failed to build indexes: %w", err)
+	}
+
 	return &BoltStorage{db: db, dataDir: dataDir}, nil
 }
 
+// rebuildIndexes recreates the secondary index buckets from the records, so
+// stores written before an index existed (or left inconsistent) are covered
+func rebuildIndexes(tx *bolt.Tx) error {
+	for _, idx := range []struct {
+		index, records []byte
+		key            func(v []byte) (string, error)
+	}{
+		{usernameIndexBucket, usersBucket, func(v []byte) (string, error) {
+			var u User
+			err := msgpack.Unmarshal(v, &u)
+			return u.Username, err
+		}},
+		{tokenIndexBucket, sessionsBucket, func(v []byte) (string, error) {
+			var s Session
+			err := msgpack.Unmarshal(v, &s)
+			return s.Token, err
+		}},
+		{keyHashIndexBucket, apiKeysBucket, func(v []byte) (string, error) {
+			var k APIKey
+			err := msgpack.Unmarshal(v, &k)
+			return k.KeyHash, err
+		}},
+	} {
+		if err := tx.DeleteBucket(idx.index); err != nil && err != bolt.ErrBucketNotFound {
+			return err
+		}
+		index, err := tx.CreateBucket(idx.index)
+		if err != nil {
+			return err
+		}
+		err = tx.Bucket(idx.records).ForEach(func(k, v []byte) error {
+			key, err := idx.key(v)
+			if err != nil || key == "" {
+				return nil // skip invalid entries
+			}
+			return index.Put([]byte(key), k)
+		})
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// reindex moves an index entry from oldKey to newKey for the record id.
+// Empty keys are not indexed.
+func reindex(index *bolt.Bucket, oldKey, newKey, id string) error {
+	if oldKey != "" && oldKey != newKey && string(index.Get([]byte(oldKey))) == id {
+		if err := index.Delete([]byte(oldKey)); err != nil {
+			return err
+		}
+	}
+	if newKey == "" {
+		return nil
+	}
+	return index.Put([]byte(newKey), []byte(id))
+}
+
+// storedUsername returns the username currently stored for a user ID, if any
+func storedUsername(b *bolt.Bucket, id string) string {
+	var u User
+	if data := b.Get([]byte(id)); data == nil || msgpack.Unmarshal(data, &u) != nil {
+		return ""
+	}
+	return u.Username
+}
+
+// storedToken returns the token currently stored for a session ID, if any
+func storedToken(b *bolt.Bucket, id string) string {
+	var s Session
+	if data := b.Get([]byte(id)); data == nil || msgpack.Unmarshal(data, &s) != nil {
+		return ""
+	}
+	return s.Token
+}
+
+// storedKeyHash returns the key hash currently stored for an API key ID, if any
+func storedKeyHash(b *bolt.Bucket, id string) string {
+	var k APIKey
+	if data := b.Get([]byte(id)); data == nil || msgpack.Unmarshal(data, &k) != nil {
+		return ""
+	}
+	return k.KeyHash
+}
+
 // Close closes the database
 func (s *BoltStorage) Close() error {
 	return s.db.Close()
@@ -250,6 +352,9 @@ func (s *BoltStorage) CreateUser(user *User) error {
 		if err != nil {
 			return err
 		}
+		if err := reindex(tx.Bucket(usernameIndexBucket), storedUsername(b, user.ID), user.Username, user.ID); err != nil {
+			return err
+		}
 		return b.Put([]byte(user.ID), data)
 	})
 }
@@ -273,27 +378,29 @@ func (s *BoltStorage) GetUser(id string) (*User, error) {
 
 // GetUserByUsername retrieves a user by username
 func (s *BoltStorage) GetUserByUsername(username string) (*User, error) {
-	var user *User
+	var user User
 	err := s.db.View(func(tx *bolt.Tx) error {
-		b := tx.Bucket(usersBucket)
-		return b.ForEach(func(k, v []byte) error {
-			var u User
-			if err := msgpack.Unmarshal(v, &u); err != nil {
-				return err
-			}
-			if u.Username == username {
-				user = &u
-			}
-			return nil
-		})
+		notFound := fmt.Errorf("user not found: %s", username)
+		id := tx.Bucket(usernameIndexBucket).Get([]byte(username))
+		if id == nil {
+			return notFound
+		}
+		data := tx.Bucket(usersBucket).Get(id)
+		if data == nil {
+			return notFound
+		}
+		if err := msgpack.Unmarshal(data, &user); err != nil {
+			return err
+		}
+		if user.Username != username {
+			return notFound
+		}
+		return nil
 	})
 	if err != nil {
 		return nil, err
 	}
-	if user == nil {
-		return nil, fmt.Errorf("user not found: %s", username)
-	}
-	return user, nil
+	return &user, nil
 }
 
 // ListUsers returns all users
@@ -324,6 +431,9 @@ func (s *BoltStorage) UpdateUser(user *User) error {
 		if err != nil {
 			return err
 		}
+		if err := reindex(tx.Bucket(usernameIndexBucket), storedUsername(b, user.ID), user.Username, user.ID); err != nil {
+			return err
+		}
 		return b.Put([]byte(user.ID), data)
 	})
 }
@@ -335,6 +445,9 @@ func (s *BoltStorage) DeleteUser(id string) error {
 		if b.Get([]byte(id)) == nil {
 			return fmt.Errorf("user not found: %s", id)
 		}
+		if err := reindex(tx.Bucket(usernameIndexBucket), storedUsername(b, id), "", id); err != nil {
+			return err
+		}
 		return b.Delete([]byte(id))
 	})
 }
@@ -360,6 +473,9 @@ func (s *BoltStorage) CreateSession(session *Session) error {
 		if err != nil {
 			return err
 		}
+		if err := reindex(tx.Bucket(tokenIndexBucket), storedToken(b, session.ID), session.Token, session.ID); err != nil {
+			return err
+		}
 		return b.Put([]byte(session.ID), data)
 	})
 }
@@ -383,61 +499,410 @@ func (s *BoltStorage) GetSession(id string) (*Session, error) {
 
 // GetSessionByToken retrieves a session by token
 func (s *BoltStorage) GetSessionByToken(token string) (*Session, error) {
-	var session *Session
+	var session Session
 	err := s.db.View(func(tx *bolt.Tx) error {
-		b := tx.Bucket(sessionsBucket)
-		return b.ForEach(func(k, v []byte) error {
-			var s Session
-			if err := msgpack.Unmarshal(v, &s); err != nil {
-				return err
-			}
-			if s.Token == token {
-				session = &s
-			}
-			return nil
-		})
+		notFound := fmt.Errorf("session not found")
+		if token == "" {
+			return notFound
+		}
+		id := tx.Bucket(tokenIndexBucket).Get([]byte(token))
+		if id == nil {
+			return notFound
+		}
+		data := tx.Bucket(sessionsBucket).Get(id)
+		if data == nil {
+			return notFound
+		}
+		if err := msgpack.Unmarshal(data, &session); err != nil {
+			return err
+		}
+		if session.Token != token {
+			return notFound
+		}
+		return nil
 	})
 	if err != nil {
 		return nil, err
 	}
-	if session == nil {
-		return nil, fmt.Errorf("session not found")
-	}
-	return session, nil
+	return &session, nil
 }
 
 // DeleteSession removes a session
 func (s *BoltStorage) DeleteSession(id string) error {
 	return s.db.Update(func(tx *bolt.Tx) error {
 		b := tx.Bucket(sessionsBucket)
+		if err := reindex(tx.Bucket(tokenIndexBucket), storedToken(b, id), "", id); err != nil {
+			return err
+		}
 		return b.Delete([]byte(id))
 	})
 }
 
-// DeleteExpiredSessions removes all expired sessions
-func (s *BoltStorage) DeleteExpiredSessions() error {
+// DeleteExpiredSessions removes all expired sessions and returns how many were removed
+func (s *BoltStorage) DeleteExpiredSessions() (int, error) {
 	now := time.Now()
-	return s.db.Update(func(tx *bolt.Tx) error {
+	var deleted int
+	err := s.db.Update(func(tx *bolt.Tx) error {
 		b := tx.Bucket(sessionsBucket)
-		var toDelete [][]byte
+		index := tx.Bucket(tokenIndexBucket)
+		var toDelete []Session
 		err := b.ForEach(func(k, v []byte) error {
 			var session Session
 			if err := msgpack.Unmarshal(v, &session); err != nil {
 				return nil // skip invalid entries
 			}
 			if session.ExpiresAt.Before(now) {
-				toDelete = append(toDelete, k)
+				toDelete = append(toDelete, session)
+			}
+			return nil
+		})
+		if err != nil {
+			return err
+		}
+		for _, session := range toDelete {
+			if err := reindex(index, session.Token, "", session.ID); err != nil {
+				return err
+			}
+			if err := b.Delete([]byte(session.ID)); err != nil {
+				return err
+			}
+			deleted++
+		}
+		return nil
+	})
+	return deleted, err
+}
+
+// API key operations
+
+// CreateAPIKey stores a new API key
+func (s *BoltStorage) CreateAPIKey(key *APIKey) error {
+	return s.db.Update(func(tx *bolt.Tx) error {
+		b := tx.Bucket(apiKeysBucket)
+		data, err := msgpack.Marshal(key)
+		if err != nil {
+			return err
+		}
+		if err := reindex(tx.Bucket(keyHashIndexBucket), storedKeyHash(b, key.ID), key.KeyHash, key.ID); err != nil {
+			return err
+		}
+		return b.Put([]byte(key.ID), data)
+	})
+}
+
+// GetAPIKey retrieves an API key by ID
+func (s *BoltStorage) GetAPIKey(id string) (*APIKey, error) {
+	var key APIKey
+	err := s.db.View(func(tx *bolt.Tx) error {
+		b := tx.Bucket(apiKeysBucket)
+		data := b.Get([]byte(id))
+		if data == nil {
+			return fmt.Errorf("api key not found: %s", id)
+		}
+		return msgpack.Unmarshal(data, &key)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &key, nil
+}
+
+// GetAPIKeyByHash retrieves an API key by the hash of its secret
+func (s *BoltStorage) GetAPIKeyByHash(hash string) (*APIKey, error) {
+	var key APIKey
+	err := s.db.View(func(tx *bolt.Tx) error {
+		notFound := fmt.Errorf("api key not found")
+		if hash == "" {
+			return notFound
+		}
+		id := tx.Bucket(keyHashIndexBucket).Get([]byte(hash))
+		if id == nil {
+			return notFound
+		}
+		data := tx.Bucket(apiKeysBucket).Get(id)
+		if data == nil {
+			return notFound
+		}
+		if err := msgpack.Unmarshal(data, &key); err != nil {
+			return err
+		}
+		if key.KeyHash != hash {
+			return notFound
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &key, nil
+}
+
+// ListAPIKeys returns all API keys
+func (s *BoltStorage) ListAPIKeys() []*APIKey {
+	var keys []*APIKey
+	s.db.View(func(tx *bolt.Tx) error {
+		b := tx.Bucket(apiKeysBucket)
+		return b.ForEach(func(k, v []byte) error {
+			var key APIKey
+			if err := msgpack.Unmarshal(v, &key); err != nil {
+				return err
 			}
+			keys = append(keys, &key)
 			return nil
 		})
+	})
+	return keys
+}
+
+// UpdateAPIKey updates an existing API key
+func (s *BoltStorage) UpdateAPIKey(key *APIKey) error {
+	return s.db.Update(func(tx *bolt.Tx) error {
+		b := tx.Bucket(apiKeysBucket)
+		if b.Get([]byte(key.ID)) == nil {
+			return fmt.Errorf("api key not found: %s", key.ID)
+		}
+		data, err := msgpack.Marshal(key)
 		if err != nil {
 			return err
 		}
-		for _, key := range toDelete {
-			if err := b.Delete(key); err != nil {
+		if err := reindex(tx.Bucket(keyHashIndexBucket), storedKeyHash(b, key.ID), key.KeyHash, key.ID); err != nil {
+			return err
+		}
+		return b.Put([]byte(key.ID), data)
+	})
+}
+
+// DeleteAPIKey removes an API key
+func (s *BoltStorage) DeleteAPIKey(id string) error {
+	return s.db.Update(func(tx *bolt.Tx) error {
+		b := tx.Bucket(apiKeysBucket)
+		if b.Get([]byte(id)) == nil {
+			return fmt.Errorf("api key not found: %s", id)
+		}
+		if err := reindex(tx.Bucket(keyHashIndexBucket), storedKeyHash(b, id), "", id); err != nil {
+			return err
+		}
+		return b.Delete([]byte(id))
+	})
+}
+
+// Metrics history operations
+
+// metricsKey encodes a timestamp so keys sort chronologically
+func metricsKey(t time.Time) []byte {
+	key := make([]byte, 8)
+	binary.BigEndian.PutUint64(key, uint64(t.UnixNano()))
+	return key
+}
+
+// AddMetricsPoint stores a metrics point for a database
+func (s *BoltStorage) AddMetricsPoint(databaseID string, point *MetricsPoint) error {
+	return s.db.Update(func(tx *bolt.Tx) error {
+		b, err := tx.Bucket(metricsBucket).CreateBucketIfNotExists([]byte(databaseID))
+		if err != nil {
+			return err
+		}
+		data, err := msgpack.Marshal(point)
+		if err != nil {
+			return err
+		}
+		return b.Put(metricsKey(point.Timestamp), data)
+	})
+}
+
+// ListMetricsPoints returns a database's metrics points recorded at or after since, oldest first
+func (s *BoltStorage) ListMetricsPoints(databaseID string, since time.Time) []*MetricsPoint {
+	points := []*MetricsPoint{}
+	s.db.View(func(tx *bolt.Tx) error {
+		b := tx.Bucket(metricsBucket).Bucket([]byte(databaseID))
+		if b == nil {
+			return nil
+		}
+		c := b.Cursor()
+		for k, v := c.Seek(metricsKey(since)); k != nil; k, v = c.Next() {
+			var point MetricsPoint
+			if err := msgpack.Unmarshal(v, &point); err == nil {
+				points = append(points, &point)
+			}
+		}
+		return nil
+	})
+	return points
+}
+
+// DeleteMetricsPoints removes all metrics points for a database
+func (s *BoltStorage) DeleteMetricsPoints(databaseID string) error {
+	return s.db.Update(func(tx *bolt.Tx) error {
+		err := tx.Bucket(metricsBucket).DeleteBucket([]byte(databaseID))
+		if err == bolt.ErrBucketNotFound {
+			return nil
+		}
+		return err
+	})
+}
+
+// DeleteMetricsBefore removes metrics points older than cutoff across all databases
+// and returns how many were removed
+func (s *BoltStorage) DeleteMetricsBefore(cutoff time.Time) (int, error) {
+	var deleted int
+	end := metricsKey(cutoff)
+	err := s.db.Update(func(tx *bolt.Tx) error {
+		root := tx.Bucket(metricsBucket)
+		return root.ForEachBucket(func(name []byte) error {
+			c := root.Bucket(name).Cursor()
+			// Keys are chronological, so delete from the start until the cutoff
+			for k, _ := c.First(); k != nil && bytes.Compare(k, end) < 0; k, _ = c.First() {
+				if err := c.Delete(); err != nil {
+					return err
+				}
+				deleted++
+			}
+			return nil
+		})
+	})
+	return deleted, err
+}
+
+// Audit log operations
+
+// AddAuditEvent appends an event to the audit log
+func (s *BoltStorage) AddAuditEvent(event *AuditEvent) error {
+	return s.db.Update(func(tx *bolt.Tx) error {
+		data, err := msgpack.Marshal(event)
+		if err != nil {
+			return err
+		}
+		// Suffix the ID so events recorded in the same nanosecond don't collide
+		key := append(metricsKey(event.Timestamp), event.ID...)
+		return tx.Bucket(auditBucket).Put(key, data)
+	})
+}
+
+// ListAuditEvents returns audit events matching the filter, newest first
+func (s *BoltStorage) ListAuditEvents(filter AuditFilter) []*AuditEvent {
+	events := []*AuditEvent{}
+	s.db.View(func(tx *bolt.Tx) error {
+		c := tx.Bucket(auditBucket).Cursor()
+
+		// Walk backwards from the upper bound
+		var k, v []byte
+		if filter.Until.IsZero() {
+			k, v = c.Last()
+		} else {
+			k, v = c.Seek(metricsKey(filter.Until.Add(time.Nanosecond)))
+			if k == nil {
+				k, v = c.Last()
+			} else {
+				k, v = c.Prev()
+			}
+		}
+
+		var since []byte
+		if !filter.Since.IsZero() {
+			since = metricsKey(filter.Since)
+		}
+		for ; k != nil && bytes.Compare(k[:8], since) >= 0; k, v = c.Prev() {
+			var event AuditEvent
+			if err := msgpack.Unmarshal(v, &event); err != nil {
+				continue
+			}
+			if filter.User != "" && event.Username != filter.User && event.UserID != filter.User {
+				continue
+			}
+			if filter.TargetID != "" && event.TargetID != filter.TargetID {
+				continue
+			}
+			events = append(events, &event)
+			if filter.Limit > 0 && len(events) >= filter.Limit {
+				break
+			}
+		}
+		return nil
+	})
+	return events
+}
+
+// Query history operations
+
+// AddQueryHistory appends an entry to a user's query history for a database,
+// dropping the oldest entries beyond max. A max of 0 keeps every entry.
+func (s *BoltStorage) AddQueryHistory(entry *QueryHistoryEntry, max int) error {
+	return s.db.Update(func(tx *bolt.Tx) error {
+		db, err := tx.Bucket(queryHistoryBucket).CreateBucketIfNotExists([]byte(entry.DatabaseID))
+		if err != nil {
+			return err
+		}
+		b, err := db.CreateBucketIfNotExists([]byte(entry.UserID))
+		if err != nil {
+			return err
+		}
+		data, err := msgpack.Marshal(entry)
+		if err != nil {
+			return err
+		}
+		if err := b.Put(append(metricsKey(entry.Timestamp), entry.ID...), data); err != nil {
+			return err
+		}
+		if max <= 0 {
+			return nil
+		}
+
+		count := 0
+		c := b.Cursor()
+		for k, _ := c.First(); k != nil; k, _ = c.Next() {
+			count++
+		}
+		// Keys are chronological, so the oldest entries come first
+		for k, _ := c.First(); k != nil && count > max; k, _ = c.First() {
+			if err := c.Delete(); err != nil {
 				return err
 			}
+			count--
 		}
 		return nil
 	})
 }
+
+// ListQueryHistory returns a user's query history for a database, newest first
+func (s *BoltStorage) ListQueryHistory(databaseID, userID string) []*QueryHistoryEntry {
+	entries := []*QueryHistoryEntry{}
+	s.db.View(func(tx *bolt.Tx) error {
+		db := tx.Bucket(queryHistoryBucket).Bucket([]byte(databaseID))
+		if db == nil {
+			return nil
+		}
+		b := db.Bucket([]byte(userID))
+		if b == nil {
+			return nil
+		}
+		c := b.Cursor()
+		for k, v := c.Last(); k != nil; k, v = c.Prev() {
+			var entry QueryHistoryEntry
+			if err := msgpack.Unmarshal(v, &entry); err == nil {
+				entries = append(entries, &entry)
+			}
+		}
+		return nil
+	})
+	return entries
+}
+
+// DeleteQueryHistory clears a user's query history for a database, or every
+// user's when userID is empty
+func (s *BoltStorage) DeleteQueryHistory(databaseID, userID string) error {
+	return s.db.Update(func(tx *bolt.Tx) error {
+		b := tx.Bucket(queryHistoryBucket)
+		name := []byte(databaseID)
+		if userID != "" {
+			if b = b.Bucket(name); b == nil {
+				return nil
+			}
+			name = []byte(userID)
+		}
+		err := b.DeleteBucket(name)
+		if err == bolt.ErrBucketNotFound {
+			return nil
+		}
+		return err
+	})
+}
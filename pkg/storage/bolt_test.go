@@ -0,0 +1,28 @@
+package storage
+
+import (
+	"testing"
+)
+
+// openBoltTest returns a function opening the same bolt store in a
+// temporary directory each time it is called
+func openBoltTest(t *testing.T) func() Storage {
+	tmpDir := t.TempDir()
+	return func() Storage {
+		store, err := New(tmpDir+"/test.db", tmpDir)
+		if err != nil {
+			t.Fatalf("failed to open test storage: %v", err)
+		}
+		return store
+	}
+}
+
+func TestBoltRecordCRUD(t *testing.T) {
+	store := openBoltTest(t)()
+	defer store.Close()
+	testRecordCRUD(t, store)
+}
+
+func TestBoltAuthLookupIndexes(t *testing.T) {
+	testAuthLookupIndexes(t, openBoltTest(t))
+}
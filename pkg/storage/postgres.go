@@ -0,0 +1,601 @@
+package storage
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	_ "github.com/lib/pq" // PostgreSQL driver
+	"github.com/vmihailenco/msgpack/v5"
+)
+
+// migrationLockID is the advisory lock held while migrating, so replicas
+// starting together don't race each other
+const migrationLockID = 0x64626e657374 // "dbnest"
+
+// postgresMigrations are applied in order; a migration's version is its index + 1.
+// Never edit a released migration, append a new one instead.
+//
+// Records are stored as msgpack, the same encoding BoltStorage uses, alongside
+// the columns needed for lookups and filtering.
+var postgresMigrations = []string{
+	`CREATE TABLE databases (
+		id   TEXT PRIMARY KEY,
+		data BYTEA NOT NULL
+	);
+	CREATE TABLE backups (
+		id          TEXT PRIMARY KEY,
+		database_id TEXT NOT NULL,
+		data        BYTEA NOT NULL
+	);
+	CREATE INDEX backups_database_id_idx ON backups (database_id);
+	CREATE TABLE users (
+		id       TEXT PRIMARY KEY,
+		username TEXT NOT NULL,
+		data     BYTEA NOT NULL
+	);
+	CREATE INDEX users_username_idx ON users (username);
+	CREATE TABLE sessions (
+		id         TEXT PRIMARY KEY,
+		token      TEXT NOT NULL,
+		expires_at TIMESTAMPTZ NOT NULL,
+		data       BYTEA NOT NULL
+	);
+	CREATE INDEX sessions_token_idx ON sessions (token);
+	CREATE INDEX sessions_expires_at_idx ON sessions (expires_at);
+	CREATE TABLE settings (
+		key   TEXT PRIMARY KEY,
+		value TEXT NOT NULL
+	);
+	CREATE TABLE api_keys (
+		id       TEXT PRIMARY KEY,
+		key_hash TEXT NOT NULL,
+		data     BYTEA NOT NULL
+	);
+	CREATE INDEX api_keys_key_hash_idx ON api_keys (key_hash);
+	CREATE TABLE metrics (
+		database_id TEXT NOT NULL,
+		ts          BIGINT NOT NULL,
+		data        BYTEA NOT NULL,
+		PRIMARY KEY (database_id, ts)
+	);
+	CREATE INDEX metrics_ts_idx ON metrics (ts);
+	CREATE TABLE audit_events (
+		id        TEXT PRIMARY KEY,
+		ts        BIGINT NOT NULL,
+		user_id   TEXT NOT NULL,
+		username  TEXT NOT NULL,
+		target_id TEXT NOT NULL,
+		data      BYTEA NOT NULL
+	);
+	CREATE INDEX audit_events_ts_idx ON audit_events (ts);`,
+	`CREATE TABLE query_history (
+		id          TEXT PRIMARY KEY,
+		database_id TEXT NOT NULL,
+		user_id     TEXT NOT NULL,
+		ts          BIGINT NOT NULL,
+		data        BYTEA NOT NULL
+	);
+	CREATE INDEX query_history_owner_idx ON query_history (database_id, user_id, ts);`,
+}
+
+// PostgresStorage implements Storage interface using PostgreSQL, allowing
+// several DBnest instances to share state
+type PostgresStorage struct {
+	db      *sql.DB
+	dataDir string
+}
+
+// NewPostgresStorage connects to PostgreSQL and applies pending schema migrations
+func NewPostgresStorage(dsn string, dataDir string) (*PostgresStorage, error) {
+	db, err := sql.Open("postgres", dsn)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open postgres database: %w", err)
+	}
+	if err := db.Ping(); err != nil {
+		db.Close()
+		return nil, fmt.Errorf("failed to connect to postgres database: %w", err)
+	}
+
+	s := &PostgresStorage{db: db, dataDir: dataDir}
+	if err := s.migrate(); err != nil {
+		db.Close()
+		return nil, fmt.Errorf("failed to migrate postgres schema: %w", err)
+	}
+	return s, nil
+}
+
+// migrate applies migrations newer than the recorded schema version
+func (s *PostgresStorage) migrate() error {
+	tx, err := s.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.Exec(`SELECT pg_advisory_xact_lock($1)`, migrationLockID); err != nil {
+		return err
+	}
+	if _, err := tx.Exec(`CREATE TABLE IF NOT EXISTS schema_migrations (version INTEGER PRIMARY KEY)`); err != nil {
+		return err
+	}
+
+	var current int
+	if err := tx.QueryRow(`SELECT COALESCE(MAX(version), 0) FROM schema_migrations`).Scan(&current); err != nil {
+		return err
+	}
+	for i := current; i < len(postgresMigrations); i++ {
+		if _, err := tx.Exec(postgresMigrations[i]); err != nil {
+			return fmt.Errorf("migration %d: %w", i+1, err)
+		}
+		if _, err := tx.Exec(`INSERT INTO schema_migrations (version) VALUES ($1)`, i+1); err != nil {
+			return err
+		}
+	}
+	return tx.Commit()
+}
+
+// Close closes the database
+func (s *PostgresStorage) Close() error {
+	return s.db.Close()
+}
+
+// DataDir returns the data directory
+func (s *PostgresStorage) DataDir() string {
+	return s.dataDir
+}
+
+// getRecord decodes the single msgpack data column returned by query into out.
+// notFound is returned when there is no matching row.
+func (s *PostgresStorage) getRecord(out interface{}, notFound error, query string, args ...interface{}) error {
+	var data []byte
+	err := s.db.QueryRow(query, args...).Scan(&data)
+	if errors.Is(err, sql.ErrNoRows) {
+		return notFound
+	}
+	if err != nil {
+		return err
+	}
+	return msgpack.Unmarshal(data, out)
+}
+
+// listRecords decodes the msgpack data column of every row returned by query,
+// skipping rows that fail to decode
+func listRecords[T any](s *PostgresStorage, query string, args ...interface{}) []*T {
+	var records []*T
+	rows, err := s.db.Query(query, args...)
+	if err != nil {
+		return records
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var data []byte
+		if err := rows.Scan(&data); err != nil {
+			continue
+		}
+		var record T
+		if err := msgpack.Unmarshal(data, &record); err != nil {
+			continue
+		}
+		records = append(records, &record)
+	}
+	return records
+}
+
+// execAffecting runs a statement and returns notFound if it touched no rows
+func (s *PostgresStorage) execAffecting(notFound error, query string, args ...interface{}) error {
+	res, err := s.db.Exec(query, args...)
+	if err != nil {
+		return err
+	}
+	n, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if n == 0 {
+		return notFound
+	}
+	return nil
+}
+
+// Database operations
+
+// CreateDatabase stores a new database
+func (s *PostgresStorage) CreateDatabase(db *DatabaseInstance) error {
+	data, err := msgpack.Marshal(db)
+	if err != nil {
+		return err
+	}
+	_, err = s.db.Exec(`INSERT INTO databases (id, data) VALUES ($1, $2)
+		ON CONFLICT (id) DO UPDATE SET data = EXCLUDED.data`, db.ID, data)
+	return err
+}
+
+// GetDatabase retrieves a database by ID
+func (s *PostgresStorage) GetDatabase(id string) (*DatabaseInstance, error) {
+	var db DatabaseInstance
+	if err := s.getRecord(&db, fmt.Errorf("database not found: %s", id), `SELECT data FROM databases WHERE id = $1`, id); err != nil {
+		return nil, err
+	}
+	return &db, nil
+}
+
+// ListDatabases returns all databases
+func (s *PostgresStorage) ListDatabases() []*DatabaseInstance {
+	return listRecords[DatabaseInstance](s, `SELECT data FROM databases ORDER BY id`)
+}
+
+// UpdateDatabase updates an existing database
+func (s *PostgresStorage) UpdateDatabase(db *DatabaseInstance) error {
+	data, err := msgpack.Marshal(db)
+	if err != nil {
+		return err
+	}
+	return s.execAffecting(fmt.Errorf("database not found: %s", db.ID), `UPDATE databases SET data = $2 WHERE id = $1`, db.ID, data)
+}
+
+// DeleteDatabase removes a database
+func (s *PostgresStorage) DeleteDatabase(id string) error {
+	return s.execAffecting(fmt.Errorf("database not found: %s", id), `DELETE FROM databases WHERE id = $1`, id)
+}
+
+// Backup operations
+
+// CreateBackup stores a new backup
+func (s *PostgresStorage) CreateBackup(backup *Backup) error {
+	data, err := msgpack.Marshal(backup)
+	if err != nil {
+		return err
+	}
+	_, err = s.db.Exec(`INSERT INTO backups (id, database_id, data) VALUES ($1, $2, $3)
+		ON CONFLICT (id) DO UPDATE SET database_id = EXCLUDED.database_id, data = EXCLUDED.data`,
+		backup.ID, backup.DatabaseID, data)
+	return err
+}
+
+// GetBackup retrieves a backup by ID
+func (s *PostgresStorage) GetBackup(id string) (*Backup, error) {
+	var backup Backup
+	if err := s.getRecord(&backup, fmt.Errorf("backup not found: %s", id), `SELECT data FROM backups WHERE id = $1`, id); err != nil {
+		return nil, err
+	}
+	return &backup, nil
+}
+
+// GetBackupPath returns the file path for a backup
+func (s *PostgresStorage) GetBackupPath(id string) string {
+	backup, err := s.GetBackup(id)
+	if err != nil {
+		return ""
+	}
+	return backup.FilePath
+}
+
+// ListBackups returns all backups, optionally filtered by database ID
+func (s *PostgresStorage) ListBackups(databaseID string) []*Backup {
+	return listRecords[Backup](s, `SELECT data FROM backups WHERE $1 = '' OR database_id = $1 ORDER BY id`, databaseID)
+}
+
+// UpdateBackup updates an existing backup
+func (s *PostgresStorage) UpdateBackup(backup *Backup) error {
+	data, err := msgpack.Marshal(backup)
+	if err != nil {
+		return err
+	}
+	return s.execAffecting(fmt.Errorf("backup not found: %s", backup.ID),
+		`UPDATE backups SET database_id = $2, data = $3 WHERE id = $1`, backup.ID, backup.DatabaseID, data)
+}
+
+// DeleteBackup removes a backup
+func (s *PostgresStorage) DeleteBackup(id string) error {
+	return s.execAffecting(fmt.Errorf("backup not found: %s", id), `DELETE FROM backups WHERE id = $1`, id)
+}
+
+// Settings operations
+
+// GetSetting retrieves a setting value
+func (s *PostgresStorage) GetSetting(key string) (string, error) {
+	var value string
+	err := s.db.QueryRow(`SELECT value FROM settings WHERE key = $1`, key).Scan(&value)
+	if errors.Is(err, sql.ErrNoRows) {
+		return "", fmt.Errorf("setting not found: %s", key)
+	}
+	return value, err
+}
+
+// SetSetting stores a setting value
+func (s *PostgresStorage) SetSetting(key, value string) error {
+	_, err := s.db.Exec(`INSERT INTO settings (key, value) VALUES ($1, $2)
+		ON CONFLICT (key) DO UPDATE SET value = EXCLUDED.value`, key, value)
+	return err
+}
+
+// User operations
+
+// CreateUser stores a new user
+func (s *PostgresStorage) CreateUser(user *User) error {
+	data, err := msgpack.Marshal(user)
+	if err != nil {
+		return err
+	}
+	_, err = s.db.Exec(`INSERT INTO users (id, username, data) VALUES ($1, $2, $3)
+		ON CONFLICT (id) DO UPDATE SET username = EXCLUDED.username, data = EXCLUDED.data`,
+		user.ID, user.Username, data)
+	return err
+}
+
+// GetUser retrieves a user by ID
+func (s *PostgresStorage) GetUser(id string) (*User, error) {
+	var user User
+	if err := s.getRecord(&user, fmt.Errorf("user not found: %s", id), `SELECT data FROM users WHERE id = $1`, id); err != nil {
+		return nil, err
+	}
+	return &user, nil
+}
+
+// GetUserByUsername retrieves a user by username
+func (s *PostgresStorage) GetUserByUsername(username string) (*User, error) {
+	var user User
+	if err := s.getRecord(&user, fmt.Errorf("user not found: %s", username),
+		`SELECT data FROM users WHERE username = $1 ORDER BY id LIMIT 1`, username); err != nil {
+		return nil, err
+	}
+	return &user, nil
+}
+
+// ListUsers returns all users
+func (s *PostgresStorage) ListUsers() []*User {
+	return listRecords[User](s, `SELECT data FROM users ORDER BY id`)
+}
+
+// UpdateUser updates an existing user
+func (s *PostgresStorage) UpdateUser(user *User) error {
+	data, err := msgpack.Marshal(user)
+	if err != nil {
+		return err
+	}
+	return s.execAffecting(fmt.Errorf("user not found: %s", user.ID),
+		`UPDATE users SET username = $2, data = $3 WHERE id = $1`, user.ID, user.Username, data)
+}
+
+// DeleteUser removes a user
+func (s *PostgresStorage) DeleteUser(id string) error {
+	return s.execAffecting(fmt.Errorf("user not found: %s", id), `DELETE FROM users WHERE id = $1`, id)
+}
+
+// UserCount returns the number of users
+func (s *PostgresStorage) UserCount() int {
+	var count int
+	s.db.QueryRow(`SELECT COUNT(*) FROM users`).Scan(&count)
+	return count
+}
+
+// Session operations
+
+// CreateSession stores a new session
+func (s *PostgresStorage) CreateSession(session *Session) error {
+	data, err := msgpack.Marshal(session)
+	if err != nil {
+		return err
+	}
+	_, err = s.db.Exec(`INSERT INTO sessions (id, token, expires_at, data) VALUES ($1, $2, $3, $4)
+		ON CONFLICT (id) DO UPDATE SET token = EXCLUDED.token, expires_at = EXCLUDED.expires_at, data = EXCLUDED.data`,
+		session.ID, session.Token, session.ExpiresAt, data)
+	return err
+}
+
+// GetSession retrieves a session by ID
+func (s *PostgresStorage) GetSession(id string) (*Session, error) {
+	var session Session
+	if err := s.getRecord(&session, fmt.Errorf("session not found: %s", id), `SELECT data FROM sessions WHERE id = $1`, id); err != nil {
+		return nil, err
+	}
+	return &session, nil
+}
+
+// GetSessionByToken retrieves a session by token
+func (s *PostgresStorage) GetSessionByToken(token string) (*Session, error) {
+	var session Session
+	if err := s.getRecord(&session, fmt.Errorf("session not found"),
+		`SELECT data FROM sessions WHERE token = $1 ORDER BY id LIMIT 1`, token); err != nil {
+		return nil, err
+	}
+	return &session, nil
+}
+
+// DeleteSession removes a session
+func (s *PostgresStorage) DeleteSession(id string) error {
+	_, err := s.db.Exec(`DELETE FROM sessions WHERE id = $1`, id)
+	return err
+}
+
+// DeleteExpiredSessions removes all expired sessions and returns how many were removed
+func (s *PostgresStorage) DeleteExpiredSessions() (int, error) {
+	res, err := s.db.Exec(`DELETE FROM sessions WHERE expires_at < $1`, time.Now())
+	if err != nil {
+		return 0, err
+	}
+	n, err := res.RowsAffected()
+	return int(n), err
+}
+
+// API key operations
+
+// CreateAPIKey stores a new API key
+func (s *PostgresStorage) CreateAPIKey(key *APIKey) error {
+	data, err := msgpack.Marshal(key)
+	if err != nil {
+		return err
+	}
+	_, err = s.db.Exec(`INSERT INTO api_keys (id, key_hash, data) VALUES ($1, $2, $3)
+		ON CONFLICT (id) DO UPDATE SET key_hash = EXCLUDED.key_hash, data = EXCLUDED.data`,
+		key.ID, key.KeyHash, data)
+	return err
+}
+
+// GetAPIKey retrieves an API key by ID
+func (s *PostgresStorage) GetAPIKey(id string) (*APIKey, error) {
+	var key APIKey
+	if err := s.getRecord(&key, fmt.Errorf("api key not found: %s", id), `SELECT data FROM api_keys WHERE id = $1`, id); err != nil {
+		return nil, err
+	}
+	return &key, nil
+}
+
+// GetAPIKeyByHash retrieves an API key by the hash of its secret
+func (s *PostgresStorage) GetAPIKeyByHash(hash string) (*APIKey, error) {
+	var key APIKey
+	if err := s.getRecord(&key, fmt.Errorf("api key not found"),
+		`SELECT data FROM api_keys WHERE key_hash = $1 ORDER BY id LIMIT 1`, hash); err != nil {
+		return nil, err
+	}
+	return &key, nil
+}
+
+// ListAPIKeys returns all API keys
+func (s *PostgresStorage) ListAPIKeys() []*APIKey {
+	return listRecords[APIKey](s, `SELECT data FROM api_keys ORDER BY id`)
+}
+
+// UpdateAPIKey updates an existing API key
+func (s *PostgresStorage) UpdateAPIKey(key *APIKey) error {
+	data, err := msgpack.Marshal(key)
+	if err != nil {
+		return err
+	}
+	return s.execAffecting(fmt.Errorf("api key not found: %s", key.ID),
+		`UPDATE api_keys SET key_hash = $2, data = $3 WHERE id = $1`, key.ID, key.KeyHash, data)
+}
+
+// DeleteAPIKey removes an API key
+func (s *PostgresStorage) DeleteAPIKey(id string) error {
+	return s.execAffecting(fmt.Errorf("api key not found: %s", id), `DELETE FROM api_keys WHERE id = $1`, id)
+}
+
+// Metrics history operations
+
+// AddMetricsPoint stores a metrics point for a database
+func (s *PostgresStorage) AddMetricsPoint(databaseID string, point *MetricsPoint) error {
+	data, err := msgpack.Marshal(point)
+	if err != nil {
+		return err
+	}
+	_, err = s.db.Exec(`INSERT INTO metrics (database_id, ts, data) VALUES ($1, $2, $3)
+		ON CONFLICT (database_id, ts) DO UPDATE SET data = EXCLUDED.data`,
+		databaseID, point.Timestamp.UnixNano(), data)
+	return err
+}
+
+// ListMetricsPoints returns a database's metrics points recorded at or after since, oldest first
+func (s *PostgresStorage) ListMetricsPoints(databaseID string, since time.Time) []*MetricsPoint {
+	points := listRecords[MetricsPoint](s, `SELECT data FROM metrics WHERE database_id = $1 AND ts >= $2 ORDER BY ts`,
+		databaseID, since.UnixNano())
+	if points == nil {
+		points = []*MetricsPoint{}
+	}
+	return points
+}
+
+// DeleteMetricsPoints removes all metrics points for a database
+func (s *PostgresStorage) DeleteMetricsPoints(databaseID string) error {
+	_, err := s.db.Exec(`DELETE FROM metrics WHERE database_id = $1`, databaseID)
+	return err
+}
+
+// DeleteMetricsBefore removes metrics points older than cutoff across all databases
+// and returns how many were removed
+func (s *PostgresStorage) DeleteMetricsBefore(cutoff time.Time) (int, error) {
+	res, err := s.db.Exec(`DELETE FROM metrics WHERE ts < $1`, cutoff.UnixNano())
+	if err != nil {
+		return 0, err
+	}
+	n, err := res.RowsAffected()
+	return int(n), err
+}
+
+// Audit log operations
+
+// AddAuditEvent appends an event to the audit log
+func (s *PostgresStorage) AddAuditEvent(event *AuditEvent) error {
+	data, err := msgpack.Marshal(event)
+	if err != nil {
+		return err
+	}
+	_, err = s.db.Exec(`INSERT INTO audit_events (id, ts, user_id, username, target_id, data) VALUES ($1, $2, $3, $4, $5, $6)`,
+		event.ID, event.Timestamp.UnixNano(), event.UserID, event.Username, event.TargetID, data)
+	return err
+}
+
+// ListAuditEvents returns audit events matching the filter, newest first
+func (s *PostgresStorage) ListAuditEvents(filter AuditFilter) []*AuditEvent {
+	var since, until int64
+	if !filter.Since.IsZero() {
+		since = filter.Since.UnixNano()
+	}
+	if !filter.Until.IsZero() {
+		until = filter.Until.UnixNano()
+	}
+	limit := sql.NullInt64{Int64: int64(filter.Limit), Valid: filter.Limit > 0}
+
+	events := listRecords[AuditEvent](s, `SELECT data FROM audit_events
+		WHERE ts >= $1::BIGINT
+		  AND ($2::BIGINT = 0 OR ts <= $2::BIGINT)
+		  AND ($3 = '' OR username = $3 OR user_id = $3)
+		  AND ($4 = '' OR target_id = $4)
+		ORDER BY ts DESC, id DESC
+		LIMIT $5::BIGINT`,
+		since, until, filter.User, filter.TargetID, limit)
+	if events == nil {
+		events = []*AuditEvent{}
+	}
+	return events
+}
+
+// Query history operations
+
+// AddQueryHistory appends an entry to a user's query history for a database,
+// dropping the oldest entries beyond max. A max of 0 keeps every entry.
+func (s *PostgresStorage) AddQueryHistory(entry *QueryHistoryEntry, max int) error {
+	data, err := msgpack.Marshal(entry)
+	if err != nil {
+		return err
+	}
+	tx, err := s.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.Exec(`INSERT INTO query_history (id, database_id, user_id, ts, data) VALUES ($1, $2, $3, $4, $5)`,
+		entry.ID, entry.DatabaseID, entry.UserID, entry.Timestamp.UnixNano(), data); err != nil {
+		return err
+	}
+	if max > 0 {
+		if _, err := tx.Exec(`DELETE FROM query_history WHERE id IN (
+			SELECT id FROM query_history WHERE database_id = $1 AND user_id = $2
+			ORDER BY ts DESC, id DESC OFFSET $3)`,
+			entry.DatabaseID, entry.UserID, max); err != nil {
+			return err
+		}
+	}
+	return tx.Commit()
+}
+
+// ListQueryHistory returns a user's query history for a database, newest first
+func (s *PostgresStorage) ListQueryHistory(databaseID, userID string) []*QueryHistoryEntry {
+	entries := listRecords[QueryHistoryEntry](s, `SELECT data FROM query_history
+		WHERE database_id = $1 AND user_id = $2 ORDER BY ts DESC, id DESC`, databaseID, userID)
+	if entries == nil {
+		entries = []*QueryHistoryEntry{}
+	}
+	return entries
+}
+
+// DeleteQueryHistory clears a user's query history for a database, or every
+// user's when userID is empty
+func (s *PostgresStorage) DeleteQueryHistory(databaseID, userID string) error {
+	_, err := s.db.Exec(`DELETE FROM query_history WHERE database_id = $1 AND ($2 = '' OR user_id = $2)`,
+		databaseID, userID)
+	return err
+}
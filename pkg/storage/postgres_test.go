@@ -0,0 +1,58 @@
+package storage
+
+import (
+	"os"
+	"testing"
+)
+
+// postgresTestDSN names the environment variable holding the DSN of a
+// PostgreSQL database to run the postgres tests against. They are skipped
+// when it is unset. The tests empty every DBnest table in that database.
+const postgresTestDSN = "DBNEST_TEST_POSTGRES_DSN"
+
+// openPostgresTest empties the test database and returns a function opening
+// a store on it
+func openPostgresTest(t *testing.T) func() Storage {
+	dsn := os.Getenv(postgresTestDSN)
+	if dsn == "" {
+		t.Skipf("%s is not set", postgresTestDSN)
+	}
+	tmpDir := t.TempDir()
+	open := func() Storage {
+		store, err := NewPostgresStorage(dsn, tmpDir)
+		if err != nil {
+			t.Fatalf("failed to open test storage: %v", err)
+		}
+		return store
+	}
+
+	store := open().(*PostgresStorage)
+	defer store.Close()
+	if _, err := store.db.Exec(`TRUNCATE databases, backups, users, sessions, settings,
+		api_keys, metrics, audit_events, query_history`); err != nil {
+		t.Fatalf("failed to empty test database: %v", err)
+	}
+	return open
+}
+
+func TestPostgresRecordCRUD(t *testing.T) {
+	store := openPostgresTest(t)()
+	defer store.Close()
+	testRecordCRUD(t, store)
+}
+
+func TestPostgresAuthLookupIndexes(t *testing.T) {
+	testAuthLookupIndexes(t, openPostgresTest(t))
+}
+
+func TestPostgresMigrationsAreIdempotent(t *testing.T) {
+	open := openPostgresTest(t)
+	open().Close()
+	// Reopening finds every migration applied and runs none again
+	store := open()
+	defer store.Close()
+	var version int
+	if err := store.(*PostgresStorage).db.QueryRow(`SELECT MAX(version) FROM schema_migrations`).Scan(&version); err != nil || version != len(postgresMigrations) {
+		t.Errorf("expected schema version %d, got %d (%v)", len(postgresMigrations), version, err)
+	}
+}
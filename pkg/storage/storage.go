@@ -1,6 +1,7 @@
 package storage
 
 import (
+	"fmt"
 	"time"
 )
 
@@ -10,6 +11,8 @@ type DatabaseInstance struct {
 	Name           string    `json:"name" msgpack:"name"`
 	Engine         string    `json:"engine" msgpack:"engine"`
 	Version        string    `json:"version" msgpack:"version"`
+	Image          string    `json:"image,omitempty" msgpack:"image"`                 // Custom image overriding the engine default
+	HostDataPath   string    `json:"hostDataPath,omitempty" msgpack:"host_data_path"` // Host directory bind-mounted instead of a named volume
 	Status         string    `json:"status" msgpack:"status"`
 	Host           string    `json:"host" msgpack:"host"`
 	Port           int       `json:"port" msgpack:"port"`
@@ -29,12 +32,56 @@ type DatabaseInstance struct {
 	// Container networking options
 	ExposePort bool   `json:"exposePort" msgpack:"expose_port"`    // Whether to expose port to host
 	Network    string `json:"network,omitempty" msgpack:"network"` // Docker network name
+	// BindAddress is the host IP the port is published on; empty means all IPv4 interfaces
+	BindAddress string `json:"bindAddress,omitempty" msgpack:"bind_address"`
+
+	// ExtraEnv holds additional container env vars supplied at creation
+	ExtraEnv map[string]string `json:"extraEnv,omitempty" msgpack:"extra_env"`
+	// ConfigParams are engine server settings (e.g. shared_buffers) passed as
+	// command-line flags whenever the container is created
+	ConfigParams map[string]string `json:"configParams,omitempty" msgpack:"config_params"`
+	// RestartPolicy is the container restart policy; empty means the runtime default
+	RestartPolicy string `json:"restartPolicy,omitempty" msgpack:"restart_policy"`
+	// RunAsUser and RunAsGroup are the IDs the container runs as; zero means the image's user
+	RunAsUser  int `json:"runAsUser,omitempty" msgpack:"run_as_user"`
+	RunAsGroup int `json:"runAsGroup,omitempty" msgpack:"run_as_group"`
+	// UsernsMode is the container's user namespace mode; empty means the runtime default
+	UsernsMode string `json:"usernsMode,omitempty" msgpack:"userns_mode"`
+	// ReadOnlyRootfs mounts the container's root filesystem read-only
+	ReadOnlyRootfs bool `json:"readOnlyRootfs,omitempty" msgpack:"read_only_rootfs"`
+	// RedisBackupMode selects RDB snapshots or AOF for Redis backups; empty means RDB
+	RedisBackupMode string `json:"redisBackupMode,omitempty" msgpack:"redis_backup_mode"`
+	// RedisDB is the logical Redis database index used by queries and the CLI
+	RedisDB int `json:"redisDb" msgpack:"redis_db"`
+	// Labels are extra container labels supplied at creation
+	Labels map[string]string `json:"labels,omitempty" msgpack:"labels"`
+	// ClonedFrom is the ID of the database this one was cloned from
+	ClonedFrom string `json:"clonedFrom,omitempty" msgpack:"cloned_from"`
+	// ImageDigest pins the image by registry digest (sha256:...). Set from the
+	// request or resolved after the first pull, so repairs reuse the exact image.
+	ImageDigest string `json:"imageDigest,omitempty" msgpack:"image_digest"`
 
 	// Backup scheduling fields (per-database)
 	BackupEnabled        bool       `json:"backupEnabled" msgpack:"backup_enabled"`
 	BackupSchedule       string     `json:"backupSchedule,omitempty" msgpack:"backup_schedule"`    // cron expression e.g. "0 2 * * *"
 	BackupRetentionCount int        `json:"backupRetentionCount" msgpack:"backup_retention_count"` // keep last N backups
 	LastBackupAt         *time.Time `json:"lastBackupAt,omitempty" msgpack:"last_backup_at"`
+	// LastBackup is the outcome of the most recent scheduled backup run
+	LastBackup *BackupResult `json:"lastBackup,omitempty" msgpack:"last_backup"`
+	// BackupSkipUnchanged skips scheduled backups while the engine's change
+	// marker (WAL position, binlog position, Redis save state) stays at
+	// LastChangeMarker, the value seen before the last scheduled backup
+	BackupSkipUnchanged bool   `json:"backupSkipUnchanged" msgpack:"backup_skip_unchanged"`
+	LastChangeMarker    string `json:"lastChangeMarker,omitempty" msgpack:"last_change_marker"`
+}
+
+// BackupResult records how a scheduled backup run ended
+type BackupResult struct {
+	Success  bool      `json:"success" msgpack:"success"`
+	BackupID string    `json:"backupId,omitempty" msgpack:"backup_id"`
+	Error    string    `json:"error,omitempty" msgpack:"error"`
+	Attempts int       `json:"attempts" msgpack:"attempts"`
+	At       time.Time `json:"at" msgpack:"at"`
 }
 
 // Backup represents a database backup
@@ -46,6 +93,31 @@ type Backup struct {
 	Size         int64     `json:"size" msgpack:"size"` // bytes
 	Status       string    `json:"status" msgpack:"status"`
 	FilePath     string    `json:"-" msgpack:"file_path"`
+	// Tables lists the tables a partial backup contains; empty for a full backup
+	Tables []string `json:"tables,omitempty" msgpack:"tables"`
+	// Encrypted is set when the backup file is encrypted with the backup key
+	Encrypted bool `json:"encrypted" msgpack:"encrypted"`
+	// Checksum is the hex SHA-256 of the backup file as written, checked
+	// before a restore. Empty for backups taken before checksums were added.
+	Checksum string `json:"checksum,omitempty" msgpack:"checksum"`
+	// Error is why a failed backup failed
+	Error string `json:"error,omitempty" msgpack:"error"`
+	// Attempts is how many tries the scheduled run that made this backup
+	// took. Zero for manual backups.
+	Attempts int `json:"attempts,omitempty" msgpack:"attempts"`
+}
+
+// MetricsPoint represents a single metrics snapshot for a database
+type MetricsPoint struct {
+	Timestamp     time.Time `json:"timestamp" msgpack:"timestamp"`
+	CPUPercent    float64   `json:"cpuPercent" msgpack:"cpu_percent"`
+	MemoryUsage   int64     `json:"memoryUsage" msgpack:"memory_usage"`
+	MemoryLimit   int64     `json:"memoryLimit" msgpack:"memory_limit"`
+	MemoryPercent float64   `json:"memoryPercent" msgpack:"memory_percent"`
+	StorageUsed   int64     `json:"storageUsed" msgpack:"storage_used"`
+	Connections   int       `json:"connections" msgpack:"connections"`
+	NetworkRx     int64     `json:"networkRx" msgpack:"network_rx"`
+	NetworkTx     int64     `json:"networkTx" msgpack:"network_tx"`
 }
 
 // User represents an authenticated user
@@ -65,6 +137,52 @@ type Session struct {
 	CreatedAt time.Time `json:"createdAt" msgpack:"created_at"`
 }
 
+// APIKey represents a long-lived API key used for programmatic access
+type APIKey struct {
+	ID         string     `json:"id" msgpack:"id"`
+	Name       string     `json:"name" msgpack:"name"`
+	UserID     string     `json:"userId" msgpack:"user_id"`
+	Role       string     `json:"role" msgpack:"role"`
+	Prefix     string     `json:"prefix" msgpack:"prefix"` // First characters of the key, for identification
+	KeyHash    string     `json:"-" msgpack:"key_hash"`    // Never sent to frontend
+	CreatedAt  time.Time  `json:"createdAt" msgpack:"created_at"`
+	ExpiresAt  *time.Time `json:"expiresAt,omitempty" msgpack:"expires_at"`
+	LastUsedAt *time.Time `json:"lastUsedAt,omitempty" msgpack:"last_used_at"`
+}
+
+// AuditEvent records a mutating operation and who performed it
+type AuditEvent struct {
+	ID        string    `json:"id" msgpack:"id"`
+	Timestamp time.Time `json:"timestamp" msgpack:"timestamp"`
+	UserID    string    `json:"userId,omitempty" msgpack:"user_id"`
+	Username  string    `json:"username,omitempty" msgpack:"username"`
+	Action    string    `json:"action" msgpack:"action"` // e.g. "database.create"
+	TargetID  string    `json:"targetId,omitempty" msgpack:"target_id"`
+	Result    string    `json:"result" msgpack:"result"` // "success" or "failure"
+	Error     string    `json:"error,omitempty" msgpack:"error"`
+}
+
+// QueryHistoryEntry records a console query a user ran against a database
+type QueryHistoryEntry struct {
+	ID         string    `json:"id" msgpack:"id"`
+	DatabaseID string    `json:"databaseId" msgpack:"database_id"`
+	UserID     string    `json:"userId" msgpack:"user_id"`
+	Timestamp  time.Time `json:"timestamp" msgpack:"timestamp"`
+	Query      string    `json:"query" msgpack:"query"`
+	RowCount   int       `json:"rowCount" msgpack:"row_count"` // rows returned across all statements
+	Success    bool      `json:"success" msgpack:"success"`
+	Error      string    `json:"error,omitempty" msgpack:"error"`
+}
+
+// AuditFilter narrows an audit log query. Zero-valued fields match everything.
+type AuditFilter struct {
+	User     string // Matches username or user ID
+	TargetID string
+	Since    time.Time
+	Until    time.Time
+	Limit    int
+}
+
 // Storage defines the interface for data persistence
 type Storage interface {
 	Close() error
@@ -99,14 +217,56 @@ type Storage interface {
 	GetSession(id string) (*Session, error)
 	GetSessionByToken(token string) (*Session, error)
 	DeleteSession(id string) error
-	DeleteExpiredSessions() error
+	DeleteExpiredSessions() (int, error)
+
+	// API key operations
+	CreateAPIKey(key *APIKey) error
+	GetAPIKey(id string) (*APIKey, error)
+	GetAPIKeyByHash(hash string) (*APIKey, error)
+	ListAPIKeys() []*APIKey
+	UpdateAPIKey(key *APIKey) error
+	DeleteAPIKey(id string) error
+
+	// Metrics history operations
+	AddMetricsPoint(databaseID string, point *MetricsPoint) error
+	ListMetricsPoints(databaseID string, since time.Time) []*MetricsPoint
+	DeleteMetricsPoints(databaseID string) error
+	DeleteMetricsBefore(cutoff time.Time) (int, error)
+
+	// Audit log operations
+	AddAuditEvent(event *AuditEvent) error
+	ListAuditEvents(filter AuditFilter) []*AuditEvent
+
+	// Query history operations
+	AddQueryHistory(entry *QueryHistoryEntry, max int) error
+	ListQueryHistory(databaseID, userID string) []*QueryHistoryEntry
+	DeleteQueryHistory(databaseID, userID string) error
 
 	// Settings operations
 	GetSetting(key string) (string, error)
 	SetSetting(key, value string) error
 }
 
+// Storage backends
+const (
+	BackendBolt     = "bolt"
+	BackendPostgres = "postgres"
+)
+
 // New creates a new storage instance based on type
 func New(path, dataDir string) (Storage, error) {
 	return NewBoltStorage(path, dataDir)
 }
+
+// Open creates a storage instance for the given backend. path is the BoltDB
+// file and dsn the PostgreSQL connection string; each is used only by its backend.
+func Open(backend, path, dsn, dataDir string) (Storage, error) {
+	switch backend {
+	case "", BackendBolt:
+		return New(path, dataDir)
+	case BackendPostgres:
+		return NewPostgresStorage(dsn, dataDir)
+	default:
+		return nil, fmt.Errorf("unknown storage backend: %s (valid: bolt, postgres)", backend)
+	}
+}
@@ -0,0 +1,132 @@
+package storage
+
+import (
+	"testing"
+	"time"
+)
+
+// The tests below run against every backend; each backend's test file
+// passes them a fresh store.
+
+// testRecordCRUD covers creating, reading, updating and deleting records,
+// and the lookups by database ID and key hash
+func testRecordCRUD(t *testing.T, store Storage) {
+	store.CreateDatabase(&DatabaseInstance{ID: "db-1", Name: "app", Engine: "postgresql", Status: "creating"})
+	store.CreateDatabase(&DatabaseInstance{ID: "db-2", Name: "cache", Engine: "redis", Status: "running"})
+	if err := store.UpdateDatabase(&DatabaseInstance{ID: "db-1", Name: "app", Engine: "postgresql", Status: "running"}); err != nil {
+		t.Fatalf("UpdateDatabase failed: %v", err)
+	}
+	if db, err := store.GetDatabase("db-1"); err != nil || db.Status != "running" {
+		t.Errorf("expected updated database, got %v, %v", db, err)
+	}
+	if dbs := store.ListDatabases(); len(dbs) != 2 {
+		t.Errorf("expected 2 databases, got %d", len(dbs))
+	}
+
+	store.CreateBackup(&Backup{ID: "bk-1", DatabaseID: "db-1", Status: "in-progress", CreatedAt: time.Now()})
+	store.CreateBackup(&Backup{ID: "bk-2", DatabaseID: "db-1", Status: "completed", CreatedAt: time.Now()})
+	store.CreateBackup(&Backup{ID: "bk-3", DatabaseID: "db-2", Status: "completed", CreatedAt: time.Now()})
+	if backups := store.ListBackups("db-1"); len(backups) != 2 {
+		t.Errorf("expected 2 backups of db-1, got %d", len(backups))
+	}
+	if backups := store.ListBackups(""); len(backups) != 3 {
+		t.Errorf("expected 3 backups in total, got %d", len(backups))
+	}
+	store.UpdateBackup(&Backup{ID: "bk-1", DatabaseID: "db-1", Status: "failed", Error: "boom"})
+	if backup, err := store.GetBackup("bk-1"); err != nil || backup.Status != "failed" || backup.Error != "boom" {
+		t.Errorf("expected updated backup, got %v, %v", backup, err)
+	}
+	store.DeleteBackup("bk-1")
+	if _, err := store.GetBackup("bk-1"); err == nil {
+		t.Error("expected deleted backup to be gone")
+	}
+
+	store.CreateAPIKey(&APIKey{ID: "key-1", Name: "ci", Role: "admin", KeyHash: "hash-1"})
+	if key, err := store.GetAPIKeyByHash("hash-1"); err != nil || key.ID != "key-1" {
+		t.Errorf("expected key by hash, got %v, %v", key, err)
+	}
+	store.DeleteAPIKey("key-1")
+	if _, err := store.GetAPIKeyByHash("hash-1"); err == nil {
+		t.Error("expected deleted key hash to no longer resolve")
+	}
+
+	if _, err := store.GetSetting("jwt_secret"); err == nil {
+		t.Error("expected missing setting to be an error")
+	}
+	store.SetSetting("jwt_secret", "a")
+	store.SetSetting("jwt_secret", "b")
+	if value, err := store.GetSetting("jwt_secret"); err != nil || value != "b" {
+		t.Errorf("expected overwritten setting, got %q, %v", value, err)
+	}
+
+	if err := store.DeleteDatabase("db-2"); err != nil {
+		t.Fatalf("DeleteDatabase failed: %v", err)
+	}
+	if _, err := store.GetDatabase("db-2"); err == nil {
+		t.Error("expected deleted database to be gone")
+	}
+}
+
+// testAuthLookupIndexes checks the username and session token lookups follow
+// renames, expiry and deletes, and still resolve after reopening the store
+func testAuthLookupIndexes(t *testing.T, open func() Storage) {
+	store := open()
+
+	store.CreateUser(&User{ID: "u1", Username: "alice"})
+	store.CreateSession(&Session{ID: "s1", UserID: "u1", Token: "live", ExpiresAt: time.Now().Add(time.Hour)})
+	store.CreateSession(&Session{ID: "s2", UserID: "u1", Token: "stale", ExpiresAt: time.Now().Add(-time.Hour)})
+	store.CreateAPIKey(&APIKey{ID: "k1", UserID: "u1", KeyHash: "hash-1"})
+	store.CreateAPIKey(&APIKey{ID: "k2", UserID: "u1", KeyHash: "hash-2"})
+
+	// Renaming moves the index entry
+	store.UpdateUser(&User{ID: "u1", Username: "alice2"})
+	if _, err := store.GetUserByUsername("alice"); err == nil {
+		t.Error("expected old username to no longer resolve")
+	}
+	if u, err := store.GetUserByUsername("alice2"); err != nil || u.ID != "u1" {
+		t.Errorf("expected renamed user to resolve, got %v, %v", u, err)
+	}
+
+	if n, _ := store.DeleteExpiredSessions(); n != 1 {
+		t.Errorf("expected 1 expired session removed, got %d", n)
+	}
+	if _, err := store.GetSessionByToken("stale"); err == nil {
+		t.Error("expected expired session token to no longer resolve")
+	}
+
+	// Rotating a key's hash moves the index entry
+	store.UpdateAPIKey(&APIKey{ID: "k1", UserID: "u1", KeyHash: "hash-1b"})
+	if _, err := store.GetAPIKeyByHash("hash-1"); err == nil {
+		t.Error("expected old key hash to no longer resolve")
+	}
+	if k, err := store.GetAPIKeyByHash("hash-1b"); err != nil || k.ID != "k1" {
+		t.Errorf("expected rotated key to resolve, got %v, %v", k, err)
+	}
+	if _, err := store.GetAPIKeyByHash(""); err == nil {
+		t.Error("expected an empty key hash not to resolve")
+	}
+
+	// Indexes are rebuilt when the store is reopened
+	store.Close()
+	store = open()
+	defer store.Close()
+	if s, err := store.GetSessionByToken("live"); err != nil || s.ID != "s1" {
+		t.Errorf("expected session to resolve after reopen, got %v, %v", s, err)
+	}
+	if k, err := store.GetAPIKeyByHash("hash-2"); err != nil || k.ID != "k2" {
+		t.Errorf("expected API key to resolve after reopen, got %v, %v", k, err)
+	}
+
+	store.DeleteSession("s1")
+	if _, err := store.GetSessionByToken("live"); err == nil {
+		t.Error("expected deleted session token to no longer resolve")
+	}
+	store.DeleteAPIKey("k2")
+	if _, err := store.GetAPIKeyByHash("hash-2"); err == nil {
+		t.Error("expected deleted API key hash to no longer resolve")
+	}
+	store.DeleteUser("u1")
+	if _, err := store.GetUserByUsername("alice2"); err == nil {
+		t.Error("expected deleted user to no longer resolve")
+	}
+}
@@ -0,0 +1,37 @@
+// Package version holds the build information stamped into the binary.
+//
+// The values are set at build time with -ldflags, e.g.
+//
+//	go build -ldflags "-X github.com/sirrobot01/dbnest/pkg/version.Version=v1.2.0" ./cmd/dbnest
+//
+// and keep their defaults in development builds.
+package version
+
+import "runtime"
+
+var (
+	// Version is the release version, e.g. v1.2.0
+	Version = "dev"
+	// Commit is the git commit the binary was built from
+	Commit = "unknown"
+	// Date is when the binary was built, in RFC 3339
+	Date = "unknown"
+)
+
+// Info describes the running build
+type Info struct {
+	Version   string `json:"version"`
+	Commit    string `json:"commit"`
+	Date      string `json:"date"`
+	GoVersion string `json:"goVersion"`
+}
+
+// Get returns the build information of the running binary
+func Get() Info {
+	return Info{
+		Version:   Version,
+		Commit:    Commit,
+		Date:      Date,
+		GoVersion: runtime.Version(),
+	}
+}